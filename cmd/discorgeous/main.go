@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"log/slog"
 	"os"
 	"os/signal"
+	"sort"
 	"syscall"
 	"time"
 
@@ -12,12 +14,25 @@ import (
 	"github.com/dgnsrekt/discorgeous-go/internal/config"
 	"github.com/dgnsrekt/discorgeous-go/internal/discord"
 	"github.com/dgnsrekt/discorgeous-go/internal/logging"
+	"github.com/dgnsrekt/discorgeous-go/internal/metrics/prom"
 	"github.com/dgnsrekt/discorgeous-go/internal/playback"
 	"github.com/dgnsrekt/discorgeous-go/internal/queue"
 	"github.com/dgnsrekt/discorgeous-go/internal/tts"
 )
 
+// startupJoinTimeout bounds the JOIN_ON_START connection attempt.
+const startupJoinTimeout = 30 * time.Second
+
 func main() {
+	// ENV_FILE settings override the environment, and are re-read on SIGHUP
+	envFile := os.Getenv("ENV_FILE")
+	if envFile != "" {
+		if err := config.LoadEnvFile(envFile); err != nil {
+			os.Stderr.WriteString("failed to load env file: " + err.Error() + "\n")
+			os.Exit(1)
+		}
+	}
+
 	// Load configuration from environment
 	cfg, err := config.Load()
 	if err != nil {
@@ -26,12 +41,14 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Initialize structured logger
-	logger := logging.New(cfg.LogLevel, cfg.LogFormat)
+	// Initialize structured logger; SIGHUP can change its level
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(logging.ParseLevel(cfg.LogLevel))
+	logger := logging.NewWithLevel(logLevel, cfg.LogFormat)
 	logger.Info("starting discorgeous", "version", "0.1.0")
 
 	// Warn if bearer token auth is disabled
-	if cfg.AuthDisabled() {
+	if cfg.AuthDisabled() && cfg.TLSClientCA == "" {
 		logger.Warn("HTTP bearer authentication is disabled (BEARER_TOKEN is empty)")
 	}
 
@@ -41,6 +58,7 @@ func main() {
 		"log_format", cfg.LogFormat,
 		"http_port", cfg.HTTPPort,
 		"auto_leave_idle", cfg.AutoLeaveIdle,
+		"stay_connected", cfg.StayConnected,
 		"max_text_length", cfg.MaxTextLength,
 		"queue_capacity", cfg.QueueCapacity,
 	)
@@ -58,60 +76,138 @@ func main() {
 		cancel()
 	}()
 
+	// Core packages report to this; it is served at /v1/metrics
+	promMetrics := prom.New("discorgeous", logger)
+
 	// Initialize TTS engine registry with Piper
 	ttsRegistry := tts.NewRegistry()
+	ttsRegistry.SetMetrics(promMetrics)
 	if cfg.PiperModel != "" {
-		piperCfg := tts.PiperConfig{
-			BinaryPath:   cfg.PiperPath,
-			ModelPath:    cfg.PiperModel,
-			DefaultVoice: cfg.DefaultVoice,
+		registerPiper(ttsRegistry, cfg, "", cfg.PiperModel, logger)
+	}
+
+	// Register named models in a stable order so the default is predictable
+	modelNames := make([]string, 0, len(cfg.PiperModels))
+	for name := range cfg.PiperModels {
+		modelNames = append(modelNames, name)
+	}
+	sort.Strings(modelNames)
+	for _, name := range modelNames {
+		modelPath := cfg.PiperModels[name]
+		if _, err := os.Stat(modelPath); err != nil {
+			logger.Warn("skipping Piper model", "name", name, "model", modelPath, "error", err)
+			continue
 		}
-		piperEngine, err := tts.NewPiperEngine(piperCfg, logger)
-		if err != nil {
-			logger.Warn("failed to initialize Piper TTS", "error", err)
-		} else {
-			if err := ttsRegistry.Register(piperEngine); err != nil {
-				logger.Warn("failed to register Piper TTS", "error", err)
-			} else {
-				logger.Info("Piper TTS engine registered", "model", cfg.PiperModel)
-			}
+		registerPiper(ttsRegistry, cfg, name, modelPath, logger)
+	}
+
+	if cfg.TTSCommand != "" {
+		registerCommand(ttsRegistry, cfg, logger)
+	}
+
+	if cfg.PollyRegion != "" {
+		registerPolly(ctx, ttsRegistry, cfg, logger)
+	}
+
+	if cfg.GoogleTTSCreds != "" {
+		registerGoogle(ctx, ttsRegistry, cfg, logger)
+	}
+
+	if len(ttsRegistry.List()) == 0 {
+		logger.Warn("no TTS engine configured, TTS will not work")
+	}
+
+	for lang, name := range cfg.LangEngines {
+		if err := ttsRegistry.SetLanguageEngine(lang, name); err != nil {
+			logger.Warn("skipping language mapping", "language", lang, "engine", name, "error", err)
 		}
-	} else {
-		logger.Warn("no Piper model configured, TTS will not work")
 	}
 
 	// Initialize audio converter
 	audioConv, err := audio.NewConverter()
 	if err != nil {
 		logger.Warn("ffmpeg not available, audio conversion will fail", "error", err)
+	} else {
+		audioConv.SetMetrics(promMetrics)
+		audioConv.SetFastResample(cfg.FastResample)
+		audioConv.SetMaxOutputBytes(cfg.MaxSynthBytes)
 	}
 
-	// Initialize Discord voice manager
+	// Initialize Discord voice manager, unless audio goes to a file sink
 	var voiceManager *discord.VoiceManager
-	if cfg.DiscordToken != "" && cfg.GuildID != "" && cfg.DefaultVoiceChannelID != "" {
+	var sink *playback.FileSink
+	joinedOnStart := false
+	if path := cfg.PlaybackSinkFile(); path != "" {
+		sink, err = playback.NewFileSink(path)
+		if err != nil {
+			logger.Error("failed to open playback sink", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("writing audio to playback sink instead of Discord", "path", path)
+	} else if cfg.DiscordToken != "" && cfg.GuildID != "" && cfg.DefaultVoiceChannelID != "" {
 		voiceManager, err = discord.NewVoiceManager(
 			cfg.DiscordToken,
 			cfg.GuildID,
 			cfg.DefaultVoiceChannelID,
+			discord.OpusConfig{
+				Application: cfg.OpusApplication,
+				Bitrate:     cfg.OpusBitrate,
+			},
+			discord.ConnectConfig{
+				Timeout:      cfg.VoiceConnectTimeout,
+				Retries:      cfg.VoiceConnectRetries,
+				RetryDelay:   cfg.VoiceConnectRetryDelay,
+				PollInterval: cfg.VoiceConnectPoll,
+			},
 			logger,
 		)
 		if err != nil {
 			logger.Error("failed to create voice manager", "error", err)
 			os.Exit(1)
 		}
+		voiceManager.SetTrimSilence(cfg.TrimSilence)
+		voiceManager.SetKeepalive(cfg.VoiceKeepalive)
+		voiceManager.SetMaxAudioDuration(time.Duration(cfg.MaxAudioSeconds) * time.Second)
+		if cfg.FollowUserID != "" {
+			voiceManager.SetFollowUser(cfg.FollowUserID)
+		}
 
 		if err := voiceManager.Open(); err != nil {
 			logger.Error("failed to open Discord session", "error", err)
 			os.Exit(1)
 		}
-		defer voiceManager.Close()
 		logger.Info("Discord session opened")
+
+		// Join up front so the first message doesn't wait on the connection;
+		// the idle timeout still applies unless staying connected. On failure
+		// we join lazily as usual.
+		if cfg.JoinOnStart || cfg.StayConnected {
+			joinCtx, joinCancel := context.WithTimeout(ctx, startupJoinTimeout)
+			if err := voiceManager.Connect(joinCtx); err != nil {
+				logger.Warn("failed to join voice channel on start, will join on first message", "error", err)
+			} else {
+				joinedOnStart = true
+			}
+			joinCancel()
+		}
 	} else {
 		logger.Warn("Discord credentials not configured, voice will not work")
 	}
 
 	// Create and start the speech queue
 	speechQueue := queue.NewQueue(cfg.QueueCapacity, cfg.AutoLeaveIdle, logger)
+	speechQueue.SetMetrics(promMetrics)
+	speechQueue.SetHighWater(cfg.QueueWarnDepth)
+	speechQueue.SetHistorySize(cfg.HistorySize)
+	var deadLetter *queue.DeadLetterFile
+	if cfg.DeadLetterPath != "" {
+		deadLetter, err = queue.NewDeadLetterFile(cfg.DeadLetterPath, logger)
+		if err != nil {
+			logger.Error("failed to open dead-letter file", "error", err)
+			os.Exit(1)
+		}
+		speechQueue.SetDeadLetter(deadLetter)
+	}
 
 	// Set idle callback to disconnect from voice
 	speechQueue.SetIdleCallback(func() {
@@ -123,23 +219,53 @@ func main() {
 		}
 	})
 
-	// Set shutdown callback to disconnect from voice during graceful shutdown
-	speechQueue.SetShutdownCallback(func() {
-		logger.Info("shutdown: disconnecting from voice channel if connected")
-		if voiceManager != nil && voiceManager.IsConnected() {
-			if err := voiceManager.Disconnect(); err != nil {
-				logger.Error("failed to disconnect from voice during shutdown", "error", err)
-			} else {
-				logger.Info("disconnected from voice channel during shutdown")
-			}
-		}
-	})
+	speechQueue.SetIdleDelay(cfg.DisconnectDelay, cfg.MinConnected)
+	speechQueue.SetStayConnected(cfg.StayConnected)
 
-	// Set playback handler
+	// Keep speaking on across back-to-back jobs that are ready to play;
+	// clear it once the run ends and the speaking hold has passed
+	if voiceManager != nil {
+		voiceManager.SetCoalesceSpeaking(cfg.CoalescePlayback)
+		voiceManager.SetSpeakingHold(time.Duration(cfg.SpeakingHoldMS) * time.Millisecond)
+		speechQueue.SetCoalescePlayback(cfg.CoalescePlayback)
+		speechQueue.SetDrainedCallback(voiceManager.StopSpeaking)
+	}
+	speechQueue.SetInterruptMode(queue.InterruptMode(cfg.InterruptMode), cfg.InterruptGrace)
+	if start, end, loc, ok := cfg.QuietWindow(); ok {
+		logger.Info("quiet hours enabled", "window", cfg.QuietHours, "timezone", loc.String(), "mode", cfg.QuietMode)
+		speechQueue.SetQuietHours(start, end, loc, queue.QuietMode(cfg.QuietMode))
+	}
+
+	// Create the synthesis pipeline; playback additionally needs Discord voice
+	var handler *playback.Handler
+	// Keep a nil manager or sink a nil interface, not a typed nil
+	var voice playback.VoiceSender
+	switch {
+	case sink != nil:
+		voice = sink
+	case voiceManager != nil:
+		voice = voiceManager
+	}
 	defaultEngine, _ := ttsRegistry.Default()
-	if voiceManager != nil && audioConv != nil && defaultEngine != nil {
-		handler := playback.NewHandler(ttsRegistry, audioConv, voiceManager, logger)
-		speechQueue.SetPlaybackHandler(handler.Handle)
+	if audioConv != nil && defaultEngine != nil {
+		handler = playback.NewHandler(ttsRegistry, audioConv, voice, logger)
+		handler.SetStreaming(cfg.PiperStreaming)
+		handler.SetNormalizeText(cfg.NormalizeText)
+		handler.SetStripMarkdown(cfg.StripMarkdown)
+		handler.SetEmojiMode(tts.EmojiMode(cfg.EmojiMode))
+		handler.SetRedactor(tts.NewRedactor(cfg.RedactWords, cfg.RedactWith))
+		handler.SetDetectLanguage(cfg.AutodetectLang)
+		handler.SetSynthesisTimeout(cfg.SynthTimeout)
+		handler.SetDropIfDisconnected(cfg.DropIfDisconnected)
+		handler.SetSilencePadding(time.Duration(cfg.LeadSilenceMS)*time.Millisecond, time.Duration(cfg.TrailSilenceMS)*time.Millisecond)
+		handler.SetChimePath(cfg.NotifyChimePath)
+		handler.SetRecordDir(cfg.RecordDir)
+	}
+
+	// Set playback handler
+	if handler != nil && voice != nil {
+		speechQueue.SetPreparer(handler)
+		speechQueue.SetRetryPolicy(cfg.MaxRetries, cfg.RetryDelay, playback.IsTransient)
 		logger.Info("audio pipeline ready")
 	} else {
 		// Fallback handler for when not all components are available
@@ -154,13 +280,42 @@ func main() {
 	}
 
 	speechQueue.Start()
-	defer speechQueue.Stop()
 
 	// Create and start HTTP server
 	server := api.New(cfg, logger, speechQueue)
+	server.SetVoices(ttsRegistry)
+	server.SetJoinedOnStart(joinedOnStart)
+	server.SetMetricsHandler(promMetrics.Handler())
+	if handler != nil {
+		server.SetSynthesizer(handler)
+	}
+	if voiceManager != nil {
+		server.SetVoiceConnection(voiceManager)
+	}
 
+	// SIGHUP reloads the settings that are safe to change while running
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
 	go func() {
-		if err := server.Start(); err != nil {
+		current := *cfg
+		targets := reloadTargets{logLevel: logLevel, voices: server}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hupCh:
+				logger.Info("received SIGHUP, reloading configuration")
+				reload(envFile, &current, targets, logger)
+			}
+		}
+	}()
+
+	go func() {
+		start := server.Start
+		if cfg.TLSEnabled() {
+			start = server.StartTLS
+		}
+		if err := start(); err != nil {
 			logger.Error("HTTP server error", "error", err)
 			cancel()
 		}
@@ -173,9 +328,109 @@ func main() {
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer shutdownCancel()
 
-	if err := server.Shutdown(shutdownCtx); err != nil {
-		logger.Error("failed to shutdown HTTP server", "error", err)
+	steps := shutdownSteps{server: server, queue: speechQueue}
+	if deadLetter != nil {
+		steps.deadLetter = deadLetter
+	}
+	if handler != nil {
+		steps.recorder = handler
+	}
+	if sink != nil {
+		steps.sink = sink
+	}
+	if voiceManager != nil {
+		steps.voice = voiceManager
+	}
+	if err := shutdown(shutdownCtx, steps, logger); err != nil {
+		logger.Error("shutdown finished with errors", "error", err)
+		os.Exit(1)
 	}
 
 	logger.Info("shutdown complete")
 }
+
+// registerPiper creates a Piper engine for modelPath and registers it under
+// name, or under the engine's default name if name is empty.
+func registerPiper(registry *tts.Registry, cfg *config.Config, name, modelPath string, logger *slog.Logger) {
+	piperEngine, err := tts.NewPiperEngine(tts.PiperConfig{
+		Name:           name,
+		BinaryPath:     cfg.PiperPath,
+		ModelPath:      modelPath,
+		DefaultVoice:   cfg.DefaultVoice,
+		SampleRate:     cfg.PiperSampleRate,
+		MaxOutputBytes: cfg.MaxSynthBytes,
+	}, logger)
+	if err != nil {
+		logger.Warn("failed to initialize Piper TTS", "model", modelPath, "error", err)
+		return
+	}
+
+	if err := registry.Register(tts.Limit(piperEngine, cfg.PiperMaxSynth)); err != nil {
+		logger.Warn("failed to register Piper TTS", "name", piperEngine.Name(), "error", err)
+		return
+	}
+
+	logger.Info("Piper TTS engine registered", "name", piperEngine.Name(), "model", modelPath)
+}
+
+// registerCommand creates the TTS_COMMAND engine and registers it.
+func registerCommand(registry *tts.Registry, cfg *config.Config, logger *slog.Logger) {
+	commandEngine, err := tts.NewCommandEngine(tts.CommandConfig{
+		Command:        cfg.TTSCommand,
+		DefaultVoice:   cfg.DefaultVoice,
+		SampleRate:     cfg.TTSCommandRate,
+		MaxOutputBytes: cfg.MaxSynthBytes,
+	}, logger)
+	if err != nil {
+		logger.Warn("failed to initialize command TTS", "error", err)
+		return
+	}
+
+	// The command may well call a remote service, so it shares the cloud limit
+	if err := registry.Register(tts.Limit(commandEngine, cfg.CloudMaxSynth)); err != nil {
+		logger.Warn("failed to register command TTS", "name", commandEngine.Name(), "error", err)
+		return
+	}
+
+	logger.Info("command TTS engine registered", "name", commandEngine.Name())
+}
+
+// registerPolly creates the AWS Polly engine and registers it. It is
+// skipped with a warning if no AWS credentials are available.
+func registerPolly(ctx context.Context, registry *tts.Registry, cfg *config.Config, logger *slog.Logger) {
+	pollyEngine, err := tts.NewPollyEngine(ctx, tts.PollyConfig{
+		Region:       cfg.PollyRegion,
+		DefaultVoice: cfg.PollyVoice,
+		Engine:       cfg.PollyEngine,
+	}, logger)
+	if err != nil {
+		logger.Warn("failed to initialize Polly TTS", "region", cfg.PollyRegion, "error", err)
+		return
+	}
+
+	if err := registry.Register(tts.Limit(pollyEngine, cfg.CloudMaxSynth)); err != nil {
+		logger.Warn("failed to register Polly TTS", "name", pollyEngine.Name(), "error", err)
+		return
+	}
+
+	logger.Info("Polly TTS engine registered", "name", pollyEngine.Name(), "region", cfg.PollyRegion)
+}
+
+// registerGoogle creates the Google Cloud TTS engine and registers it.
+func registerGoogle(ctx context.Context, registry *tts.Registry, cfg *config.Config, logger *slog.Logger) {
+	googleEngine, err := tts.NewGoogleEngine(ctx, tts.GoogleConfig{
+		CredentialsFile: cfg.GoogleTTSCreds,
+		DefaultVoice:    cfg.GoogleTTSVoice,
+	}, logger)
+	if err != nil {
+		logger.Warn("failed to initialize Google TTS", "credentials", cfg.GoogleTTSCreds, "error", err)
+		return
+	}
+
+	if err := registry.Register(tts.Limit(googleEngine, cfg.CloudMaxSynth)); err != nil {
+		logger.Warn("failed to register Google TTS", "name", googleEngine.Name(), "error", err)
+		return
+	}
+
+	logger.Info("Google TTS engine registered", "name", googleEngine.Name())
+}
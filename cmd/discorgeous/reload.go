@@ -0,0 +1,99 @@
+package main
+
+import (
+	"log/slog"
+	"maps"
+	"reflect"
+
+	"github.com/dgnsrekt/discorgeous-go/internal/config"
+	"github.com/dgnsrekt/discorgeous-go/internal/logging"
+)
+
+// voiceSettings takes reloaded DEFAULT_VOICE and VOICE_ALIASES values;
+// *api.Server implements it.
+type voiceSettings interface {
+	SetVoiceSettings(defaultVoice string, aliases map[string]config.VoiceAlias)
+}
+
+// reloadTargets holds what a reload updates. Nil fields are skipped.
+type reloadTargets struct {
+	logLevel *slog.LevelVar
+	voices   voiceSettings
+}
+
+// reloadableFields are the Config fields a reload applies while running.
+// Changes to any other field take effect on the next restart.
+var reloadableFields = map[string]bool{
+	"LogLevel":     true,
+	"DefaultVoice": true,
+	"VoiceAliases": true,
+}
+
+// applyReload applies the settings in next that are safe to change at
+// runtime and differ from current, then updates current to match. It
+// returns the names of the fields it applied and of those that changed but
+// need a restart, which are left alone. Values of the latter aren't logged
+// as some are secrets.
+func applyReload(current, next *config.Config, t reloadTargets, logger *slog.Logger) (applied, ignored []string) {
+	if next.LogLevel != current.LogLevel {
+		logger.Info("reloaded setting", "setting", "LogLevel", "old", current.LogLevel, "new", next.LogLevel)
+		if t.logLevel != nil {
+			t.logLevel.Set(logging.ParseLevel(next.LogLevel))
+		}
+		current.LogLevel = next.LogLevel
+		applied = append(applied, "LogLevel")
+	}
+
+	voiceChanged := false
+	if next.DefaultVoice != current.DefaultVoice {
+		logger.Info("reloaded setting", "setting", "DefaultVoice", "old", current.DefaultVoice, "new", next.DefaultVoice)
+		current.DefaultVoice = next.DefaultVoice
+		applied = append(applied, "DefaultVoice")
+		voiceChanged = true
+	}
+	if !maps.Equal(next.VoiceAliases, current.VoiceAliases) {
+		logger.Info("reloaded setting", "setting", "VoiceAliases", "aliases", len(next.VoiceAliases))
+		current.VoiceAliases = next.VoiceAliases
+		applied = append(applied, "VoiceAliases")
+		voiceChanged = true
+	}
+	if voiceChanged && t.voices != nil {
+		t.voices.SetVoiceSettings(current.DefaultVoice, current.VoiceAliases)
+	}
+
+	cur := reflect.ValueOf(current).Elem()
+	nxt := reflect.ValueOf(next).Elem()
+	for i := range cur.NumField() {
+		field := cur.Type().Field(i)
+		if !field.IsExported() || reloadableFields[field.Name] {
+			continue
+		}
+		if !reflect.DeepEqual(cur.Field(i).Interface(), nxt.Field(i).Interface()) {
+			ignored = append(ignored, field.Name)
+		}
+	}
+	if len(ignored) > 0 {
+		logger.Warn("changed settings need a restart to take effect", "settings", ignored)
+	}
+
+	return applied, ignored
+}
+
+// reload re-reads envFile, if set, and the configuration and applies it
+// with applyReload. If either doesn't load, nothing changes.
+func reload(envFile string, current *config.Config, t reloadTargets, logger *slog.Logger) {
+	if envFile != "" {
+		if err := config.LoadEnvFile(envFile); err != nil {
+			logger.Error("reload failed, keeping current configuration", "error", err)
+			return
+		}
+	}
+	next, err := config.Load()
+	if err != nil {
+		logger.Error("reload failed, keeping current configuration", "error", err)
+		return
+	}
+
+	applied, _ := applyReload(current, next, t, logger)
+	logger.Info("configuration reloaded", "applied", len(applied))
+}
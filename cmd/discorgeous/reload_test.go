@@ -0,0 +1,84 @@
+package main
+
+import (
+	"log/slog"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/dgnsrekt/discorgeous-go/internal/config"
+)
+
+// fakeVoiceSettings records the last SetVoiceSettings call.
+type fakeVoiceSettings struct {
+	calls   int
+	voice   string
+	aliases map[string]config.VoiceAlias
+}
+
+func (f *fakeVoiceSettings) SetVoiceSettings(defaultVoice string, aliases map[string]config.VoiceAlias) {
+	f.calls++
+	f.voice = defaultVoice
+	f.aliases = aliases
+}
+
+func TestApplyReload(t *testing.T) {
+	narrator := map[string]config.VoiceAlias{"narrator": {Voice: "bob"}}
+
+	tests := []struct {
+		name        string
+		change      func(c *config.Config)
+		wantApplied []string
+		wantIgnored []string
+		wantLevel   slog.Level
+		wantVoice   string
+		wantCalls   int
+	}{
+		{"nothing changed", func(c *config.Config) {}, nil, nil, slog.LevelInfo, "", 0},
+		{"log level", func(c *config.Config) { c.LogLevel = "debug" }, []string{"LogLevel"}, nil, slog.LevelDebug, "", 0},
+		{"default voice", func(c *config.Config) { c.DefaultVoice = "amy" }, []string{"DefaultVoice"}, nil, slog.LevelInfo, "amy", 1},
+		{"voice aliases", func(c *config.Config) { c.VoiceAliases = narrator }, []string{"VoiceAliases"}, nil, slog.LevelInfo, "default", 1},
+		{"restart only", func(c *config.Config) {
+			c.HTTPPort = 9090
+			c.DiscordToken = "new-token"
+		}, nil, []string{"DiscordToken", "HTTPPort"}, slog.LevelInfo, "", 0},
+		{"mixed", func(c *config.Config) {
+			c.LogLevel = "warn"
+			c.DefaultVoice = "amy"
+			c.QueueCapacity = 5
+		}, []string{"LogLevel", "DefaultVoice"}, []string{"QueueCapacity"}, slog.LevelWarn, "amy", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := &config.Config{HTTPPort: 8080, QueueCapacity: 100, LogLevel: "info", DefaultVoice: "default", DiscordToken: "token"}
+			next := *current
+			tt.change(&next)
+
+			level := new(slog.LevelVar)
+			voices := &fakeVoiceSettings{}
+			applied, ignored := applyReload(current, &next, reloadTargets{logLevel: level, voices: voices}, testLogger())
+
+			if !slices.Equal(applied, tt.wantApplied) {
+				t.Errorf("applied = %v, want %v", applied, tt.wantApplied)
+			}
+			if !slices.Equal(ignored, tt.wantIgnored) {
+				t.Errorf("ignored = %v, want %v", ignored, tt.wantIgnored)
+			}
+			if level.Level() != tt.wantLevel {
+				t.Errorf("log level = %v, want %v", level.Level(), tt.wantLevel)
+			}
+			if voices.calls != tt.wantCalls || voices.voice != tt.wantVoice {
+				t.Errorf("SetVoiceSettings calls = %d with voice %q, want %d with %q", voices.calls, voices.voice, tt.wantCalls, tt.wantVoice)
+			}
+
+			// Applied settings are recorded; restart-only ones are not
+			if current.LogLevel != next.LogLevel || current.DefaultVoice != next.DefaultVoice || !maps.Equal(current.VoiceAliases, next.VoiceAliases) {
+				t.Errorf("current = %+v, want the reloadable settings of next", current)
+			}
+			if tt.wantIgnored != nil && current.HTTPPort == next.HTTPPort && current.QueueCapacity == next.QueueCapacity {
+				t.Error("restart-only settings were copied into current")
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+)
+
+// httpShutdowner stops the HTTP server from accepting requests.
+type httpShutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+// queueStopper stops the playback queue, cancelling the current job.
+type queueStopper interface {
+	Stop()
+}
+
+// recordingCloser waits for recordings still being written.
+type recordingCloser interface {
+	Close()
+}
+
+// voiceCloser leaves the voice channel and closes the Discord session.
+type voiceCloser interface {
+	IsConnected() bool
+	Disconnect() error
+	Close() error
+}
+
+// shutdownSteps holds what shutdown tears down. Nil fields are skipped.
+type shutdownSteps struct {
+	server     httpShutdowner
+	queue      queueStopper
+	deadLetter io.Closer // writes the dead-letter entries still buffered
+	recorder   recordingCloser
+	sink       io.Closer // the PLAYBACK_SINK file, when audio goes there
+	voice      voiceCloser
+}
+
+// shutdown tears the service down in order: stop accepting HTTP requests,
+// stop the queue so no new job starts and the current one is cancelled,
+// flush the dead-letter file, wait for recordings, close the playback sink,
+// leave the voice channel, then close the Discord session. Every step runs
+// even if an earlier one fails, and their errors are returned together.
+// Files are closed here rather than in deferred calls, which the os.Exit
+// after a failed shutdown would skip.
+func shutdown(ctx context.Context, s shutdownSteps, logger *slog.Logger) error {
+	var errs []error
+
+	if s.server != nil {
+		if err := s.server.Shutdown(ctx); err != nil {
+			logger.Error("failed to shutdown HTTP server", "error", err)
+			errs = append(errs, err)
+		}
+	}
+
+	if s.queue != nil {
+		s.queue.Stop()
+	}
+
+	if s.deadLetter != nil {
+		if err := s.deadLetter.Close(); err != nil {
+			logger.Error("failed to close dead-letter file", "error", err)
+			errs = append(errs, err)
+		}
+	}
+
+	if s.recorder != nil {
+		s.recorder.Close()
+	}
+
+	if s.sink != nil {
+		if err := s.sink.Close(); err != nil {
+			logger.Error("failed to close playback sink", "error", err)
+			errs = append(errs, err)
+		}
+	}
+
+	if s.voice != nil {
+		if s.voice.IsConnected() {
+			logger.Info("shutdown: disconnecting from voice channel")
+			if err := s.voice.Disconnect(); err != nil {
+				logger.Error("failed to disconnect from voice during shutdown", "error", err)
+				errs = append(errs, err)
+			}
+		}
+		if err := s.voice.Close(); err != nil {
+			logger.Error("failed to close Discord session", "error", err)
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgnsrekt/discorgeous-go/internal/queue"
+)
+
+// stepLog records the order shutdown steps run in.
+type stepLog struct {
+	mu    sync.Mutex
+	order []string
+}
+
+func (s *stepLog) add(step string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.order = append(s.order, step)
+}
+
+func (s *stepLog) list() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.order)
+}
+
+type fakeServer struct {
+	steps *stepLog
+	err   error
+}
+
+func (f *fakeServer) Shutdown(ctx context.Context) error {
+	f.steps.add("http")
+	return f.err
+}
+
+// fakeCloser records its name when closed.
+type fakeCloser struct {
+	steps *stepLog
+	name  string
+	err   error
+}
+
+func (f *fakeCloser) Close() error {
+	f.steps.add(f.name)
+	return f.err
+}
+
+type fakeRecorder struct{ steps *stepLog }
+
+func (f *fakeRecorder) Close() { f.steps.add("recordings") }
+
+type fakeVoice struct {
+	steps     *stepLog
+	connected bool
+	closeErr  error
+}
+
+func (f *fakeVoice) IsConnected() bool { return f.connected }
+
+func (f *fakeVoice) Disconnect() error {
+	f.steps.add("disconnect")
+	return nil
+}
+
+func (f *fakeVoice) Close() error {
+	f.steps.add("close")
+	return f.closeErr
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestShutdown_Order(t *testing.T) {
+	order := &stepLog{}
+
+	q := queue.NewQueue(10, time.Minute, testLogger())
+	started := make(chan struct{})
+	q.SetPlaybackHandler(func(ctx context.Context, job *queue.SpeakJob) error {
+		close(started)
+		<-ctx.Done()
+		order.add("job cancelled")
+		return ctx.Err()
+	})
+	q.Start()
+	q.Enqueue(queue.NewSpeakJob("Long running", "default", false, 0, ""))
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for job to start")
+	}
+
+	err := shutdown(context.Background(), shutdownSteps{
+		server:     &fakeServer{steps: order},
+		queue:      q,
+		deadLetter: &fakeCloser{steps: order, name: "dead letters"},
+		recorder:   &fakeRecorder{steps: order},
+		sink:       &fakeCloser{steps: order, name: "sink"},
+		voice:      &fakeVoice{steps: order, connected: true},
+	}, testLogger())
+	if err != nil {
+		t.Fatalf("shutdown() error = %v", err)
+	}
+
+	want := []string{"http", "job cancelled", "dead letters", "recordings", "sink", "disconnect", "close"}
+	if got := order.list(); !slices.Equal(got, want) {
+		t.Errorf("shutdown order = %v, want %v", got, want)
+	}
+}
+
+func TestShutdown_AggregatesErrors(t *testing.T) {
+	order := &stepLog{}
+	httpErr := errors.New("http shutdown timed out")
+	closeErr := errors.New("session close failed")
+	deadLetterErr := errors.New("dead-letter flush failed")
+
+	err := shutdown(context.Background(), shutdownSteps{
+		server:     &fakeServer{steps: order, err: httpErr},
+		deadLetter: &fakeCloser{steps: order, name: "dead letters", err: deadLetterErr},
+		voice:      &fakeVoice{steps: order, closeErr: closeErr},
+	}, testLogger())
+	if !errors.Is(err, httpErr) || !errors.Is(err, closeErr) || !errors.Is(err, deadLetterErr) {
+		t.Errorf("shutdown() error = %v, want every step error", err)
+	}
+
+	// A failed step doesn't stop the rest; not connected skips disconnect
+	want := []string{"http", "dead letters", "close"}
+	if got := order.list(); !slices.Equal(got, want) {
+		t.Errorf("shutdown order = %v, want %v", got, want)
+	}
+}
@@ -2,15 +2,25 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/dgnsrekt/discorgeous-go/internal/logging"
+	"github.com/dgnsrekt/discorgeous-go/internal/metrics/prom"
 	"github.com/dgnsrekt/discorgeous-go/internal/relay"
 )
 
 func main() {
+	testMessage := flag.String("test-message", "", "send one synthetic ntfy message through the relay, print the outcome, and exit")
+	testTitle := flag.String("test-title", "", "title for --test-message")
+	testTopic := flag.String("test-topic", "", "topic for --test-message (default: the first of NTFY_TOPICS)")
+	flag.Parse()
+
 	// Load configuration from environment
 	cfg, err := relay.Load()
 	if err != nil {
@@ -31,11 +41,16 @@ func main() {
 	logger.Info("configuration loaded",
 		"ntfy_server", cfg.NtfyServer,
 		"ntfy_topics", cfg.NtfyTopics,
+		"ntfy_since", cfg.NtfySince,
 		"discorgeous_api_url", cfg.DiscorgeousAPIURL,
+		"max_in_flight", cfg.MaxInFlight,
+		"voice", cfg.Voice,
 		"prefix", cfg.Prefix,
+		"speak_topic", cfg.SpeakTopic,
 		"interrupt", cfg.Interrupt,
 		"dedupe_window", cfg.DedupeWindow,
 		"max_text_length", cfg.MaxTextLength,
+		"http_port", cfg.HTTPPort,
 	)
 
 	// Setup graceful shutdown
@@ -54,6 +69,33 @@ func main() {
 	// Create and run the relay client
 	client := relay.NewClient(cfg, logger)
 
+	if *testMessage != "" {
+		topic := *testTopic
+		if topic == "" {
+			topic = cfg.NtfyTopics[0]
+		}
+		os.Exit(sendTestMessage(ctx, client, topic, *testTitle, *testMessage))
+	}
+
+	promMetrics := prom.New("ntfy", logger)
+	client.SetMetrics(promMetrics)
+
+	// Serve /healthz, /ready and /metrics if a port is configured
+	if cfg.HTTPPort > 0 {
+		server := newHTTPServer(cfg.HTTPPort, client, promMetrics.Handler())
+		go func() {
+			logger.Info("starting HTTP server", "addr", server.Addr)
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error("http server error", "error", err)
+			}
+		}()
+		defer func() {
+			shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer shutdownCancel()
+			server.Shutdown(shutdownCtx)
+		}()
+	}
+
 	logger.Info("starting relay client")
 	if err := client.Run(ctx); err != nil {
 		logger.Error("relay client error", "error", err)
@@ -62,3 +104,24 @@ func main() {
 
 	logger.Info("shutdown complete")
 }
+
+// sendTestMessage runs one synthetic message through the client, prints
+// what happened, and returns the process exit code.
+func sendTestMessage(ctx context.Context, client *relay.Client, topic, title, message string) int {
+	msg := relay.NtfyMessage{
+		ID:      "test-message",
+		Time:    time.Now().Unix(),
+		Event:   "message",
+		Topic:   topic,
+		Title:   title,
+		Message: message,
+	}
+
+	outcome, err := client.HandleMessage(ctx, msg)
+	if err != nil {
+		fmt.Printf("%s: %v\n", outcome, err)
+		return 1
+	}
+	fmt.Printf("%s: %q\n", outcome, client.FormatText(topic, title, message))
+	return 0
+}
@@ -1,116 +1,163 @@
 package api
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/dgnsrekt/discorgeous-go/internal/audio"
+	"github.com/dgnsrekt/discorgeous-go/internal/config"
 	"github.com/dgnsrekt/discorgeous-go/internal/queue"
+	"github.com/dgnsrekt/discorgeous-go/internal/tts"
+	"github.com/dgnsrekt/discorgeous-go/internal/wav"
+	"github.com/dgnsrekt/discorgeous-go/pkg/discorgeous"
 )
 
-// SpeakRequest represents the request body for /v1/speak.
-type SpeakRequest struct {
-	Text      string `json:"text"`
-	Voice     string `json:"voice,omitempty"`
-	Interrupt bool   `json:"interrupt,omitempty"`
-	TTLMS     int    `json:"ttl_ms,omitempty"`
-	DedupeKey string `json:"dedupe_key,omitempty"`
-}
-
-// SpeakResponse represents the response body for /v1/speak.
-type SpeakResponse struct {
-	JobID   string `json:"job_id"`
-	Message string `json:"message"`
-}
+// queueFullRetryAfter is the Retry-After sent when a job cannot be queued
+// because the queue is full.
+const queueFullRetryAfter = 5 * time.Second
 
-// ErrorResponse represents an error response.
-type ErrorResponse struct {
-	Error string `json:"error"`
-}
+// sseKeepaliveInterval is how often a comment is sent on idle event streams
+// so proxies and clients don't time out the connection.
+const sseKeepaliveInterval = 15 * time.Second
 
-// HealthResponse represents the response body for /v1/healthz.
-type HealthResponse struct {
-	Status string `json:"status"`
-}
+// These request and response types are shared with the Go client so the
+// wire format is defined once.
+type (
+	SpeakRequest      = discorgeous.SpeakRequest
+	SpeakResponse     = discorgeous.SpeakResponse
+	ErrorResponse     = discorgeous.ErrorResponse
+	HealthResponse    = discorgeous.HealthResponse
+	InterruptResponse = discorgeous.InterruptResponse
+)
 
 // handleHealthz handles GET /v1/healthz requests.
 func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(HealthResponse{Status: "ok"})
+	json.NewEncoder(w).Encode(HealthResponse{Status: "ok", JoinedOnStart: s.joinedOnStart})
 }
 
-// handleSpeak handles POST /v1/speak requests.
+// handleSpeak handles POST /v1/speak requests. A request repeating the
+// Idempotency-Key of a recent successful one gets its response again
+// without queuing anything.
 func (s *Server) handleSpeak(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
+	idemKey := r.Header.Get("Idempotency-Key")
+	if idemKey != "" && s.idempotency != nil {
+		if len(idemKey) > maxIdempotencyKeyLength {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength)})
+			return
+		}
+
+		// Keys are per caller, so clients can't replay each other's requests
+		idemKey = requestAPIKey(r) + "\x00" + idemKey
+		resp, state := s.idempotency.begin(idemKey)
+		switch state {
+		case idempotencyReplay:
+			s.logger.Info("replaying idempotent speak request", "job_id", resp.JobID)
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(resp)
+			return
+		case idempotencyInFlight:
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "a request with this Idempotency-Key is in progress"})
+			return
+		}
+		// Only a request that queues its job is remembered
+		defer func() {
+			if idemKey != "" {
+				s.idempotency.abandon(idemKey)
+			}
+		}()
+	}
+
 	var req SpeakRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := s.decodeBody(r, &req); err != nil {
 		s.logger.Warn("failed to decode speak request", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "invalid JSON body"})
+		json.NewEncoder(w).Encode(ErrorResponse{Error: decodeErrorMessage(err)})
 		return
 	}
 
-	// Validate text is present
-	if req.Text == "" {
+	if msg := s.validateSpeak(&req); msg != "" {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "text is required"})
+		json.NewEncoder(w).Encode(ErrorResponse{Error: msg})
 		return
 	}
 
-	// Validate text length
-	if len(req.Text) > s.cfg.MaxTextLength {
-		s.logger.Warn("text exceeds max length", "length", len(req.Text), "max", s.cfg.MaxTextLength)
+	// ?block=true waits for queue space instead of failing when full
+	block := false
+	if v := r.URL.Query().Get("block"); v != "" {
+		var err error
+		if block, err = strconv.ParseBool(v); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "block must be a boolean"})
+			return
+		}
+	}
+
+	defaultTTL, ok := s.defaultTTL(r)
+	if !ok {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "text exceeds maximum length"})
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "default_ttl_ms must be a non-negative integer"})
 		return
 	}
 
-	// Validate TTL if provided
-	if req.TTLMS < 0 {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "ttl_ms must be non-negative"})
+	if req.Urgent && !requestHasScope(r, config.ScopeAdmin) {
+		s.logger.Warn("urgent speak request without admin scope", "remote_addr", r.RemoteAddr)
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "urgent requires the admin scope"})
 		return
 	}
 
-	// Use default voice if not provided
-	voice := req.Voice
-	if voice == "" {
-		voice = s.cfg.DefaultVoice
+	if !s.channelAllowed(req.ChannelID) {
+		s.logger.Warn("speak request for a channel that isn't allowed", "channel_id", req.ChannelID, "remote_addr", r.RemoteAddr)
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "channel_id is not an allowed channel"})
+		return
 	}
 
-	// Convert TTL from milliseconds to duration
-	var ttl time.Duration
-	if req.TTLMS > 0 {
-		ttl = time.Duration(req.TTLMS) * time.Millisecond
-	} else if s.cfg.DefaultTTL > 0 {
-		ttl = s.cfg.DefaultTTL
+	// Create the job first so a bad voice doesn't interrupt playback
+	job := s.newSpeakJob(r, &req, defaultTTL)
+	if msg := s.validateJob(job); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: msg})
+		return
 	}
 
 	// Handle interrupt: cancel current playback and clear queue
-	if req.Interrupt && s.queue != nil {
+	if (req.Interrupt || req.Urgent) && s.queue != nil {
 		s.queue.Interrupt()
 	}
 
-	// Create and enqueue the job
-	job := queue.NewSpeakJob(req.Text, voice, req.Interrupt, ttl, req.DedupeKey)
+	// Enqueue the job
 
 	if s.queue != nil {
-		if err := s.queue.Enqueue(job); err != nil {
-			if errors.Is(err, queue.ErrQueueFull) {
-				w.WriteHeader(http.StatusServiceUnavailable)
-				json.NewEncoder(w).Encode(ErrorResponse{Error: "queue is full"})
-				return
-			}
-			if errors.Is(err, queue.ErrDuplicateJob) {
-				w.WriteHeader(http.StatusConflict)
-				json.NewEncoder(w).Encode(ErrorResponse{Error: "duplicate job"})
-				return
-			}
-			s.logger.Error("failed to enqueue job", "error", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(ErrorResponse{Error: "failed to enqueue job"})
+		var err error
+		if req.Urgent {
+			// Skip capacity so a queue refilled since the interrupt can't block it
+			err = s.queue.EnqueueFront(job)
+		} else if block {
+			ctx, cancel := context.WithTimeout(r.Context(), s.blockWait)
+			err = s.queue.EnqueueWait(ctx, job)
+			cancel()
+		} else {
+			err = s.queue.Enqueue(job)
+		}
+		if err != nil {
+			s.writeEnqueueError(w, err)
 			return
 		}
 	}
@@ -118,15 +165,734 @@ func (s *Server) handleSpeak(w http.ResponseWriter, r *http.Request) {
 	s.logger.Info("speak request enqueued",
 		"job_id", job.ID,
 		"text_length", len(req.Text),
-		"voice", voice,
+		"voice", job.Voice,
 		"interrupt", req.Interrupt,
-		"ttl_ms", req.TTLMS,
+		"urgent", req.Urgent,
+		"ttl", job.TTL,
 		"dedupe_key", req.DedupeKey,
+		"queue_position", job.Position,
+		"replaced_job_id", job.Replaces,
+	)
+
+	resp := SpeakResponse{
+		JobID:         job.ID,
+		Message:       "job enqueued",
+		QueuePosition: job.Position,
+		ReplacedJobID: job.Replaces,
+	}
+	if job.Replaces != "" {
+		resp.Message = "job replaced"
+	}
+	if idemKey != "" && s.idempotency != nil {
+		s.idempotency.complete(idemKey, resp)
+		idemKey = ""
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// errTrailingData is returned by decodeBody in strict mode when the body
+// continues after the JSON value.
+var errTrailingData = errors.New("unexpected data after JSON object")
+
+// decodeBody decodes the JSON request body into v. With STRICT_JSON,
+// unknown fields and anything after the JSON value are rejected.
+func (s *Server) decodeBody(r *http.Request, v any) error {
+	dec := json.NewDecoder(r.Body)
+	if !s.cfg.StrictJSON {
+		return dec.Decode(v)
+	}
+
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		return errTrailingData
+	}
+	return nil
+}
+
+// decodeErrorMessage returns the client-facing message for a decodeBody
+// error, naming the offending field for unknown fields.
+func decodeErrorMessage(err error) string {
+	if errors.Is(err, errTrailingData) {
+		return "invalid JSON body: " + err.Error()
+	}
+	// encoding/json has no typed error for unknown fields
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return "invalid JSON body: unknown field " + field
+	}
+	return "invalid JSON body"
+}
+
+// validateSpeak checks a speak request against the server limits and
+// returns the client-facing error message, or "" if it is valid.
+func (s *Server) validateSpeak(req *SpeakRequest) string {
+	// Validate text is present
+	if req.Text == "" {
+		return "text is required"
+	}
+
+	// Validate text length
+	if len(req.Text) > s.cfg.MaxTextLength {
+		s.logger.Warn("text exceeds max length", "length", len(req.Text), "max", s.cfg.MaxTextLength)
+		return "text exceeds maximum length"
+	}
+
+	// Validate intro/outro length
+	if len(req.Intro) > s.cfg.MaxTextLength {
+		return "intro exceeds maximum length"
+	}
+	if len(req.Outro) > s.cfg.MaxTextLength {
+		return "outro exceeds maximum length"
+	}
+
+	// Validate TTL if provided
+	if req.TTLMS != nil && *req.TTLMS < 0 {
+		return "ttl_ms must be non-negative"
+	}
+
+	// Validate max_seconds if provided
+	if req.MaxSeconds < 0 {
+		return "max_seconds must be non-negative"
+	}
+
+	if req.Language != "" && !config.ValidLanguageTag(req.Language) {
+		return "language must be a language tag like en or en-US"
+	}
+
+	if req.Locale != "" && !tts.SupportedLocale(req.Locale) {
+		return "locale must be a de, en, es or fr locale such as en-US"
+	}
+
+	switch req.OnDuplicate {
+	case "", discorgeous.OnDuplicateReject, discorgeous.OnDuplicateReplace:
+	default:
+		return "on_duplicate must be reject or replace"
+	}
+
+	return ""
+}
+
+// channelAllowed reports whether a request may speak in channelID: the
+// default channel, which an empty ID means, or one of ALLOWED_CHANNELS.
+func (s *Server) channelAllowed(channelID string) bool {
+	return channelID == "" || channelID == s.cfg.DefaultVoiceChannelID || slices.Contains(s.cfg.AllowedChannels, channelID)
+}
+
+// newSpeakJob builds the queue job for a validated speak request, filling
+// in the default voice and the given default TTL.
+func (s *Server) newSpeakJob(r *http.Request, req *SpeakRequest, defaultTTL time.Duration) *queue.SpeakJob {
+	// Use default voice if not provided
+	voice := req.Voice
+	if voice == "" {
+		voice = s.defaultVoice(r)
+	}
+
+	// Convert TTL from milliseconds to duration; an explicit 0 means none
+	ttl := defaultTTL
+	if req.TTLMS != nil {
+		ttl = time.Duration(*req.TTLMS) * time.Millisecond
+	}
+
+	job := queue.NewSpeakJob(req.Text, voice, req.Interrupt || req.Urgent, ttl, req.DedupeKey)
+	if job.DedupeKey == "" && s.cfg.AutoDedupe {
+		job.DedupeKey = autoDedupeKey(job.Text, job.Voice)
+	}
+	s.resolveVoiceAlias(job)
+	if req.Chime != nil && !*req.Chime {
+		job.SkipChime = true
+	}
+	job.Intro = req.Intro
+	job.Outro = req.Outro
+	job.MaxDuration = time.Duration(req.MaxSeconds) * time.Second
+	job.Language = req.Language
+	if job.Language == "" {
+		job.Language = s.cfg.DefaultLanguage
+	}
+	job.Locale = req.Locale
+	job.ChannelID = req.ChannelID
+	job.ReplaceDuplicate = req.OnDuplicate == discorgeous.OnDuplicateReplace
+	return job
+}
+
+// validateJob checks a built job against the engine that will speak it and
+// returns the client-facing error message, or "" if it is valid.
+func (s *Server) validateJob(job *queue.SpeakJob) string {
+	if msg := s.validateVoice(job); msg != "" {
+		return msg
+	}
+	return s.validateTextLength(job)
+}
+
+// validateTextLength checks a job's text, intro and outro against the
+// limit of the engine that will speak them, when it is lower than
+// MAX_TEXT_LENGTH, and returns the client-facing error message naming the
+// effective limit, or "" if they fit.
+func (s *Server) validateTextLength(job *queue.SpeakJob) string {
+	if s.voices == nil {
+		return ""
+	}
+
+	engine := job.Engine
+	if engine == "" && slices.Contains(s.voices.List(), job.Voice) {
+		engine = job.Voice
+	}
+	limit, ok := s.voices.MaxTextLength(engine)
+	if !ok || limit >= s.cfg.MaxTextLength {
+		return ""
+	}
+
+	for _, part := range []struct{ name, text string }{
+		{"text", job.Text},
+		{"intro", job.Intro},
+		{"outro", job.Outro},
+	} {
+		if len(part.text) > limit {
+			s.logger.Warn("text exceeds engine max length", "engine", engine, "length", len(part.text), "max", limit)
+			return fmt.Sprintf("%s exceeds maximum length of %d for this engine", part.name, limit)
+		}
+	}
+	return ""
+}
+
+// validateVoice checks a job's voice against the engine that will speak it
+// and returns the client-facing error message, or "" if the voice is known
+// or the engine doesn't list its voices.
+func (s *Server) validateVoice(job *queue.SpeakJob) string {
+	if s.voices == nil || job.Voice == "" || job.Voice == "default" {
+		return ""
+	}
+
+	// Without an explicit engine, a voice may name the engine itself
+	engines := s.voices.List()
+	if job.Engine == "" && slices.Contains(engines, job.Voice) {
+		return ""
+	}
+
+	known, ok := s.voices.Voices(job.Engine)
+	if !ok || slices.Contains(known, job.Voice) {
+		return ""
+	}
+
+	valid := []string{"default"}
+	if job.Engine == "" {
+		valid = append(valid, slices.Sorted(slices.Values(engines))...)
+	}
+	valid = append(valid, known...)
+	return fmt.Sprintf("unknown voice %q; valid voices: %s", job.Voice, strings.Join(valid, ", "))
+}
+
+// autoDedupeKey derives a dedupe key for AUTO_DEDUPE from a message's text
+// and voice, so repeats of the same message collapse while one is queued.
+func autoDedupeKey(text, voice string) string {
+	hash := sha256.Sum256([]byte(voice + "\x00" + text))
+	return "auto:" + hex.EncodeToString(hash[:8])
+}
+
+// resolveVoiceAlias replaces a job voice that names a VOICE_ALIASES entry
+// with the alias's engine and voice. Other voices are left as literals.
+func (s *Server) resolveVoiceAlias(job *queue.SpeakJob) {
+	s.voiceMu.RLock()
+	alias, ok := s.voiceAliases[job.Voice]
+	s.voiceMu.RUnlock()
+	if !ok {
+		return
+	}
+	job.Engine = alias.Engine
+	job.Voice = alias.Voice
+}
+
+// defaultVoice returns the voice for a request that names none: the
+// authenticating API key's default_voice, else DEFAULT_VOICE.
+func (s *Server) defaultVoice(r *http.Request) string {
+	if voice := s.cfg.APIKeyVoices[requestAPIKey(r)]; voice != "" {
+		return voice
+	}
+	s.voiceMu.RLock()
+	defer s.voiceMu.RUnlock()
+	return s.defVoice
+}
+
+// defaultTTL returns the TTL for messages that omit ttl_ms: the
+// ?default_ttl_ms query parameter, else the API key's default_ttl, else
+// DEFAULT_TTL. It reports false if the query parameter is invalid.
+func (s *Server) defaultTTL(r *http.Request) (time.Duration, bool) {
+	if v := r.URL.Query().Get("default_ttl_ms"); v != "" {
+		ms, err := strconv.Atoi(v)
+		if err != nil || ms < 0 {
+			return 0, false
+		}
+		return time.Duration(ms) * time.Millisecond, true
+	}
+	if ttl, ok := s.cfg.APIKeyTTLs[requestAPIKey(r)]; ok {
+		return ttl, true
+	}
+	return s.cfg.DefaultTTL, true
+}
+
+// enqueueError maps a queue error to an HTTP status and client-facing message.
+func (s *Server) enqueueError(err error) (int, string) {
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable, "timed out waiting for queue space"
+	case errors.Is(err, queue.ErrQueueFull):
+		return http.StatusServiceUnavailable, "queue is full"
+	case errors.Is(err, queue.ErrDuplicateJob):
+		return http.StatusConflict, "duplicate job"
+	default:
+		s.logger.Error("failed to enqueue job", "error", err)
+		return http.StatusInternalServerError, "failed to enqueue job"
+	}
+}
+
+// writeEnqueueError writes the response for a failed enqueue. A 503 carries
+// Retry-After so clients such as the relay back off while the queue drains.
+func (s *Server) writeEnqueueError(w http.ResponseWriter, err error) {
+	status, msg := s.enqueueError(err)
+	if status == http.StatusServiceUnavailable {
+		w.Header().Set("Retry-After", strconv.Itoa(int(queueFullRetryAfter/time.Second)))
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: msg})
+}
+
+// BatchSpeakRequest represents the request body for POST /v1/speak/batch.
+type BatchSpeakRequest struct {
+	Messages []SpeakRequest `json:"messages"`
+	// Partial enqueues as many messages as fit instead of rejecting the
+	// whole batch when one cannot be queued.
+	Partial bool `json:"partial,omitempty"`
+}
+
+// BatchSpeakResult reports the outcome of one message in a batch.
+type BatchSpeakResult struct {
+	JobID         string `json:"job_id,omitempty"`
+	QueuePosition int    `json:"queue_position,omitempty"`
+	ReplacedJobID string `json:"replaced_job_id,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// BatchSpeakResponse represents the response body for POST /v1/speak/batch.
+type BatchSpeakResponse struct {
+	Results []BatchSpeakResult `json:"results"`
+}
+
+// handleSpeakBatch handles POST /v1/speak/batch, enqueuing the messages in
+// order. By default the batch is all-or-nothing; with partial set, each
+// message that cannot be queued is reported in its result instead.
+func (s *Server) handleSpeakBatch(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req BatchSpeakRequest
+	if err := s.decodeBody(r, &req); err != nil {
+		s.logger.Warn("failed to decode batch speak request", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: decodeErrorMessage(err)})
+		return
+	}
+
+	if len(req.Messages) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "messages is required"})
+		return
+	}
+
+	defaultTTL, ok := s.defaultTTL(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "default_ttl_ms must be a non-negative integer"})
+		return
+	}
+
+	// Every message must be valid before any is queued
+	interrupt := false
+	jobs := make([]*queue.SpeakJob, len(req.Messages))
+	for i := range req.Messages {
+		if !s.channelAllowed(req.Messages[i].ChannelID) {
+			s.logger.Warn("batch speak request for a channel that isn't allowed", "channel_id", req.Messages[i].ChannelID, "remote_addr", r.RemoteAddr)
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: fmt.Sprintf("messages[%d]: channel_id is not an allowed channel", i)})
+			return
+		}
+
+		msg := s.validateSpeak(&req.Messages[i])
+		if msg == "" && req.Messages[i].Urgent {
+			msg = "urgent is not supported in batches"
+		}
+		if msg == "" {
+			jobs[i] = s.newSpeakJob(r, &req.Messages[i], defaultTTL)
+			msg = s.validateJob(jobs[i])
+		}
+		if msg != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: fmt.Sprintf("messages[%d]: %s", i, msg)})
+			return
+		}
+		interrupt = interrupt || req.Messages[i].Interrupt
+	}
+
+	// An interrupt anywhere in the batch clears the queue once, up front,
+	// so it cannot drop earlier messages of the same batch
+	if interrupt && s.queue != nil {
+		s.queue.Interrupt()
+	}
+
+	results := make([]BatchSpeakResult, len(jobs))
+	if s.queue != nil {
+		if req.Partial {
+			for i, err := range s.queue.EnqueueEach(jobs) {
+				if err != nil {
+					_, results[i].Error = s.enqueueError(err)
+				}
+			}
+		} else if err := s.queue.EnqueueAll(jobs); err != nil {
+			s.writeEnqueueError(w, err)
+			return
+		}
+	}
+
+	enqueued := 0
+	for i, job := range jobs {
+		if results[i].Error == "" {
+			results[i].JobID = job.ID
+			results[i].QueuePosition = job.Position
+			results[i].ReplacedJobID = job.Replaces
+			enqueued++
+		}
+	}
+
+	s.logger.Info("batch speak request enqueued",
+		"messages", len(jobs),
+		"enqueued", enqueued,
+		"partial", req.Partial,
+		"interrupt", interrupt,
 	)
 
 	w.WriteHeader(http.StatusAccepted)
-	json.NewEncoder(w).Encode(SpeakResponse{
-		JobID:   job.ID,
-		Message: "job enqueued",
+	json.NewEncoder(w).Encode(BatchSpeakResponse{Results: results})
+}
+
+// handleInterrupt handles POST /v1/interrupt, stopping current playback and
+// clearing the queue without speaking anything new. With ?disconnect=true
+// it also leaves the voice channel.
+func (s *Server) handleInterrupt(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	disconnect := false
+	if v := r.URL.Query().Get("disconnect"); v != "" {
+		var err error
+		if disconnect, err = strconv.ParseBool(v); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "disconnect must be a boolean"})
+			return
+		}
+	}
+
+	if disconnect && s.voice == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "voice not configured"})
+		return
+	}
+
+	var resp InterruptResponse
+	if s.queue != nil {
+		resp.Cleared = s.queue.Interrupt()
+	}
+
+	if disconnect {
+		if err := s.voice.Disconnect(); err != nil {
+			s.logger.Error("failed to disconnect from voice", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "failed to disconnect from voice"})
+			return
+		}
+		resp.Disconnected = true
+	}
+
+	s.logger.Info("interrupt request handled",
+		"jobs_cleared", resp.Cleared,
+		"disconnected", resp.Disconnected,
+	)
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+// SynthesizeRequest represents the request body for POST /v1/synthesize.
+type SynthesizeRequest struct {
+	Text  string `json:"text"`
+	Voice string `json:"voice,omitempty"`
+}
+
+// handleSynthesize handles POST /v1/synthesize, returning the synthesized
+// audio as WAV, or as raw big-endian PCM when the client accepts audio/L16.
+func (s *Server) handleSynthesize(w http.ResponseWriter, r *http.Request) {
+	if s.synthesizer == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "synthesis not configured"})
+		return
+	}
+
+	var req SynthesizeRequest
+	if err := s.decodeBody(r, &req); err != nil {
+		s.logger.Warn("failed to decode synthesize request", "error", err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: decodeErrorMessage(err)})
+		return
+	}
+
+	if req.Text == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "text is required"})
+		return
+	}
+
+	if len(req.Text) > s.cfg.MaxTextLength {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "text exceeds maximum length"})
+		return
+	}
+
+	voice := req.Voice
+	if voice == "" {
+		voice = s.defaultVoice(r)
+	}
+
+	// The request context is cancelled if the client disconnects
+	job := queue.NewSpeakJob(req.Text, voice, false, 0, "")
+	job.Language = s.cfg.DefaultLanguage
+	s.resolveVoiceAlias(job)
+	if msg := s.validateJob(job); msg != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: msg})
+		return
+	}
+	pcm, err := s.synthesizer.Synthesize(r.Context(), job)
+	if err != nil {
+		if r.Context().Err() != nil {
+			s.logger.Info("synthesis cancelled by client", "job_id", job.ID)
+			return
+		}
+		s.logger.Error("synthesis failed", "job_id", job.ID, "error", err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "synthesis failed"})
+		return
+	}
+
+	var body []byte
+	if strings.Contains(r.Header.Get("Accept"), "audio/L16") {
+		w.Header().Set("Content-Type", fmt.Sprintf("audio/L16; rate=%d; channels=%d", audio.DiscordSampleRate, audio.DiscordChannels))
+		body = swapEndian16(pcm)
+	} else {
+		w.Header().Set("Content-Type", "audio/wav")
+		body = wav.WrapRawPCM(pcm, audio.DiscordSampleRate, audio.DiscordChannels, 16)
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// swapEndian16 converts 16-bit little-endian PCM to big-endian, as audio/L16
+// requires, in place.
+func swapEndian16(pcm []byte) []byte {
+	for i := 0; i+1 < len(pcm); i += 2 {
+		pcm[i], pcm[i+1] = pcm[i+1], pcm[i]
+	}
+	return pcm
+}
+
+// handleMetrics handles GET /v1/metrics, serving the configured metrics
+// handler.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if s.metrics == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "metrics not configured"})
+		return
+	}
+	s.metrics.ServeHTTP(w, r)
+}
+
+// HistoryResponse represents the response body for GET /v1/history.
+type HistoryResponse struct {
+	Jobs []queue.HistoryEntry `json:"jobs"`
+}
+
+// handleHistory handles GET /v1/history, listing recently finished jobs
+// newest first. ?limit= caps how many are returned.
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if s.queue == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "queue not available"})
+		return
+	}
+
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "limit must be a positive integer"})
+			return
+		}
+		limit = n
+	}
+
+	json.NewEncoder(w).Encode(HistoryResponse{Jobs: s.queue.History(limit)})
+}
+
+// handleEvents handles GET /v1/events, streaming job lifecycle events as
+// Server-Sent Events until the client disconnects.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if s.queue == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "queue not available"})
+		return
+	}
+
+	rc := http.NewResponseController(w)
+
+	// The server's WriteTimeout would otherwise cut the stream short
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		s.logger.Warn("failed to clear write deadline for event stream", "error", err)
+	}
+
+	events, unsubscribe := s.queue.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+
+	s.logger.Debug("event stream opened", "remote_addr", r.RemoteAddr)
+
+	keepalive := time.NewTicker(sseKeepaliveInterval)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			s.logger.Debug("event stream closed", "remote_addr", r.RemoteAddr)
+			return
+		case <-s.done:
+			s.logger.Debug("event stream closed for shutdown", "remote_addr", r.RemoteAddr)
+			return
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			rc.Flush()
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				s.logger.Error("failed to marshal event", "error", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data); err != nil {
+				return
+			}
+			rc.Flush()
+		}
+	}
+}
+
+// DefaultVoiceRequest represents the request body for POST /v1/config/default-voice.
+type DefaultVoiceRequest struct {
+	Voice string `json:"voice"`
+}
+
+// DefaultVoiceResponse represents the response body for POST /v1/config/default-voice.
+type DefaultVoiceResponse struct {
+	Voice string `json:"voice"`
+}
+
+// handleSetDefaultVoice handles POST /v1/config/default-voice, switching the
+// engine used for jobs that don't name a voice. The change lasts until restart.
+func (s *Server) handleSetDefaultVoice(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if s.voices == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "voices not configured"})
+		return
+	}
+
+	var req DefaultVoiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "invalid JSON body"})
+		return
+	}
+
+	if req.Voice == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "voice is required"})
+		return
+	}
+
+	if !slices.Contains(s.voices.List(), req.Voice) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "unknown voice"})
+		return
+	}
+
+	if err := s.voices.SetDefault(req.Voice); err != nil {
+		s.logger.Error("failed to set default voice", "voice", req.Voice, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "failed to set default voice"})
+		return
+	}
+
+	s.logger.Info("default voice changed", "voice", req.Voice)
+
+	json.NewEncoder(w).Encode(DefaultVoiceResponse{Voice: req.Voice})
+}
+
+// VoicesResponse represents the response body for GET /v1/voices.
+type VoicesResponse struct {
+	// Default is the voice used for messages that name none.
+	Default string `json:"default"`
+	// Voices are the selectable voices: engine names and VOICE_ALIASES
+	// names, sorted.
+	Voices []string `json:"voices"`
+}
+
+// handleVoices handles GET /v1/voices, listing the voices a speak request
+// may name.
+func (s *Server) handleVoices(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	voices := []string{}
+	if s.voices != nil {
+		voices = append(voices, s.voices.List()...)
+	}
+	s.voiceMu.RLock()
+	for name := range s.voiceAliases {
+		voices = append(voices, name)
+	}
+	s.voiceMu.RUnlock()
+	slices.Sort(voices)
+
+	json.NewEncoder(w).Encode(VoicesResponse{
+		Default: s.defaultVoice(r),
+		Voices:  slices.Compact(voices),
 	})
 }
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dgnsrekt/discorgeous-go/internal/config"
+)
+
+// signedRequest builds a POST /test request signed with secret at ts.
+func signedRequest(secret string, ts time.Time, body string) *http.Request {
+	return signedRequestTo(secret, ts, "POST", "/test", body)
+}
+
+// signedRequestTo builds a request for target signed with secret at ts.
+func signedRequestTo(secret string, ts time.Time, method, target, body string) *http.Request {
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+	req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	req.Header.Set(timestampHeader, timestamp)
+	req.Header.Set(signatureHeader, hex.EncodeToString(computeSignature(secret, timestamp, method, req.URL.RequestURI(), []byte(body))))
+	return req
+}
+
+func hmacTestServer() *Server {
+	cfg := testConfig()
+	cfg.HMACSecret = "shared-secret"
+	cfg.HMACMaxSkew = 5 * time.Minute
+	return testServer(cfg)
+}
+
+func TestHMACValidSignature(t *testing.T) {
+	srv := hmacTestServer()
+
+	var gotBody string
+	handler := srv.withHMAC(srv.withScope(config.ScopeSpeak, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// No bearer token: the signature alone authenticates
+	req := signedRequestTo("shared-secret", time.Now(), "POST", "/v1/speak?block=true", `{"text":"hello"}`)
+	w := httptest.NewRecorder()
+
+	handler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if gotBody != `{"text":"hello"}` {
+		t.Errorf("handler read body %q, want the original body", gotBody)
+	}
+}
+
+func TestHMACRejectsInvalidSignatures(t *testing.T) {
+	srv := hmacTestServer()
+
+	tamperedBody := signedRequest("shared-secret", time.Now(), `{"text":"hello"}`)
+	tamperedBody.Body = io.NopCloser(bytes.NewBufferString(`{"text":"goodbye"}`))
+
+	badTimestamp := signedRequest("shared-secret", time.Now(), `{}`)
+	badTimestamp.Header.Set(timestampHeader, "yesterday")
+
+	// A signature captured from one request must not carry over to
+	// another route, method or query
+	signed := signedRequestTo("shared-secret", time.Now(), "POST", "/v1/speak", `{}`)
+	otherRoute := httptest.NewRequest("POST", "/v1/interrupt?disconnect=true", bytes.NewBufferString(`{}`))
+	otherMethod := httptest.NewRequest("PUT", "/v1/speak", bytes.NewBufferString(`{}`))
+	otherQuery := httptest.NewRequest("POST", "/v1/speak?block=true", bytes.NewBufferString(`{}`))
+	for _, req := range []*http.Request{otherRoute, otherMethod, otherQuery} {
+		req.Header.Set(timestampHeader, signed.Header.Get(timestampHeader))
+		req.Header.Set(signatureHeader, signed.Header.Get(signatureHeader))
+	}
+
+	tests := []struct {
+		name string
+		req  *http.Request
+	}{
+		{"tampered body", tamperedBody},
+		{"replayed on another route", otherRoute},
+		{"replayed with another method", otherMethod},
+		{"replayed with another query", otherQuery},
+		{"wrong secret", signedRequest("other-secret", time.Now(), `{}`)},
+		{"expired", signedRequest("shared-secret", time.Now().Add(-10*time.Minute), `{}`)},
+		{"from the future", signedRequest("shared-secret", time.Now().Add(10*time.Minute), `{}`)},
+		{"malformed timestamp", badTimestamp},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := srv.withHMAC(srv.withScope(config.ScopeSpeak, func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			}))
+
+			w := httptest.NewRecorder()
+			handler(w, tt.req)
+
+			if called {
+				t.Error("handler should not have been called")
+			}
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+			}
+		})
+	}
+}
+
+func TestHMACRejectsReplay(t *testing.T) {
+	srv := hmacTestServer()
+
+	handler := srv.withHMAC(srv.withScope(config.ScopeSpeak, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	ts := time.Now()
+	tests := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{"first use", signedRequest("shared-secret", ts, `{"text":"hello"}`), http.StatusOK},
+		{"replayed", signedRequest("shared-secret", ts, `{"text":"hello"}`), http.StatusUnauthorized},
+		{"signed afresh", signedRequest("shared-secret", ts.Add(time.Second), `{"text":"hello"}`), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler(w, tt.req)
+		if w.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.want, w.Code)
+		}
+	}
+}
+
+func TestSignatureCacheExpiry(t *testing.T) {
+	c := newSignatureCache()
+	now := time.Now()
+	expires := now.Add(time.Minute)
+
+	if !c.add("sig", expires, now) {
+		t.Fatal("add() = false for a new signature")
+	}
+	if c.add("sig", expires, expires) {
+		t.Error("add() = true for a signature still in its window")
+	}
+	if !c.add("sig", expires, expires.Add(time.Nanosecond)) {
+		t.Error("add() = false for an expired signature")
+	}
+}
+
+func TestHMACUnsignedFallsBackToBearer(t *testing.T) {
+	srv := hmacTestServer()
+
+	handler := srv.withHMAC(srv.withScope(config.ScopeSpeak, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("POST", "/test", bytes.NewBufferString(`{}`))
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+
+	handler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	// Without a token or signature the request is rejected
+	req = httptest.NewRequest("POST", "/test", bytes.NewBufferString(`{}`))
+	w = httptest.NewRecorder()
+
+	handler(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// maxIdempotencyKeyLength bounds the Idempotency-Key header.
+const maxIdempotencyKeyLength = 255
+
+// idempotencyState is what begin found for a key.
+type idempotencyState int
+
+const (
+	// idempotencyNew means the key is unseen and now reserved for the caller.
+	idempotencyNew idempotencyState = iota
+	// idempotencyReplay means the key's request already succeeded.
+	idempotencyReplay
+	// idempotencyInFlight means a request with the key is still running.
+	idempotencyInFlight
+)
+
+// idempotencyEntry is the stored outcome of a request with a key. A nil
+// response marks a request still in flight.
+type idempotencyEntry struct {
+	response *SpeakResponse
+	expires  time.Time
+}
+
+// idempotencyCache remembers the responses of successful speak requests by
+// Idempotency-Key for a TTL, so retries of a request get the original
+// response instead of queuing the message again.
+type idempotencyCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]idempotencyEntry
+}
+
+// newIdempotencyCache creates a cache keeping responses for ttl.
+func newIdempotencyCache(ttl time.Duration) *idempotencyCache {
+	return &idempotencyCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]idempotencyEntry),
+	}
+}
+
+// begin looks key up. An unseen or expired key is reserved for the caller,
+// who must then call complete or abandon. A replay returns the stored
+// response.
+func (c *idempotencyCache) begin(key string) (SpeakResponse, idempotencyState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if entry, ok := c.entries[key]; ok && now.Before(entry.expires) {
+		if entry.response == nil {
+			return SpeakResponse{}, idempotencyInFlight
+		}
+		return *entry.response, idempotencyReplay
+	}
+
+	c.pruneLocked(now)
+	c.entries[key] = idempotencyEntry{expires: now.Add(c.ttl)}
+	return SpeakResponse{}, idempotencyNew
+}
+
+// complete stores the response for a key reserved by begin.
+func (c *idempotencyCache) complete(key string, resp SpeakResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = idempotencyEntry{response: &resp, expires: c.now().Add(c.ttl)}
+}
+
+// abandon releases a key reserved by begin whose request failed, so a
+// retry is handled afresh.
+func (c *idempotencyCache) abandon(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// pruneLocked drops expired entries. The caller must hold c.mu.
+func (c *idempotencyCache) pruneLocked(now time.Time) {
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+}
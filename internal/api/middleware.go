@@ -1,16 +1,47 @@
 package api
 
 import (
+	"context"
+	"crypto/subtle"
 	"net/http"
+	"slices"
 	"strings"
+
+	"github.com/dgnsrekt/discorgeous-go/internal/config"
 )
 
-// withAuth wraps a handler with bearer token authentication.
-func (s *Server) withAuth(next http.HandlerFunc) http.HandlerFunc {
+// grantedScopesKey holds the scopes withScope authorized a request with.
+type grantedScopesKey struct{}
+
+// apiKeyKey holds the API_KEYS token a request authenticated with.
+type apiKeyKey struct{}
+
+// withScope wraps a handler with bearer token authentication, allowing only
+// tokens granted scope. Tokens from BEARER_TOKEN and BEARER_TOKENS carry
+// every scope; API_KEYS tokens carry the scopes they are configured with.
+// Callers with a valid signature or verified client certificate are
+// authorized by it instead. The caller's scopes are stored in the request
+// context for handlers that gate options on them; see requestHasScope.
+func (s *Server) withScope(scope string, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		// Signed requests and verified client certificates need no token
+		scopes, ok := signedScopes(r)
+		if !ok {
+			scopes, ok = s.clientCertScopes(r)
+		}
+		if ok {
+			if !hasScope(scopes, scope) {
+				s.logger.Warn("caller lacks required scope", "remote_addr", r.RemoteAddr, "scope", scope)
+				http.Error(w, `{"error":"insufficient scope"}`, http.StatusForbidden)
+				return
+			}
+			next(w, withGrantedScopes(r, scopes))
+			return
+		}
+
 		// If no bearer token is configured, skip auth
-		if s.cfg.BearerToken == "" {
-			next(w, r)
+		if s.cfg.AuthDisabled() {
+			next(w, withGrantedScopes(r, []string{config.ScopeAdmin}))
 			return
 		}
 
@@ -29,13 +60,95 @@ func (s *Server) withAuth(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		token := parts[1]
-		if token != s.cfg.BearerToken {
+		key, scopes, ok := s.tokenScopes(parts[1])
+		if !ok {
 			s.logger.Warn("invalid bearer token", "remote_addr", r.RemoteAddr)
 			http.Error(w, `{"error":"invalid token"}`, http.StatusUnauthorized)
 			return
 		}
 
+		if !hasScope(scopes, scope) {
+			s.logger.Warn("token lacks required scope", "remote_addr", r.RemoteAddr, "scope", scope)
+			http.Error(w, `{"error":"insufficient scope"}`, http.StatusForbidden)
+			return
+		}
+
+		r = withGrantedScopes(r, scopes)
+		if key != "" {
+			r = r.WithContext(context.WithValue(r.Context(), apiKeyKey{}, key))
+		}
 		next(w, r)
 	}
 }
+
+// requestAPIKey returns the API_KEYS token withScope authenticated r with,
+// or "" if it used another credential.
+func requestAPIKey(r *http.Request) string {
+	key, _ := r.Context().Value(apiKeyKey{}).(string)
+	return key
+}
+
+// withGrantedScopes returns r with scopes recorded as the caller's grant.
+func withGrantedScopes(r *http.Request, scopes []string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), grantedScopesKey{}, scopes))
+}
+
+// requestHasScope reports whether withScope authorized r with scope.
+func requestHasScope(r *http.Request, scope string) bool {
+	scopes, _ := r.Context().Value(grantedScopesKey{}).([]string)
+	return hasScope(scopes, scope)
+}
+
+// tokenScopes returns the scopes granted to token and whether it is valid.
+// For API_KEYS tokens it also returns the matching key; full-access tokens
+// return "".
+func (s *Server) tokenScopes(token string) (string, []string, bool) {
+	if validToken(token, s.cfg.ValidTokens()) {
+		return "", []string{config.ScopeAdmin}, true
+	}
+
+	var matchedKey string
+	var scopes []string
+	matched := false
+	for key, keyScopes := range s.cfg.APIKeys {
+		if validToken(token, []string{key}) {
+			matchedKey = key
+			scopes = keyScopes
+			matched = true
+		}
+	}
+	return matchedKey, scopes, matched
+}
+
+// clientCertScopes returns the scopes granted to the request's verified
+// client certificate, and false if it has none. Without TLS_CLIENT_SCOPES
+// a verified certificate carries every scope; with it, the certificate's
+// common name must be listed.
+func (s *Server) clientCertScopes(r *http.Request) ([]string, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+	if len(s.cfg.TLSClientScopes) == 0 {
+		return []string{config.ScopeAdmin}, true
+	}
+	cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	return s.cfg.TLSClientScopes[cn], true
+}
+
+// hasScope reports whether scopes grant scope, treating admin as every scope.
+func hasScope(scopes []string, scope string) bool {
+	return slices.Contains(scopes, scope) || slices.Contains(scopes, config.ScopeAdmin)
+}
+
+// validToken reports whether token matches any of the valid tokens.
+// Every candidate is compared in constant time so the result does not
+// leak which token, or how much of one, matched.
+func validToken(token string, valid []string) bool {
+	matched := 0
+	for _, v := range valid {
+		if len(token) == len(v) {
+			matched |= subtle.ConstantTimeCompare([]byte(token), []byte(v))
+		}
+	}
+	return matched == 1
+}
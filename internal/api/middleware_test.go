@@ -1,10 +1,15 @@
 package api
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/dgnsrekt/discorgeous-go/internal/config"
 )
 
 func TestAuthMiddlewareMissingHeader(t *testing.T) {
@@ -13,7 +18,7 @@ func TestAuthMiddlewareMissingHeader(t *testing.T) {
 	srv := testServer(cfg)
 
 	called := false
-	handler := srv.withAuth(func(w http.ResponseWriter, r *http.Request) {
+	handler := srv.withScope(config.ScopeSpeak, func(w http.ResponseWriter, r *http.Request) {
 		called = true
 	})
 
@@ -46,7 +51,7 @@ func TestAuthMiddlewareInvalidFormat(t *testing.T) {
 	srv := testServer(cfg)
 
 	called := false
-	handler := srv.withAuth(func(w http.ResponseWriter, r *http.Request) {
+	handler := srv.withScope(config.ScopeSpeak, func(w http.ResponseWriter, r *http.Request) {
 		called = true
 	})
 
@@ -71,7 +76,7 @@ func TestAuthMiddlewareInvalidToken(t *testing.T) {
 	srv := testServer(cfg)
 
 	called := false
-	handler := srv.withAuth(func(w http.ResponseWriter, r *http.Request) {
+	handler := srv.withScope(config.ScopeSpeak, func(w http.ResponseWriter, r *http.Request) {
 		called = true
 	})
 
@@ -105,7 +110,7 @@ func TestAuthMiddlewareValidToken(t *testing.T) {
 	srv := testServer(cfg)
 
 	called := false
-	handler := srv.withAuth(func(w http.ResponseWriter, r *http.Request) {
+	handler := srv.withScope(config.ScopeSpeak, func(w http.ResponseWriter, r *http.Request) {
 		called = true
 		w.WriteHeader(http.StatusOK)
 	})
@@ -131,7 +136,7 @@ func TestAuthMiddlewareNoBearerConfigured(t *testing.T) {
 	srv := testServer(cfg)
 
 	called := false
-	handler := srv.withAuth(func(w http.ResponseWriter, r *http.Request) {
+	handler := srv.withScope(config.ScopeSpeak, func(w http.ResponseWriter, r *http.Request) {
 		called = true
 		w.WriteHeader(http.StatusOK)
 	})
@@ -157,7 +162,7 @@ func TestAuthMiddlewareCaseInsensitiveBearer(t *testing.T) {
 	srv := testServer(cfg)
 
 	called := false
-	handler := srv.withAuth(func(w http.ResponseWriter, r *http.Request) {
+	handler := srv.withScope(config.ScopeSpeak, func(w http.ResponseWriter, r *http.Request) {
 		called = true
 		w.WriteHeader(http.StatusOK)
 	})
@@ -176,3 +181,173 @@ func TestAuthMiddlewareCaseInsensitiveBearer(t *testing.T) {
 		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
 	}
 }
+
+func TestAuthMiddlewareMultipleTokens(t *testing.T) {
+	cfg := testConfig()
+	cfg.BearerToken = ""
+	cfg.BearerTokens = []string{"old-token", "new-token"}
+	srv := testServer(cfg)
+
+	tests := []struct {
+		name     string
+		token    string
+		wantCode int
+	}{
+		{"first token", "old-token", http.StatusOK},
+		{"second token", "new-token", http.StatusOK},
+		{"unknown token", "other-token", http.StatusUnauthorized},
+		{"prefix of a token", "new-tok", http.StatusUnauthorized},
+		{"empty token", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := srv.withScope(config.ScopeSpeak, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+
+			handler(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("expected status %d, got %d", tt.wantCode, w.Code)
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewarePrimaryAndRotatedTokens(t *testing.T) {
+	cfg := testConfig()
+	cfg.BearerToken = "secret-token"
+	cfg.BearerTokens = []string{"rotated-token"}
+	srv := testServer(cfg)
+
+	for _, token := range []string{"secret-token", "rotated-token"} {
+		called := false
+		handler := srv.withScope(config.ScopeSpeak, func(w http.ResponseWriter, r *http.Request) {
+			called = true
+		})
+
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+
+		handler(w, req)
+
+		if !called {
+			t.Errorf("handler should have been called with token %q", token)
+		}
+	}
+}
+
+func TestAuthMiddlewareScopedKeys(t *testing.T) {
+	cfg := testConfig()
+	cfg.BearerToken = ""
+	cfg.APIKeys = map[string][]string{
+		"dashboard-token": {config.ScopeRead},
+		"relay-token":     {config.ScopeSpeak},
+		"admin-token":     {config.ScopeAdmin},
+	}
+	srv := testServer(cfg)
+
+	tests := []struct {
+		name     string
+		token    string
+		scope    string
+		wantCode int
+	}{
+		{"read key reads", "dashboard-token", config.ScopeRead, http.StatusOK},
+		{"read key cannot speak", "dashboard-token", config.ScopeSpeak, http.StatusForbidden},
+		{"speak key speaks", "relay-token", config.ScopeSpeak, http.StatusOK},
+		{"speak key cannot administer", "relay-token", config.ScopeAdmin, http.StatusForbidden},
+		{"admin key speaks", "admin-token", config.ScopeSpeak, http.StatusOK},
+		{"admin key administers", "admin-token", config.ScopeAdmin, http.StatusOK},
+		{"unknown key", "other-token", config.ScopeRead, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := srv.withScope(tt.scope, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+
+			handler(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("expected status %d, got %d", tt.wantCode, w.Code)
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareBearerTokenHasAllScopes(t *testing.T) {
+	cfg := testConfig()
+	cfg.BearerToken = "secret-token"
+	cfg.APIKeys = map[string][]string{"dashboard-token": {config.ScopeRead}}
+	srv := testServer(cfg)
+
+	handler := srv.withScope(config.ScopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer secret-token")
+	w := httptest.NewRecorder()
+
+	handler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+}
+
+// withClientCert marks req as carrying a verified client certificate with cn.
+func withClientCert(req *http.Request, cn string) {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: cn}}
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+}
+
+func TestAuthMiddlewareClientCert(t *testing.T) {
+	tests := []struct {
+		name     string
+		scopes   map[string][]string
+		cn       string
+		scope    string
+		wantCode int
+	}{
+		{"no mapping grants all scopes", nil, "relay", config.ScopeAdmin, http.StatusOK},
+		{"mapped CN with scope", map[string][]string{"relay": {config.ScopeSpeak}}, "relay", config.ScopeSpeak, http.StatusOK},
+		{"mapped CN without scope", map[string][]string{"relay": {config.ScopeSpeak}}, "relay", config.ScopeRead, http.StatusForbidden},
+		{"unmapped CN", map[string][]string{"relay": {config.ScopeSpeak}}, "dashboard", config.ScopeSpeak, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.TLSClientScopes = tt.scopes
+			srv := testServer(cfg)
+
+			handler := srv.withScope(tt.scope, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			// No bearer token: the certificate alone authenticates
+			req := httptest.NewRequest("GET", "/test", nil)
+			withClientCert(req, tt.cn)
+			w := httptest.NewRecorder()
+
+			handler(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("expected status %d, got %d", tt.wantCode, w.Code)
+			}
+		})
+	}
+}
@@ -2,46 +2,160 @@ package api
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"os"
+	"sync"
 	"time"
 
 	"github.com/dgnsrekt/discorgeous-go/internal/config"
 	"github.com/dgnsrekt/discorgeous-go/internal/queue"
 )
 
+// Synthesizer renders a job's speech to Discord PCM without playing it.
+type Synthesizer interface {
+	Synthesize(ctx context.Context, job *queue.SpeakJob) ([]byte, error)
+}
+
+// VoiceRegistry lists the selectable voices and switches the default.
+type VoiceRegistry interface {
+	List() []string
+	SetDefault(name string) error
+	// Voices returns the voices the named engine, or the default engine
+	// if name is empty, accepts. ok is false if it can't list them.
+	Voices(name string) (voices []string, ok bool)
+	// MaxTextLength returns the text length limit of the named engine, or
+	// the default engine if name is empty. ok is false if there is no such
+	// engine.
+	MaxTextLength(name string) (limit int, ok bool)
+}
+
+// VoiceConnection is the voice connection POST /v1/interrupt can drop.
+type VoiceConnection interface {
+	Disconnect() error
+}
+
+// writeTimeout bounds how long a handler may take to write its response.
+const writeTimeout = 10 * time.Second
+
+// maxBlockWait bounds how long ?block=true waits for queue space, leaving
+// time to write the response before writeTimeout. A job queued after the
+// response could no longer be sent would be retried and spoken twice.
+const maxBlockWait = writeTimeout - 2*time.Second
+
 // Server handles HTTP API requests.
 type Server struct {
-	cfg    *config.Config
-	logger *slog.Logger
-	server *http.Server
-	queue  *queue.Queue
+	cfg         *config.Config
+	logger      *slog.Logger
+	server      *http.Server
+	queue       *queue.Queue
+	synthesizer Synthesizer
+	voices      VoiceRegistry
+	voice       VoiceConnection
+	metrics     http.Handler
+	idempotency *idempotencyCache // nil when IDEMPOTENCY_TTL is 0
+	signatures  *signatureCache   // signed requests already accepted
+
+	joinedOnStart bool
+	blockWait     time.Duration // limit on ?block=true waits
+
+	// done is closed when Shutdown starts, ending event streams, which
+	// Shutdown would otherwise wait on until its deadline
+	done     chan struct{}
+	doneOnce sync.Once
+
+	// DEFAULT_VOICE and VOICE_ALIASES, which SetVoiceSettings can change
+	// while requests are served
+	voiceMu      sync.RWMutex
+	defVoice     string
+	voiceAliases map[string]config.VoiceAlias
 }
 
 // New creates a new API server.
 func New(cfg *config.Config, logger *slog.Logger, q *queue.Queue) *Server {
 	s := &Server{
-		cfg:    cfg,
-		logger: logger,
-		queue:  q,
+		cfg:          cfg,
+		logger:       logger,
+		queue:        q,
+		defVoice:     cfg.DefaultVoice,
+		voiceAliases: cfg.VoiceAliases,
+		signatures:   newSignatureCache(),
+		done:         make(chan struct{}),
+		blockWait:    maxBlockWait,
+	}
+	if cfg.IdempotencyTTL > 0 {
+		s.idempotency = newIdempotencyCache(cfg.IdempotencyTTL)
 	}
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /v1/healthz", s.handleHealthz)
-	mux.HandleFunc("POST /v1/speak", s.withAuth(s.handleSpeak))
+	mux.HandleFunc("POST /v1/speak", s.withHMAC(s.withScope(config.ScopeSpeak, s.handleSpeak)))
+	mux.HandleFunc("POST /v1/speak/batch", s.withHMAC(s.withScope(config.ScopeSpeak, s.handleSpeakBatch)))
+	mux.HandleFunc("POST /v1/interrupt", s.withHMAC(s.withScope(config.ScopeSpeak, s.handleInterrupt)))
+	mux.HandleFunc("POST /v1/synthesize", s.withHMAC(s.withScope(config.ScopeSpeak, s.handleSynthesize)))
+	mux.HandleFunc("GET /v1/events", s.withHMAC(s.withScope(config.ScopeRead, s.handleEvents)))
+	mux.HandleFunc("GET /v1/history", s.withHMAC(s.withScope(config.ScopeRead, s.handleHistory)))
+	mux.HandleFunc("GET /v1/metrics", s.withHMAC(s.withScope(config.ScopeRead, s.handleMetrics)))
+	mux.HandleFunc("GET /v1/voices", s.withHMAC(s.withScope(config.ScopeRead, s.handleVoices)))
+	mux.HandleFunc("POST /v1/config/default-voice", s.withHMAC(s.withScope(config.ScopeAdmin, s.handleSetDefaultVoice)))
+	if cfg.WebUI {
+		mux.HandleFunc("GET /{$}", s.handleWebUI)
+	}
 
 	s.server = &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.HTTPPort),
 		Handler:      mux,
 		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		WriteTimeout: writeTimeout,
 		IdleTimeout:  60 * time.Second,
 	}
+	s.server.RegisterOnShutdown(func() {
+		s.doneOnce.Do(func() { close(s.done) })
+	})
 
 	return s
 }
 
+// SetSynthesizer enables POST /v1/synthesize.
+func (s *Server) SetSynthesizer(synth Synthesizer) {
+	s.synthesizer = synth
+}
+
+// SetVoices enables POST /v1/config/default-voice and checks the voice of
+// each spoken message against the engines that list theirs.
+func (s *Server) SetVoices(voices VoiceRegistry) {
+	s.voices = voices
+}
+
+// SetVoiceConnection enables ?disconnect=true on POST /v1/interrupt.
+func (s *Server) SetVoiceConnection(voice VoiceConnection) {
+	s.voice = voice
+}
+
+// SetMetricsHandler enables GET /v1/metrics, served by h.
+func (s *Server) SetMetricsHandler(h http.Handler) {
+	s.metrics = h
+}
+
+// SetJoinedOnStart records that the bot joined voice at startup, which
+// /v1/healthz reports.
+func (s *Server) SetJoinedOnStart(joined bool) {
+	s.joinedOnStart = joined
+}
+
+// SetVoiceSettings replaces the DEFAULT_VOICE and VOICE_ALIASES the server
+// was created with. It is safe to call while serving requests.
+func (s *Server) SetVoiceSettings(defaultVoice string, aliases map[string]config.VoiceAlias) {
+	s.voiceMu.Lock()
+	defer s.voiceMu.Unlock()
+	s.defVoice = defaultVoice
+	s.voiceAliases = aliases
+}
+
 // Start begins listening for HTTP requests.
 func (s *Server) Start() error {
 	s.logger.Info("starting HTTP server", "addr", s.server.Addr)
@@ -51,6 +165,47 @@ func (s *Server) Start() error {
 	return nil
 }
 
+// StartTLS begins listening for HTTPS requests using the configured
+// certificate. If TLS_CLIENT_CA is set, clients must present a
+// certificate signed by it.
+func (s *Server) StartTLS() error {
+	tlsConfig, err := s.tlsConfig()
+	if err != nil {
+		return err
+	}
+	s.server.TLSConfig = tlsConfig
+
+	s.logger.Info("starting HTTPS server",
+		"addr", s.server.Addr,
+		"client_auth", tlsConfig.ClientAuth == tls.RequireAndVerifyClientCert,
+	)
+	if err := s.server.ListenAndServeTLS(s.cfg.TLSCert, s.cfg.TLSKey); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("https server error: %w", err)
+	}
+	return nil
+}
+
+// tlsConfig builds the server TLS configuration from the client CA settings.
+func (s *Server) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if s.cfg.TLSClientCA == "" {
+		return tlsConfig, nil
+	}
+
+	caPEM, err := os.ReadFile(s.cfg.TLSClientCA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, errors.New("client CA contains no PEM certificates")
+	}
+
+	tlsConfig.ClientCAs = pool
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	return tlsConfig, nil
+}
+
 // Shutdown gracefully stops the server.
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.logger.Info("shutting down HTTP server")
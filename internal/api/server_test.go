@@ -1,10 +1,26 @@
 package api
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -54,6 +70,100 @@ func TestHealthz(t *testing.T) {
 	}
 }
 
+func TestSpeakTTLResolution(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		query string
+		body  string
+		want  time.Duration
+	}{
+		{"omitted uses DEFAULT_TTL", "test-token", "", `{"text":"Hi"}`, 30 * time.Second},
+		{"explicit zero means no TTL", "test-token", "", `{"text":"Hi","ttl_ms":0}`, 0},
+		{"explicit value", "test-token", "", `{"text":"Hi","ttl_ms":5000}`, 5 * time.Second},
+		{"key default", "alerts-token", "", `{"text":"Hi"}`, 10 * time.Second},
+		{"key default of no TTL", "archive-token", "", `{"text":"Hi"}`, 0},
+		{"query beats key default", "alerts-token", "?default_ttl_ms=2000", `{"text":"Hi"}`, 2 * time.Second},
+		{"explicit beats query", "alerts-token", "?default_ttl_ms=2000", `{"text":"Hi","ttl_ms":0}`, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.DefaultTTL = 30 * time.Second
+			cfg.APIKeys = map[string][]string{
+				"alerts-token":  {config.ScopeSpeak},
+				"archive-token": {config.ScopeSpeak},
+			}
+			cfg.APIKeyTTLs = map[string]time.Duration{
+				"alerts-token":  10 * time.Second,
+				"archive-token": 0,
+			}
+			srv := testServer(cfg)
+
+			ttls := make(chan time.Duration, 1)
+			srv.queue.SetPlaybackHandler(func(ctx context.Context, job *queue.SpeakJob) error {
+				ttls <- job.TTL
+				return nil
+			})
+			srv.queue.Start()
+			defer srv.queue.Stop()
+
+			req := httptest.NewRequest("POST", "/v1/speak"+tt.query, bytes.NewBufferString(tt.body))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+
+			srv.server.Handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusAccepted {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+			}
+
+			select {
+			case ttl := <-ttls:
+				if ttl != tt.want {
+					t.Errorf("job TTL = %v, want %v", ttl, tt.want)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for job to play")
+			}
+		})
+	}
+}
+
+func TestSpeakInvalidDefaultTTL(t *testing.T) {
+	srv := testServer(testConfig())
+
+	req := httptest.NewRequest("POST", "/v1/speak?default_ttl_ms=-1", bytes.NewBufferString(`{"text":"Hi"}`))
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+
+	srv.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestHealthzJoinedOnStart(t *testing.T) {
+	srv := testServer(testConfig())
+	srv.SetJoinedOnStart(true)
+
+	req := httptest.NewRequest("GET", "/v1/healthz", nil)
+	w := httptest.NewRecorder()
+
+	srv.handleHealthz(w, req)
+
+	var resp HealthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if !resp.JoinedOnStart {
+		t.Error("expected joined_on_start to be true")
+	}
+}
+
 func TestSpeakSuccess(t *testing.T) {
 	cfg := testConfig()
 	srv := testServer(cfg)
@@ -63,8 +173,8 @@ func TestSpeakSuccess(t *testing.T) {
 	req.Header.Set("Authorization", "Bearer test-token")
 	w := httptest.NewRecorder()
 
-	// Manually call withAuth wrapper
-	handler := srv.withAuth(srv.handleSpeak)
+	// Manually call withScope wrapper
+	handler := srv.withScope(config.ScopeSpeak, srv.handleSpeak)
 	handler(w, req)
 
 	if w.Code != http.StatusAccepted {
@@ -90,7 +200,7 @@ func TestSpeakMissingText(t *testing.T) {
 	req.Header.Set("Authorization", "Bearer test-token")
 	w := httptest.NewRecorder()
 
-	handler := srv.withAuth(srv.handleSpeak)
+	handler := srv.withScope(config.ScopeSpeak, srv.handleSpeak)
 	handler(w, req)
 
 	if w.Code != http.StatusBadRequest {
@@ -117,7 +227,7 @@ func TestSpeakTextTooLong(t *testing.T) {
 	req.Header.Set("Authorization", "Bearer test-token")
 	w := httptest.NewRecorder()
 
-	handler := srv.withAuth(srv.handleSpeak)
+	handler := srv.withScope(config.ScopeSpeak, srv.handleSpeak)
 	handler(w, req)
 
 	if w.Code != http.StatusBadRequest {
@@ -134,6 +244,272 @@ func TestSpeakTextTooLong(t *testing.T) {
 	}
 }
 
+func TestSpeakQueueFullRetryAfter(t *testing.T) {
+	cfg := testConfig()
+	cfg.QueueCapacity = 1
+	srv := testServer(cfg)
+
+	if err := srv.queue.Enqueue(queue.NewSpeakJob("Hello", "default", false, 0, "")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := httptest.NewRequest("POST", "/v1/speak", bytes.NewBufferString(`{"text":"World"}`))
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+
+	srv.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusServiceUnavailable, w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Retry-After"); got != "5" {
+		t.Errorf("Retry-After = %q, want 5", got)
+	}
+}
+
+func TestSpeakBlockTimesOut(t *testing.T) {
+	cfg := testConfig()
+	cfg.QueueCapacity = 1
+	srv := testServer(cfg)
+
+	if err := srv.queue.Enqueue(queue.NewSpeakJob("Hello", "default", false, 0, "")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	body := `{"text":"World"}`
+	req := httptest.NewRequest("POST", "/v1/speak?block=true", bytes.NewBufferString(body)).WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+
+	handler := srv.withScope(config.ScopeSpeak, srv.handleSpeak)
+	handler(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %d: %s", http.StatusServiceUnavailable, w.Code, w.Body.String())
+	}
+
+	var resp ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if resp.Error != "timed out waiting for queue space" {
+		t.Errorf("expected error 'timed out waiting for queue space', got '%s'", resp.Error)
+	}
+}
+
+func TestSpeakBlockWaitLimit(t *testing.T) {
+	cfg := testConfig()
+	cfg.QueueCapacity = 1
+	srv := testServer(cfg)
+	srv.blockWait = 20 * time.Millisecond
+
+	if err := srv.queue.Enqueue(queue.NewSpeakJob("Hello", "default", false, 0, "")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The request has no deadline of its own
+	req := httptest.NewRequest("POST", "/v1/speak?block=true", bytes.NewBufferString(`{"text":"World"}`))
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		srv.server.Handler.ServeHTTP(w, req)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("block=true waited past its limit")
+	}
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %d: %s", http.StatusServiceUnavailable, w.Code, w.Body.String())
+	}
+	if srv.queue.Len() != 1 {
+		t.Errorf("expected queue length 1, got %d", srv.queue.Len())
+	}
+}
+
+func TestSpeakInvalidBlock(t *testing.T) {
+	cfg := testConfig()
+	srv := testServer(cfg)
+
+	body := `{"text":"Hello"}`
+	req := httptest.NewRequest("POST", "/v1/speak?block=maybe", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+
+	handler := srv.withScope(config.ScopeSpeak, srv.handleSpeak)
+	handler(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestSpeakUrgentPreemptsFullQueue(t *testing.T) {
+	cfg := testConfig()
+	cfg.QueueCapacity = 1
+	srv := testServer(cfg)
+
+	if err := srv.queue.Enqueue(queue.NewSpeakJob("Queued", "default", false, 0, "")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	body := `{"text":"Evacuate","urgent":true}`
+	req := httptest.NewRequest("POST", "/v1/speak", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+
+	srv.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+	}
+
+	var resp SpeakResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	// The interrupt cleared the queue, leaving only the urgent job
+	if srv.queue.Len() != 1 {
+		t.Errorf("expected queue length 1, got %d", srv.queue.Len())
+	}
+}
+
+func TestSpeakUrgentRequiresAdmin(t *testing.T) {
+	cfg := testConfig()
+	cfg.APIKeys = map[string][]string{"relay-token": {config.ScopeSpeak}}
+	srv := testServer(cfg)
+
+	body := `{"text":"Evacuate","urgent":true}`
+	req := httptest.NewRequest("POST", "/v1/speak", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer relay-token")
+	w := httptest.NewRecorder()
+
+	srv.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status %d, got %d", http.StatusForbidden, w.Code)
+	}
+	if srv.queue.Len() != 0 {
+		t.Errorf("expected nothing enqueued, got %d", srv.queue.Len())
+	}
+}
+
+func TestSpeakBatch(t *testing.T) {
+	srv := testServer(testConfig())
+
+	body := `{"messages":[{"text":"One"},{"text":"Two"}]}`
+	req := httptest.NewRequest("POST", "/v1/speak/batch", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+
+	srv.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+	}
+
+	var resp BatchSpeakResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(resp.Results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(resp.Results))
+	}
+	for i, result := range resp.Results {
+		if result.JobID == "" || result.Error != "" {
+			t.Errorf("results[%d] = %+v, want a job ID and no error", i, result)
+		}
+	}
+	if srv.queue.Len() != 2 {
+		t.Errorf("expected queue length 2, got %d", srv.queue.Len())
+	}
+}
+
+func TestSpeakBatchRejectedWhenFull(t *testing.T) {
+	cfg := testConfig()
+	cfg.QueueCapacity = 1
+	srv := testServer(cfg)
+
+	body := `{"messages":[{"text":"One"},{"text":"Two"}]}`
+	req := httptest.NewRequest("POST", "/v1/speak/batch", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+
+	srv.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "5" {
+		t.Errorf("Retry-After = %q, want 5", got)
+	}
+	if srv.queue.Len() != 0 {
+		t.Errorf("expected nothing enqueued, got %d", srv.queue.Len())
+	}
+}
+
+func TestSpeakBatchPartial(t *testing.T) {
+	cfg := testConfig()
+	cfg.QueueCapacity = 1
+	srv := testServer(cfg)
+
+	body := `{"messages":[{"text":"One"},{"text":"Two"}],"partial":true}`
+	req := httptest.NewRequest("POST", "/v1/speak/batch", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+
+	srv.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+	}
+
+	var resp BatchSpeakResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.Results[0].JobID == "" {
+		t.Errorf("expected first message enqueued, got %+v", resp.Results[0])
+	}
+	if resp.Results[1].Error != "queue is full" {
+		t.Errorf("expected second message to fail with 'queue is full', got %+v", resp.Results[1])
+	}
+}
+
+func TestSpeakBatchInvalidMessage(t *testing.T) {
+	srv := testServer(testConfig())
+
+	body := `{"messages":[{"text":"One"},{"text":""}]}`
+	req := httptest.NewRequest("POST", "/v1/speak/batch", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+
+	srv.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	var resp ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.Error != "messages[1]: text is required" {
+		t.Errorf("expected error 'messages[1]: text is required', got '%s'", resp.Error)
+	}
+	if srv.queue.Len() != 0 {
+		t.Errorf("expected nothing enqueued, got %d", srv.queue.Len())
+	}
+}
+
 func TestSpeakInvalidJSON(t *testing.T) {
 	cfg := testConfig()
 	srv := testServer(cfg)
@@ -143,7 +519,7 @@ func TestSpeakInvalidJSON(t *testing.T) {
 	req.Header.Set("Authorization", "Bearer test-token")
 	w := httptest.NewRecorder()
 
-	handler := srv.withAuth(srv.handleSpeak)
+	handler := srv.withScope(config.ScopeSpeak, srv.handleSpeak)
 	handler(w, req)
 
 	if w.Code != http.StatusBadRequest {
@@ -169,7 +545,7 @@ func TestSpeakNegativeTTL(t *testing.T) {
 	req.Header.Set("Authorization", "Bearer test-token")
 	w := httptest.NewRecorder()
 
-	handler := srv.withAuth(srv.handleSpeak)
+	handler := srv.withScope(config.ScopeSpeak, srv.handleSpeak)
 	handler(w, req)
 
 	if w.Code != http.StatusBadRequest {
@@ -195,10 +571,1366 @@ func TestSpeakWithOptionalFields(t *testing.T) {
 	req.Header.Set("Authorization", "Bearer test-token")
 	w := httptest.NewRecorder()
 
-	handler := srv.withAuth(srv.handleSpeak)
+	handler := srv.withScope(config.ScopeSpeak, srv.handleSpeak)
 	handler(w, req)
 
 	if w.Code != http.StatusAccepted {
 		t.Errorf("expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
 	}
 }
+
+func TestSpeakIntroTooLong(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxTextLength = 10
+	srv := testServer(cfg)
+
+	body := `{"text":"Hi","intro":"This intro is longer than 10 characters"}`
+	req := httptest.NewRequest("POST", "/v1/speak", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+
+	handler := srv.withScope(config.ScopeSpeak, srv.handleSpeak)
+	handler(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	var resp ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if resp.Error != "intro exceeds maximum length" {
+		t.Errorf("expected error 'intro exceeds maximum length', got '%s'", resp.Error)
+	}
+}
+
+func TestEventsStream(t *testing.T) {
+	cfg := testConfig()
+	srv := testServer(cfg)
+
+	ts := httptest.NewServer(srv.server.Handler)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL+"/v1/events", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /v1/events error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	job := queue.NewSpeakJob("Hello", "default", false, 0, "")
+	if err := srv.queue.Enqueue(job); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	var gotEvent, gotData bool
+	timeout := time.After(5 * time.Second)
+	for !gotData {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatal("event stream closed before event was received")
+			}
+			if line == "event: enqueued" {
+				gotEvent = true
+			}
+			if gotEvent && strings.HasPrefix(line, "data: ") {
+				var ev queue.Event
+				if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev); err != nil {
+					t.Fatalf("failed to unmarshal event: %v", err)
+				}
+				if ev.JobID != job.ID {
+					t.Errorf("event job_id = %q, want %q", ev.JobID, job.ID)
+				}
+				gotData = true
+			}
+		case <-timeout:
+			t.Fatal("timeout waiting for enqueued event")
+		}
+	}
+}
+
+func TestShutdownEndsEventStreams(t *testing.T) {
+	srv := testServer(testConfig())
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.server.Serve(ln)
+
+	req, _ := http.NewRequest("GET", "http://"+ln.Addr().String()+"/v1/events", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /v1/events error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v with a subscriber connected", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Shutdown() took %v, want it to end the stream promptly", elapsed)
+	}
+}
+
+func TestEventsRequiresAuth(t *testing.T) {
+	cfg := testConfig()
+	srv := testServer(cfg)
+
+	req := httptest.NewRequest("GET", "/v1/events", nil)
+	w := httptest.NewRecorder()
+
+	srv.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	srv := testServer(testConfig())
+
+	done := make(chan struct{}, 2)
+	srv.queue.SetJobCompletedCallback(func(job *queue.SpeakJob) { done <- struct{}{} })
+	srv.queue.SetPlaybackHandler(func(ctx context.Context, job *queue.SpeakJob) error { return nil })
+	srv.queue.Start()
+	defer srv.queue.Stop()
+
+	first := queue.NewSpeakJob("First", "default", false, 0, "")
+	second := queue.NewSpeakJob("Second", "default", false, 0, "")
+	for _, job := range []*queue.SpeakJob{first, second} {
+		if err := srv.queue.Enqueue(job); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for job to finish")
+		}
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCode int
+		wantIDs  []string
+	}{
+		{"all", "", http.StatusOK, []string{second.ID, first.ID}},
+		{"limited", "?limit=1", http.StatusOK, []string{second.ID}},
+		{"zero limit", "?limit=0", http.StatusBadRequest, nil},
+		{"non-numeric limit", "?limit=ten", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/v1/history"+tt.query, nil)
+			req.Header.Set("Authorization", "Bearer test-token")
+			w := httptest.NewRecorder()
+
+			srv.server.Handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var resp HistoryResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if len(resp.Jobs) != len(tt.wantIDs) {
+				t.Fatalf("expected %d jobs, got %d", len(tt.wantIDs), len(resp.Jobs))
+			}
+			for i, id := range tt.wantIDs {
+				if resp.Jobs[i].JobID != id || resp.Jobs[i].Status != queue.StatusCompleted {
+					t.Errorf("job %d = %+v, want %s completed", i, resp.Jobs[i], id)
+				}
+			}
+		})
+	}
+}
+
+// writeTestCA writes a self-signed CA certificate in PEM form and returns its path.
+func writeTestCA(t *testing.T) string {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return path
+}
+
+func TestTLSConfig(t *testing.T) {
+	t.Run("without client CA", func(t *testing.T) {
+		srv := testServer(testConfig())
+
+		tlsConfig, err := srv.tlsConfig()
+		if err != nil {
+			t.Fatalf("tlsConfig() error = %v", err)
+		}
+		if tlsConfig.ClientAuth != tls.NoClientCert {
+			t.Errorf("ClientAuth = %v, want NoClientCert", tlsConfig.ClientAuth)
+		}
+	})
+
+	t.Run("with client CA", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.TLSClientCA = writeTestCA(t)
+		srv := testServer(cfg)
+
+		tlsConfig, err := srv.tlsConfig()
+		if err != nil {
+			t.Fatalf("tlsConfig() error = %v", err)
+		}
+		if tlsConfig.ClientAuth != tls.RequireAndVerifyClientCert {
+			t.Errorf("ClientAuth = %v, want RequireAndVerifyClientCert", tlsConfig.ClientAuth)
+		}
+		if tlsConfig.ClientCAs == nil {
+			t.Error("ClientCAs = nil, want the configured CA")
+		}
+	})
+
+	t.Run("client CA without certificates", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.TLSClientCA = filepath.Join(t.TempDir(), "empty.pem")
+		os.WriteFile(cfg.TLSClientCA, []byte("not a certificate"), 0o600)
+		srv := testServer(cfg)
+
+		if _, err := srv.tlsConfig(); err == nil {
+			t.Error("tlsConfig() expected error for CA file without certificates")
+		}
+	})
+}
+
+// fakeSynthesizer returns fixed PCM, or err, for every job.
+type fakeSynthesizer struct {
+	pcm []byte
+	err error
+	job *queue.SpeakJob
+}
+
+func (f *fakeSynthesizer) Synthesize(ctx context.Context, job *queue.SpeakJob) ([]byte, error) {
+	f.job = job
+	if f.err != nil {
+		return nil, f.err
+	}
+	return append([]byte(nil), f.pcm...), nil
+}
+
+func TestSynthesizeWAV(t *testing.T) {
+	srv := testServer(testConfig())
+	synth := &fakeSynthesizer{pcm: []byte{0x01, 0x02, 0x03, 0x04}}
+	srv.SetSynthesizer(synth)
+
+	req := httptest.NewRequest("POST", "/v1/synthesize", bytes.NewBufferString(`{"text": "Hello", "voice": "amy"}`))
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+
+	srv.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "audio/wav" {
+		t.Errorf("Content-Type = %s, want audio/wav", ct)
+	}
+	body := w.Body.Bytes()
+	if len(body) != 44+4 || string(body[:4]) != "RIFF" {
+		t.Errorf("body is not a WAV wrapping the PCM: %d bytes", len(body))
+	}
+	if synth.job.Voice != "amy" || synth.job.Text != "Hello" {
+		t.Errorf("synthesized job = %+v, want text Hello voice amy", synth.job)
+	}
+	if srv.queue.Len() != 0 {
+		t.Errorf("queue length = %d, want synthesis to bypass the queue", srv.queue.Len())
+	}
+}
+
+func TestSynthesizeL16(t *testing.T) {
+	srv := testServer(testConfig())
+	srv.SetSynthesizer(&fakeSynthesizer{pcm: []byte{0x01, 0x02, 0x03, 0x04}})
+
+	req := httptest.NewRequest("POST", "/v1/synthesize", bytes.NewBufferString(`{"text": "Hello"}`))
+	req.Header.Set("Authorization", "Bearer test-token")
+	req.Header.Set("Accept", "audio/L16")
+	w := httptest.NewRecorder()
+
+	srv.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "audio/L16") {
+		t.Errorf("Content-Type = %s, want audio/L16", ct)
+	}
+	if got := w.Body.Bytes(); !bytes.Equal(got, []byte{0x02, 0x01, 0x04, 0x03}) {
+		t.Errorf("body = %x, want big-endian samples 02010403", got)
+	}
+}
+
+func TestSynthesizeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		synth    Synthesizer
+		strict   bool
+		body     string
+		wantCode int
+		wantErr  string
+	}{
+		{"not configured", nil, false, `{"text": "Hello"}`, http.StatusServiceUnavailable, ""},
+		{"missing text", &fakeSynthesizer{}, false, `{}`, http.StatusBadRequest, ""},
+		{"invalid json", &fakeSynthesizer{}, false, `{`, http.StatusBadRequest, ""},
+		{"unknown field in strict mode", &fakeSynthesizer{}, true, `{"txt": "Hello"}`, http.StatusBadRequest, `invalid JSON body: unknown field "txt"`},
+		{"unknown voice", &fakeSynthesizer{}, false, `{"text": "Hello", "voice": "nobody"}`, http.StatusBadRequest, `unknown voice "nobody"; valid voices: default, piper, amy`},
+		{"synthesis fails", &fakeSynthesizer{err: errors.New("boom")}, false, `{"text": "Hello"}`, http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.StrictJSON = tt.strict
+			srv := testServer(cfg)
+			srv.SetVoices(&fakeVoices{names: []string{"piper"}, def: "piper", known: []string{"amy"}})
+			if tt.synth != nil {
+				srv.SetSynthesizer(tt.synth)
+			}
+
+			req := httptest.NewRequest("POST", "/v1/synthesize", bytes.NewBufferString(tt.body))
+			req.Header.Set("Authorization", "Bearer test-token")
+			w := httptest.NewRecorder()
+
+			srv.server.Handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("expected status %d, got %d", tt.wantCode, w.Code)
+			}
+			if tt.wantErr != "" {
+				var resp ErrorResponse
+				json.NewDecoder(w.Body).Decode(&resp)
+				if resp.Error != tt.wantErr {
+					t.Errorf("error = %q, want %q", resp.Error, tt.wantErr)
+				}
+			}
+		})
+	}
+}
+
+// fakeVoices is a VoiceRegistry over a fixed set of names. Every engine
+// accepts the speakers in known; nil means engines can't list theirs.
+type fakeVoices struct {
+	names  []string
+	def    string
+	known  []string
+	limits map[string]int // engine -> MaxTextLength
+}
+
+func (f *fakeVoices) List() []string { return f.names }
+
+func (f *fakeVoices) Voices(name string) ([]string, bool) { return f.known, f.known != nil }
+
+func (f *fakeVoices) MaxTextLength(name string) (int, bool) {
+	if name == "" {
+		name = f.def
+	}
+	limit, ok := f.limits[name]
+	return limit, ok
+}
+
+func (f *fakeVoices) SetDefault(name string) error {
+	f.def = name
+	return nil
+}
+
+func TestSetDefaultVoice(t *testing.T) {
+	srv := testServer(testConfig())
+	voices := &fakeVoices{names: []string{"piper", "amy"}, def: "piper"}
+	srv.SetVoices(voices)
+
+	req := httptest.NewRequest("POST", "/v1/config/default-voice", bytes.NewBufferString(`{"voice": "amy"}`))
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+
+	srv.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var resp DefaultVoiceResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.Voice != "amy" {
+		t.Errorf("expected voice 'amy', got '%s'", resp.Voice)
+	}
+	if voices.def != "amy" {
+		t.Errorf("expected default 'amy', got '%s'", voices.def)
+	}
+}
+
+func TestSetDefaultVoiceUnknown(t *testing.T) {
+	srv := testServer(testConfig())
+	voices := &fakeVoices{names: []string{"piper"}, def: "piper"}
+	srv.SetVoices(voices)
+
+	req := httptest.NewRequest("POST", "/v1/config/default-voice", bytes.NewBufferString(`{"voice": "nobody"}`))
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+
+	srv.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	if voices.def != "piper" {
+		t.Errorf("default changed to '%s' for an unknown voice", voices.def)
+	}
+}
+
+func TestSetDefaultVoiceRequiresAdmin(t *testing.T) {
+	cfg := testConfig()
+	cfg.APIKeys = map[string][]string{"relay-token": {config.ScopeSpeak}}
+	srv := testServer(cfg)
+	srv.SetVoices(&fakeVoices{names: []string{"amy"}})
+
+	req := httptest.NewRequest("POST", "/v1/config/default-voice", bytes.NewBufferString(`{"voice": "amy"}`))
+	req.Header.Set("Authorization", "Bearer relay-token")
+	w := httptest.NewRecorder()
+
+	srv.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status %d, got %d", http.StatusForbidden, w.Code)
+	}
+}
+
+func TestSetVoiceSettings(t *testing.T) {
+	srv := testServer(testConfig())
+	srv.SetVoiceSettings("amy", map[string]config.VoiceAlias{"narrator": {Engine: "polly", Voice: "Matthew"}})
+
+	jobs := make(chan *queue.SpeakJob, 2)
+	srv.queue.SetPlaybackHandler(func(ctx context.Context, job *queue.SpeakJob) error {
+		jobs <- job
+		return nil
+	})
+	srv.queue.Start()
+	defer srv.queue.Stop()
+
+	for _, body := range []string{`{"text":"Hi"}`, `{"text":"Hi","voice":"narrator"}`} {
+		req := httptest.NewRequest("POST", "/v1/speak", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer test-token")
+		w := httptest.NewRecorder()
+		srv.server.Handler.ServeHTTP(w, req)
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+		}
+	}
+
+	want := []string{"/amy", "polly/Matthew"}
+	for i := range want {
+		select {
+		case job := <-jobs:
+			if got := job.Engine + "/" + job.Voice; got != want[i] {
+				t.Errorf("job %d engine/voice = %q, want %q", i, got, want[i])
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for job to play")
+		}
+	}
+}
+
+func TestSpeakDefaultVoiceResolution(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		body  string
+		want  string
+	}{
+		{"request voice wins", "tenant-token", `{"text":"Hi","voice":"bob"}`, "bob"},
+		{"key default voice", "tenant-token", `{"text":"Hi"}`, "amy"},
+		{"key without default voice", "relay-token", `{"text":"Hi"}`, "default"},
+		{"full-access token", "test-token", `{"text":"Hi"}`, "default"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.APIKeys = map[string][]string{
+				"tenant-token": {config.ScopeSpeak},
+				"relay-token":  {config.ScopeSpeak},
+			}
+			cfg.APIKeyVoices = map[string]string{"tenant-token": "amy"}
+			srv := testServer(cfg)
+
+			voices := make(chan string, 1)
+			srv.queue.SetPlaybackHandler(func(ctx context.Context, job *queue.SpeakJob) error {
+				voices <- job.Voice
+				return nil
+			})
+			srv.queue.Start()
+			defer srv.queue.Stop()
+
+			req := httptest.NewRequest("POST", "/v1/speak", bytes.NewBufferString(tt.body))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+
+			srv.server.Handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusAccepted {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+			}
+
+			select {
+			case voice := <-voices:
+				if voice != tt.want {
+					t.Errorf("job voice = %q, want %q", voice, tt.want)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for job to play")
+			}
+		})
+	}
+}
+
+func TestSpeakLocale(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+		want     string
+	}{
+		{"request locale", `{"text":"1.000,50 €","locale":"de-DE"}`, http.StatusAccepted, "de-DE"},
+		{"no locale", `{"text":"Hi"}`, http.StatusAccepted, ""},
+		{"unsupported locale", `{"text":"Hi","locale":"ja-JP"}`, http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := testServer(testConfig())
+
+			locales := make(chan string, 1)
+			srv.queue.SetPlaybackHandler(func(ctx context.Context, job *queue.SpeakJob) error {
+				locales <- job.Locale
+				return nil
+			})
+			srv.queue.Start()
+			defer srv.queue.Stop()
+
+			req := httptest.NewRequest("POST", "/v1/speak", bytes.NewBufferString(tt.body))
+			req.Header.Set("Authorization", "Bearer test-token")
+			w := httptest.NewRecorder()
+
+			srv.server.Handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if tt.wantCode != http.StatusAccepted {
+				return
+			}
+
+			select {
+			case locale := <-locales:
+				if locale != tt.want {
+					t.Errorf("job locale = %q, want %q", locale, tt.want)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for job to play")
+			}
+		})
+	}
+}
+
+func TestSpeakLanguage(t *testing.T) {
+	tests := []struct {
+		name        string
+		defaultLang string
+		body        string
+		wantCode    int
+		want        string
+	}{
+		{"request language", "en-US", `{"text":"Hallo","language":"de-DE"}`, http.StatusAccepted, "de-DE"},
+		{"default language", "en-US", `{"text":"Hi"}`, http.StatusAccepted, "en-US"},
+		{"no default", "", `{"text":"Hi"}`, http.StatusAccepted, ""},
+		{"script subtag", "", `{"text":"Hi","language":"zh-Hant-TW"}`, http.StatusAccepted, "zh-Hant-TW"},
+		{"invalid language", "", `{"text":"Hi","language":"english please"}`, http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.DefaultLanguage = tt.defaultLang
+			srv := testServer(cfg)
+
+			languages := make(chan string, 1)
+			srv.queue.SetPlaybackHandler(func(ctx context.Context, job *queue.SpeakJob) error {
+				languages <- job.Language
+				return nil
+			})
+			srv.queue.Start()
+			defer srv.queue.Stop()
+
+			req := httptest.NewRequest("POST", "/v1/speak", bytes.NewBufferString(tt.body))
+			req.Header.Set("Authorization", "Bearer test-token")
+			w := httptest.NewRecorder()
+
+			srv.server.Handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if tt.wantCode != http.StatusAccepted {
+				return
+			}
+
+			select {
+			case language := <-languages:
+				if language != tt.want {
+					t.Errorf("job language = %q, want %q", language, tt.want)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for job to play")
+			}
+		})
+	}
+}
+
+func TestSpeakVoiceAliasResolution(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      string
+		body       string
+		wantEngine string
+		wantVoice  string
+	}{
+		{"alias to engine and voice", "test-token", `{"text":"Hi","voice":"narrator"}`, "polly", "Matthew:neural"},
+		{"alias to voice only", "test-token", `{"text":"Hi","voice":"deep"}`, "", "3"},
+		{"unknown voice is literal", "test-token", `{"text":"Hi","voice":"bob"}`, "", "bob"},
+		{"key default voice alias", "tenant-token", `{"text":"Hi"}`, "polly", "Matthew:neural"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.APIKeys = map[string][]string{"tenant-token": {config.ScopeSpeak}}
+			cfg.APIKeyVoices = map[string]string{"tenant-token": "narrator"}
+			cfg.VoiceAliases = map[string]config.VoiceAlias{
+				"narrator": {Engine: "polly", Voice: "Matthew:neural"},
+				"deep":     {Voice: "3"},
+			}
+			srv := testServer(cfg)
+
+			jobs := make(chan *queue.SpeakJob, 1)
+			srv.queue.SetPlaybackHandler(func(ctx context.Context, job *queue.SpeakJob) error {
+				jobs <- job
+				return nil
+			})
+			srv.queue.Start()
+			defer srv.queue.Stop()
+
+			req := httptest.NewRequest("POST", "/v1/speak", bytes.NewBufferString(tt.body))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+
+			srv.server.Handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusAccepted {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+			}
+
+			select {
+			case job := <-jobs:
+				if job.Engine != tt.wantEngine || job.Voice != tt.wantVoice {
+					t.Errorf("job engine/voice = %q/%q, want %q/%q", job.Engine, job.Voice, tt.wantEngine, tt.wantVoice)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for job to play")
+			}
+		})
+	}
+}
+
+func TestSpeakAutoDedupe(t *testing.T) {
+	tests := []struct {
+		name       string
+		autoDedupe bool
+		second     string
+		want       int
+	}{
+		{"repeat collapsed", true, `{"text":"Hi"}`, http.StatusConflict},
+		{"different voice", true, `{"text":"Hi","voice":"amy"}`, http.StatusAccepted},
+		{"different text", true, `{"text":"Hello"}`, http.StatusAccepted},
+		{"explicit key kept", true, `{"text":"Hi","dedupe_key":"again"}`, http.StatusAccepted},
+		{"disabled", false, `{"text":"Hi"}`, http.StatusAccepted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.AutoDedupe = tt.autoDedupe
+			// The queue is not started, so the first job stays queued
+			srv := testServer(cfg)
+
+			for i, body := range []string{`{"text":"Hi"}`, tt.second} {
+				req := httptest.NewRequest("POST", "/v1/speak", bytes.NewBufferString(body))
+				req.Header.Set("Authorization", "Bearer test-token")
+				w := httptest.NewRecorder()
+
+				srv.server.Handler.ServeHTTP(w, req)
+
+				want := http.StatusAccepted
+				if i == 1 {
+					want = tt.want
+				}
+				if w.Code != want {
+					t.Fatalf("request %d: expected status %d, got %d: %s", i+1, want, w.Code, w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestSpeakOnDuplicate(t *testing.T) {
+	tests := []struct {
+		name         string
+		onDuplicate  string
+		wantCode     int
+		wantReplaced bool
+	}{
+		{"default rejects", "", http.StatusConflict, false},
+		{"reject", "reject", http.StatusConflict, false},
+		{"replace", "replace", http.StatusAccepted, true},
+		{"invalid", "merge", http.StatusBadRequest, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The queue is not started, so the first job stays queued
+			srv := testServer(testConfig())
+			var first SpeakResponse
+			for i, body := range []string{
+				`{"text":"Building","dedupe_key":"status"}`,
+				`{"text":"Deployed","dedupe_key":"status","on_duplicate":"` + tt.onDuplicate + `"}`,
+			} {
+				req := httptest.NewRequest("POST", "/v1/speak", bytes.NewBufferString(body))
+				req.Header.Set("Authorization", "Bearer test-token")
+				w := httptest.NewRecorder()
+
+				srv.server.Handler.ServeHTTP(w, req)
+
+				want := http.StatusAccepted
+				if i == 1 {
+					want = tt.wantCode
+				}
+				if w.Code != want {
+					t.Fatalf("request %d: expected status %d, got %d: %s", i+1, want, w.Code, w.Body.String())
+				}
+				if i == 0 {
+					json.NewDecoder(w.Body).Decode(&first)
+					continue
+				}
+				if !tt.wantReplaced {
+					continue
+				}
+				var resp SpeakResponse
+				json.NewDecoder(w.Body).Decode(&resp)
+				if resp.ReplacedJobID != first.JobID || resp.QueuePosition != 1 {
+					t.Errorf("response = %+v, want job %s replaced at position 1", resp, first.JobID)
+				}
+			}
+			if got := srv.queue.Len(); got != 1 {
+				t.Errorf("queue length = %d, want 1", got)
+			}
+		})
+	}
+}
+
+func TestSpeakChannel(t *testing.T) {
+	tests := []struct {
+		name     string
+		allowed  []string
+		path     string
+		body     string
+		wantCode int
+	}{
+		{"no channel", nil, "/v1/speak", `{"text":"Hello"}`, http.StatusAccepted},
+		{"default channel", nil, "/v1/speak", `{"text":"Hello","channel_id":"lobby"}`, http.StatusAccepted},
+		{"other channel without allowlist", nil, "/v1/speak", `{"text":"Hello","channel_id":"music"}`, http.StatusForbidden},
+		{"allowed channel", []string{"music"}, "/v1/speak", `{"text":"Hello","channel_id":"music"}`, http.StatusAccepted},
+		{"default channel with allowlist", []string{"music"}, "/v1/speak", `{"text":"Hello","channel_id":"lobby"}`, http.StatusAccepted},
+		{"denied channel", []string{"music"}, "/v1/speak", `{"text":"Hello","channel_id":"staff"}`, http.StatusForbidden},
+		{"batch allowed", []string{"music"}, "/v1/speak/batch", `{"messages":[{"text":"One"},{"text":"Two","channel_id":"music"}]}`, http.StatusAccepted},
+		{"batch denied", []string{"music"}, "/v1/speak/batch", `{"messages":[{"text":"One"},{"text":"Two","channel_id":"staff"}]}`, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.DefaultVoiceChannelID = "lobby"
+			cfg.AllowedChannels = tt.allowed
+			srv := testServer(cfg)
+
+			req := httptest.NewRequest("POST", tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Authorization", "Bearer test-token")
+			w := httptest.NewRecorder()
+
+			srv.server.Handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if tt.wantCode == http.StatusForbidden && srv.queue.Len() != 0 {
+				t.Errorf("expected nothing enqueued, got %d", srv.queue.Len())
+			}
+		})
+	}
+}
+
+func TestSpeakStrictJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		strict   bool
+		path     string
+		body     string
+		wantCode int
+		wantErr  string
+	}{
+		{"unknown field", true, "/v1/speak", `{"txt":"Hello"}`, http.StatusBadRequest, `invalid JSON body: unknown field "txt"`},
+		{"trailing object", true, "/v1/speak", `{"text":"Hello"}{"text":"again"}`, http.StatusBadRequest, "invalid JSON body: unexpected data after JSON object"},
+		{"trailing garbage", true, "/v1/speak", `{"text":"Hello"} garbage`, http.StatusBadRequest, "invalid JSON body: unexpected data after JSON object"},
+		{"trailing whitespace", true, "/v1/speak", "{\"text\":\"Hello\"}\n", http.StatusAccepted, ""},
+		{"batch unknown field", true, "/v1/speak/batch", `{"messages":[{"text":"Hello","volume":3}]}`, http.StatusBadRequest, `invalid JSON body: unknown field "volume"`},
+		{"lenient by default", false, "/v1/speak", `{"text":"Hello","txt":"Hello"} garbage`, http.StatusAccepted, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.StrictJSON = tt.strict
+			srv := testServer(cfg)
+
+			req := httptest.NewRequest("POST", tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Authorization", "Bearer test-token")
+			w := httptest.NewRecorder()
+
+			srv.server.Handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if tt.wantErr == "" {
+				return
+			}
+			var resp ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if resp.Error != tt.wantErr {
+				t.Errorf("error = %q, want %q", resp.Error, tt.wantErr)
+			}
+		})
+	}
+}
+
+// fakeVoiceConnection counts disconnects.
+type fakeVoiceConnection struct {
+	disconnects int
+}
+
+func (f *fakeVoiceConnection) Disconnect() error {
+	f.disconnects++
+	return nil
+}
+
+func TestInterrupt(t *testing.T) {
+	tests := []struct {
+		name             string
+		query            string
+		voice            bool
+		wantCode         int
+		wantDisconnected bool
+	}{
+		{"clears queue", "", true, http.StatusOK, false},
+		{"and disconnects", "?disconnect=true", true, http.StatusOK, true},
+		{"disconnect without voice", "?disconnect=true", false, http.StatusServiceUnavailable, false},
+		{"invalid disconnect", "?disconnect=maybe", true, http.StatusBadRequest, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := testServer(testConfig())
+			voice := &fakeVoiceConnection{}
+			if tt.voice {
+				srv.SetVoiceConnection(voice)
+			}
+
+			// Worker not started, so queued jobs stay put until interrupted
+			for _, text := range []string{"One", "Two", "Three"} {
+				srv.queue.Enqueue(queue.NewSpeakJob(text, "default", false, 0, ""))
+			}
+
+			req := httptest.NewRequest("POST", "/v1/interrupt"+tt.query, nil)
+			req.Header.Set("Authorization", "Bearer test-token")
+			w := httptest.NewRecorder()
+
+			srv.server.Handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				if srv.queue.Len() != 3 {
+					t.Errorf("expected a rejected interrupt to leave 3 jobs, got %d", srv.queue.Len())
+				}
+				return
+			}
+
+			var resp InterruptResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if resp.Cleared != 3 {
+				t.Errorf("expected 3 jobs cleared, got %d", resp.Cleared)
+			}
+			if srv.queue.Len() != 0 {
+				t.Errorf("expected empty queue, got length %d", srv.queue.Len())
+			}
+			if resp.Disconnected != tt.wantDisconnected {
+				t.Errorf("disconnected = %v, want %v", resp.Disconnected, tt.wantDisconnected)
+			}
+			wantCalls := 0
+			if tt.wantDisconnected {
+				wantCalls = 1
+			}
+			if voice.disconnects != wantCalls {
+				t.Errorf("Disconnect called %d times, want %d", voice.disconnects, wantCalls)
+			}
+		})
+	}
+}
+
+func TestInterruptRequiresSpeakScope(t *testing.T) {
+	cfg := testConfig()
+	cfg.APIKeys = map[string][]string{"reader-token": {config.ScopeRead}}
+	srv := testServer(cfg)
+	srv.queue.Enqueue(queue.NewSpeakJob("Queued", "default", false, 0, ""))
+
+	req := httptest.NewRequest("POST", "/v1/interrupt", nil)
+	req.Header.Set("Authorization", "Bearer reader-token")
+	w := httptest.NewRecorder()
+
+	srv.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status %d, got %d", http.StatusForbidden, w.Code)
+	}
+	if srv.queue.Len() != 1 {
+		t.Errorf("expected queue untouched, got length %d", srv.queue.Len())
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		token    string
+		handler  http.Handler
+		wantCode int
+	}{
+		{"served", "reader-token", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("discorgeous_queue_depth 0\n"))
+		}), http.StatusOK},
+		{"not configured", "reader-token", nil, http.StatusServiceUnavailable},
+		{"requires read scope", "speaker-token", http.NotFoundHandler(), http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.APIKeys = map[string][]string{
+				"reader-token":  {config.ScopeRead},
+				"speaker-token": {config.ScopeSpeak},
+			}
+			srv := testServer(cfg)
+			if tt.handler != nil {
+				srv.SetMetricsHandler(tt.handler)
+			}
+
+			req := httptest.NewRequest("GET", "/v1/metrics", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+
+			srv.server.Handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if tt.wantCode == http.StatusOK && !strings.Contains(w.Body.String(), "discorgeous_queue_depth") {
+				t.Errorf("body = %q, want the metrics handler's output", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestSpeakQueuePosition(t *testing.T) {
+	cfg := testConfig()
+	// The queue is not started, so jobs pile up
+	srv := testServer(cfg)
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer test-token")
+		w := httptest.NewRecorder()
+		srv.server.Handler.ServeHTTP(w, req)
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+		}
+		return w
+	}
+	position := func(w *httptest.ResponseRecorder) int {
+		t.Helper()
+		var resp SpeakResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		return resp.QueuePosition
+	}
+
+	for want := 1; want <= 3; want++ {
+		if got := position(post("/v1/speak", `{"text":"Hello"}`)); got != want {
+			t.Errorf("queue_position = %d, want %d", got, want)
+		}
+	}
+
+	// Interrupting clears the queue, so the new job is next
+	if got := position(post("/v1/speak", `{"text":"Now","interrupt":true}`)); got != 1 {
+		t.Errorf("queue_position after interrupt = %d, want 1", got)
+	}
+
+	w := post("/v1/speak/batch", `{"messages":[{"text":"One"},{"text":"Two"}]}`)
+	var batch BatchSpeakResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &batch); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	for i, result := range batch.Results {
+		if want := i + 2; result.QueuePosition != want {
+			t.Errorf("batch message %d queue_position = %d, want %d", i, result.QueuePosition, want)
+		}
+	}
+}
+
+func TestSpeakValidatesVoice(t *testing.T) {
+	tests := []struct {
+		name     string
+		known    []string
+		voice    string
+		wantCode int
+	}{
+		{"known speaker", []string{"amy", "bob"}, "amy", http.StatusAccepted},
+		{"unknown speaker", []string{"amy", "bob"}, "nobody", http.StatusBadRequest},
+		{"default voice", []string{"amy"}, "default", http.StatusAccepted},
+		{"omitted voice", []string{"amy"}, "", http.StatusAccepted},
+		{"engine name", []string{"amy"}, "piper", http.StatusAccepted},
+		{"engine can't list voices", nil, "nobody", http.StatusAccepted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := testServer(testConfig())
+			srv.SetVoices(&fakeVoices{names: []string{"piper"}, def: "piper", known: tt.known})
+
+			body, _ := json.Marshal(SpeakRequest{Text: "Hello", Voice: tt.voice})
+			req := httptest.NewRequest("POST", "/v1/speak", bytes.NewReader(body))
+			req.Header.Set("Authorization", "Bearer test-token")
+			w := httptest.NewRecorder()
+
+			srv.server.Handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if tt.wantCode == http.StatusBadRequest {
+				var resp ErrorResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("failed to unmarshal response: %v", err)
+				}
+				want := `unknown voice "nobody"; valid voices: default, piper, amy, bob`
+				if resp.Error != want {
+					t.Errorf("expected error %q, got %q", want, resp.Error)
+				}
+				if srv.queue.Len() != 0 {
+					t.Errorf("expected nothing queued, got %d jobs", srv.queue.Len())
+				}
+			}
+		})
+	}
+}
+
+func TestSpeakEngineMaxTextLength(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      SpeakRequest
+		wantCode int
+		wantErr  string
+	}{
+		{"default engine", SpeakRequest{Text: "Hello, world!"}, http.StatusAccepted, ""},
+		{"within engine limit", SpeakRequest{Text: "Hello", Voice: "polly"}, http.StatusAccepted, ""},
+		{"over engine limit", SpeakRequest{Text: "Hello, world!", Voice: "polly"}, http.StatusBadRequest, "text exceeds maximum length of 10 for this engine"},
+		{"intro over engine limit", SpeakRequest{Text: "Hello", Intro: "Attention please", Voice: "polly"}, http.StatusBadRequest, "intro exceeds maximum length of 10 for this engine"},
+		{"unknown engine", SpeakRequest{Text: "Hello, world!", Voice: "amy"}, http.StatusAccepted, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := testServer(testConfig())
+			srv.SetVoices(&fakeVoices{
+				names:  []string{"piper", "polly"},
+				def:    "piper",
+				limits: map[string]int{"piper": 1 << 20, "polly": 10},
+			})
+
+			body, _ := json.Marshal(tt.req)
+			req := httptest.NewRequest("POST", "/v1/speak", bytes.NewReader(body))
+			req.Header.Set("Authorization", "Bearer test-token")
+			w := httptest.NewRecorder()
+
+			srv.server.Handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if tt.wantErr != "" {
+				var resp ErrorResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("failed to unmarshal response: %v", err)
+				}
+				if resp.Error != tt.wantErr {
+					t.Errorf("expected error %q, got %q", tt.wantErr, resp.Error)
+				}
+				if srv.queue.Len() != 0 {
+					t.Errorf("expected nothing queued, got %d jobs", srv.queue.Len())
+				}
+			}
+		})
+	}
+}
+
+func TestSpeakBatchValidatesVoice(t *testing.T) {
+	srv := testServer(testConfig())
+	srv.SetVoices(&fakeVoices{names: []string{"piper"}, known: []string{"amy"}})
+
+	body := `{"messages": [{"text": "one", "voice": "amy"}, {"text": "two", "voice": "nobody"}]}`
+	req := httptest.NewRequest("POST", "/v1/speak/batch", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+
+	srv.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "messages[1]: unknown voice") {
+		t.Errorf("expected the error to name messages[1], got %s", w.Body.String())
+	}
+	if srv.queue.Len() != 0 {
+		t.Errorf("expected nothing queued, got %d jobs", srv.queue.Len())
+	}
+}
+
+func TestSpeakIdempotencyKey(t *testing.T) {
+	cfg := testConfig()
+	cfg.IdempotencyTTL = time.Minute
+	srv := testServer(cfg)
+	now := time.Now()
+	srv.idempotency.now = func() time.Time { return now }
+
+	speak := func(key, body string) (*httptest.ResponseRecorder, SpeakResponse) {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/v1/speak", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer test-token")
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		w := httptest.NewRecorder()
+		srv.server.Handler.ServeHTTP(w, req)
+		var resp SpeakResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	// A failed request isn't remembered, so its retry is handled afresh
+	if w, _ := speak("retry-1", `{"text":""}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	w, first := speak("retry-1", `{"text":"Hello"}`)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+	}
+	if w.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("first request marked as replayed")
+	}
+
+	w, replay := speak("retry-1", `{"text":"Hello"}`)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("replay: expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+	}
+	if replay != first {
+		t.Errorf("replay response = %+v, want the original %+v", replay, first)
+	}
+	if w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("replay not marked with Idempotent-Replayed")
+	}
+	if srv.queue.Len() != 1 {
+		t.Errorf("queued %d jobs after replay, want 1", srv.queue.Len())
+	}
+
+	// Another key, or the same one after the TTL, queues a new job
+	if _, other := speak("retry-2", `{"text":"Hello"}`); other.JobID == first.JobID {
+		t.Error("different key returned the original job")
+	}
+	now = now.Add(time.Minute)
+	if _, expired := speak("retry-1", `{"text":"Hello"}`); expired.JobID == first.JobID {
+		t.Error("expired key returned the original job")
+	}
+	if srv.queue.Len() != 3 {
+		t.Errorf("queued %d jobs, want 3", srv.queue.Len())
+	}
+
+	if w, _ := speak(strings.Repeat("k", maxIdempotencyKeyLength+1), `{"text":"Hello"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("overlong key: expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestIdempotencyCacheInFlight(t *testing.T) {
+	c := newIdempotencyCache(time.Minute)
+
+	if _, state := c.begin("key"); state != idempotencyNew {
+		t.Fatalf("first begin = %v, want idempotencyNew", state)
+	}
+	if _, state := c.begin("key"); state != idempotencyInFlight {
+		t.Errorf("begin while in flight = %v, want idempotencyInFlight", state)
+	}
+
+	c.abandon("key")
+	if _, state := c.begin("key"); state != idempotencyNew {
+		t.Errorf("begin after abandon = %v, want idempotencyNew", state)
+	}
+
+	c.complete("key", SpeakResponse{JobID: "job-1"})
+	if resp, state := c.begin("key"); state != idempotencyReplay || resp.JobID != "job-1" {
+		t.Errorf("begin after complete = %+v, %v, want job-1 replayed", resp, state)
+	}
+}
+
+func TestVoices(t *testing.T) {
+	cfg := testConfig()
+	cfg.VoiceAliases = map[string]config.VoiceAlias{"narrator": {Voice: "bob"}, "amy": {Voice: "amy"}}
+	srv := testServer(cfg)
+	srv.SetVoices(&fakeVoices{names: []string{"piper", "amy"}})
+
+	req := httptest.NewRequest("GET", "/v1/voices", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+
+	srv.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var resp VoicesResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Default != "default" || !slices.Equal(resp.Voices, []string{"amy", "narrator", "piper"}) {
+		t.Errorf("response = %+v, want default voice and [amy narrator piper]", resp)
+	}
+}
+
+func TestWebUI(t *testing.T) {
+	tests := []struct {
+		name     string
+		enabled  bool
+		path     string
+		wantCode int
+	}{
+		{"enabled", true, "/", http.StatusOK},
+		{"disabled", false, "/", http.StatusNotFound},
+		{"only the root", true, "/index.html", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.WebUI = tt.enabled
+			srv := testServer(cfg)
+
+			req := httptest.NewRequest("GET", tt.path, nil)
+			w := httptest.NewRecorder()
+
+			srv.server.Handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d", tt.wantCode, w.Code)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			if got := w.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+				t.Errorf("Content-Type = %q, want text/html; charset=utf-8", got)
+			}
+			if !strings.Contains(w.Body.String(), "/v1/speak") {
+				t.Error("page doesn't call /v1/speak")
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// webUIPage is the page served at / when WEB_UI is enabled.
+//
+//go:embed webui/index.html
+var webUIPage []byte
+
+// webUIPolicy only lets the page's own inline script and style run and
+// talk to this server.
+const webUIPolicy = "default-src 'none'; script-src 'unsafe-inline'; style-src 'unsafe-inline'; connect-src 'self'; form-action 'none'; frame-ancestors 'none'"
+
+// handleWebUI handles GET /, serving a page for speaking test messages by
+// hand. It calls the API with a token typed into the page, so serving it
+// needs no authentication.
+func (s *Server) handleWebUI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", webUIPolicy)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write(webUIPage)
+}
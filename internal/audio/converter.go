@@ -7,6 +7,12 @@ import (
 	"fmt"
 	"io"
 	"os/exec"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dgnsrekt/discorgeous-go/internal/logging"
+	"github.com/dgnsrekt/discorgeous-go/internal/metrics"
 )
 
 const (
@@ -18,6 +24,12 @@ const (
 	DiscordFrameSize = 960
 	// DiscordFrameBytes is the size of one frame in bytes (stereo 16-bit).
 	DiscordFrameBytes = DiscordFrameSize * DiscordChannels * 2
+	// DefaultSilenceThreshold is the absolute sample amplitude at or below
+	// which Discord PCM is treated as silence (about -54 dBFS).
+	DefaultSilenceThreshold = 64
+	// silencePaddingBytes is how much near-silence is kept around audible
+	// content when trimming, so soft attacks and decays are not clipped.
+	silencePaddingBytes = DiscordFrameBytes
 )
 
 var (
@@ -25,11 +37,19 @@ var (
 	ErrFFmpegNotFound = errors.New("ffmpeg not found in PATH")
 	// ErrConversionFailed is returned when ffmpeg conversion fails.
 	ErrConversionFailed = errors.New("audio conversion failed")
+	// ErrEmptyInput is returned when there is no audio to convert.
+	ErrEmptyInput = errors.New("empty input data")
+	// ErrOutputTooLarge is returned when converted audio exceeds the
+	// converter's maximum output size.
+	ErrOutputTooLarge = errors.New("converted audio too large")
 )
 
 // Converter handles audio format conversion for Discord.
 type Converter struct {
-	ffmpegPath string
+	ffmpegPath   string
+	metrics      metrics.Metrics
+	fastResample bool
+	maxOutput    int
 }
 
 // NewConverter creates a new audio converter.
@@ -38,12 +58,32 @@ func NewConverter() (*Converter, error) {
 	if err != nil {
 		return nil, ErrFFmpegNotFound
 	}
-	return &Converter{ffmpegPath: path}, nil
+	return &Converter{ffmpegPath: path, metrics: metrics.Nop{}}, nil
 }
 
 // NewConverterWithPath creates a converter with a specific ffmpeg path.
 func NewConverterWithPath(path string) *Converter {
-	return &Converter{ffmpegPath: path}
+	return &Converter{ffmpegPath: path, metrics: metrics.Nop{}}
+}
+
+// SetMetrics sets where conversion timings and failures are reported.
+// If m is nil, nothing is reported. Call it before converting.
+func (c *Converter) SetMetrics(m metrics.Metrics) {
+	c.metrics = metrics.OrNop(m)
+}
+
+// SetFastResample converts 16-bit mono or stereo PCM WAV, such as Piper's
+// output, in pure Go instead of running ffmpeg. Other input, and streamed
+// audio, still goes through ffmpeg.
+func (c *Converter) SetFastResample(enabled bool) {
+	c.fastResample = enabled
+}
+
+// SetMaxOutputBytes caps the PCM one ConvertToDiscordPCM call may produce;
+// past it ffmpeg is killed and ErrOutputTooLarge returned. Zero or less is
+// unlimited. Streamed conversion isn't capped, as it is never held whole.
+func (c *Converter) SetMaxOutputBytes(n int) {
+	c.maxOutput = n
 }
 
 // ConvertToDiscordPCM converts WAV audio to Discord-ready 48kHz stereo 16-bit PCM.
@@ -51,7 +91,18 @@ func NewConverterWithPath(path string) *Converter {
 // Output: Raw PCM bytes (48kHz, stereo, 16-bit signed little-endian)
 func (c *Converter) ConvertToDiscordPCM(ctx context.Context, wavData []byte) ([]byte, error) {
 	if len(wavData) == 0 {
-		return nil, errors.New("empty input data")
+		return nil, ErrEmptyInput
+	}
+
+	if c.fastResample {
+		start := time.Now()
+		if pcm, ok := fastConvert(wavData); ok {
+			c.metrics.Observe("audio_conversion_seconds", time.Since(start).Seconds())
+			if c.maxOutput > 0 && len(pcm) > c.maxOutput {
+				return nil, fmt.Errorf("%w: more than %d bytes", ErrOutputTooLarge, c.maxOutput)
+			}
+			return pcm, nil
+		}
 	}
 
 	// ffmpeg command to convert any WAV to Discord format:
@@ -74,36 +125,266 @@ func (c *Converter) ConvertToDiscordPCM(ctx context.Context, wavData []byte) ([]
 	cmd := exec.CommandContext(ctx, c.ffmpegPath, args...)
 	cmd.Stdin = bytes.NewReader(wavData)
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
+	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
-	if err := cmd.Run(); err != nil {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrConversionFailed, err)
+	}
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
 		if ctx.Err() != nil {
 			return nil, ctx.Err()
 		}
-		return nil, fmt.Errorf("%w: %s", ErrConversionFailed, stderr.String())
+		c.metrics.Counter("audio_conversion_failures_total", 1)
+		return nil, fmt.Errorf("%w: %v", ErrConversionFailed, err)
+	}
+
+	// Read one byte past the cap to tell output that fits from output that
+	// doesn't, then kill ffmpeg rather than buffer the rest
+	var src io.Reader = stdout
+	if c.maxOutput > 0 {
+		src = io.LimitReader(stdout, int64(c.maxOutput)+1)
+	}
+	pcm, readErr := io.ReadAll(src)
+	if c.maxOutput > 0 && len(pcm) > c.maxOutput {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		c.metrics.Counter("audio_conversion_failures_total", 1)
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrOutputTooLarge, c.maxOutput)
+	}
+
+	if err := cmd.Wait(); err != nil || readErr != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		c.metrics.Counter("audio_conversion_failures_total", 1)
+		return nil, fmt.Errorf("%w: %s", ErrConversionFailed, logging.SanitizeStderr(stderr.String()))
+	}
+	c.metrics.Observe("audio_conversion_seconds", time.Since(start).Seconds())
+
+	return pcm, nil
+}
+
+// ConvertStreamToDiscordPCM converts a stream of raw signed little-endian PCM
+// to Discord-ready 48kHz stereo 16-bit PCM as it arrives.
+// The returned reader must be closed; closing before EOF kills ffmpeg.
+func (c *Converter) ConvertStreamToDiscordPCM(ctx context.Context, pcm io.Reader, sampleRate, channels, bitsPerSample int) (io.ReadCloser, error) {
+	if bitsPerSample != 16 {
+		return nil, fmt.Errorf("%w: unsupported bit depth %d", ErrConversionFailed, bitsPerSample)
+	}
+
+	// Same as ConvertToDiscordPCM, but the input is headerless raw PCM
+	// so its format must be described explicitly.
+	args := []string{
+		"-f", "s16le",
+		"-ar", fmt.Sprintf("%d", sampleRate),
+		"-ac", fmt.Sprintf("%d", channels),
+		"-i", "pipe:0",
+		"-ar", fmt.Sprintf("%d", DiscordSampleRate),
+		"-ac", fmt.Sprintf("%d", DiscordChannels),
+		"-f", "s16le",
+		"-loglevel", "error",
+		"pipe:1",
+	}
+
+	cmd := exec.CommandContext(ctx, c.ffmpegPath, args...)
+	cmd.Stdin = pcm
+
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrConversionFailed, err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		c.metrics.Counter("audio_conversion_failures_total", 1)
+		return nil, fmt.Errorf("%w: %v", ErrConversionFailed, err)
+	}
+
+	return &ffmpegStream{ctx: ctx, cmd: cmd, stdout: stdout, stderr: stderr}, nil
+}
+
+// ffmpegStream reads ffmpeg's stdout and reaps the process on Close.
+type ffmpegStream struct {
+	ctx    context.Context
+	cmd    *exec.Cmd
+	stdout io.ReadCloser
+	stderr *bytes.Buffer
+
+	// eof is set by Read, which may run on another goroutine than Close
+	eof      atomic.Bool
+	once     sync.Once
+	closeErr error
+}
+
+// Read reads converted PCM from ffmpeg's stdout.
+func (s *ffmpegStream) Read(b []byte) (int, error) {
+	n, err := s.stdout.Read(b)
+	if err == io.EOF {
+		s.eof.Store(true)
+	}
+	return n, err
+}
+
+// Close waits for ffmpeg to exit, killing it first if output was not fully read.
+func (s *ffmpegStream) Close() error {
+	s.once.Do(func() {
+		if !s.eof.Load() {
+			// Consumer stopped early (interrupt or downstream failure).
+			// Drain the pipe after the kill so no read is in progress
+			// when Wait closes it.
+			_ = s.cmd.Process.Kill()
+			_, _ = io.Copy(io.Discard, s.stdout)
+			_ = s.cmd.Wait()
+			return
+		}
+
+		if err := s.cmd.Wait(); err != nil {
+			if s.ctx.Err() != nil {
+				s.closeErr = s.ctx.Err()
+				return
+			}
+			s.closeErr = fmt.Errorf("%w: %s", ErrConversionFailed, logging.SanitizeStderr(s.stderr.String()))
+		}
+	})
+	return s.closeErr
+}
+
+// PadToFrame pads PCM with trailing silence up to a multiple of
+// DiscordFrameBytes, so audio appended after it starts on a frame boundary.
+func PadToFrame(pcm []byte) []byte {
+	if rem := len(pcm) % DiscordFrameBytes; rem != 0 {
+		pcm = append(pcm, make([]byte, DiscordFrameBytes-rem)...)
 	}
+	return pcm
+}
 
-	return stdout.Bytes(), nil
+// SilencePCM returns Discord PCM silence lasting at least d, rounded up to
+// whole frames. It returns nil when d is not positive.
+func SilencePCM(d time.Duration) []byte {
+	if d <= 0 {
+		return nil
+	}
+	frame := time.Second * DiscordFrameSize / DiscordSampleRate
+	frames := (d + frame - 1) / frame
+	return make([]byte, int(frames)*DiscordFrameBytes)
+}
+
+// ConcatPCM joins Discord PCM segments into one buffer. Every segment except
+// the last is padded with silence to a multiple of DiscordFrameBytes, so each
+// segment starts on a frame boundary and no frame straddles a join.
+// Empty segments are skipped.
+func ConcatPCM(frames ...[]byte) []byte {
+	var nonEmpty [][]byte
+	total := 0
+	for _, f := range frames {
+		if len(f) == 0 {
+			continue
+		}
+		nonEmpty = append(nonEmpty, f)
+		total += len(f) + DiscordFrameBytes
+	}
+
+	out := make([]byte, 0, total)
+	for i, f := range nonEmpty {
+		out = append(out, f...)
+		if i < len(nonEmpty)-1 {
+			out = PadToFrame(out)
+		}
+	}
+	return out
+}
+
+// TrimSilence removes leading and trailing runs of near-silent samples from
+// Discord PCM (48kHz stereo 16-bit little-endian). A sample frame is silent
+// when every channel's amplitude is at or below threshold. A short margin is
+// kept around audible content. Returns an empty slice if the input is all
+// silence; audible content in the middle is never touched.
+func TrimSilence(pcm []byte, threshold int) []byte {
+	const sampleFrameBytes = DiscordChannels * 2
+
+	n := len(pcm) / sampleFrameBytes
+	loud := func(i int) bool {
+		for ch := 0; ch < DiscordChannels; ch++ {
+			off := i*sampleFrameBytes + ch*2
+			v := int(int16(uint16(pcm[off]) | uint16(pcm[off+1])<<8))
+			if v > threshold || v < -threshold {
+				return true
+			}
+		}
+		return false
+	}
+
+	first := 0
+	for first < n && !loud(first) {
+		first++
+	}
+	if first == n {
+		return pcm[:0]
+	}
+
+	last := n - 1
+	for last > first && !loud(last) {
+		last--
+	}
+
+	start := first*sampleFrameBytes - silencePaddingBytes
+	if start < 0 {
+		start = 0
+	}
+	end := (last+1)*sampleFrameBytes + silencePaddingBytes
+	if end > n*sampleFrameBytes {
+		end = n * sampleFrameBytes
+	}
+
+	return pcm[start:end]
+}
+
+// FrameSource yields Discord-sized PCM frames until io.EOF.
+type FrameSource interface {
+	ReadFrame() ([]byte, error)
 }
 
 // PCMFrameReader wraps raw PCM data and provides Discord-sized frames.
 type PCMFrameReader struct {
 	data   []byte
 	offset int
+	pad    bool
 }
 
 // NewPCMFrameReader creates a new frame reader from raw PCM data.
+// A trailing partial frame is dropped.
 func NewPCMFrameReader(pcmData []byte) *PCMFrameReader {
 	return &PCMFrameReader{data: pcmData}
 }
 
+// NewPaddedPCMFrameReader is like NewPCMFrameReader but returns a trailing
+// partial frame zero-padded to DiscordFrameBytes instead of dropping it,
+// so the end of a clip isn't cut short.
+func NewPaddedPCMFrameReader(pcmData []byte) *PCMFrameReader {
+	return &PCMFrameReader{data: pcmData, pad: true}
+}
+
 // ReadFrame reads the next Discord-sized frame (960 samples * 2 channels * 2 bytes).
-// Returns io.EOF when no more complete frames are available.
+// Returns io.EOF when no more complete frames are available, or, for a
+// padded reader, once the padded partial frame has been returned.
 func (r *PCMFrameReader) ReadFrame() ([]byte, error) {
 	if r.offset+DiscordFrameBytes > len(r.data) {
-		return nil, io.EOF
+		if !r.pad || r.offset >= len(r.data) {
+			return nil, io.EOF
+		}
+		frame := make([]byte, DiscordFrameBytes)
+		copy(frame, r.data[r.offset:])
+		r.offset = len(r.data)
+		return frame, nil
 	}
 
 	frame := r.data[r.offset : r.offset+DiscordFrameBytes]
@@ -120,3 +401,30 @@ func (r *PCMFrameReader) Reset() {
 func (r *PCMFrameReader) Remaining() int {
 	return len(r.data) - r.offset
 }
+
+// PCMStreamFrameReader reads Discord-sized frames from a PCM stream.
+type PCMStreamFrameReader struct {
+	r     io.Reader
+	frame []byte
+}
+
+// NewPCMStreamFrameReader creates a frame reader over a stream of raw PCM.
+func NewPCMStreamFrameReader(r io.Reader) *PCMStreamFrameReader {
+	return &PCMStreamFrameReader{r: r, frame: make([]byte, DiscordFrameBytes)}
+}
+
+// ReadFrame reads the next Discord-sized frame, blocking until it is available.
+// A trailing partial frame is zero-padded, as with NewPaddedPCMFrameReader,
+// and io.EOF is returned once the stream is exhausted.
+// The returned slice is reused by the next call.
+func (r *PCMStreamFrameReader) ReadFrame() ([]byte, error) {
+	n, err := io.ReadFull(r.r, r.frame)
+	if err == io.ErrUnexpectedEOF {
+		clear(r.frame[n:])
+		return r.frame, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.frame, nil
+}
@@ -1,12 +1,17 @@
 package audio
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"io"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/dgnsrekt/discorgeous-go/internal/metrics"
 	"github.com/dgnsrekt/discorgeous-go/internal/wav"
 )
 
@@ -114,6 +119,73 @@ func TestConverter_ConvertToDiscordPCM_ValidWAV(t *testing.T) {
 	}
 }
 
+// writeScript writes an executable shell script standing in for ffmpeg.
+func writeScript(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ffmpeg")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0o755); err != nil {
+		t.Fatalf("failed to write fake ffmpeg: %v", err)
+	}
+	return path
+}
+
+func TestConverter_ConvertToDiscordPCM_MaxOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		wantErr error
+	}{
+		{"under the cap", "cat > /dev/null\nprintf 'pcm'\n", nil},
+		{"exactly the cap", "cat > /dev/null\nprintf '1234'\n", nil},
+		{"over the cap", "cat > /dev/null\nprintf '12345'\n", ErrOutputTooLarge},
+		{"endless output", "cat > /dev/null\nexec yes\n", ErrOutputTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv := NewConverterWithPath(writeScript(t, tt.script))
+			conv.SetMaxOutputBytes(4)
+
+			pcm, err := conv.ConvertToDiscordPCM(context.Background(), []byte("wav"))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ConvertToDiscordPCM() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && len(pcm) == 0 {
+				t.Error("ConvertToDiscordPCM() returned no output")
+			}
+		})
+	}
+}
+
+func TestConverter_Metrics(t *testing.T) {
+	rec := metrics.NewRecorder()
+	ok := NewConverterWithPath(writeScript(t, "cat > /dev/null\nprintf 'pcm'\n"))
+	ok.SetMetrics(rec)
+	failing := NewConverterWithPath(writeScript(t, "cat > /dev/null\nexit 1\n"))
+	failing.SetMetrics(rec)
+
+	wavData := wav.CreateMinimalPiper(100)
+	if _, err := ok.ConvertToDiscordPCM(context.Background(), wavData); err != nil {
+		t.Fatalf("ConvertToDiscordPCM() error = %v", err)
+	}
+	if _, err := failing.ConvertToDiscordPCM(context.Background(), wavData); !errors.Is(err, ErrConversionFailed) {
+		t.Fatalf("ConvertToDiscordPCM() error = %v, want ErrConversionFailed", err)
+	}
+
+	if got := rec.Observations("audio_conversion_seconds"); len(got) != 1 {
+		t.Errorf("audio_conversion_seconds has %d observations, want 1", len(got))
+	}
+	if got := rec.CounterValue("audio_conversion_failures_total"); got != 1 {
+		t.Errorf("audio_conversion_failures_total = %v, want 1", got)
+	}
+
+	// Without metrics conversion behaves the same
+	ok.SetMetrics(nil)
+	if pcm, err := ok.ConvertToDiscordPCM(context.Background(), wavData); err != nil || string(pcm) != "pcm" {
+		t.Errorf("ConvertToDiscordPCM() with nil metrics = %q, %v, want pcm", pcm, err)
+	}
+}
+
 func TestPCMFrameReader_ReadFrame(t *testing.T) {
 	// Create PCM data for exactly 2 frames
 	data := make([]byte, DiscordFrameBytes*2)
@@ -167,6 +239,53 @@ func TestPCMFrameReader_PartialFrame(t *testing.T) {
 	}
 }
 
+func TestPaddedPCMFrameReader_PartialFrame(t *testing.T) {
+	// 1.5 frames of non-zero samples
+	data := make([]byte, DiscordFrameBytes+DiscordFrameBytes/2)
+	for i := range data {
+		data[i] = 0x7F
+	}
+
+	reader := NewPaddedPCMFrameReader(data)
+
+	if _, err := reader.ReadFrame(); err != nil {
+		t.Fatalf("ReadFrame() 1 error = %v", err)
+	}
+
+	// Second frame is the partial one, zero-padded at the tail
+	frame, err := reader.ReadFrame()
+	if err != nil {
+		t.Fatalf("ReadFrame() 2 error = %v", err)
+	}
+	if len(frame) != DiscordFrameBytes {
+		t.Fatalf("frame length = %d, want %d", len(frame), DiscordFrameBytes)
+	}
+	half := DiscordFrameBytes / 2
+	for i, b := range frame {
+		if i < half && b != 0x7F {
+			t.Fatalf("frame[%d] = %#x, want audio data 0x7f", i, b)
+		}
+		if i >= half && b != 0 {
+			t.Fatalf("frame[%d] = %#x, want zero padding", i, b)
+		}
+	}
+
+	if _, err := reader.ReadFrame(); err != io.EOF {
+		t.Errorf("ReadFrame() 3 error = %v, want io.EOF", err)
+	}
+}
+
+func TestPaddedPCMFrameReader_ExactFrames(t *testing.T) {
+	reader := NewPaddedPCMFrameReader(make([]byte, DiscordFrameBytes))
+
+	if _, err := reader.ReadFrame(); err != nil {
+		t.Fatalf("ReadFrame() 1 error = %v", err)
+	}
+	if _, err := reader.ReadFrame(); err != io.EOF {
+		t.Errorf("ReadFrame() 2 error = %v, want io.EOF with no partial frame", err)
+	}
+}
+
 func TestPCMFrameReader_Reset(t *testing.T) {
 	data := make([]byte, DiscordFrameBytes)
 	reader := NewPCMFrameReader(data)
@@ -192,6 +311,98 @@ func TestPCMFrameReader_Reset(t *testing.T) {
 	}
 }
 
+func TestPCMStreamFrameReader_ReadFrame(t *testing.T) {
+	// 2.5 frames: two complete frames, then a trailing partial one
+	data := make([]byte, DiscordFrameBytes*2+DiscordFrameBytes/2)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+
+	reader := NewPCMStreamFrameReader(bytes.NewReader(data))
+
+	for i := 0; i < 2; i++ {
+		frame, err := reader.ReadFrame()
+		if err != nil {
+			t.Fatalf("ReadFrame() %d error = %v", i+1, err)
+		}
+		if len(frame) != DiscordFrameBytes {
+			t.Errorf("frame %d length = %d, want %d", i+1, len(frame), DiscordFrameBytes)
+		}
+		if !bytes.Equal(frame, data[i*DiscordFrameBytes:(i+1)*DiscordFrameBytes]) {
+			t.Errorf("frame %d content mismatch", i+1)
+		}
+	}
+
+	// Partial trailing frame is zero-padded rather than dropped
+	frame, err := reader.ReadFrame()
+	if err != nil {
+		t.Fatalf("ReadFrame() 3 error = %v", err)
+	}
+	if len(frame) != DiscordFrameBytes {
+		t.Errorf("frame 3 length = %d, want %d", len(frame), DiscordFrameBytes)
+	}
+	tail := data[2*DiscordFrameBytes:]
+	if !bytes.Equal(frame[:len(tail)], tail) {
+		t.Error("frame 3 content mismatch")
+	}
+	if !bytes.Equal(frame[len(tail):], make([]byte, DiscordFrameBytes-len(tail))) {
+		t.Error("frame 3 padding is not zeroed")
+	}
+
+	if _, err := reader.ReadFrame(); err != io.EOF {
+		t.Errorf("ReadFrame() 4 error = %v, want io.EOF", err)
+	}
+}
+
+func TestPCMStreamFrameReader_ExactFrames(t *testing.T) {
+	reader := NewPCMStreamFrameReader(bytes.NewReader(make([]byte, DiscordFrameBytes)))
+
+	if _, err := reader.ReadFrame(); err != nil {
+		t.Fatalf("ReadFrame() 1 error = %v", err)
+	}
+	if _, err := reader.ReadFrame(); err != io.EOF {
+		t.Errorf("ReadFrame() 2 error = %v, want io.EOF with no partial frame", err)
+	}
+}
+
+func TestConverter_ConvertStreamToDiscordPCM(t *testing.T) {
+	_, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		t.Skip("ffmpeg not installed, skipping converter tests")
+	}
+
+	conv, _ := NewConverter()
+
+	// 100ms of 22050Hz mono silence
+	raw := make([]byte, 2205*2)
+
+	stream, err := conv.ConvertStreamToDiscordPCM(context.Background(), bytes.NewReader(raw), 22050, 1, 16)
+	if err != nil {
+		t.Fatalf("ConvertStreamToDiscordPCM() error = %v", err)
+	}
+
+	pcm, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if err := stream.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+
+	if len(pcm) == 0 {
+		t.Error("ConvertStreamToDiscordPCM() returned empty output")
+	}
+}
+
+func TestConverter_ConvertStreamToDiscordPCM_UnsupportedBitDepth(t *testing.T) {
+	conv := NewConverterWithPath("ffmpeg")
+
+	_, err := conv.ConvertStreamToDiscordPCM(context.Background(), bytes.NewReader(nil), 22050, 1, 24)
+	if !errors.Is(err, ErrConversionFailed) {
+		t.Errorf("expected ErrConversionFailed, got %v", err)
+	}
+}
+
 func TestDiscordConstants(t *testing.T) {
 	// Verify Discord audio constants are correct
 	if DiscordSampleRate != 48000 {
@@ -208,3 +419,149 @@ func TestDiscordConstants(t *testing.T) {
 		t.Errorf("DiscordFrameBytes = %d, want 3840", DiscordFrameBytes)
 	}
 }
+
+// stereoPCM builds Discord PCM where each sample frame has the given amplitude on both channels.
+func stereoPCM(amplitudes ...int16) []byte {
+	pcm := make([]byte, 0, len(amplitudes)*4)
+	for _, a := range amplitudes {
+		for ch := 0; ch < DiscordChannels; ch++ {
+			pcm = append(pcm, byte(uint16(a)), byte(uint16(a)>>8))
+		}
+	}
+	return pcm
+}
+
+func TestTrimSilence(t *testing.T) {
+	pad := silencePaddingBytes / 4 // padding in sample frames
+
+	silence := make([]int16, 3*pad)
+	loud := []int16{1000, 0, -1000} // quiet sample in the middle must survive
+
+	var samples []int16
+	samples = append(samples, silence...)
+	samples = append(samples, loud...)
+	samples = append(samples, silence...)
+	pcm := stereoPCM(samples...)
+
+	trimmed := TrimSilence(pcm, DefaultSilenceThreshold)
+
+	wantLen := (pad + len(loud) + pad) * 4
+	if len(trimmed) != wantLen {
+		t.Fatalf("trimmed length = %d, want %d", len(trimmed), wantLen)
+	}
+
+	// Audible content is preserved exactly
+	if !bytes.Equal(trimmed[pad*4:pad*4+len(loud)*4], stereoPCM(loud...)) {
+		t.Error("audible content was altered by trimming")
+	}
+}
+
+func TestTrimSilence_AllSilence(t *testing.T) {
+	pcm := stereoPCM(0, 10, -10, DefaultSilenceThreshold, -DefaultSilenceThreshold)
+
+	if trimmed := TrimSilence(pcm, DefaultSilenceThreshold); len(trimmed) != 0 {
+		t.Errorf("trimmed length = %d, want 0 for all-silence input", len(trimmed))
+	}
+
+	if trimmed := TrimSilence(nil, DefaultSilenceThreshold); len(trimmed) != 0 {
+		t.Errorf("trimmed length = %d, want 0 for empty input", len(trimmed))
+	}
+}
+
+func TestTrimSilence_NoSilence(t *testing.T) {
+	pcm := stereoPCM(500, -500, 500)
+
+	trimmed := TrimSilence(pcm, DefaultSilenceThreshold)
+	if !bytes.Equal(trimmed, pcm) {
+		t.Error("TrimSilence() modified input with no silence")
+	}
+}
+
+func TestTrimSilence_OneChannelLoud(t *testing.T) {
+	// Left silent, right audible: the sample frame counts as audible
+	pcm := []byte{0, 0, 0xe8, 0x03}
+
+	if trimmed := TrimSilence(pcm, DefaultSilenceThreshold); len(trimmed) != 4 {
+		t.Errorf("trimmed length = %d, want 4", len(trimmed))
+	}
+}
+
+func TestPadToFrame(t *testing.T) {
+	tests := []struct {
+		in   int
+		want int
+	}{
+		{0, 0},
+		{1, DiscordFrameBytes},
+		{DiscordFrameBytes, DiscordFrameBytes},
+		{DiscordFrameBytes + 1, DiscordFrameBytes * 2},
+	}
+
+	for _, tt := range tests {
+		got := PadToFrame(make([]byte, tt.in))
+		if len(got) != tt.want {
+			t.Errorf("PadToFrame(%d bytes) length = %d, want %d", tt.in, len(got), tt.want)
+		}
+	}
+}
+
+func TestSilencePCM(t *testing.T) {
+	tests := []struct {
+		d      time.Duration
+		frames int
+	}{
+		{0, 0},
+		{-time.Second, 0},
+		{time.Millisecond, 1},
+		{20 * time.Millisecond, 1},
+		{30 * time.Millisecond, 2},
+		{time.Second, 50},
+	}
+
+	for _, tt := range tests {
+		got := SilencePCM(tt.d)
+		if len(got) != tt.frames*DiscordFrameBytes {
+			t.Errorf("SilencePCM(%v) length = %d, want %d frames", tt.d, len(got), tt.frames)
+		}
+		if !bytes.Equal(got, make([]byte, len(got))) {
+			t.Errorf("SilencePCM(%v) is not silent", tt.d)
+		}
+	}
+}
+
+func TestConcatPCM(t *testing.T) {
+	a := bytes.Repeat([]byte{1}, 10)
+	b := bytes.Repeat([]byte{2}, DiscordFrameBytes)
+	c := bytes.Repeat([]byte{3}, 6)
+
+	out := ConcatPCM(a, nil, b, c)
+
+	// a padded to one frame, b already aligned, c left as-is at the end
+	wantLen := DiscordFrameBytes + DiscordFrameBytes + len(c)
+	if len(out) != wantLen {
+		t.Fatalf("ConcatPCM() length = %d, want %d", len(out), wantLen)
+	}
+
+	// Each segment starts on a frame boundary
+	if !bytes.Equal(out[:10], a) {
+		t.Error("first segment not at offset 0")
+	}
+	if !bytes.Equal(out[10:DiscordFrameBytes], make([]byte, DiscordFrameBytes-10)) {
+		t.Error("padding after first segment is not silence")
+	}
+	if !bytes.Equal(out[DiscordFrameBytes:2*DiscordFrameBytes], b) {
+		t.Error("second segment not frame-aligned")
+	}
+	if !bytes.Equal(out[2*DiscordFrameBytes:], c) {
+		t.Error("last segment not frame-aligned")
+	}
+}
+
+func TestConcatPCM_Empty(t *testing.T) {
+	if out := ConcatPCM(); len(out) != 0 {
+		t.Errorf("ConcatPCM() length = %d, want 0", len(out))
+	}
+	if out := ConcatPCM(nil, []byte{}); len(out) != 0 {
+		t.Errorf("ConcatPCM(empty) length = %d, want 0", len(out))
+	}
+}
@@ -0,0 +1,57 @@
+package audio
+
+import (
+	"github.com/dgnsrekt/discorgeous-go/internal/wav"
+)
+
+// ResampleToDiscord converts 16-bit little-endian PCM with one or two
+// channels at sampleRate to Discord PCM (48kHz stereo) in pure Go. Samples
+// are linearly interpolated and mono is duplicated into both channels,
+// which is cheap and good enough for speech. A trailing odd byte is ignored.
+func ResampleToDiscord(pcm []byte, sampleRate, channels int) []byte {
+	if sampleRate <= 0 || channels < 1 || channels > 2 {
+		return nil
+	}
+
+	inFrames := len(pcm) / (2 * channels)
+	if inFrames == 0 {
+		return nil
+	}
+	outFrames := inFrames * DiscordSampleRate / sampleRate
+
+	sample := func(frame, channel int) int {
+		if channels == 1 {
+			channel = 0
+		}
+		i := (frame*channels + channel) * 2
+		return int(int16(uint16(pcm[i]) | uint16(pcm[i+1])<<8))
+	}
+
+	out := make([]byte, outFrames*DiscordChannels*2)
+	for i := 0; i < outFrames; i++ {
+		// Position in the input as whole frames plus a fraction of 48000
+		pos := i * sampleRate
+		frame, frac := pos/DiscordSampleRate, pos%DiscordSampleRate
+		next := min(frame+1, inFrames-1)
+
+		for ch := 0; ch < DiscordChannels; ch++ {
+			s0, s1 := sample(frame, ch), sample(next, ch)
+			v := s0 + (s1-s0)*frac/DiscordSampleRate
+			o := (i*DiscordChannels + ch) * 2
+			out[o] = byte(v)
+			out[o+1] = byte(v >> 8)
+		}
+	}
+	return out
+}
+
+// fastConvert converts wavData with ResampleToDiscord if it is 16-bit mono
+// or stereo PCM, reporting false for anything that needs ffmpeg.
+func fastConvert(wavData []byte) ([]byte, bool) {
+	format, pcm, err := wav.Parse(wavData)
+	if err != nil || format.AudioFormat != wav.FormatPCM || format.BitsPerSample != 16 ||
+		format.Channels < 1 || format.Channels > 2 || format.SampleRate <= 0 {
+		return nil, false
+	}
+	return ResampleToDiscord(pcm, format.SampleRate, format.Channels), true
+}
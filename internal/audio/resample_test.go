@@ -0,0 +1,76 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dgnsrekt/discorgeous-go/internal/wav"
+)
+
+func TestResampleToDiscord_Length(t *testing.T) {
+	tests := []struct {
+		name       string
+		sampleRate int
+		channels   int
+		inFrames   int
+		wantFrames int // Discord 20ms frames
+	}{
+		{"piper 1s mono", 22050, 1, 22050, 50},
+		{"16kHz 1s mono", 16000, 1, 16000, 50},
+		{"44.1kHz 0.5s stereo", 44100, 2, 22050, 25},
+		{"already 48kHz", 48000, 2, 960, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pcm := make([]byte, tt.inFrames*tt.channels*2)
+			out := ResampleToDiscord(pcm, tt.sampleRate, tt.channels)
+			if want := tt.wantFrames * DiscordFrameBytes; len(out) != want {
+				t.Errorf("output length = %d, want %d", len(out), want)
+			}
+		})
+	}
+}
+
+func TestResampleToDiscord_Values(t *testing.T) {
+	// 24kHz mono ramp 0, 1000, 2000: each input sample becomes two output
+	// frames, the second halfway to the next sample, in both channels
+	pcm := []byte{}
+	for _, v := range []int16{0, 1000, 2000} {
+		pcm = append(pcm, byte(v), byte(uint16(v)>>8))
+	}
+
+	out := ResampleToDiscord(pcm, 24000, 1)
+
+	want := []int16{0, 500, 1000, 1500, 2000, 2000}
+	if len(out) != len(want)*4 {
+		t.Fatalf("output length = %d, want %d", len(out), len(want)*4)
+	}
+	for i, w := range want {
+		left := int16(uint16(out[i*4]) | uint16(out[i*4+1])<<8)
+		right := int16(uint16(out[i*4+2]) | uint16(out[i*4+3])<<8)
+		if left != w || right != w {
+			t.Errorf("frame %d = (%d, %d), want (%d, %d)", i, left, right, w, w)
+		}
+	}
+}
+
+func TestConverter_FastResample(t *testing.T) {
+	// No ffmpeg: PCM WAV must not need it, anything else must try it
+	c := NewConverterWithPath("/nonexistent/ffmpeg")
+	c.SetFastResample(true)
+
+	pcm, err := c.ConvertToDiscordPCM(context.Background(), wav.CreateMinimalPiper(22050))
+	if err != nil {
+		t.Fatalf("ConvertToDiscordPCM() error = %v", err)
+	}
+	if len(pcm) != 50*DiscordFrameBytes {
+		t.Errorf("output length = %d, want %d", len(pcm), 50*DiscordFrameBytes)
+	}
+
+	_, err = c.ConvertToDiscordPCM(context.Background(), wav.CreateMinimal(100, 22050, 1, 8))
+	if !errors.Is(err, ErrConversionFailed) {
+		t.Errorf("ConvertToDiscordPCM(8-bit) error = %v, want the ffmpeg fallback to fail", err)
+	}
+}
@@ -1,33 +1,123 @@
 package config
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// API key scopes. ScopeAdmin grants every other scope.
+const (
+	ScopeSpeak = "speak"
+	ScopeRead  = "read"
+	ScopeAdmin = "admin"
+)
+
 // Config holds all application configuration.
 type Config struct {
 	// Discord settings
 	DiscordToken          string
 	GuildID               string
 	DefaultVoiceChannelID string
+	FollowUserID          string   // speak in this user's voice channel; empty disables
+	AllowedChannels       []string // channels requests may name besides the default
 
 	// HTTP settings
-	HTTPPort    int
-	BearerToken string
+	HTTPPort     int
+	BearerToken  string
+	BearerTokens []string                 // additional accepted tokens, for key rotation
+	APIKeys      map[string][]string      // token -> scopes
+	APIKeyVoices map[string]string        // token -> default voice
+	APIKeyTTLs   map[string]time.Duration // token -> default TTL; 0 means none
+	HMACSecret   string
+	HMACMaxSkew  time.Duration
+	WebUI        bool // serve a page for sending test messages at /
+
+	// TLS settings; plaintext HTTP is used unless TLSCert and TLSKey are set
+	TLSCert         string
+	TLSKey          string
+	TLSClientCA     string              // require client certs signed by this CA
+	TLSClientScopes map[string][]string // client cert CN -> scopes
 
 	// TTS settings
-	PiperPath    string
-	PiperModel   string
-	DefaultVoice string
+	PiperPath       string
+	PiperModel      string
+	PiperModels     map[string]string // voice name -> model path
+	PiperSampleRate int               // 0 means auto-detect from the model's .onnx.json
+	PiperStreaming  bool
+	PiperMaxSynth   int    // concurrent synthesis limit per Piper model; 0 means unlimited
+	TTSCommand      string // command line for the "command" engine; empty disables it
+	TTSCommandRate  int    // sample rate of raw PCM output; 0 means the command writes WAV
+	PollyRegion     string // AWS region for the "polly" engine; empty disables it
+	PollyVoice      string
+	PollyEngine     string
+	GoogleTTSCreds  string // service account key for the "google" engine; empty disables it
+	GoogleTTSVoice  string
+	CloudMaxSynth   int // concurrent synthesis limit per cloud or command engine; 0 means unlimited
+	MaxSynthBytes   int // cap on one synthesis's or conversion's output; 0 means unlimited
+	DefaultVoice    string
+	DefaultLanguage string // BCP-47 hint for requests that name no language; empty leaves it to the engine
+	NormalizeText   bool
+	StripMarkdown   bool
+	EmojiMode       string
+	RedactWords     []string
+	RedactWith      string
+	AutodetectLang  bool
+	LangEngines     map[string]string // ISO 639-1 code -> engine name
+	VoiceAliases    map[string]VoiceAlias
+
+	// Playback settings
+	NotifyChimePath string
+	MaxAudioSeconds int // 0 means unlimited
+	InterruptMode   string
+	InterruptGrace  time.Duration
+	MaxRetries      int
+	RetryDelay      time.Duration
+	SynthTimeout    time.Duration // 0 means no limit
+	RecordDir       string        // save spoken audio here; empty disables
+	DeadLetterPath  string        // append permanently failed jobs here; empty disables
+	LeadSilenceMS   int           // silence before each message
+	TrailSilenceMS  int           // silence after each message
+	PlaybackSink    string        // "file:/path" writes PCM there instead of Discord
+
+	// Opus encoder settings
+	OpusApplication string
+	OpusBitrate     int // bits per second; 0 keeps the encoder default
+	TrimSilence     bool
+	FastResample    bool          // convert 16-bit PCM WAV in Go instead of ffmpeg
+	VoiceKeepalive  time.Duration // 0 disables
+
+	// Voice connection settings
+	VoiceConnectTimeout    time.Duration // 0 uses the default
+	VoiceConnectRetries    int           // retries after the first attempt
+	VoiceConnectRetryDelay time.Duration
+	VoiceConnectPoll       time.Duration // readiness poll interval; 0 uses the default
+	DropIfDisconnected     bool          // fail jobs outright when a quick connect fails
+	CoalescePlayback       bool          // keep one speaking session across ready back-to-back jobs
+	SpeakingHoldMS         int           // keep speaking on this long after playback ends
 
 	// Behavior settings
-	AutoLeaveIdle time.Duration
-	MaxTextLength int
-	QueueCapacity int
-	DefaultTTL    time.Duration
+	AutoLeaveIdle   time.Duration
+	JoinOnStart     bool
+	StayConnected   bool // never leave for being idle; implies JoinOnStart
+	DisconnectDelay time.Duration
+	MinConnected    time.Duration
+	MaxTextLength   int
+	StrictJSON      bool // reject unknown fields and trailing data in speak requests
+	QueueCapacity   int
+	QueueWarnDepth  int // depth at which enqueues warn the queue is filling; 0 disables
+	HistorySize     int // finished jobs kept for GET /v1/history
+	DefaultTTL      time.Duration
+	AutoDedupe      bool          // derive a dedupe key from text and voice when a request has none
+	IdempotencyTTL  time.Duration // how long Idempotency-Key responses are kept; 0 ignores the header
+	QuietHours      string        // daily "HH:MM-HH:MM" window with no playback; empty disables
+	QuietTimezone   string        // IANA zone QuietHours is read in; empty means local time
+	QuietMode       string        // what happens to jobs during quiet hours: drop or defer
 
 	// Logging settings
 	LogLevel  string
@@ -38,30 +128,140 @@ type Config struct {
 func Load() (*Config, error) {
 	cfg := &Config{
 		// Discord settings (required)
-		DiscordToken:          os.Getenv("DISCORD_TOKEN"),
 		GuildID:               os.Getenv("GUILD_ID"),
 		DefaultVoiceChannelID: os.Getenv("DEFAULT_VOICE_CHANNEL_ID"),
+		FollowUserID:          os.Getenv("FOLLOW_USER_ID"),
+		AllowedChannels:       getEnvList("ALLOWED_CHANNELS"),
 
 		// HTTP settings
-		HTTPPort:    getEnvInt("HTTP_PORT", 8080),
-		BearerToken: os.Getenv("BEARER_TOKEN"),
+		HTTPPort:     getEnvInt("HTTP_PORT", 8080),
+		BearerTokens: getEnvList("BEARER_TOKENS"),
+		HMACSecret:   os.Getenv("HMAC_SECRET"),
+		HMACMaxSkew:  getEnvDuration("HMAC_MAX_SKEW", 5*time.Minute),
+		WebUI:        getEnvBool("WEB_UI", false),
+
+		// TLS settings
+		TLSCert:     os.Getenv("TLS_CERT"),
+		TLSKey:      os.Getenv("TLS_KEY"),
+		TLSClientCA: os.Getenv("TLS_CLIENT_CA"),
 
 		// TTS settings
-		PiperPath:    getEnvString("PIPER_PATH", "piper"),
-		PiperModel:   getEnvString("PIPER_MODEL", ""),
-		DefaultVoice: getEnvString("DEFAULT_VOICE", "default"),
+		PiperPath:       getEnvString("PIPER_PATH", "piper"),
+		PiperModel:      getEnvString("PIPER_MODEL", ""),
+		PiperSampleRate: getEnvInt("PIPER_SAMPLE_RATE", 0),
+		PiperStreaming:  getEnvBool("PIPER_STREAMING", false),
+		PiperMaxSynth:   getEnvInt("PIPER_MAX_CONCURRENT_SYNTH", 0),
+		TTSCommand:      os.Getenv("TTS_COMMAND"),
+		TTSCommandRate:  getEnvInt("TTS_COMMAND_SAMPLE_RATE", 0),
+		PollyRegion:     os.Getenv("POLLY_REGION"),
+		PollyVoice:      getEnvString("POLLY_VOICE", "Joanna"),
+		PollyEngine:     os.Getenv("POLLY_ENGINE"),
+		GoogleTTSCreds:  os.Getenv("GOOGLE_TTS_CREDENTIALS"),
+		GoogleTTSVoice:  getEnvString("GOOGLE_TTS_VOICE", "en-US-Standard-C"),
+		CloudMaxSynth:   getEnvInt("MAX_CONCURRENT_SYNTH", 0),
+		MaxSynthBytes:   getEnvInt("MAX_SYNTH_BYTES", 256<<20),
+		DefaultVoice:    getEnvString("DEFAULT_VOICE", "default"),
+		DefaultLanguage: os.Getenv("DEFAULT_LANGUAGE"),
+		NormalizeText:   getEnvBool("NORMALIZE_TEXT", false),
+		StripMarkdown:   getEnvBool("STRIP_MARKDOWN", false),
+		EmojiMode:       getEnvString("EMOJI_MODE", "keep"),
+		RedactWords:     getEnvList("REDACT_WORDS"),
+		RedactWith:      getEnvString("REDACT_PLACEHOLDER", "bleep"),
+		AutodetectLang:  getEnvBool("AUTODETECT_LANG", false),
+
+		// Playback settings
+		NotifyChimePath: os.Getenv("NOTIFY_CHIME_PATH"),
+		MaxAudioSeconds: getEnvInt("MAX_AUDIO_SECONDS", 0),
+		InterruptMode:   getEnvString("INTERRUPT_MODE", "hard"),
+		InterruptGrace:  getEnvDuration("INTERRUPT_GRACE", 3*time.Second),
+		MaxRetries:      getEnvInt("PLAYBACK_MAX_RETRIES", 2),
+		RetryDelay:      getEnvDuration("PLAYBACK_RETRY_DELAY", 500*time.Millisecond),
+		RecordDir:       os.Getenv("RECORD_DIR"),
+		DeadLetterPath:  os.Getenv("DEAD_LETTER_PATH"),
+		SynthTimeout:    getEnvDuration("SYNTHESIS_TIMEOUT", 0),
+		LeadSilenceMS:   getEnvInt("PLAYBACK_LEAD_SILENCE_MS", 0),
+		TrailSilenceMS:  getEnvInt("PLAYBACK_TRAIL_SILENCE_MS", 0),
+		PlaybackSink:    os.Getenv("PLAYBACK_SINK"),
+
+		// Opus encoder settings
+		OpusApplication: getEnvString("OPUS_APPLICATION", "voip"),
+		OpusBitrate:     getEnvInt("OPUS_BITRATE", 0),
+		TrimSilence:     getEnvBool("TRIM_SILENCE", false),
+		FastResample:    getEnvBool("FAST_RESAMPLE", false),
+		VoiceKeepalive:  getEnvDuration("VOICE_KEEPALIVE", 0),
+
+		// Voice connection settings
+		VoiceConnectTimeout:    getEnvDuration("VOICE_CONNECT_TIMEOUT", 10*time.Second),
+		VoiceConnectRetries:    getEnvInt("VOICE_CONNECT_RETRIES", 2),
+		VoiceConnectRetryDelay: getEnvDuration("VOICE_CONNECT_RETRY_DELAY", time.Second),
+		VoiceConnectPoll:       getEnvDuration("VOICE_CONNECT_POLL_INTERVAL", 20*time.Millisecond),
+		DropIfDisconnected:     getEnvBool("DROP_IF_DISCONNECTED", false),
+		CoalescePlayback:       getEnvBool("COALESCE_PLAYBACK", true),
+		SpeakingHoldMS:         getEnvInt("SPEAKING_HOLD_MS", 0),
 
 		// Behavior settings
-		AutoLeaveIdle: getEnvDuration("AUTO_LEAVE_IDLE", 5*time.Minute),
-		MaxTextLength: getEnvInt("MAX_TEXT_LENGTH", 1000),
-		QueueCapacity: getEnvInt("QUEUE_CAPACITY", 100),
-		DefaultTTL:    getEnvDuration("DEFAULT_TTL", 30*time.Second),
+		AutoLeaveIdle:   getEnvDuration("AUTO_LEAVE_IDLE", 5*time.Minute),
+		JoinOnStart:     getEnvBool("JOIN_ON_START", false),
+		StayConnected:   getEnvBool("STAY_CONNECTED", false),
+		DisconnectDelay: getEnvDuration("DISCONNECT_DELAY", 0),
+		MinConnected:    getEnvDuration("MIN_CONNECTED_TIME", 0),
+		MaxTextLength:   getEnvInt("MAX_TEXT_LENGTH", 1000),
+		StrictJSON:      getEnvBool("STRICT_JSON", false),
+		QueueCapacity:   getEnvInt("QUEUE_CAPACITY", 100),
+		QueueWarnDepth:  getEnvInt("QUEUE_WARN_DEPTH", 0),
+		HistorySize:     getEnvInt("HISTORY_SIZE", 100),
+		DefaultTTL:      getEnvDuration("DEFAULT_TTL", 30*time.Second),
+		AutoDedupe:      getEnvBool("AUTO_DEDUPE", false),
+		IdempotencyTTL:  getEnvDuration("IDEMPOTENCY_TTL", 10*time.Minute),
+		QuietHours:      os.Getenv("QUIET_HOURS"),
+		QuietTimezone:   os.Getenv("QUIET_TIMEZONE"),
+		QuietMode:       getEnvString("QUIET_MODE", "drop"),
 
 		// Logging settings
 		LogLevel:  getEnvString("LOG_LEVEL", "info"),
 		LogFormat: getEnvString("LOG_FORMAT", "text"),
 	}
 
+	discordToken, err := getEnvSecret("DISCORD_TOKEN")
+	if err != nil {
+		return nil, err
+	}
+	cfg.DiscordToken = discordToken
+
+	bearerToken, err := getEnvSecret("BEARER_TOKEN")
+	if err != nil {
+		return nil, err
+	}
+	cfg.BearerToken = bearerToken
+
+	if err := parseAPIKeys(cfg, os.Getenv("API_KEYS")); err != nil {
+		return nil, err
+	}
+
+	piperModels, err := parseStringMap("PIPER_MODELS", os.Getenv("PIPER_MODELS"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.PiperModels = piperModels
+
+	langEngines, err := parseStringMap("LANG_ENGINES", os.Getenv("LANG_ENGINES"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.LangEngines = langEngines
+
+	voiceAliases, err := parseVoiceAliases(os.Getenv("VOICE_ALIASES"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.VoiceAliases = voiceAliases
+
+	clientScopes, err := parseScopes("TLS_CLIENT_SCOPES", os.Getenv("TLS_CLIENT_SCOPES"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.TLSClientScopes = clientScopes
+
 	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
@@ -71,7 +271,58 @@ func Load() (*Config, error) {
 
 // AuthDisabled returns true if bearer token authentication is disabled.
 func (c *Config) AuthDisabled() bool {
-	return c.BearerToken == ""
+	return len(c.ValidTokens()) == 0 && len(c.APIKeys) == 0 && c.HMACSecret == ""
+}
+
+// TLSEnabled returns true if the API should be served over TLS.
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCert != "" && c.TLSKey != ""
+}
+
+// PlaybackSinkFile returns the file PLAYBACK_SINK sends audio to, or ""
+// to play on Discord.
+func (c *Config) PlaybackSinkFile() string {
+	path, ok := strings.CutPrefix(c.PlaybackSink, "file:")
+	if !ok {
+		return ""
+	}
+	return path
+}
+
+// QuietWindow returns the daily QUIET_HOURS window as times since midnight
+// in loc, or ok false if quiet hours are off. It assumes the config has
+// been validated.
+func (c *Config) QuietWindow() (start, end time.Duration, loc *time.Location, ok bool) {
+	if c.QuietHours == "" {
+		return 0, 0, nil, false
+	}
+	start, end, err := parseQuietHours(c.QuietHours)
+	if err != nil {
+		return 0, 0, nil, false
+	}
+	loc, err = c.quietLocation()
+	if err != nil {
+		return 0, 0, nil, false
+	}
+	return start, end, loc, true
+}
+
+// quietLocation returns the QUIET_TIMEZONE location, or local time if it
+// is unset; time.LoadLocation would read "" as UTC.
+func (c *Config) quietLocation() (*time.Location, error) {
+	if c.QuietTimezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(c.QuietTimezone)
+}
+
+// ValidTokens returns every bearer token the API accepts.
+func (c *Config) ValidTokens() []string {
+	var tokens []string
+	if c.BearerToken != "" {
+		tokens = append(tokens, c.BearerToken)
+	}
+	return append(tokens, c.BearerTokens...)
 }
 
 // Validate checks that required configuration values are set.
@@ -91,10 +342,168 @@ func (c *Config) Validate() error {
 		return errors.New("QUEUE_CAPACITY must be at least 1")
 	}
 
+	if c.QueueWarnDepth < 0 || c.QueueWarnDepth > c.QueueCapacity {
+		return errors.New("QUEUE_WARN_DEPTH must be between 0 and QUEUE_CAPACITY")
+	}
+
+	if c.HistorySize < 0 {
+		return errors.New("HISTORY_SIZE must be non-negative")
+	}
+
+	if c.IdempotencyTTL < 0 {
+		return errors.New("IDEMPOTENCY_TTL must be non-negative")
+	}
+
+	if err := validateScopes("API_KEYS", c.APIKeys); err != nil {
+		return err
+	}
+
+	if c.HMACSecret != "" && c.HMACMaxSkew <= 0 {
+		return errors.New("HMAC_MAX_SKEW must be positive")
+	}
+
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return errors.New("TLS_CERT and TLS_KEY must be set together")
+	}
+
+	if c.TLSClientCA != "" && !c.TLSEnabled() {
+		return errors.New("TLS_CLIENT_CA requires TLS_CERT and TLS_KEY")
+	}
+
+	if err := validateScopes("TLS_CLIENT_SCOPES", c.TLSClientScopes); err != nil {
+		return err
+	}
+
+	if c.PiperSampleRate < 0 {
+		return errors.New("PIPER_SAMPLE_RATE must be non-negative")
+	}
+
+	if c.TTSCommandRate < 0 {
+		return errors.New("TTS_COMMAND_SAMPLE_RATE must be non-negative")
+	}
+
+	if c.PiperMaxSynth < 0 {
+		return errors.New("PIPER_MAX_CONCURRENT_SYNTH must be non-negative")
+	}
+
+	if c.CloudMaxSynth < 0 {
+		return errors.New("MAX_CONCURRENT_SYNTH must be non-negative")
+	}
+
+	if c.MaxSynthBytes < 0 {
+		return errors.New("MAX_SYNTH_BYTES must be non-negative")
+	}
+
+	if c.MaxAudioSeconds < 0 {
+		return errors.New("MAX_AUDIO_SECONDS must be non-negative")
+	}
+
+	validInterruptModes := map[string]bool{"hard": true, "soft": true}
+	if c.InterruptMode != "" && !validInterruptModes[c.InterruptMode] {
+		return errors.New("INTERRUPT_MODE must be one of: hard, soft")
+	}
+
+	if c.InterruptGrace < 0 {
+		return errors.New("INTERRUPT_GRACE must be non-negative")
+	}
+
+	if c.MaxRetries < 0 {
+		return errors.New("PLAYBACK_MAX_RETRIES must be non-negative")
+	}
+
+	if c.RetryDelay < 0 {
+		return errors.New("PLAYBACK_RETRY_DELAY must be non-negative")
+	}
+
+	if c.SynthTimeout < 0 {
+		return errors.New("SYNTHESIS_TIMEOUT must be non-negative")
+	}
+
+	if c.LeadSilenceMS < 0 {
+		return errors.New("PLAYBACK_LEAD_SILENCE_MS must be non-negative")
+	}
+
+	if c.TrailSilenceMS < 0 {
+		return errors.New("PLAYBACK_TRAIL_SILENCE_MS must be non-negative")
+	}
+
+	if c.PlaybackSink != "" && c.PlaybackSinkFile() == "" {
+		return errors.New("PLAYBACK_SINK must be file:<path>")
+	}
+
+	if c.DefaultLanguage != "" && !ValidLanguageTag(c.DefaultLanguage) {
+		return errors.New("DEFAULT_LANGUAGE must be a language tag like en or en-US")
+	}
+
+	validEmojiModes := map[string]bool{"keep": true, "strip": true, "describe": true}
+	if c.EmojiMode != "" && !validEmojiModes[c.EmojiMode] {
+		return errors.New("EMOJI_MODE must be one of: keep, strip, describe")
+	}
+
+	validOpusApplications := map[string]bool{"voip": true, "audio": true, "lowdelay": true}
+	if c.OpusApplication != "" && !validOpusApplications[c.OpusApplication] {
+		return errors.New("OPUS_APPLICATION must be one of: voip, audio, lowdelay")
+	}
+
+	if c.OpusBitrate != 0 && (c.OpusBitrate < 6000 || c.OpusBitrate > 510000) {
+		return errors.New("OPUS_BITRATE must be 0 (default) or between 6000 and 510000")
+	}
+
+	if c.VoiceKeepalive < 0 {
+		return errors.New("VOICE_KEEPALIVE must be non-negative")
+	}
+
+	if c.SpeakingHoldMS < 0 {
+		return errors.New("SPEAKING_HOLD_MS must be non-negative")
+	}
+
+	if c.VoiceConnectTimeout < 0 {
+		return errors.New("VOICE_CONNECT_TIMEOUT must be non-negative")
+	}
+
+	if c.VoiceConnectRetries < 0 {
+		return errors.New("VOICE_CONNECT_RETRIES must be non-negative")
+	}
+
+	if c.VoiceConnectRetryDelay < 0 {
+		return errors.New("VOICE_CONNECT_RETRY_DELAY must be non-negative")
+	}
+
+	if c.VoiceConnectPoll < 0 {
+		return errors.New("VOICE_CONNECT_POLL_INTERVAL must be non-negative")
+	}
+
 	if c.AutoLeaveIdle < 0 {
 		return errors.New("AUTO_LEAVE_IDLE must be non-negative")
 	}
 
+	if c.DisconnectDelay < 0 {
+		return errors.New("DISCONNECT_DELAY must be non-negative")
+	}
+
+	if c.MinConnected < 0 {
+		return errors.New("MIN_CONNECTED_TIME must be non-negative")
+	}
+
+	if c.QuietHours != "" {
+		start, end, err := parseQuietHours(c.QuietHours)
+		if err != nil {
+			return err
+		}
+		if start == end {
+			return errors.New("QUIET_HOURS must not start and end at the same time")
+		}
+	}
+
+	if _, err := c.quietLocation(); err != nil {
+		return errors.New("QUIET_TIMEZONE must be a time zone name like Europe/Berlin")
+	}
+
+	validQuietModes := map[string]bool{"drop": true, "defer": true}
+	if c.QuietMode != "" && !validQuietModes[c.QuietMode] {
+		return errors.New("QUIET_MODE must be one of: drop, defer")
+	}
+
 	validLogLevels := map[string]bool{"debug": true, "info": true, "warn": true, "error": true}
 	if !validLogLevels[c.LogLevel] {
 		return errors.New("LOG_LEVEL must be one of: debug, info, warn, error")
@@ -108,6 +517,169 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// languageTagPattern loosely matches a BCP-47 tag: a 2-3 letter language
+// followed by subtags such as a script or region.
+var languageTagPattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{1,8})*$`)
+
+// ValidLanguageTag reports whether tag looks like a BCP-47 language tag,
+// e.g. "en", "en-US" or "zh-Hant-TW". It checks the shape, not whether the
+// language exists.
+func ValidLanguageTag(tag string) bool {
+	return languageTagPattern.MatchString(tag)
+}
+
+// validateScopes checks that every name is non-empty and every scope is known.
+func validateScopes(key string, scopes map[string][]string) error {
+	validScopes := map[string]bool{ScopeSpeak: true, ScopeRead: true, ScopeAdmin: true}
+	for name, granted := range scopes {
+		if name == "" {
+			return errors.New(key + " names must be non-empty")
+		}
+		for _, scope := range granted {
+			if !validScopes[scope] {
+				return errors.New(key + " scopes must be one of: speak, read, admin")
+			}
+		}
+	}
+	return nil
+}
+
+// parseScopes parses a JSON object mapping each name to its scopes,
+// e.g. {"dashboard-token": ["read"], "relay-token": ["speak"]}.
+func parseScopes(key, value string) (map[string][]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var scopes map[string][]string
+	if err := json.Unmarshal([]byte(value), &scopes); err != nil {
+		return nil, errors.New(key + " must be a JSON object of name to scopes")
+	}
+	return scopes, nil
+}
+
+// apiKeyEntry is the object form of an API_KEYS value.
+type apiKeyEntry struct {
+	Scopes       []string `json:"scopes"`
+	DefaultVoice string   `json:"default_voice"`
+	// DefaultTTL is a duration string; nil leaves DEFAULT_TTL in effect.
+	DefaultTTL *string `json:"default_ttl"`
+}
+
+// parseAPIKeys parses API_KEYS into cfg. The value is a JSON object mapping
+// each token to either its scopes or an object with scopes and optional
+// per-key defaults, e.g.
+// {"relay-token": ["speak"], "tenant-token": {"scopes": ["speak"], "default_voice": "amy", "default_ttl": "10s"}}.
+func parseAPIKeys(cfg *Config, value string) error {
+	if value == "" {
+		return nil
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(value), &raw); err != nil {
+		return errors.New("API_KEYS must be a JSON object of token to scopes")
+	}
+
+	scopes := make(map[string][]string, len(raw))
+	var voices map[string]string
+	var ttls map[string]time.Duration
+	for token, entry := range raw {
+		var granted []string
+		if err := json.Unmarshal(entry, &granted); err == nil {
+			scopes[token] = granted
+			continue
+		}
+		var obj apiKeyEntry
+		if err := json.Unmarshal(entry, &obj); err != nil {
+			return errors.New("API_KEYS values must be a list of scopes or an object with scopes, default_voice and default_ttl")
+		}
+		scopes[token] = obj.Scopes
+		if obj.DefaultVoice != "" {
+			if voices == nil {
+				voices = make(map[string]string)
+			}
+			voices[token] = obj.DefaultVoice
+		}
+		if obj.DefaultTTL != nil {
+			ttl, err := time.ParseDuration(*obj.DefaultTTL)
+			if err != nil || ttl < 0 {
+				return errors.New("API_KEYS default_ttl must be a non-negative duration")
+			}
+			if ttls == nil {
+				ttls = make(map[string]time.Duration)
+			}
+			ttls[token] = ttl
+		}
+	}
+	cfg.APIKeys = scopes
+	cfg.APIKeyVoices = voices
+	cfg.APIKeyTTLs = ttls
+	return nil
+}
+
+// parseStringMap parses a JSON object of string values, e.g. {"de": "thorsten"}.
+func parseStringMap(key, value string) (map[string]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var m map[string]string
+	if err := json.Unmarshal([]byte(value), &m); err != nil {
+		return nil, errors.New(key + " must be a JSON object of string values")
+	}
+	return m, nil
+}
+
+// VoiceAlias is the engine and voice a VOICE_ALIASES name stands for.
+// An empty Engine means the engine the voice would normally select.
+type VoiceAlias struct {
+	Engine string `json:"engine"`
+	Voice  string `json:"voice"`
+}
+
+// parseVoiceAliases parses VOICE_ALIASES, a JSON object mapping alias
+// names to {"engine": ..., "voice": ...}.
+func parseVoiceAliases(value string) (map[string]VoiceAlias, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var aliases map[string]VoiceAlias
+	if err := json.Unmarshal([]byte(value), &aliases); err != nil {
+		return nil, errors.New("VOICE_ALIASES must be a JSON object of {\"engine\", \"voice\"} objects")
+	}
+	for name, alias := range aliases {
+		if name == "" {
+			return nil, errors.New("VOICE_ALIASES names must not be empty")
+		}
+		if alias.Engine == "" && alias.Voice == "" {
+			return nil, errors.New("VOICE_ALIASES entry " + strconv.Quote(name) + " needs an engine or a voice")
+		}
+	}
+	return aliases, nil
+}
+
+// parseQuietHours parses a QUIET_HOURS window like "22:00-07:00" into
+// times since midnight.
+func parseQuietHours(value string) (start, end time.Duration, err error) {
+	from, to, ok := strings.Cut(value, "-")
+	if ok {
+		start, err = parseClock(strings.TrimSpace(from))
+	}
+	if ok && err == nil {
+		end, err = parseClock(strings.TrimSpace(to))
+	}
+	if !ok || err != nil {
+		return 0, 0, errors.New("QUIET_HOURS must be HH:MM-HH:MM, e.g. 22:00-07:00")
+	}
+	return start, end, nil
+}
+
+// parseClock parses a 24-hour "HH:MM" time into the time since midnight.
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
 // getEnvString returns the environment variable value or a default.
 func getEnvString(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -126,6 +698,28 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getEnvBool returns the environment variable as a bool or a default.
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			return boolVal
+		}
+	}
+	return defaultValue
+}
+
+// getEnvList returns the environment variable split on commas, with
+// whitespace trimmed and empty entries dropped.
+func getEnvList(key string) []string {
+	var values []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
 // getEnvDuration returns the environment variable as a duration or a default.
 func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
@@ -135,3 +729,22 @@ func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	}
 	return defaultValue
 }
+
+// getEnvSecret returns the secret named key, read from the file named by
+// key+"_FILE" with surrounding whitespace trimmed if that is set, and from
+// key itself otherwise. A file that can't be read or is empty is an error.
+func getEnvSecret(key string) (string, error) {
+	path := os.Getenv(key + "_FILE")
+	if path == "" {
+		return os.Getenv(key), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("%s_FILE: %w", key, err)
+	}
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return "", fmt.Errorf("%s_FILE: %s is empty", key, path)
+	}
+	return secret, nil
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -9,10 +11,13 @@ import (
 func TestLoad_Defaults(t *testing.T) {
 	// Clear relevant env vars to test defaults
 	envVars := []string{
-		"DISCORD_TOKEN", "GUILD_ID", "DEFAULT_VOICE_CHANNEL_ID",
-		"HTTP_PORT", "BEARER_TOKEN", "PIPER_PATH", "PIPER_MODEL",
-		"DEFAULT_VOICE", "AUTO_LEAVE_IDLE", "MAX_TEXT_LENGTH",
-		"QUEUE_CAPACITY", "DEFAULT_TTL", "LOG_LEVEL", "LOG_FORMAT",
+		"DISCORD_TOKEN", "DISCORD_TOKEN_FILE", "GUILD_ID", "DEFAULT_VOICE_CHANNEL_ID", "FOLLOW_USER_ID", "ALLOWED_CHANNELS",
+		"HTTP_PORT", "BEARER_TOKEN", "BEARER_TOKEN_FILE", "WEB_UI", "PIPER_PATH", "PIPER_MODEL",
+		"PIPER_SAMPLE_RATE", "TTS_COMMAND", "TTS_COMMAND_SAMPLE_RATE", "POLLY_REGION", "POLLY_VOICE", "GOOGLE_TTS_CREDENTIALS", "GOOGLE_TTS_VOICE", "MAX_CONCURRENT_SYNTH", "PIPER_MAX_CONCURRENT_SYNTH", "MAX_SYNTH_BYTES", "VOICE_ALIASES", "INTERRUPT_MODE", "INTERRUPT_GRACE",
+		"PLAYBACK_MAX_RETRIES", "PLAYBACK_RETRY_DELAY", "SYNTHESIS_TIMEOUT", "PLAYBACK_LEAD_SILENCE_MS", "PLAYBACK_TRAIL_SILENCE_MS", "PLAYBACK_SINK", "DEAD_LETTER_PATH", "OPUS_APPLICATION", "OPUS_BITRATE", "TRIM_SILENCE", "FAST_RESAMPLE", "STRIP_MARKDOWN", "EMOJI_MODE", "DEFAULT_LANGUAGE", "VOICE_KEEPALIVE",
+		"VOICE_CONNECT_TIMEOUT", "VOICE_CONNECT_RETRIES", "VOICE_CONNECT_RETRY_DELAY", "VOICE_CONNECT_POLL_INTERVAL", "DROP_IF_DISCONNECTED", "COALESCE_PLAYBACK", "SPEAKING_HOLD_MS",
+		"DEFAULT_VOICE", "AUTO_LEAVE_IDLE", "JOIN_ON_START", "STAY_CONNECTED", "DISCONNECT_DELAY", "MIN_CONNECTED_TIME", "MAX_TEXT_LENGTH", "STRICT_JSON",
+		"QUEUE_CAPACITY", "QUEUE_WARN_DEPTH", "HISTORY_SIZE", "DEFAULT_TTL", "AUTO_DEDUPE", "IDEMPOTENCY_TTL", "QUIET_HOURS", "QUIET_TIMEZONE", "QUIET_MODE", "LOG_LEVEL", "LOG_FORMAT",
 	}
 	for _, v := range envVars {
 		os.Unsetenv(v)
@@ -27,24 +32,148 @@ func TestLoad_Defaults(t *testing.T) {
 	if cfg.HTTPPort != 8080 {
 		t.Errorf("HTTPPort = %d, want 8080", cfg.HTTPPort)
 	}
+	if cfg.WebUI {
+		t.Error("WebUI = true, want false")
+	}
 	if cfg.PiperPath != "piper" {
 		t.Errorf("PiperPath = %s, want piper", cfg.PiperPath)
 	}
+	if cfg.PiperSampleRate != 0 {
+		t.Errorf("PiperSampleRate = %d, want 0 (auto-detect)", cfg.PiperSampleRate)
+	}
+	if cfg.FollowUserID != "" {
+		t.Errorf("FollowUserID = %q, want empty (disabled)", cfg.FollowUserID)
+	}
+	if cfg.TTSCommand != "" || cfg.TTSCommandRate != 0 {
+		t.Errorf("TTSCommand = %q, TTSCommandRate = %d, want disabled", cfg.TTSCommand, cfg.TTSCommandRate)
+	}
+	if cfg.PiperMaxSynth != 0 || cfg.CloudMaxSynth != 0 {
+		t.Errorf("PiperMaxSynth = %d, CloudMaxSynth = %d, want 0 (unlimited)", cfg.PiperMaxSynth, cfg.CloudMaxSynth)
+	}
+	if cfg.MaxSynthBytes != 256<<20 {
+		t.Errorf("MaxSynthBytes = %d, want 256MiB", cfg.MaxSynthBytes)
+	}
+	if cfg.PollyRegion != "" || cfg.PollyVoice != "Joanna" {
+		t.Errorf("PollyRegion = %q, PollyVoice = %q, want disabled with Joanna", cfg.PollyRegion, cfg.PollyVoice)
+	}
+	if cfg.GoogleTTSCreds != "" || cfg.GoogleTTSVoice != "en-US-Standard-C" {
+		t.Errorf("GoogleTTSCreds = %q, GoogleTTSVoice = %q, want disabled with en-US-Standard-C", cfg.GoogleTTSCreds, cfg.GoogleTTSVoice)
+	}
 	if cfg.DefaultVoice != "default" {
 		t.Errorf("DefaultVoice = %s, want default", cfg.DefaultVoice)
 	}
 	if cfg.AutoLeaveIdle != 5*time.Minute {
 		t.Errorf("AutoLeaveIdle = %v, want 5m", cfg.AutoLeaveIdle)
 	}
+	if cfg.JoinOnStart {
+		t.Error("JoinOnStart = true, want false")
+	}
+	if cfg.StayConnected {
+		t.Error("StayConnected = true, want false")
+	}
+	if cfg.DisconnectDelay != 0 || cfg.MinConnected != 0 {
+		t.Errorf("DisconnectDelay, MinConnected = %v, %v, want 0, 0", cfg.DisconnectDelay, cfg.MinConnected)
+	}
 	if cfg.MaxTextLength != 1000 {
 		t.Errorf("MaxTextLength = %d, want 1000", cfg.MaxTextLength)
 	}
 	if cfg.QueueCapacity != 100 {
 		t.Errorf("QueueCapacity = %d, want 100", cfg.QueueCapacity)
 	}
+	if cfg.QueueWarnDepth != 0 {
+		t.Errorf("QueueWarnDepth = %d, want 0", cfg.QueueWarnDepth)
+	}
+	if cfg.HistorySize != 100 {
+		t.Errorf("HistorySize = %d, want 100", cfg.HistorySize)
+	}
 	if cfg.DefaultTTL != 30*time.Second {
 		t.Errorf("DefaultTTL = %v, want 30s", cfg.DefaultTTL)
 	}
+	if cfg.AutoDedupe {
+		t.Error("AutoDedupe = true, want false")
+	}
+	if cfg.IdempotencyTTL != 10*time.Minute {
+		t.Errorf("IdempotencyTTL = %v, want 10m", cfg.IdempotencyTTL)
+	}
+	if cfg.QuietHours != "" {
+		t.Errorf("QuietHours = %q, want empty", cfg.QuietHours)
+	}
+	if cfg.QuietMode != "drop" {
+		t.Errorf("QuietMode = %q, want drop", cfg.QuietMode)
+	}
+	if _, _, _, ok := cfg.QuietWindow(); ok {
+		t.Error("QuietWindow() ok = true, want false")
+	}
+	if cfg.StrictJSON {
+		t.Error("StrictJSON = true, want false")
+	}
+	if cfg.InterruptMode != "hard" {
+		t.Errorf("InterruptMode = %s, want hard", cfg.InterruptMode)
+	}
+	if cfg.InterruptGrace != 3*time.Second {
+		t.Errorf("InterruptGrace = %v, want 3s", cfg.InterruptGrace)
+	}
+	if cfg.MaxRetries != 2 {
+		t.Errorf("MaxRetries = %d, want 2", cfg.MaxRetries)
+	}
+	if cfg.RetryDelay != 500*time.Millisecond {
+		t.Errorf("RetryDelay = %v, want 500ms", cfg.RetryDelay)
+	}
+	if cfg.SynthTimeout != 0 {
+		t.Errorf("SynthTimeout = %v, want 0", cfg.SynthTimeout)
+	}
+	if cfg.LeadSilenceMS != 0 || cfg.TrailSilenceMS != 0 {
+		t.Errorf("silence padding = %dms, %dms, want 0, 0", cfg.LeadSilenceMS, cfg.TrailSilenceMS)
+	}
+	if cfg.PlaybackSink != "" || cfg.PlaybackSinkFile() != "" {
+		t.Errorf("PlaybackSink = %q, want empty", cfg.PlaybackSink)
+	}
+	if cfg.RecordDir != "" {
+		t.Errorf("RecordDir = %q, want empty", cfg.RecordDir)
+	}
+	if cfg.DeadLetterPath != "" {
+		t.Errorf("DeadLetterPath = %q, want empty", cfg.DeadLetterPath)
+	}
+	if cfg.VoiceKeepalive != 0 {
+		t.Errorf("VoiceKeepalive = %v, want 0", cfg.VoiceKeepalive)
+	}
+	if cfg.VoiceConnectTimeout != 10*time.Second || cfg.VoiceConnectRetries != 2 || cfg.VoiceConnectRetryDelay != time.Second {
+		t.Errorf("voice connect timeout = %v, retries = %d, delay = %v, want 10s, 2, 1s",
+			cfg.VoiceConnectTimeout, cfg.VoiceConnectRetries, cfg.VoiceConnectRetryDelay)
+	}
+	if cfg.VoiceConnectPoll != 20*time.Millisecond {
+		t.Errorf("VoiceConnectPoll = %v, want 20ms", cfg.VoiceConnectPoll)
+	}
+	if cfg.DropIfDisconnected {
+		t.Error("DropIfDisconnected = true, want false")
+	}
+	if !cfg.CoalescePlayback {
+		t.Error("CoalescePlayback = false, want true")
+	}
+	if cfg.SpeakingHoldMS != 0 {
+		t.Errorf("SpeakingHoldMS = %d, want 0", cfg.SpeakingHoldMS)
+	}
+	if cfg.OpusApplication != "voip" {
+		t.Errorf("OpusApplication = %s, want voip", cfg.OpusApplication)
+	}
+	if cfg.OpusBitrate != 0 {
+		t.Errorf("OpusBitrate = %d, want 0", cfg.OpusBitrate)
+	}
+	if cfg.TrimSilence {
+		t.Error("TrimSilence = true, want false")
+	}
+	if cfg.FastResample {
+		t.Error("FastResample = true, want false")
+	}
+	if cfg.StripMarkdown {
+		t.Error("StripMarkdown = true, want false")
+	}
+	if cfg.EmojiMode != "keep" {
+		t.Errorf("EmojiMode = %s, want keep", cfg.EmojiMode)
+	}
+	if cfg.DefaultLanguage != "" {
+		t.Errorf("DefaultLanguage = %s, want empty", cfg.DefaultLanguage)
+	}
 	if cfg.LogLevel != "info" {
 		t.Errorf("LogLevel = %s, want info", cfg.LogLevel)
 	}
@@ -185,6 +314,469 @@ func TestValidate_InvalidQueueCapacity(t *testing.T) {
 	}
 }
 
+func TestLoad_OpusSettings(t *testing.T) {
+	os.Setenv("OPUS_APPLICATION", "audio")
+	os.Setenv("OPUS_BITRATE", "96000")
+	defer func() {
+		os.Unsetenv("OPUS_APPLICATION")
+		os.Unsetenv("OPUS_BITRATE")
+	}()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.OpusApplication != "audio" {
+		t.Errorf("OpusApplication = %s, want audio", cfg.OpusApplication)
+	}
+	if cfg.OpusBitrate != 96000 {
+		t.Errorf("OpusBitrate = %d, want 96000", cfg.OpusBitrate)
+	}
+}
+
+func TestValidate_OpusSettings(t *testing.T) {
+	tests := []struct {
+		name        string
+		application string
+		bitrate     int
+		wantErr     bool
+	}{
+		{"voip default bitrate", "voip", 0, false},
+		{"audio with bitrate", "audio", 128000, false},
+		{"lowdelay min bitrate", "lowdelay", 6000, false},
+		{"max bitrate", "voip", 510000, false},
+		{"unknown application", "music", 0, true},
+		{"bitrate too low", "voip", 5999, true},
+		{"bitrate too high", "voip", 510001, true},
+		{"negative bitrate", "voip", -1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				HTTPPort:        8080,
+				MaxTextLength:   1000,
+				QueueCapacity:   100,
+				OpusApplication: tt.application,
+				OpusBitrate:     tt.bitrate,
+				LogLevel:        "info",
+				LogFormat:       "text",
+			}
+
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_VoiceConnect(t *testing.T) {
+	os.Setenv("VOICE_CONNECT_TIMEOUT", "30s")
+	os.Setenv("VOICE_CONNECT_RETRIES", "0")
+	os.Setenv("VOICE_CONNECT_RETRY_DELAY", "5s")
+	defer func() {
+		os.Unsetenv("VOICE_CONNECT_TIMEOUT")
+		os.Unsetenv("VOICE_CONNECT_RETRIES")
+		os.Unsetenv("VOICE_CONNECT_RETRY_DELAY")
+	}()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.VoiceConnectTimeout != 30*time.Second {
+		t.Errorf("VoiceConnectTimeout = %v, want 30s", cfg.VoiceConnectTimeout)
+	}
+	if cfg.VoiceConnectRetries != 0 {
+		t.Errorf("VoiceConnectRetries = %d, want 0", cfg.VoiceConnectRetries)
+	}
+	if cfg.VoiceConnectRetryDelay != 5*time.Second {
+		t.Errorf("VoiceConnectRetryDelay = %v, want 5s", cfg.VoiceConnectRetryDelay)
+	}
+}
+
+func TestValidate_VoiceConnect(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		retries int
+		delay   time.Duration
+		poll    time.Duration
+		wantErr bool
+	}{
+		{"defaults", 10 * time.Second, 2, time.Second, 20 * time.Millisecond, false},
+		{"single attempt", 10 * time.Second, 0, 0, 0, false},
+		{"negative timeout", -time.Second, 2, time.Second, 0, true},
+		{"negative retries", 10 * time.Second, -1, time.Second, 0, true},
+		{"negative delay", 10 * time.Second, 2, -time.Second, 0, true},
+		{"negative poll interval", 10 * time.Second, 2, time.Second, -time.Millisecond, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				HTTPPort:               8080,
+				MaxTextLength:          1000,
+				QueueCapacity:          100,
+				VoiceConnectTimeout:    tt.timeout,
+				VoiceConnectRetries:    tt.retries,
+				VoiceConnectRetryDelay: tt.delay,
+				VoiceConnectPoll:       tt.poll,
+				LogLevel:               "info",
+				LogFormat:              "text",
+			}
+
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_InvalidInterruptMode(t *testing.T) {
+	cfg := &Config{
+		HTTPPort:      8080,
+		MaxTextLength: 1000,
+		QueueCapacity: 100,
+		InterruptMode: "gentle",
+		LogLevel:      "info",
+		LogFormat:     "text",
+	}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Error("Validate() expected error for invalid interrupt mode")
+	}
+}
+
+func TestValidate_InvalidEmojiMode(t *testing.T) {
+	cfg := &Config{
+		HTTPPort:      8080,
+		MaxTextLength: 1000,
+		QueueCapacity: 100,
+		EmojiMode:     "translate",
+		LogLevel:      "info",
+		LogFormat:     "text",
+	}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Error("Validate() expected error for invalid emoji mode")
+	}
+}
+
+func TestValidate_PlaybackSink(t *testing.T) {
+	tests := []struct {
+		sink     string
+		wantFile string
+		wantErr  bool
+	}{
+		{"", "", false},
+		{"file:/tmp/out.pcm", "/tmp/out.pcm", false},
+		{"file:", "", true},
+		{"/tmp/out.pcm", "", true},
+		{"discord", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sink, func(t *testing.T) {
+			cfg := &Config{
+				HTTPPort:      8080,
+				MaxTextLength: 1000,
+				QueueCapacity: 100,
+				PlaybackSink:  tt.sink,
+				LogLevel:      "info",
+				LogFormat:     "text",
+			}
+
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && cfg.PlaybackSinkFile() != tt.wantFile {
+				t.Errorf("PlaybackSinkFile() = %q, want %q", cfg.PlaybackSinkFile(), tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestValidate_QuietHours(t *testing.T) {
+	tests := []struct {
+		name      string
+		hours     string
+		timezone  string
+		mode      string
+		wantStart time.Duration
+		wantEnd   time.Duration
+		wantErr   bool
+	}{
+		{"off", "", "", "drop", 0, 0, false},
+		{"overnight", "22:00-07:00", "", "drop", 22 * time.Hour, 7 * time.Hour, false},
+		{"daytime with zone", "09:30 - 17:00", "Europe/Berlin", "defer", 9*time.Hour + 30*time.Minute, 17 * time.Hour, false},
+		{"no separator", "22:00", "", "drop", 0, 0, true},
+		{"bad time", "25:00-07:00", "", "drop", 0, 0, true},
+		{"empty window", "07:00-07:00", "", "drop", 0, 0, true},
+		{"bad timezone", "22:00-07:00", "Mars/Olympus", "drop", 0, 0, true},
+		{"bad mode", "22:00-07:00", "", "mute", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				HTTPPort:      8080,
+				MaxTextLength: 1000,
+				QueueCapacity: 100,
+				QuietHours:    tt.hours,
+				QuietTimezone: tt.timezone,
+				QuietMode:     tt.mode,
+				LogLevel:      "info",
+				LogFormat:     "text",
+			}
+
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			start, end, loc, ok := cfg.QuietWindow()
+			if ok != (tt.hours != "") || start != tt.wantStart || end != tt.wantEnd {
+				t.Errorf("QuietWindow() = %v, %v, ok %v, want %v, %v", start, end, ok, tt.wantStart, tt.wantEnd)
+			}
+			wantLoc := tt.timezone
+			if wantLoc == "" {
+				wantLoc = time.Local.String()
+			}
+			if ok && loc.String() != wantLoc {
+				t.Errorf("QuietWindow() location = %v, want %s", loc, wantLoc)
+			}
+		})
+	}
+}
+
+func TestValidLanguageTag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want bool
+	}{
+		{"en", true},
+		{"en-US", true},
+		{"fil", true},
+		{"zh-Hant-TW", true},
+		{"es-419", true},
+		{"", false},
+		{"e", false},
+		{"english", false},
+		{"en_US", false},
+		{"en-", false},
+		{"en US", false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidLanguageTag(tt.tag); got != tt.want {
+			t.Errorf("ValidLanguageTag(%q) = %v, want %v", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestValidate_QueueWarnDepth(t *testing.T) {
+	tests := []struct {
+		name    string
+		depth   int
+		wantErr bool
+	}{
+		{"off", 0, false},
+		{"below capacity", 80, false},
+		{"at capacity", 100, false},
+		{"negative", -1, true},
+		{"above capacity", 101, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				HTTPPort:       8080,
+				MaxTextLength:  1000,
+				QueueCapacity:  100,
+				QueueWarnDepth: tt.depth,
+				LogLevel:       "info",
+				LogFormat:      "text",
+			}
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_APIKeys(t *testing.T) {
+	os.Setenv("API_KEYS", `{"dashboard-token": ["read"], "relay-token": ["speak", "read"]}`)
+	defer os.Unsetenv("API_KEYS")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if got := cfg.APIKeys["relay-token"]; len(got) != 2 || got[0] != ScopeSpeak || got[1] != ScopeRead {
+		t.Errorf("APIKeys[relay-token] = %v, want [speak read]", got)
+	}
+	if cfg.AuthDisabled() {
+		t.Error("AuthDisabled() = true, want false with API keys configured")
+	}
+}
+
+func TestLoad_APIKeyDefaultVoice(t *testing.T) {
+	os.Setenv("API_KEYS", `{"relay-token": ["speak"], "tenant-token": {"scopes": ["speak"], "default_voice": "amy"}}`)
+	defer os.Unsetenv("API_KEYS")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if got := cfg.APIKeys["tenant-token"]; len(got) != 1 || got[0] != ScopeSpeak {
+		t.Errorf("APIKeys[tenant-token] = %v, want [speak]", got)
+	}
+	if got := cfg.APIKeyVoices["tenant-token"]; got != "amy" {
+		t.Errorf("APIKeyVoices[tenant-token] = %q, want amy", got)
+	}
+	if _, ok := cfg.APIKeyVoices["relay-token"]; ok {
+		t.Error("APIKeyVoices[relay-token] set, want no default voice")
+	}
+}
+
+func TestLoad_APIKeyDefaultTTL(t *testing.T) {
+	os.Setenv("API_KEYS", `{"relay-token": ["speak"], "alerts-token": {"scopes": ["speak"], "default_ttl": "10s"}, "archive-token": {"scopes": ["speak"], "default_ttl": "0s"}}`)
+	defer os.Unsetenv("API_KEYS")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if got := cfg.APIKeyTTLs["alerts-token"]; got != 10*time.Second {
+		t.Errorf("APIKeyTTLs[alerts-token] = %v, want 10s", got)
+	}
+	if got, ok := cfg.APIKeyTTLs["archive-token"]; !ok || got != 0 {
+		t.Errorf("APIKeyTTLs[archive-token] = %v, %v, want an explicit 0", got, ok)
+	}
+	if _, ok := cfg.APIKeyTTLs["relay-token"]; ok {
+		t.Error("APIKeyTTLs[relay-token] set, want DEFAULT_TTL to apply")
+	}
+}
+
+func TestLoad_InvalidAPIKeys(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"not json", "dashboard-token:read"},
+		{"unknown scope", `{"dashboard-token": ["write"]}`},
+		{"empty token", `{"": ["read"]}`},
+		{"bad entry", `{"dashboard-token": "read"}`},
+		{"unknown scope in object", `{"dashboard-token": {"scopes": ["write"]}}`},
+		{"bad default ttl", `{"dashboard-token": {"scopes": ["read"], "default_ttl": "soon"}}`},
+		{"negative default ttl", `{"dashboard-token": {"scopes": ["read"], "default_ttl": "-5s"}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv("API_KEYS", tt.value)
+			defer os.Unsetenv("API_KEYS")
+
+			if _, err := Load(); err == nil {
+				t.Error("Load() expected error for invalid API_KEYS")
+			}
+		})
+	}
+}
+
+func TestLoad_VoiceAliases(t *testing.T) {
+	os.Setenv("VOICE_ALIASES", `{"narrator": {"engine": "polly", "voice": "Matthew:neural"}, "deep": {"voice": "3"}}`)
+	defer os.Unsetenv("VOICE_ALIASES")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if got := cfg.VoiceAliases["narrator"]; got != (VoiceAlias{Engine: "polly", Voice: "Matthew:neural"}) {
+		t.Errorf("VoiceAliases[narrator] = %+v, want polly/Matthew:neural", got)
+	}
+	if got := cfg.VoiceAliases["deep"]; got != (VoiceAlias{Voice: "3"}) {
+		t.Errorf("VoiceAliases[deep] = %+v, want voice 3 only", got)
+	}
+}
+
+func TestLoad_InvalidVoiceAliases(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"not json", "narrator=polly"},
+		{"string entry", `{"narrator": "polly"}`},
+		{"empty name", `{"": {"voice": "3"}}`},
+		{"empty entry", `{"narrator": {}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv("VOICE_ALIASES", tt.value)
+			defer os.Unsetenv("VOICE_ALIASES")
+
+			if _, err := Load(); err == nil {
+				t.Error("Load() expected error for invalid VOICE_ALIASES")
+			}
+		})
+	}
+}
+
+func TestValidate_TLSSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		cert     string
+		key      string
+		clientCA string
+		wantErr  bool
+	}{
+		{"plaintext", "", "", "", false},
+		{"server TLS", "cert.pem", "key.pem", "", false},
+		{"mutual TLS", "cert.pem", "key.pem", "ca.pem", false},
+		{"cert without key", "cert.pem", "", "", true},
+		{"key without cert", "", "key.pem", "", true},
+		{"client CA without TLS", "", "", "ca.pem", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				HTTPPort:      8080,
+				MaxTextLength: 1000,
+				QueueCapacity: 100,
+				TLSCert:       tt.cert,
+				TLSKey:        tt.key,
+				TLSClientCA:   tt.clientCA,
+				LogLevel:      "info",
+				LogFormat:     "text",
+			}
+
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := cfg.TLSEnabled(); got != (tt.cert != "" && tt.key != "") {
+				t.Errorf("TLSEnabled() = %v", got)
+			}
+		})
+	}
+}
+
 func TestGetEnvString(t *testing.T) {
 	os.Setenv("TEST_STRING", "value")
 	defer os.Unsetenv("TEST_STRING")
@@ -218,6 +810,26 @@ func TestGetEnvInt(t *testing.T) {
 	}
 }
 
+func TestGetEnvList(t *testing.T) {
+	os.Setenv("TEST_LIST", " one, two ,,three ")
+	defer os.Unsetenv("TEST_LIST")
+
+	got := getEnvList("TEST_LIST")
+	want := []string{"one", "two", "three"}
+	if len(got) != len(want) {
+		t.Fatalf("getEnvList() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("getEnvList()[%d] = %s, want %s", i, got[i], want[i])
+		}
+	}
+
+	if got := getEnvList("NONEXISTENT"); len(got) != 0 {
+		t.Errorf("getEnvList() = %v, want empty", got)
+	}
+}
+
 func TestGetEnvDuration(t *testing.T) {
 	os.Setenv("TEST_DURATION", "5m")
 	defer os.Unsetenv("TEST_DURATION")
@@ -238,11 +850,78 @@ func TestGetEnvDuration(t *testing.T) {
 	}
 }
 
+func TestGetEnvSecret(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(secretFile, []byte("  from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	emptyFile := filepath.Join(dir, "empty")
+	if err := os.WriteFile(emptyFile, []byte("\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		inline  string
+		file    string
+		want    string
+		wantErr bool
+	}{
+		{"inline", "inline-token", "", "inline-token", false},
+		{"file trimmed", "", secretFile, "from-file", false},
+		{"file beats inline", "inline-token", secretFile, "from-file", false},
+		{"missing file", "inline-token", filepath.Join(dir, "missing"), "", true},
+		{"empty file", "", emptyFile, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_SECRET", tt.inline)
+			t.Setenv("TEST_SECRET_FILE", tt.file)
+
+			got, err := getEnvSecret("TEST_SECRET")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getEnvSecret() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("getEnvSecret() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_SecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	for name, value := range map[string]string{"discord": "discord-secret\n", "bearer": "bearer-secret\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(value), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("DISCORD_TOKEN", "inline-discord")
+	t.Setenv("DISCORD_TOKEN_FILE", filepath.Join(dir, "discord"))
+	t.Setenv("BEARER_TOKEN_FILE", filepath.Join(dir, "bearer"))
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.DiscordToken != "discord-secret" || cfg.BearerToken != "bearer-secret" {
+		t.Errorf("tokens = %q, %q, want the file contents", cfg.DiscordToken, cfg.BearerToken)
+	}
+
+	t.Setenv("BEARER_TOKEN_FILE", filepath.Join(dir, "missing"))
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "BEARER_TOKEN_FILE") {
+		t.Errorf("Load() error = %v, want one naming BEARER_TOKEN_FILE", err)
+	}
+}
+
 func TestAuthDisabled(t *testing.T) {
 	tests := []struct {
-		name        string
-		bearerToken string
-		want        bool
+		name         string
+		bearerToken  string
+		bearerTokens []string
+		want         bool
 	}{
 		{
 			name:        "empty token means auth disabled",
@@ -259,12 +938,18 @@ func TestAuthDisabled(t *testing.T) {
 			bearerToken: "   ",
 			want:        false,
 		},
+		{
+			name:         "rotation tokens alone enable auth",
+			bearerTokens: []string{"next-token"},
+			want:         false,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			cfg := &Config{
-				BearerToken: tt.bearerToken,
+				BearerToken:  tt.bearerToken,
+				BearerTokens: tt.bearerTokens,
 			}
 			if got := cfg.AuthDisabled(); got != tt.want {
 				t.Errorf("AuthDisabled() = %v, want %v", got, tt.want)
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// LoadEnvFile sets the environment variables listed in a .env style file
+// at path, overriding values already set, so a later Load sees them. Each
+// line is KEY=VALUE, optionally prefixed with "export " and with the value
+// in single or double quotes; blank lines and lines starting with # are
+// skipped.
+func LoadEnvFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open env file: %w", err)
+	}
+	defer f.Close()
+
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return fmt.Errorf("%s:%d: expected KEY=VALUE", path, n)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			if value[0] == '"' {
+				if value, err = strconv.Unquote(value); err != nil {
+					return fmt.Errorf("%s:%d: invalid quoted value", path, n)
+				}
+			} else {
+				value = value[1 : len(value)-1]
+			}
+		}
+		vars[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read env file: %w", err)
+	}
+
+	// Set nothing unless the whole file parses
+	for key, value := range vars {
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("set %s: %w", key, err)
+		}
+	}
+	return nil
+}
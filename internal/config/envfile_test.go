@@ -0,0 +1,72 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	content := `# comment
+
+LOG_LEVEL=debug
+export DEFAULT_VOICE = amy
+VOICE_ALIASES='{"narrator": {"voice": "bob"}}'
+REDACT_WITH="a \"quoted\" value"
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"LOG_LEVEL", "DEFAULT_VOICE", "VOICE_ALIASES", "REDACT_WITH"} {
+		t.Setenv(key, "")
+	}
+
+	if err := LoadEnvFile(path); err != nil {
+		t.Fatalf("LoadEnvFile() error = %v", err)
+	}
+
+	want := map[string]string{
+		"LOG_LEVEL":     "debug",
+		"DEFAULT_VOICE": "amy",
+		"VOICE_ALIASES": `{"narrator": {"voice": "bob"}}`,
+		"REDACT_WITH":   `a "quoted" value`,
+	}
+	for key, value := range want {
+		if got := os.Getenv(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestLoadEnvFile_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"missing equals", "LOG_LEVEL debug\n"},
+		{"empty key", "=debug\n"},
+		{"bad quoting", `LOG_LEVEL="debug\q"` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".env")
+			if err := os.WriteFile(path, []byte("DEFAULT_VOICE=amy\n"+tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("DEFAULT_VOICE", "default")
+
+			if err := LoadEnvFile(path); err == nil {
+				t.Fatal("LoadEnvFile() error = nil, want an error")
+			}
+			if got := os.Getenv("DEFAULT_VOICE"); got != "default" {
+				t.Errorf("DEFAULT_VOICE = %q, want it unchanged after a failed load", got)
+			}
+		})
+	}
+
+	if err := LoadEnvFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("LoadEnvFile(missing) error = nil, want an error")
+	}
+}
@@ -0,0 +1,125 @@
+package discord
+
+import (
+	"github.com/bwmarrin/discordgo"
+)
+
+// SetFollowUser makes the voice manager target whichever voice channel
+// userID is in, moving the bot along with them while connected. When they
+// leave voice, the default channel is targeted again. It must be called
+// before Open so the guild's initial voice states are seen.
+func (vm *VoiceManager) SetFollowUser(userID string) {
+	vm.mu.Lock()
+	vm.followUserID = userID
+	vm.mu.Unlock()
+
+	if userID == "" {
+		return
+	}
+	vm.session.AddHandler(vm.onGuildCreate)
+	vm.session.AddHandler(vm.onVoiceStateUpdate)
+}
+
+// CurrentTargetChannel returns the voice channel the next job plays in.
+func (vm *VoiceManager) CurrentTargetChannel() string {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	return vm.targetChannelLocked()
+}
+
+// TargetChannel makes audio play in channelID until it is called again,
+// moving an open connection there. An empty channelID goes back to the
+// default or followed channel.
+func (vm *VoiceManager) TargetChannel(channelID string) error {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+
+	from := vm.targetChannelLocked()
+	vm.jobChannelID = channelID
+	target := vm.targetChannelLocked()
+	if target == from || !vm.connected || vm.voiceConnection == nil {
+		return nil
+	}
+
+	vm.logger.Info("moving to requested voice channel",
+		"from_channel_id", from,
+		"channel_id", target,
+	)
+	return vm.voiceConnection.ChangeChannel(target, false, true)
+}
+
+// targetChannelLocked returns the channel audio plays in. vm.mu must be
+// held.
+func (vm *VoiceManager) targetChannelLocked() string {
+	if vm.jobChannelID != "" {
+		return vm.jobChannelID
+	}
+	return vm.channelID
+}
+
+// onGuildCreate picks up the followed user's channel if they were already
+// in voice when the bot started.
+func (vm *VoiceManager) onGuildCreate(_ *discordgo.Session, g *discordgo.GuildCreate) {
+	if g.Guild == nil || g.ID != vm.guildID {
+		return
+	}
+	for _, vs := range g.VoiceStates {
+		if vs.UserID == vm.followedUser() {
+			// Voice states in a guild payload omit the guild ID
+			state := *vs
+			state.GuildID = g.ID
+			vm.followVoiceState(&state)
+			return
+		}
+	}
+}
+
+// onVoiceStateUpdate retargets the bot when the followed user joins,
+// moves or leaves a voice channel.
+func (vm *VoiceManager) onVoiceStateUpdate(_ *discordgo.Session, vs *discordgo.VoiceStateUpdate) {
+	if vs.VoiceState == nil {
+		return
+	}
+	vm.followVoiceState(vs.VoiceState)
+}
+
+// followedUser returns the user being followed, if any.
+func (vm *VoiceManager) followedUser() string {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	return vm.followUserID
+}
+
+// followVoiceState updates the target channel from a voice state of the
+// followed user, moving an open connection to the new channel.
+func (vm *VoiceManager) followVoiceState(vs *discordgo.VoiceState) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+
+	if vm.followUserID == "" || vs.UserID != vm.followUserID || vs.GuildID != vm.guildID {
+		return
+	}
+
+	target := vs.ChannelID
+	if target == "" {
+		// The user left voice
+		target = vm.defaultChannelID
+	}
+	if target == vm.channelID {
+		return
+	}
+
+	vm.logger.Info("following user to voice channel",
+		"user_id", vs.UserID,
+		"from_channel_id", vm.channelID,
+		"channel_id", target,
+	)
+	vm.channelID = target
+
+	// A job playing in a channel it asked for stays there
+	if vm.jobChannelID == "" && vm.connected && vm.voiceConnection != nil {
+		if err := vm.voiceConnection.ChangeChannel(target, false, true); err != nil {
+			vm.logger.Warn("failed to move to followed channel", "channel_id", target, "error", err)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package discord
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func newFollowingVoiceManager() *VoiceManager {
+	return &VoiceManager{
+		logger:           testLogger(),
+		guildID:          "guild",
+		channelID:        "default",
+		defaultChannelID: "default",
+		followUserID:     "alice",
+	}
+}
+
+func voiceStateUpdate(guildID, userID, channelID string) *discordgo.VoiceStateUpdate {
+	return &discordgo.VoiceStateUpdate{VoiceState: &discordgo.VoiceState{
+		GuildID:   guildID,
+		UserID:    userID,
+		ChannelID: channelID,
+	}}
+}
+
+func TestVoiceManager_FollowUser(t *testing.T) {
+	vm := newFollowingVoiceManager()
+
+	steps := []struct {
+		name  string
+		event *discordgo.VoiceStateUpdate
+		want  string
+	}{
+		{"user joins", voiceStateUpdate("guild", "alice", "general"), "general"},
+		{"other user moves", voiceStateUpdate("guild", "bob", "music"), "general"},
+		{"other guild", voiceStateUpdate("elsewhere", "alice", "lounge"), "general"},
+		{"user moves", voiceStateUpdate("guild", "alice", "gaming"), "gaming"},
+		{"user leaves voice", voiceStateUpdate("guild", "alice", ""), "default"},
+	}
+
+	for _, step := range steps {
+		vm.onVoiceStateUpdate(nil, step.event)
+		if got := vm.CurrentTargetChannel(); got != step.want {
+			t.Errorf("%s: CurrentTargetChannel() = %q, want %q", step.name, got, step.want)
+		}
+	}
+}
+
+func TestVoiceManager_TargetChannel(t *testing.T) {
+	vm := newFollowingVoiceManager()
+
+	steps := []struct {
+		name    string
+		channel *string // TargetChannel argument, if called
+		event   *discordgo.VoiceStateUpdate
+		want    string
+	}{
+		{"requested channel", ptr("music"), nil, "music"},
+		{"followed user moves", nil, voiceStateUpdate("guild", "alice", "general"), "music"},
+		{"back to usual channel", ptr(""), nil, "general"},
+		{"user leaves voice", nil, voiceStateUpdate("guild", "alice", ""), "default"},
+	}
+
+	for _, step := range steps {
+		if step.channel != nil {
+			if err := vm.TargetChannel(*step.channel); err != nil {
+				t.Fatalf("%s: TargetChannel() error = %v", step.name, err)
+			}
+		}
+		if step.event != nil {
+			vm.onVoiceStateUpdate(nil, step.event)
+		}
+		if got := vm.CurrentTargetChannel(); got != step.want {
+			t.Errorf("%s: CurrentTargetChannel() = %q, want %q", step.name, got, step.want)
+		}
+	}
+}
+
+func ptr(s string) *string { return &s }
+
+func TestVoiceManager_FollowUser_Disabled(t *testing.T) {
+	vm := newFollowingVoiceManager()
+	vm.followUserID = ""
+
+	vm.onVoiceStateUpdate(nil, voiceStateUpdate("guild", "alice", "general"))
+	if got := vm.CurrentTargetChannel(); got != "default" {
+		t.Errorf("CurrentTargetChannel() = %q, want the default channel", got)
+	}
+}
+
+func TestVoiceManager_FollowUser_InitialState(t *testing.T) {
+	vm := newFollowingVoiceManager()
+
+	vm.onGuildCreate(nil, &discordgo.GuildCreate{Guild: &discordgo.Guild{
+		ID: "guild",
+		VoiceStates: []*discordgo.VoiceState{
+			{UserID: "bob", ChannelID: "music"},
+			{UserID: "alice", ChannelID: "general"},
+		},
+	}})
+	if got := vm.CurrentTargetChannel(); got != "general" {
+		t.Errorf("CurrentTargetChannel() = %q, want general", got)
+	}
+}
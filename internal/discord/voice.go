@@ -3,6 +3,7 @@ package discord
 import (
 	"context"
 	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"io"
 	"log/slog"
@@ -15,18 +16,48 @@ import (
 )
 
 const (
-	// voiceConnectTimeout is the maximum time to wait for voice connection readiness.
-	voiceConnectTimeout = 10 * time.Second
-	// voiceConnectPollInterval is the polling interval while waiting for connection.
-	voiceConnectPollInterval = 100 * time.Millisecond
+	// defaultConnectTimeout is the default maximum time to wait for voice
+	// connection readiness.
+	defaultConnectTimeout = 10 * time.Second
+	// defaultConnectPollInterval is the default polling interval while
+	// waiting for a connection to become ready.
+	defaultConnectPollInterval = 20 * time.Millisecond
 	// frameDuration is the duration of one Discord audio frame (20ms).
 	frameDuration = 20 * time.Millisecond
 	// maxOpusDataBytes is the maximum size of an encoded Opus frame.
 	maxOpusDataBytes = 4000
-	// maxConnectRetries is the maximum number of voice connection attempts.
-	maxConnectRetries = 3
-	// connectRetryDelay is the delay between connection retry attempts.
-	connectRetryDelay = 1 * time.Second
+	// defaultConnectRetries is the default number of voice connection
+	// retries after a failed first attempt.
+	defaultConnectRetries = 2
+	// defaultConnectRetryDelay is the default delay between connection attempts.
+	defaultConnectRetryDelay = 1 * time.Second
+	// minOpusBitrate and maxOpusBitrate bound the Opus target bitrate (bits/s).
+	minOpusBitrate = 6000
+	maxOpusBitrate = 510000
+	// keepaliveFrames is how many silence frames each keepalive sends.
+	keepaliveFrames = 5
+	// maxSpeakingAttempts bounds attempts to set the speaking state, which the
+	// gateway rate limits.
+	maxSpeakingAttempts = 3
+	// speakingRetryDelay is the delay before the first speaking retry; it
+	// doubles after each further attempt.
+	speakingRetryDelay = 50 * time.Millisecond
+	// maxOpusFailures is how many consecutive frames may fail to encode
+	// before a send is aborted.
+	maxOpusFailures = 5
+	// opusSampleBytes is how much of a failing frame is logged.
+	opusSampleBytes = 16
+)
+
+// silenceFrame is an Opus frame of silence, as Discord recommends sending
+// around pauses in transmission.
+var silenceFrame = []byte{0xF8, 0xFF, 0xFE}
+
+// Opus application names accepted by OpusConfig.
+const (
+	OpusApplicationVoip     = "voip"
+	OpusApplicationAudio    = "audio"
+	OpusApplicationLowDelay = "lowdelay"
 )
 
 var (
@@ -38,39 +69,138 @@ var (
 	ErrConnectionFailed = errors.New("failed to connect to voice channel")
 	// ErrSpeakingFailed is returned when setting the speaking state fails.
 	ErrSpeakingFailed = errors.New("failed to set speaking state")
+	// ErrPartialSend is returned when sending fails after some audio was
+	// already played, so resending it would repeat what listeners heard.
+	ErrPartialSend = errors.New("audio send failed partway through")
+	// ErrOpusEncodeFailed is returned when too many consecutive frames fail
+	// to encode to Opus.
+	ErrOpusEncodeFailed = errors.New("opus encoding failed repeatedly")
 )
 
+// speaker sets the speaking state; *discordgo.VoiceConnection implements it.
+type speaker interface {
+	Speaking(b bool) error
+}
+
+// frameEncoder encodes PCM samples to Opus; *gopus.Encoder implements it.
+type frameEncoder interface {
+	Encode(pcm []int16, frameSize, maxDataBytes int) ([]byte, error)
+}
+
+// OpusConfig holds Opus encoder settings.
+type OpusConfig struct {
+	// Application is the Opus application mode: voip, audio, or lowdelay.
+	// Empty or unknown values fall back to voip.
+	Application string
+	// Bitrate is the target bitrate in bits per second.
+	// Zero or out-of-range values keep the encoder's default.
+	Bitrate int
+}
+
+// ConnectConfig holds voice connection settings.
+type ConnectConfig struct {
+	// Timeout bounds the wait for a joined channel to become ready.
+	// Zero uses the default of 10s.
+	Timeout time.Duration
+	// Retries is how many more attempts follow a failed first one.
+	// Zero means a single attempt.
+	Retries int
+	// RetryDelay is the pause between attempts.
+	RetryDelay time.Duration
+	// PollInterval is how often readiness is checked while waiting.
+	// Zero uses the default of 20ms.
+	PollInterval time.Duration
+}
+
+// opusApplication maps an application name to the gopus constant.
+func opusApplication(name string) (gopus.Application, bool) {
+	switch name {
+	case OpusApplicationVoip:
+		return gopus.Voip, true
+	case OpusApplicationAudio:
+		return gopus.Audio, true
+	case OpusApplicationLowDelay:
+		return gopus.RestrictedLowDelay, true
+	default:
+		return gopus.Voip, false
+	}
+}
+
 // VoiceManager manages Discord voice connections.
 type VoiceManager struct {
-	mu              sync.Mutex
-	session         *discordgo.Session
-	voiceConnection *discordgo.VoiceConnection
-	guildID         string
-	channelID       string
-	logger          *slog.Logger
-	connected       bool
-	opusEncoder     *gopus.Encoder
+	mu               sync.Mutex
+	session          *discordgo.Session
+	voiceConnection  *discordgo.VoiceConnection
+	guildID          string
+	channelID        string // channel the next connection joins
+	defaultChannelID string
+	jobChannelID     string // channel the current job asked for, overriding channelID
+	followUserID     string // user whose voice channel is followed, if any
+	logger           *slog.Logger
+	connected        bool
+	opusEncoder      frameEncoder
+	trimSilence      bool
+	maxAudio         time.Duration
+	coalesce         bool // leave speaking on between sends until StopSpeaking
+	speaking         bool // speaking state set on the current connection
+	speakingHold     time.Duration
+	holdTimer        *time.Timer // pending clear of the speaking state, if any
+	holdGen          uint64      // bumped to invalidate a pending clear
+	connect          ConnectConfig
+
+	// sendMu is held while frames go to the connection, so keepalive
+	// silence never interleaves with audio.
+	sendMu        sync.Mutex
+	keepalive     time.Duration
+	keepaliveStop chan struct{}
+	keepaliveDone chan struct{}
 }
 
 // NewVoiceManager creates a new voice manager.
-func NewVoiceManager(token, guildID, channelID string, logger *slog.Logger) (*VoiceManager, error) {
+func NewVoiceManager(token, guildID, channelID string, opusCfg OpusConfig, connectCfg ConnectConfig, logger *slog.Logger) (*VoiceManager, error) {
 	session, err := discordgo.New("Bot " + token)
 	if err != nil {
 		return nil, err
 	}
 
-	// Create Opus encoder (48kHz, stereo, voip application)
-	encoder, err := gopus.NewEncoder(audio.DiscordSampleRate, audio.DiscordChannels, gopus.Voip)
+	application, ok := opusApplication(opusCfg.Application)
+	if !ok && opusCfg.Application != "" {
+		logger.Warn("unknown opus application, using voip", "application", opusCfg.Application)
+	}
+
+	// Create Opus encoder (48kHz, stereo)
+	encoder, err := gopus.NewEncoder(audio.DiscordSampleRate, audio.DiscordChannels, application)
 	if err != nil {
 		return nil, err
 	}
 
+	if opusCfg.Bitrate != 0 {
+		if opusCfg.Bitrate >= minOpusBitrate && opusCfg.Bitrate <= maxOpusBitrate {
+			encoder.SetBitrate(opusCfg.Bitrate)
+		} else {
+			logger.Warn("opus bitrate out of range, using encoder default",
+				"bitrate", opusCfg.Bitrate,
+				"min", minOpusBitrate,
+				"max", maxOpusBitrate,
+			)
+		}
+	}
+
+	if connectCfg.Timeout <= 0 {
+		connectCfg.Timeout = defaultConnectTimeout
+	}
+	if connectCfg.PollInterval <= 0 {
+		connectCfg.PollInterval = defaultConnectPollInterval
+	}
+
 	return &VoiceManager{
-		session:     session,
-		guildID:     guildID,
-		channelID:   channelID,
-		logger:      logger,
-		opusEncoder: encoder,
+		session:          session,
+		guildID:          guildID,
+		channelID:        channelID,
+		defaultChannelID: channelID,
+		logger:           logger,
+		opusEncoder:      encoder,
+		connect:          connectCfg,
 	}, nil
 }
 
@@ -84,11 +214,14 @@ func (vm *VoiceManager) Close() error {
 	vm.mu.Lock()
 	defer vm.mu.Unlock()
 
+	vm.stopKeepaliveLocked()
 	if vm.voiceConnection != nil {
 		vm.voiceConnection.Disconnect()
 		vm.voiceConnection = nil
 	}
 	vm.connected = false
+	vm.speaking = false
+	vm.stopHoldLocked()
 
 	return vm.session.Close()
 }
@@ -102,16 +235,24 @@ func (vm *VoiceManager) Connect(ctx context.Context) error {
 		return nil // Already connected
 	}
 
+	return vm.connectWithRetries(ctx, vm.connectOnce)
+}
+
+// connectWithRetries runs attempt until it succeeds, ctx is done, or the
+// configured retries are used up. vm.mu must be held.
+func (vm *VoiceManager) connectWithRetries(ctx context.Context, attempt func(context.Context) error) error {
+	maxAttempts := 1 + max(vm.connect.Retries, 0)
+
 	var lastErr error
-	for attempt := 1; attempt <= maxConnectRetries; attempt++ {
+	for n := 1; n <= maxAttempts; n++ {
 		vm.logger.Info("connecting to voice channel",
 			"guild_id", vm.guildID,
-			"channel_id", vm.channelID,
-			"attempt", attempt,
-			"max_attempts", maxConnectRetries,
+			"channel_id", vm.targetChannelLocked(),
+			"attempt", n,
+			"max_attempts", maxAttempts,
 		)
 
-		err := vm.connectOnce(ctx)
+		err := attempt(ctx)
 		if err == nil {
 			return nil
 		}
@@ -123,22 +264,22 @@ func (vm *VoiceManager) Connect(ctx context.Context) error {
 			return ctx.Err()
 		}
 
-		if attempt < maxConnectRetries {
+		if n < maxAttempts {
 			vm.logger.Warn("voice connection failed, retrying",
-				"attempt", attempt,
+				"attempt", n,
 				"error", err,
 			)
 			// Wait before retrying, but respect context cancellation
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
-			case <-time.After(connectRetryDelay):
+			case <-time.After(vm.connect.RetryDelay):
 			}
 		}
 	}
 
 	vm.logger.Error("voice connection failed after all retries",
-		"attempts", maxConnectRetries,
+		"attempts", maxAttempts,
 		"error", lastErr,
 	)
 	return errors.Join(ErrConnectionFailed, lastErr)
@@ -147,13 +288,13 @@ func (vm *VoiceManager) Connect(ctx context.Context) error {
 // connectOnce performs a single voice connection attempt with context-aware waiting.
 func (vm *VoiceManager) connectOnce(ctx context.Context) error {
 	// Join voice channel (mute=false, deaf=true - we don't need to hear)
-	vc, err := vm.session.ChannelVoiceJoin(vm.guildID, vm.channelID, false, true)
+	vc, err := vm.session.ChannelVoiceJoin(vm.guildID, vm.targetChannelLocked(), false, true)
 	if err != nil {
 		return err
 	}
 
 	// Wait for voice connection to be ready using context-aware polling
-	if err := vm.waitForReady(ctx, vc); err != nil {
+	if err := vm.waitForReady(ctx, func() bool { return voiceReady(vc) }); err != nil {
 		vc.Disconnect()
 		return err
 	}
@@ -162,17 +303,51 @@ func (vm *VoiceManager) connectOnce(ctx context.Context) error {
 	vm.connected = true
 	vm.logger.Info("connected to voice channel")
 
+	if vm.keepalive > 0 {
+		vm.startKeepaliveLocked(vc.OpusSend, vm.keepalive)
+	}
+
 	return nil
 }
 
-// waitForReady waits for the voice connection to become ready with context support.
+// WaitReady blocks until the current voice connection is ready to send
+// audio, returning as soon as it is. It gives up when ctx is done or the
+// connect timeout passes.
+func (vm *VoiceManager) WaitReady(ctx context.Context) error {
+	vm.mu.Lock()
+	vc := vm.voiceConnection
+	vm.mu.Unlock()
+
+	if vc == nil {
+		return ErrNotConnected
+	}
+	return vm.waitForReady(ctx, func() bool { return voiceReady(vc) })
+}
+
+// voiceReady reports whether vc can send audio. discordgo has no ready
+// event for voice connections, so this is polled.
+func voiceReady(vc *discordgo.VoiceConnection) bool {
+	vc.RLock()
+	defer vc.RUnlock()
+	return vc.Ready
+}
+
+// waitForReady waits for ready to report true with context support.
 // Uses a ticker-based approach with a deadline, avoiding tight unbounded loops.
-func (vm *VoiceManager) waitForReady(ctx context.Context, vc *discordgo.VoiceConnection) error {
+func (vm *VoiceManager) waitForReady(ctx context.Context, ready func() bool) error {
+	if ready() {
+		return nil
+	}
+
 	// Create a deadline context for the readiness wait
-	waitCtx, cancel := context.WithTimeout(ctx, voiceConnectTimeout)
+	waitCtx, cancel := context.WithTimeout(ctx, vm.connect.Timeout)
 	defer cancel()
 
-	ticker := time.NewTicker(voiceConnectPollInterval)
+	interval := vm.connect.PollInterval
+	if interval <= 0 {
+		interval = defaultConnectPollInterval
+	}
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
@@ -184,11 +359,11 @@ func (vm *VoiceManager) waitForReady(ctx context.Context, vc *discordgo.VoiceCon
 			}
 			// Timeout waiting for ready
 			vm.logger.Error("timeout waiting for voice connection ready",
-				"timeout", voiceConnectTimeout,
+				"timeout", vm.connect.Timeout,
 			)
 			return ErrConnectionFailed
 		case <-ticker.C:
-			if vc.Ready {
+			if ready() {
 				return nil
 			}
 		}
@@ -205,9 +380,12 @@ func (vm *VoiceManager) Disconnect() error {
 	}
 
 	vm.logger.Info("disconnecting from voice channel")
+	vm.stopKeepaliveLocked()
 	err := vm.voiceConnection.Disconnect()
 	vm.voiceConnection = nil
 	vm.connected = false
+	vm.speaking = false
+	vm.stopHoldLocked()
 
 	return err
 }
@@ -219,75 +397,392 @@ func (vm *VoiceManager) IsConnected() bool {
 	return vm.connected && vm.voiceConnection != nil
 }
 
+// SetTrimSilence enables trimming leading and trailing silence from buffered
+// audio before it is sent, so nothing is transmitted for silent padding.
+func (vm *VoiceManager) SetTrimSilence(enabled bool) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.trimSilence = enabled
+}
+
+// SetMaxAudioDuration caps how long a single send may play.
+// Audio beyond the cap is dropped. Zero disables the cap.
+func (vm *VoiceManager) SetMaxAudioDuration(d time.Duration) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.maxAudio = d
+}
+
+// SetKeepalive makes the bot send a few frames of silence every interval
+// while connected and idle, so the first frames after a long pause are not
+// clipped. Zero disables it. It applies from the next connection.
+func (vm *VoiceManager) SetKeepalive(interval time.Duration) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.keepalive = interval
+}
+
+// startKeepaliveLocked starts the keepalive loop sending to out. vm.mu must be held.
+func (vm *VoiceManager) startKeepaliveLocked(out chan<- []byte, interval time.Duration) {
+	vm.stopKeepaliveLocked()
+	vm.keepaliveStop = make(chan struct{})
+	vm.keepaliveDone = make(chan struct{})
+	go vm.keepaliveLoop(out, interval, vm.keepaliveStop, vm.keepaliveDone)
+}
+
+// stopKeepaliveLocked stops the keepalive loop, if running, and waits for it
+// to exit. vm.mu must be held; the loop never takes it.
+func (vm *VoiceManager) stopKeepaliveLocked() {
+	if vm.keepaliveStop == nil {
+		return
+	}
+	close(vm.keepaliveStop)
+	<-vm.keepaliveDone
+	vm.keepaliveStop = nil
+	vm.keepaliveDone = nil
+}
+
+// keepaliveLoop sends silence frames to out every interval until stop is
+// closed. Ticks that land while audio is being sent are skipped.
+func (vm *VoiceManager) keepaliveLoop(out chan<- []byte, interval time.Duration, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		if !vm.sendMu.TryLock() {
+			continue // Audio is playing, which keeps the connection busy anyway
+		}
+		vm.logger.Debug("sending voice keepalive")
+		for i := 0; i < keepaliveFrames; i++ {
+			select {
+			case <-stop:
+				vm.sendMu.Unlock()
+				return
+			case out <- silenceFrame:
+			}
+		}
+		vm.sendMu.Unlock()
+	}
+}
+
+// SetCoalesceSpeaking leaves the speaking state on after each send, so a run
+// of back-to-back messages doesn't toggle it (and hit gateway rate limits)
+// between every one. The caller ends the run with StopSpeaking.
+func (vm *VoiceManager) SetCoalesceSpeaking(enabled bool) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.coalesce = enabled
+}
+
+// SetSpeakingHold keeps the speaking state on for hold after a send ends,
+// or a coalesced run is stopped, so a message following within it doesn't
+// make the speaking indicator flicker off and on. Zero clears it at once.
+func (vm *VoiceManager) SetSpeakingHold(hold time.Duration) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.speakingHold = hold
+}
+
+// StopSpeaking clears the speaking state if it is set, ending a run of
+// coalesced sends.
+func (vm *VoiceManager) StopSpeaking() {
+	vm.mu.Lock()
+	vc := vm.voiceConnection
+	vm.mu.Unlock()
+
+	if vc != nil {
+		vm.releaseSpeaking(vc)
+	}
+}
+
+// beginSpeaking sets the speaking state on sp unless it is already set,
+// cancelling any pending hold.
+func (vm *VoiceManager) beginSpeaking(ctx context.Context, sp speaker) error {
+	vm.mu.Lock()
+	vm.stopHoldLocked()
+	already := vm.speaking
+	vm.mu.Unlock()
+
+	if already {
+		return nil
+	}
+	if err := vm.setSpeaking(ctx, sp, true); err != nil {
+		vm.logger.Error("failed to set speaking state",
+			"error", err,
+			"action", "start_speaking",
+		)
+		return errors.Join(ErrSpeakingFailed, err)
+	}
+
+	vm.mu.Lock()
+	vm.speaking = true
+	vm.mu.Unlock()
+	return nil
+}
+
+// endSpeaking releases the speaking state after a send, unless sends are
+// being coalesced.
+func (vm *VoiceManager) endSpeaking(sp speaker) {
+	vm.mu.Lock()
+	coalesce := vm.coalesce
+	vm.mu.Unlock()
+
+	if !coalesce {
+		vm.releaseSpeaking(sp)
+	}
+}
+
+// releaseSpeaking clears the speaking state on sp once the speaking hold
+// has passed without another send starting, or at once if there is no hold.
+func (vm *VoiceManager) releaseSpeaking(sp speaker) {
+	vm.mu.Lock()
+	if vm.speakingHold > 0 && vm.speaking {
+		vm.stopHoldLocked()
+		gen := vm.holdGen
+		vm.holdTimer = time.AfterFunc(vm.speakingHold, func() { vm.endHold(sp, gen) })
+		vm.mu.Unlock()
+		return
+	}
+	vm.mu.Unlock()
+
+	vm.clearSpeaking(sp)
+}
+
+// endHold clears the speaking state when the hold numbered gen expires,
+// unless a send has started since.
+func (vm *VoiceManager) endHold(sp speaker, gen uint64) {
+	// Sends begin speaking under sendMu, so one can't start between the
+	// check and the clear
+	vm.sendMu.Lock()
+	defer vm.sendMu.Unlock()
+
+	vm.mu.Lock()
+	current := gen == vm.holdGen
+	vm.mu.Unlock()
+
+	if current {
+		vm.clearSpeaking(sp)
+	}
+}
+
+// stopHoldLocked cancels a pending hold. The caller must hold vm.mu.
+func (vm *VoiceManager) stopHoldLocked() {
+	vm.holdGen++
+	if vm.holdTimer != nil {
+		vm.holdTimer.Stop()
+		vm.holdTimer = nil
+	}
+}
+
+// clearSpeaking clears the speaking state on sp if it is set. Failures are
+// logged but not returned, since the audio has already been sent.
+func (vm *VoiceManager) clearSpeaking(sp speaker) {
+	vm.mu.Lock()
+	if !vm.speaking {
+		vm.mu.Unlock()
+		return
+	}
+	vm.speaking = false
+	vm.mu.Unlock()
+
+	if err := vm.setSpeaking(context.Background(), sp, false); err != nil {
+		vm.logger.Warn("failed to clear speaking state",
+			"error", err,
+			"action", "stop_speaking",
+		)
+	}
+}
+
+// setSpeaking sets the speaking state, retrying with exponential backoff
+// since the gateway rate limits rapid changes.
+func (vm *VoiceManager) setSpeaking(ctx context.Context, sp speaker, on bool) error {
+	delay := speakingRetryDelay
+	var err error
+	for attempt := 1; attempt <= maxSpeakingAttempts; attempt++ {
+		if err = sp.Speaking(on); err == nil {
+			return nil
+		}
+		if attempt == maxSpeakingAttempts {
+			break
+		}
+		vm.logger.Debug("setting speaking state failed, retrying",
+			"speaking", on,
+			"attempt", attempt,
+			"error", err,
+		)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	return err
+}
+
 // SendAudio sends PCM audio data to the voice channel.
 // The PCM data must be 48kHz, stereo, 16-bit signed little-endian.
 func (vm *VoiceManager) SendAudio(ctx context.Context, pcmData []byte) error {
+	return vm.SendAudioWithLimit(ctx, pcmData, 0)
+}
+
+// SendAudioWithLimit is like SendAudio but stops after limit, if it is
+// shorter than the global cap. Zero means only the global cap applies.
+func (vm *VoiceManager) SendAudioWithLimit(ctx context.Context, pcmData []byte, limit time.Duration) error {
+	vm.mu.Lock()
+	trim := vm.trimSilence
+	connected := vm.connected && vm.voiceConnection != nil
+	vm.mu.Unlock()
+
+	if trim && connected {
+		trimmed := audio.TrimSilence(pcmData, audio.DefaultSilenceThreshold)
+		vm.logger.Debug("trimmed silence",
+			"original_bytes", len(pcmData),
+			"trimmed_bytes", len(trimmed),
+		)
+		if len(trimmed) == 0 {
+			// All silence: nothing to transmit
+			return nil
+		}
+		pcmData = trimmed
+	}
+
+	return vm.sendFrames(ctx, audio.NewPaddedPCMFrameReader(pcmData), limit)
+}
+
+// SendAudioStream sends PCM audio to the voice channel as it is read from r.
+// The PCM stream must be 48kHz, stereo, 16-bit signed little-endian.
+func (vm *VoiceManager) SendAudioStream(ctx context.Context, r io.Reader) error {
+	return vm.SendAudioStreamWithLimit(ctx, r, 0)
+}
+
+// SendAudioStreamWithLimit is like SendAudioStream but stops after limit,
+// if it is shorter than the global cap. Zero means only the global cap applies.
+func (vm *VoiceManager) SendAudioStreamWithLimit(ctx context.Context, r io.Reader, limit time.Duration) error {
+	return vm.sendFrames(ctx, audio.NewPCMStreamFrameReader(r), limit)
+}
+
+// maxFrames returns the frame budget for the tighter of limit and the global
+// cap, or 0 if neither is set.
+func maxFrames(limit, global time.Duration) int {
+	if limit <= 0 || (global > 0 && global < limit) {
+		limit = global
+	}
+	if limit <= 0 {
+		return 0
+	}
+	// Round up so a cap that isn't a multiple of 20ms still allows the partial frame
+	return int((limit + frameDuration - 1) / frameDuration)
+}
+
+// sendFrames sets the speaking state and sends frames from the source.
+func (vm *VoiceManager) sendFrames(ctx context.Context, frameReader audio.FrameSource, limit time.Duration) error {
 	vm.mu.Lock()
 	vc := vm.voiceConnection
 	connected := vm.connected
+	budget := maxFrames(limit, vm.maxAudio)
+	// A coalesced or held run still speaking sent its last frame at least a
+	// tick ago
+	continuing := vm.speaking
 	vm.mu.Unlock()
 
 	if !connected || vc == nil {
 		return ErrNotConnected
 	}
 
-	frameReader := audio.NewPCMFrameReader(pcmData)
+	// Hold off the keepalive for the whole send
+	vm.sendMu.Lock()
+	defer vm.sendMu.Unlock()
 
 	// Start speaking - this is required for audio to be heard
-	if err := vc.Speaking(true); err != nil {
-		vm.logger.Error("failed to set speaking state",
-			"error", err,
-			"action", "start_speaking",
-		)
-		return errors.Join(ErrSpeakingFailed, err)
+	if err := vm.beginSpeaking(ctx, vc); err != nil {
+		return err
 	}
+	defer vm.endSpeaking(vc)
 
-	defer func() {
-		// Stop speaking - log but don't fail the overall operation
-		if err := vc.Speaking(false); err != nil {
-			vm.logger.Warn("failed to clear speaking state",
-				"error", err,
-				"action", "stop_speaking",
-			)
-		}
-	}()
+	sent, err := vm.streamFrames(ctx, frameReader, vc.OpusSend, budget, continuing)
+	if err != nil && sent > 0 && ctx.Err() == nil {
+		return errors.Join(ErrPartialSend, err)
+	}
+	return err
+}
 
+// streamFrames encodes frames from the source and sends them to out with 20ms
+// pacing. If budget is positive, sending stops once that many frames are sent.
+// With immediate set the first frame doesn't wait for a tick, so a send that
+// continues the previous one keeps its pacing.
+func (vm *VoiceManager) streamFrames(ctx context.Context, frameReader audio.FrameSource, out chan<- []byte, budget int, immediate bool) (int, error) {
 	// Send frames with timing control
 	ticker := time.NewTicker(frameDuration)
 	defer ticker.Stop()
+	next := ticker.C
+	if immediate {
+		now := make(chan time.Time, 1)
+		now <- time.Now()
+		next = now
+	}
 
 	framesSent := 0
+	encodeFailures := 0
 	for {
+		if budget > 0 && framesSent >= budget {
+			vm.logger.Warn("audio truncated at maximum duration",
+				"frames_sent", framesSent,
+				"max_duration", time.Duration(budget)*frameDuration,
+			)
+			return framesSent, nil
+		}
+
 		select {
 		case <-ctx.Done():
 			vm.logger.Debug("audio sending interrupted",
 				"frames_sent", framesSent,
 				"reason", ctx.Err(),
 			)
-			return ctx.Err()
-		case <-ticker.C:
+			return framesSent, ctx.Err()
+		case <-next:
+			next = ticker.C
 			frame, err := frameReader.ReadFrame()
 			if err == io.EOF {
 				vm.logger.Debug("audio sending complete", "frames_sent", framesSent)
-				return nil // Done sending
+				return framesSent, nil // Done sending
 			}
 			if err != nil {
 				vm.logger.Error("frame read failed",
 					"error", err,
 					"frames_sent", framesSent,
 				)
-				return err
+				return framesSent, err
 			}
 
 			// Encode PCM frame to Opus
 			opusData, err := vm.encodeOpus(frame)
 			if err != nil {
+				encodeFailures++
+				if encodeFailures >= maxOpusFailures {
+					vm.logger.Error("aborting send after repeated opus encoding failures",
+						"error", err,
+						"frame", framesSent,
+						"failures", encodeFailures,
+						"sample", hex.EncodeToString(frame[:min(len(frame), opusSampleBytes)]),
+					)
+					return framesSent, errors.Join(ErrOpusEncodeFailed, err)
+				}
 				vm.logger.Error("opus encoding failed",
 					"error", err,
 					"frame", framesSent,
 				)
 				continue
 			}
+			encodeFailures = 0
 
 			// Send the frame to Discord
 			select {
@@ -296,8 +791,8 @@ func (vm *VoiceManager) SendAudio(ctx context.Context, pcmData []byte) error {
 					"frames_sent", framesSent,
 					"reason", ctx.Err(),
 				)
-				return ctx.Err()
-			case vc.OpusSend <- opusData:
+				return framesSent, ctx.Err()
+			case out <- opusData:
 				framesSent++
 			}
 		}
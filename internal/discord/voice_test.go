@@ -1,11 +1,27 @@
 package discord
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/dgnsrekt/discorgeous-go/internal/audio"
+	"layeh.com/gopus"
 )
 
+// testLogger returns a no-op logger for tests
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
 func TestErrNotConnected(t *testing.T) {
 	if ErrNotConnected.Error() != "not connected to voice channel" {
 		t.Errorf("ErrNotConnected = %q", ErrNotConnected.Error())
@@ -53,6 +69,21 @@ func TestVoiceManager_SendAudio_WhenNotConnected(t *testing.T) {
 	}
 }
 
+func TestVoiceManager_SendAudio_AllSilenceTrimmed(t *testing.T) {
+	vm := &VoiceManager{
+		connected:       true,
+		voiceConnection: &discordgo.VoiceConnection{},
+		trimSilence:     true,
+		logger:          testLogger(),
+	}
+
+	// All-silence input sends nothing (no speaking state change) and succeeds
+	err := vm.SendAudio(context.Background(), make([]byte, 3840*5))
+	if err != nil {
+		t.Errorf("SendAudio() error = %v, want nil", err)
+	}
+}
+
 func TestVoiceManager_Disconnect_WhenNotConnected(t *testing.T) {
 	vm := &VoiceManager{
 		connected:       false,
@@ -73,10 +104,10 @@ func TestConstants(t *testing.T) {
 		got      time.Duration
 		wantSecs float64
 	}{
-		{"voiceConnectTimeout", voiceConnectTimeout, 10},
-		{"voiceConnectPollInterval", voiceConnectPollInterval, 0.1},
+		{"defaultConnectTimeout", defaultConnectTimeout, 10},
+		{"defaultConnectPollInterval", defaultConnectPollInterval, 0.02},
 		{"frameDuration", frameDuration, 0.02},
-		{"connectRetryDelay", connectRetryDelay, 1},
+		{"defaultConnectRetryDelay", defaultConnectRetryDelay, 1},
 	}
 
 	for _, tt := range tests {
@@ -87,11 +118,440 @@ func TestConstants(t *testing.T) {
 		})
 	}
 
-	if maxConnectRetries != 3 {
-		t.Errorf("maxConnectRetries = %d, want 3", maxConnectRetries)
+	if defaultConnectRetries != 2 {
+		t.Errorf("defaultConnectRetries = %d, want 2", defaultConnectRetries)
 	}
 
 	if maxOpusDataBytes != 4000 {
 		t.Errorf("maxOpusDataBytes = %d, want 4000", maxOpusDataBytes)
 	}
 }
+
+func TestOpusApplication(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   gopus.Application
+		wantOK bool
+	}{
+		{OpusApplicationVoip, gopus.Voip, true},
+		{OpusApplicationAudio, gopus.Audio, true},
+		{OpusApplicationLowDelay, gopus.RestrictedLowDelay, true},
+		{"", gopus.Voip, false},
+		{"music", gopus.Voip, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := opusApplication(tt.name)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("opusApplication(%q) = (%v, %v), want (%v, %v)", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestNewVoiceManager_OpusBitrate(t *testing.T) {
+	vm, err := NewVoiceManager("token", "guild", "channel", OpusConfig{
+		Application: OpusApplicationAudio,
+		Bitrate:     96000,
+	}, ConnectConfig{}, testLogger())
+	if err != nil {
+		t.Fatalf("NewVoiceManager() error = %v", err)
+	}
+
+	encoder, ok := vm.opusEncoder.(*gopus.Encoder)
+	if !ok {
+		t.Fatalf("encoder type = %T, want *gopus.Encoder", vm.opusEncoder)
+	}
+	if got := encoder.Bitrate(); got != 96000 {
+		t.Errorf("encoder bitrate = %d, want 96000", got)
+	}
+	if got := encoder.Application(); got != gopus.Audio {
+		t.Errorf("encoder application = %v, want audio", got)
+	}
+	if vm.connect.Timeout != defaultConnectTimeout {
+		t.Errorf("connect timeout = %v, want the default %v", vm.connect.Timeout, defaultConnectTimeout)
+	}
+}
+
+func TestNewVoiceManager_OpusBitrateFallback(t *testing.T) {
+	reference, err := gopus.NewEncoder(48000, 2, gopus.Voip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaultBitrate := reference.Bitrate()
+
+	for _, bitrate := range []int{0, -1, minOpusBitrate - 1, maxOpusBitrate + 1} {
+		vm, err := NewVoiceManager("token", "guild", "channel", OpusConfig{Bitrate: bitrate}, ConnectConfig{}, testLogger())
+		if err != nil {
+			t.Fatalf("NewVoiceManager(bitrate %d) error = %v", bitrate, err)
+		}
+		if got := vm.opusEncoder.(*gopus.Encoder).Bitrate(); got != defaultBitrate {
+			t.Errorf("bitrate %d: encoder bitrate = %d, want the default %d", bitrate, got, defaultBitrate)
+		}
+	}
+}
+
+func TestVoiceManager_WaitForReady(t *testing.T) {
+	vm := &VoiceManager{logger: testLogger(), connect: ConnectConfig{
+		Timeout:      time.Second,
+		PollInterval: 5 * time.Millisecond,
+	}}
+
+	var ready atomic.Bool
+	time.AfterFunc(30*time.Millisecond, func() { ready.Store(true) })
+
+	start := time.Now()
+	if err := vm.waitForReady(context.Background(), ready.Load); err != nil {
+		t.Fatalf("waitForReady() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Errorf("waitForReady() took %v after the connection became ready", elapsed)
+	}
+
+	// Already ready returns without waiting for a tick
+	vm.connect.PollInterval = time.Hour
+	if err := vm.waitForReady(context.Background(), ready.Load); err != nil {
+		t.Errorf("waitForReady() when ready error = %v", err)
+	}
+}
+
+func TestVoiceManager_WaitForReady_Timeout(t *testing.T) {
+	vm := &VoiceManager{logger: testLogger(), connect: ConnectConfig{
+		Timeout:      20 * time.Millisecond,
+		PollInterval: 5 * time.Millisecond,
+	}}
+
+	err := vm.waitForReady(context.Background(), func() bool { return false })
+	if !errors.Is(err, ErrConnectionFailed) {
+		t.Errorf("waitForReady() error = %v, want ErrConnectionFailed", err)
+	}
+}
+
+func TestVoiceManager_WaitReady_NotConnected(t *testing.T) {
+	vm := &VoiceManager{logger: testLogger()}
+	if err := vm.WaitReady(context.Background()); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("WaitReady() error = %v, want ErrNotConnected", err)
+	}
+}
+
+func TestVoiceManager_ConnectWithRetries(t *testing.T) {
+	tests := []struct {
+		name    string
+		retries int
+		want    int
+	}{
+		{"zero retries is a single attempt", 0, 1},
+		{"default retries", defaultConnectRetries, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := &VoiceManager{logger: testLogger(), connect: ConnectConfig{Retries: tt.retries}}
+
+			attempts := 0
+			err := vm.connectWithRetries(context.Background(), func(context.Context) error {
+				attempts++
+				return errors.New("join failed")
+			})
+			if !errors.Is(err, ErrConnectionFailed) {
+				t.Errorf("connectWithRetries() error = %v, want ErrConnectionFailed", err)
+			}
+			if attempts != tt.want {
+				t.Errorf("attempts = %d, want %d", attempts, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaxFrames(t *testing.T) {
+	tests := []struct {
+		name   string
+		limit  time.Duration
+		global time.Duration
+		want   int
+	}{
+		{"no caps", 0, 0, 0},
+		{"global only", 0, time.Second, 50},
+		{"limit only", 100 * time.Millisecond, 0, 5},
+		{"limit tighter than global", 100 * time.Millisecond, time.Second, 5},
+		{"global tighter than limit", 10 * time.Second, time.Second, 50},
+		{"rounds up partial frame", 30 * time.Millisecond, 0, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := maxFrames(tt.limit, tt.global); got != tt.want {
+				t.Errorf("maxFrames(%v, %v) = %d, want %d", tt.limit, tt.global, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVoiceManager_StreamFrames_StopsAtCap(t *testing.T) {
+	encoder, err := gopus.NewEncoder(48000, 2, gopus.Voip)
+	if err != nil {
+		t.Fatalf("NewEncoder() error = %v", err)
+	}
+	vm := &VoiceManager{logger: testLogger(), opusEncoder: encoder}
+
+	// 1 second of audio (50 frames), capped at 100ms (5 frames)
+	pcm := make([]byte, 3840*50)
+	out := make(chan []byte, 50)
+	budget := maxFrames(100*time.Millisecond, 0)
+
+	start := time.Now()
+	sent, err := vm.streamFrames(context.Background(), audio.NewPCMFrameReader(pcm), out, budget, false)
+	if err != nil {
+		t.Fatalf("streamFrames() error = %v", err)
+	}
+
+	if sent != 5 {
+		t.Errorf("frames sent = %d, want 5", sent)
+	}
+	if len(out) != 5 {
+		t.Errorf("frames on channel = %d, want 5", len(out))
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("send took %v, expected to stop early", elapsed)
+	}
+}
+
+func TestVoiceManager_StreamFrames_SendsPartialStreamFrame(t *testing.T) {
+	encoder, err := gopus.NewEncoder(48000, 2, gopus.Voip)
+	if err != nil {
+		t.Fatalf("NewEncoder() error = %v", err)
+	}
+	vm := &VoiceManager{logger: testLogger(), opusEncoder: encoder}
+
+	// 2.5 frames of streamed audio: the tail is padded, not dropped
+	pcm := make([]byte, audio.DiscordFrameBytes*2+audio.DiscordFrameBytes/2)
+	out := make(chan []byte, 5)
+
+	sent, err := vm.streamFrames(context.Background(), audio.NewPCMStreamFrameReader(bytes.NewReader(pcm)), out, 0, false)
+	if err != nil {
+		t.Fatalf("streamFrames() error = %v", err)
+	}
+	if sent != 3 {
+		t.Errorf("frames sent = %d, want 3", sent)
+	}
+}
+
+func TestVoiceManager_StreamFrames_Immediate(t *testing.T) {
+	encoder, err := gopus.NewEncoder(48000, 2, gopus.Voip)
+	if err != nil {
+		t.Fatalf("NewEncoder() error = %v", err)
+	}
+	vm := &VoiceManager{logger: testLogger(), opusEncoder: encoder}
+
+	// A continuing send puts its first frame out without waiting a tick
+	pcm := make([]byte, audio.DiscordFrameBytes*2)
+	out := make(chan []byte, 2)
+	start := time.Now()
+	if _, err := vm.streamFrames(context.Background(), audio.NewPCMFrameReader(pcm), out, 1, true); err != nil {
+		t.Fatalf("streamFrames() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= frameDuration {
+		t.Errorf("immediate first frame took %v, want under %v", elapsed, frameDuration)
+	}
+
+	// A fresh send waits for the first tick
+	start = time.Now()
+	if _, err := vm.streamFrames(context.Background(), audio.NewPCMFrameReader(pcm), out, 1, false); err != nil {
+		t.Fatalf("streamFrames() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < frameDuration {
+		t.Errorf("first frame took %v, want at least %v", elapsed, frameDuration)
+	}
+}
+
+// failingEncoder is a frameEncoder that always errors.
+type failingEncoder struct {
+	calls int
+}
+
+func (e *failingEncoder) Encode(pcm []int16, frameSize, maxDataBytes int) ([]byte, error) {
+	e.calls++
+	return nil, errors.New("encoder broken")
+}
+
+func TestVoiceManager_StreamFrames_AbortsOnEncodeFailures(t *testing.T) {
+	encoder := &failingEncoder{}
+	vm := &VoiceManager{logger: testLogger(), opusEncoder: encoder}
+
+	pcm := make([]byte, audio.DiscordFrameBytes*50)
+	out := make(chan []byte, 50)
+
+	sent, err := vm.streamFrames(context.Background(), audio.NewPCMFrameReader(pcm), out, 0, false)
+	if !errors.Is(err, ErrOpusEncodeFailed) {
+		t.Fatalf("streamFrames() error = %v, want ErrOpusEncodeFailed", err)
+	}
+	if sent != 0 {
+		t.Errorf("frames sent = %d, want 0", sent)
+	}
+	if encoder.calls != maxOpusFailures {
+		t.Errorf("encode calls = %d, want %d", encoder.calls, maxOpusFailures)
+	}
+}
+
+func TestVoiceManager_KeepaliveLifecycle(t *testing.T) {
+	vm := &VoiceManager{logger: testLogger()}
+	out := make(chan []byte, 100)
+
+	vm.mu.Lock()
+	vm.startKeepaliveLocked(out, 10*time.Millisecond)
+	vm.mu.Unlock()
+
+	// Idle: silence frames arrive
+	select {
+	case frame := <-out:
+		if string(frame) != string(silenceFrame) {
+			t.Errorf("keepalive frame = %x, want %x", frame, silenceFrame)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no keepalive frame while idle")
+	}
+
+	// Sending: the keepalive stands aside
+	vm.sendMu.Lock()
+	time.Sleep(20 * time.Millisecond) // let an in-flight burst finish
+	for len(out) > 0 {
+		<-out
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := len(out); n != 0 {
+		t.Errorf("got %d keepalive frames during a send, want 0", n)
+	}
+	vm.sendMu.Unlock()
+
+	// Stopped: the loop exits and nothing more is sent
+	vm.mu.Lock()
+	vm.stopKeepaliveLocked()
+	vm.mu.Unlock()
+	for len(out) > 0 {
+		<-out
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := len(out); n != 0 {
+		t.Errorf("got %d keepalive frames after stop, want 0", n)
+	}
+}
+
+// fakeSpeaker records speaking state changes, failing the first
+// failures calls.
+type fakeSpeaker struct {
+	mu       sync.Mutex
+	failures int
+	calls    []bool
+}
+
+func (f *fakeSpeaker) Speaking(b bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, b)
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("rate limited")
+	}
+	return nil
+}
+
+func TestVoiceManager_SetSpeaking_Retries(t *testing.T) {
+	vm := &VoiceManager{logger: testLogger()}
+
+	sp := &fakeSpeaker{failures: maxSpeakingAttempts - 1}
+	if err := vm.setSpeaking(context.Background(), sp, true); err != nil {
+		t.Fatalf("setSpeaking() error = %v, want success after retries", err)
+	}
+	if len(sp.calls) != maxSpeakingAttempts {
+		t.Errorf("Speaking called %d times, want %d", len(sp.calls), maxSpeakingAttempts)
+	}
+
+	sp = &fakeSpeaker{failures: maxSpeakingAttempts}
+	if err := vm.beginSpeaking(context.Background(), sp); !errors.Is(err, ErrSpeakingFailed) {
+		t.Errorf("beginSpeaking() error = %v, want ErrSpeakingFailed", err)
+	}
+	if vm.speaking {
+		t.Error("speaking = true after failed beginSpeaking")
+	}
+}
+
+func TestVoiceManager_SetSpeaking_ContextCancelled(t *testing.T) {
+	vm := &VoiceManager{logger: testLogger()}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	sp := &fakeSpeaker{failures: maxSpeakingAttempts}
+	if err := vm.setSpeaking(ctx, sp, true); !errors.Is(err, context.Canceled) {
+		t.Errorf("setSpeaking() error = %v, want context.Canceled", err)
+	}
+	if len(sp.calls) != 1 {
+		t.Errorf("Speaking called %d times, want 1", len(sp.calls))
+	}
+}
+
+func TestVoiceManager_SpeakingToggles(t *testing.T) {
+	tests := []struct {
+		name     string
+		coalesce bool
+		want     []bool
+	}{
+		{"per send", false, []bool{true, false, true, false}},
+		{"coalesced", true, []bool{true, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := &VoiceManager{logger: testLogger(), coalesce: tt.coalesce}
+			sp := &fakeSpeaker{}
+
+			// Two back-to-back sends, then the end of the run
+			for range 2 {
+				if err := vm.beginSpeaking(context.Background(), sp); err != nil {
+					t.Fatalf("beginSpeaking() error = %v", err)
+				}
+				vm.endSpeaking(sp)
+			}
+			vm.clearSpeaking(sp)
+
+			if !slices.Equal(sp.calls, tt.want) {
+				t.Errorf("Speaking calls = %v, want %v", sp.calls, tt.want)
+			}
+			if vm.speaking {
+				t.Error("speaking = true after the run ended")
+			}
+		})
+	}
+}
+
+// recorded returns the speaking state changes so far.
+func (f *fakeSpeaker) recorded() []bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.calls)
+}
+
+func TestVoiceManager_SpeakingHold(t *testing.T) {
+	const hold = 50 * time.Millisecond
+	vm := &VoiceManager{logger: testLogger(), speakingHold: hold}
+	sp := &fakeSpeaker{}
+
+	// A send starting within the hold keeps speaking on
+	for range 2 {
+		if err := vm.beginSpeaking(context.Background(), sp); err != nil {
+			t.Fatalf("beginSpeaking() error = %v", err)
+		}
+		vm.endSpeaking(sp)
+		time.Sleep(hold / 5)
+	}
+	if got := sp.recorded(); !slices.Equal(got, []bool{true}) {
+		t.Errorf("Speaking calls during the hold = %v, want [true]", got)
+	}
+
+	// Once the hold passes with nothing new, speaking clears
+	deadline := time.Now().Add(time.Second)
+	for len(sp.recorded()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := sp.recorded(); !slices.Equal(got, []bool{true, false}) {
+		t.Errorf("Speaking calls after the hold = %v, want [true false]", got)
+	}
+}
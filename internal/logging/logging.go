@@ -7,22 +7,15 @@ import (
 
 // New creates a new structured logger with the specified level and format.
 func New(level, format string) *slog.Logger {
-	var logLevel slog.Level
-	switch level {
-	case "debug":
-		logLevel = slog.LevelDebug
-	case "info":
-		logLevel = slog.LevelInfo
-	case "warn":
-		logLevel = slog.LevelWarn
-	case "error":
-		logLevel = slog.LevelError
-	default:
-		logLevel = slog.LevelInfo
-	}
+	return NewWithLevel(ParseLevel(level), format)
+}
 
+// NewWithLevel creates a new structured logger whose level is read from
+// level on every call, so setting it changes the level of a running logger.
+// *slog.LevelVar is the usual choice.
+func NewWithLevel(level slog.Leveler, format string) *slog.Logger {
 	opts := &slog.HandlerOptions{
-		Level: logLevel,
+		Level: level,
 	}
 
 	var handler slog.Handler
@@ -2,6 +2,7 @@ package logging
 
 import (
 	"bytes"
+	"context"
 	"log/slog"
 	"strings"
 	"testing"
@@ -78,3 +79,16 @@ func TestNew_LogLevelFiltering(t *testing.T) {
 		t.Error("Warn message should appear at warn level")
 	}
 }
+
+func TestNewWithLevel_Changes(t *testing.T) {
+	level := new(slog.LevelVar)
+	logger := NewWithLevel(level, "text")
+
+	if logger.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("debug enabled at info level")
+	}
+	level.Set(slog.LevelDebug)
+	if !logger.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("debug not enabled after setting the level to debug")
+	}
+}
@@ -0,0 +1,48 @@
+package logging
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxStderrLength caps how many bytes of a subprocess's stderr
+// SanitizeStderr keeps.
+const MaxStderrLength = 1024
+
+// ansiEscape matches ANSI CSI sequences (colours, cursor movement) and OSC
+// sequences (window titles) as printed by progress bars.
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)`)
+
+// SanitizeStderr makes a subprocess's stderr fit for a log field: ANSI
+// escapes and control characters other than newline and tab are removed,
+// invalid UTF-8 is replaced, surrounding whitespace is trimmed and the
+// output is cut to its last MaxStderrLength bytes, where the error usually
+// is.
+func SanitizeStderr(s string) string {
+	s = ansiEscape.ReplaceAllString(s, "")
+	s = strings.ToValidUTF8(s, string(utf8.RuneError))
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\r':
+			// Progress updates overwrite their line; keep them apart
+			return '\n'
+		case r == '\n' || r == '\t':
+			return r
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, s)
+	s = strings.TrimSpace(s)
+
+	if len(s) > MaxStderrLength {
+		cut := len(s) - MaxStderrLength
+		for cut < len(s) && !utf8.RuneStart(s[cut]) {
+			cut++
+		}
+		s = "…" + s[cut:]
+	}
+	return s
+}
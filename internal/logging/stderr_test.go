@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSanitizeStderr(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", "", ""},
+		{"plain", "error: model not found\n", "error: model not found"},
+		{"ansi colours", "\x1b[31mERROR\x1b[0m bad model", "ERROR bad model"},
+		{"cursor movement", "\x1b[2K\x1b[1Gdone", "done"},
+		{"osc title", "\x1b]0;piper\x07ready", "ready"},
+		{"carriage returns", "10%\r50%\r100%", "10%\n50%\n100%"},
+		{"control characters", "a\x00b\x07c\td", "abc\td"},
+		{"invalid utf-8", "caf\xc3", "caf�"},
+		{"multiline", "  line one\nline two  \n", "line one\nline two"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeStderr(tt.in); got != tt.want {
+				t.Errorf("SanitizeStderr(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeStderr_Length(t *testing.T) {
+	in := strings.Repeat("é", MaxStderrLength) + "final error"
+
+	got := SanitizeStderr(in)
+
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "final error") {
+		t.Errorf("SanitizeStderr() = %q..., want the tail marked as cut", got[:16])
+	}
+	if n := len(strings.TrimPrefix(got, "…")); n > MaxStderrLength {
+		t.Errorf("kept %d bytes, want at most %d", n, MaxStderrLength)
+	}
+	if !utf8.ValidString(got) {
+		t.Error("cut split a multi-byte character")
+	}
+}
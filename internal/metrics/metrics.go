@@ -0,0 +1,36 @@
+// Package metrics defines the small instrumentation interface the core
+// packages report to, so they stay independent of any metrics backend.
+package metrics
+
+// Metrics records measurements. Labels are alternating name, value pairs,
+// and each metric must always be recorded with the same label names.
+// Implementations must be safe for concurrent use.
+type Metrics interface {
+	// Counter adds delta to a counter.
+	Counter(name string, delta float64, labels ...string)
+	// Gauge sets a gauge to value.
+	Gauge(name string, value float64, labels ...string)
+	// Observe records value, such as a duration in seconds, in a histogram.
+	Observe(name string, value float64, labels ...string)
+}
+
+// Nop is a Metrics that discards every measurement.
+type Nop struct{}
+
+// Counter does nothing.
+func (Nop) Counter(string, float64, ...string) {}
+
+// Gauge does nothing.
+func (Nop) Gauge(string, float64, ...string) {}
+
+// Observe does nothing.
+func (Nop) Observe(string, float64, ...string) {}
+
+// OrNop returns m, or Nop if m is nil, so callers can record without
+// checking for a nil implementation.
+func OrNop(m Metrics) Metrics {
+	if m == nil {
+		return Nop{}
+	}
+	return m
+}
@@ -0,0 +1,49 @@
+package metrics
+
+import "testing"
+
+func TestOrNop(t *testing.T) {
+	if _, ok := OrNop(nil).(Nop); !ok {
+		t.Error("OrNop(nil) is not Nop")
+	}
+
+	r := NewRecorder()
+	if OrNop(r) != Metrics(r) {
+		t.Error("OrNop(r) did not return r")
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder()
+	r.Counter("jobs_total", 1, "result", "completed")
+	r.Counter("jobs_total", 2, "result", "completed")
+	r.Counter("jobs_total", 1, "result", "failed")
+	r.Gauge("depth", 3)
+	r.Gauge("depth", 1)
+	r.Observe("seconds", 0.5)
+	r.Observe("seconds", 1.5)
+
+	if got := r.CounterValue("jobs_total", "result", "completed"); got != 3 {
+		t.Errorf("completed = %v, want 3", got)
+	}
+	if got := r.CounterValue("jobs_total", "result", "failed"); got != 1 {
+		t.Errorf("failed = %v, want 1", got)
+	}
+	if got, ok := r.GaugeValue("depth"); !ok || got != 1 {
+		t.Errorf("depth = %v, %v, want 1", got, ok)
+	}
+	if _, ok := r.GaugeValue("missing"); ok {
+		t.Error("GaugeValue(missing) reported set")
+	}
+	if got := r.Observations("seconds"); len(got) != 2 || got[0] != 0.5 || got[1] != 1.5 {
+		t.Errorf("Observations = %v, want [0.5 1.5]", got)
+	}
+}
+
+func TestNop(t *testing.T) {
+	// Nop must accept any labels without panicking
+	var m Metrics = Nop{}
+	m.Counter("jobs_total", 1, "result", "completed")
+	m.Gauge("depth", 0)
+	m.Observe("seconds", 1, "unpaired")
+}
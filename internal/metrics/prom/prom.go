@@ -0,0 +1,139 @@
+// Package prom implements metrics.Metrics with Prometheus collectors.
+package prom
+
+import (
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics implements metrics.Metrics with Prometheus collectors, each
+// created on first use with the label names it was first recorded with.
+type Metrics struct {
+	namespace string
+	registry  *prometheus.Registry
+	logger    *slog.Logger
+
+	mu         sync.Mutex
+	counters   map[string]*prometheus.CounterVec
+	gauges     map[string]*prometheus.GaugeVec
+	histograms map[string]*prometheus.HistogramVec
+}
+
+// New creates a Prometheus-backed Metrics with the Go runtime and process
+// collectors already registered. Every metric name is prefixed with
+// namespace.
+func New(namespace string, logger *slog.Logger) *Metrics {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return &Metrics{
+		namespace:  namespace,
+		registry:   registry,
+		logger:     logger,
+		counters:   make(map[string]*prometheus.CounterVec),
+		gauges:     make(map[string]*prometheus.GaugeVec),
+		histograms: make(map[string]*prometheus.HistogramVec),
+	}
+}
+
+// Handler serves the collected metrics in the Prometheus text format.
+func (p *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{})
+}
+
+// splitLabels separates alternating name, value pairs. A trailing name
+// without a value is dropped.
+func splitLabels(labels []string) (names, values []string) {
+	for i := 0; i+1 < len(labels); i += 2 {
+		names = append(names, labels[i])
+		values = append(values, labels[i+1])
+	}
+	return names, values
+}
+
+// register adds a new collector, logging instead of panicking if the name
+// clashes with one of another type.
+func (p *Metrics) register(name string, c prometheus.Collector) bool {
+	if err := p.registry.Register(c); err != nil {
+		p.logger.Warn("failed to register metric", "name", name, "error", err)
+		return false
+	}
+	return true
+}
+
+// Counter adds delta to the named counter.
+func (p *Metrics) Counter(name string, delta float64, labels ...string) {
+	names, values := splitLabels(labels)
+
+	p.mu.Lock()
+	vec, ok := p.counters[name]
+	if !ok {
+		vec = prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: p.namespace, Name: name, Help: name}, names)
+		if !p.register(name, vec) {
+			vec = nil
+		}
+		p.counters[name] = vec
+	}
+	p.mu.Unlock()
+
+	if vec == nil {
+		return
+	}
+	if counter, err := vec.GetMetricWithLabelValues(values...); err == nil {
+		counter.Add(delta)
+	}
+}
+
+// Gauge sets the named gauge.
+func (p *Metrics) Gauge(name string, value float64, labels ...string) {
+	names, values := splitLabels(labels)
+
+	p.mu.Lock()
+	vec, ok := p.gauges[name]
+	if !ok {
+		vec = prometheus.NewGaugeVec(prometheus.GaugeOpts{Namespace: p.namespace, Name: name, Help: name}, names)
+		if !p.register(name, vec) {
+			vec = nil
+		}
+		p.gauges[name] = vec
+	}
+	p.mu.Unlock()
+
+	if vec == nil {
+		return
+	}
+	if gauge, err := vec.GetMetricWithLabelValues(values...); err == nil {
+		gauge.Set(value)
+	}
+}
+
+// Observe records value in the named histogram, using the default buckets,
+// which suit durations in seconds.
+func (p *Metrics) Observe(name string, value float64, labels ...string) {
+	names, values := splitLabels(labels)
+
+	p.mu.Lock()
+	vec, ok := p.histograms[name]
+	if !ok {
+		vec = prometheus.NewHistogramVec(prometheus.HistogramOpts{Namespace: p.namespace, Name: name, Help: name}, names)
+		if !p.register(name, vec) {
+			vec = nil
+		}
+		p.histograms[name] = vec
+	}
+	p.mu.Unlock()
+
+	if vec == nil {
+		return
+	}
+	if observer, err := vec.GetMetricWithLabelValues(values...); err == nil {
+		observer.Observe(value)
+	}
+}
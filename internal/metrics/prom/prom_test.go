@@ -0,0 +1,40 @@
+package prom
+
+import (
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dgnsrekt/discorgeous-go/internal/metrics"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	var m metrics.Metrics = New("test", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	m.Counter("jobs_total", 2, "result", "completed")
+	m.Gauge("depth", 3)
+	m.Observe("wait_seconds", 0.25)
+
+	rec := httptest.NewRecorder()
+	m.(*Metrics).Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		`test_jobs_total{result="completed"} 2`,
+		`test_depth 3`,
+		`test_wait_seconds_count 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output missing %q", want)
+		}
+	}
+}
+
+func TestMetricsTypeClash(t *testing.T) {
+	m := New("test", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	m.Counter("clash", 1)
+	// Registering the same name as a gauge must not panic
+	m.Gauge("clash", 1)
+	m.Gauge("clash", 2)
+}
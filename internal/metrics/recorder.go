@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"strings"
+	"sync"
+)
+
+// Recorder is a Metrics that keeps measurements in memory, for tests.
+type Recorder struct {
+	mu           sync.Mutex
+	counters     map[string]float64
+	gauges       map[string]float64
+	observations map[string][]float64
+}
+
+// NewRecorder creates an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{
+		counters:     make(map[string]float64),
+		gauges:       make(map[string]float64),
+		observations: make(map[string][]float64),
+	}
+}
+
+// key identifies a metric and its label values.
+func key(name string, labels []string) string {
+	return strings.Join(append([]string{name}, labels...), "\x00")
+}
+
+// Counter adds delta to a counter.
+func (r *Recorder) Counter(name string, delta float64, labels ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counters[key(name, labels)] += delta
+}
+
+// Gauge sets a gauge to value.
+func (r *Recorder) Gauge(name string, value float64, labels ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gauges[key(name, labels)] = value
+}
+
+// Observe records value in a histogram.
+func (r *Recorder) Observe(name string, value float64, labels ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k := key(name, labels)
+	r.observations[k] = append(r.observations[k], value)
+}
+
+// CounterValue returns the total of a counter, or 0 if it was never added to.
+func (r *Recorder) CounterValue(name string, labels ...string) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.counters[key(name, labels)]
+}
+
+// GaugeValue returns the last value of a gauge and whether it was ever set.
+func (r *Recorder) GaugeValue(name string, labels ...string) (float64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v, ok := r.gauges[key(name, labels)]
+	return v, ok
+}
+
+// Observations returns the values recorded in a histogram.
+func (r *Recorder) Observations(name string, labels ...string) []float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]float64(nil), r.observations[key(name, labels)]...)
+}
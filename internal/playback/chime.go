@@ -0,0 +1,63 @@
+package playback
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	"github.com/dgnsrekt/discorgeous-go/internal/audio"
+)
+
+// chimeCache loads a chime WAV once and keeps its Discord-ready PCM.
+type chimeCache struct {
+	mu     sync.Mutex
+	path   string
+	loaded bool
+	pcm    []byte
+}
+
+// SetChimePath sets a WAV file played before each job's speech.
+// An empty path disables the chime.
+func (h *Handler) SetChimePath(path string) {
+	h.chime.mu.Lock()
+	defer h.chime.mu.Unlock()
+	h.chime.path = path
+	h.chime.loaded = false
+	h.chime.pcm = nil
+}
+
+// chimePCM returns the converted chime PCM, padded to a frame boundary, or
+// nil if no chime is configured or it could not be loaded. The conversion is
+// done once and cached; load failures are logged and not retried.
+func (h *Handler) chimePCM(ctx context.Context) []byte {
+	h.chime.mu.Lock()
+	defer h.chime.mu.Unlock()
+
+	if h.chime.path == "" || h.chime.loaded {
+		return h.chime.pcm
+	}
+
+	wavData, err := os.ReadFile(h.chime.path)
+	if err != nil {
+		h.logger.Warn("failed to read chime, continuing without it", "path", h.chime.path, "error", err)
+		h.chime.loaded = true
+		return nil
+	}
+
+	pcm, err := h.audioConv.ConvertToDiscordPCM(ctx, wavData)
+	if err != nil {
+		if ctx.Err() != nil {
+			// Try again on the next job rather than caching a cancellation
+			return nil
+		}
+		h.logger.Warn("failed to convert chime, continuing without it", "path", h.chime.path, "error", err)
+		h.chime.loaded = true
+		return nil
+	}
+
+	h.chime.pcm = audio.PadToFrame(pcm)
+	h.chime.loaded = true
+	h.logger.Debug("chime loaded", "path", h.chime.path, "pcm_bytes", len(h.chime.pcm))
+
+	return h.chime.pcm
+}
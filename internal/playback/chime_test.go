@@ -0,0 +1,71 @@
+package playback
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dgnsrekt/discorgeous-go/internal/audio"
+)
+
+// writeFakeFFmpeg creates a script standing in for ffmpeg that records each
+// invocation in countFile and writes a few bytes of PCM to stdout.
+func writeFakeFFmpeg(t *testing.T, countFile string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ffmpeg")
+	script := "#!/bin/sh\ncat > /dev/null\necho x >> " + countFile + "\nprintf 'abcdefgh'\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatalf("failed to write fake ffmpeg: %v", err)
+	}
+	return path
+}
+
+func TestHandler_ChimePCM_CachedAndPadded(t *testing.T) {
+	dir := t.TempDir()
+	countFile := filepath.Join(dir, "count")
+	chimePath := filepath.Join(dir, "chime.wav")
+	if err := os.WriteFile(chimePath, []byte("RIFF fake wav"), 0o644); err != nil {
+		t.Fatalf("failed to write chime: %v", err)
+	}
+
+	conv := audio.NewConverterWithPath(writeFakeFFmpeg(t, countFile))
+	handler := NewHandler(nil, conv, nil, testLogger())
+	handler.SetChimePath(chimePath)
+
+	for i := 0; i < 3; i++ {
+		pcm := handler.chimePCM(context.Background())
+		if len(pcm) != audio.DiscordFrameBytes {
+			t.Fatalf("chime length = %d, want %d (padded to one frame)", len(pcm), audio.DiscordFrameBytes)
+		}
+		if string(pcm[:8]) != "abcdefgh" {
+			t.Errorf("chime prefix = %q, want %q", pcm[:8], "abcdefgh")
+		}
+	}
+
+	data, err := os.ReadFile(countFile)
+	if err != nil {
+		t.Fatalf("failed to read count file: %v", err)
+	}
+	if calls := strings.Count(string(data), "x"); calls != 1 {
+		t.Errorf("ffmpeg called %d times, want 1 (cached)", calls)
+	}
+}
+
+func TestHandler_ChimePCM_MissingFile(t *testing.T) {
+	handler := NewHandler(nil, nil, nil, testLogger())
+	handler.SetChimePath(filepath.Join(t.TempDir(), "missing.wav"))
+
+	if pcm := handler.chimePCM(context.Background()); pcm != nil {
+		t.Errorf("chimePCM() = %d bytes, want nil for missing file", len(pcm))
+	}
+}
+
+func TestHandler_ChimePCM_NotConfigured(t *testing.T) {
+	handler := NewHandler(nil, nil, nil, testLogger())
+
+	if pcm := handler.chimePCM(context.Background()); pcm != nil {
+		t.Errorf("chimePCM() = %d bytes, want nil when not configured", len(pcm))
+	}
+}
@@ -0,0 +1,102 @@
+package playback
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dgnsrekt/discorgeous-go/internal/audio"
+)
+
+// frameDuration is how long one Discord PCM frame plays.
+const frameDuration = time.Second * audio.DiscordFrameSize / audio.DiscordSampleRate
+
+// FileSink is a VoiceSender that appends the Discord PCM it is sent to a
+// file instead of playing it, so the pipeline can be tested end to end
+// without Discord. Audio is written as fast as it arrives, not in real time.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+var _ VoiceSender = (*FileSink)(nil)
+
+// NewFileSink creates or truncates the file at path and returns a sink
+// writing to it.
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create playback sink: %w", err)
+	}
+	return &FileSink{file: f}, nil
+}
+
+// IsConnected always reports true; there is nothing to join.
+func (s *FileSink) IsConnected() bool { return true }
+
+// Connect does nothing.
+func (s *FileSink) Connect(ctx context.Context) error { return nil }
+
+// SendAudioWithLimit appends pcm to the file, cut to limit if it is
+// positive.
+func (s *FileSink) SendAudioWithLimit(ctx context.Context, pcm []byte, limit time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if n := limitBytes(limit); n > 0 && len(pcm) > n {
+		pcm = pcm[:n]
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.file.Write(pcm)
+	return err
+}
+
+// SendAudioStreamWithLimit appends PCM read from r to the file until r
+// ends, ctx is done, or limit's worth has been written if it is positive.
+func (s *FileSink) SendAudioStreamWithLimit(ctx context.Context, r io.Reader, limit time.Duration) error {
+	r = &contextReader{ctx: ctx, r: r}
+	if n := limitBytes(limit); n > 0 {
+		r = io.LimitReader(r, int64(n))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := io.Copy(s.file, r)
+	return err
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// limitBytes returns how many bytes of Discord PCM fit in limit, rounded up
+// to whole frames as the voice connection does, or 0 if limit is not
+// positive.
+func limitBytes(limit time.Duration) int {
+	if limit <= 0 {
+		return 0
+	}
+	frames := (limit + frameDuration - 1) / frameDuration
+	return int(frames) * audio.DiscordFrameBytes
+}
+
+// contextReader fails reads once ctx is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
@@ -0,0 +1,77 @@
+package playback
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgnsrekt/discorgeous-go/internal/audio"
+	"github.com/dgnsrekt/discorgeous-go/internal/queue"
+	"github.com/dgnsrekt/discorgeous-go/internal/tts"
+)
+
+func TestFileSink_Handle(t *testing.T) {
+	registry := tts.NewRegistry()
+	_ = registry.Register(&mockEngine{name: "mock", result: &tts.AudioResult{Data: []byte("wav"), Format: "wav"}})
+
+	path := filepath.Join(t.TempDir(), "out.pcm")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatalf("NewFileSink() error = %v", err)
+	}
+
+	conv := audio.NewConverterWithPath(writeFakeFFmpeg(t, filepath.Join(t.TempDir(), "count")))
+	handler := NewHandler(registry, conv, sink, testLogger())
+
+	for _, id := range []string{"job-1", "job-2"} {
+		job := &queue.SpeakJob{ID: id, Text: "Hello", SkipChime: true, CreatedAt: time.Now()}
+		if err := handler.Handle(context.Background(), job); err != nil {
+			t.Fatalf("Handle(%s) error = %v", id, err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "abcdefghabcdefgh" {
+		t.Errorf("sink file = %q, want the converter output of each job", got)
+	}
+}
+
+func TestFileSink_Limit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.pcm")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatalf("NewFileSink() error = %v", err)
+	}
+
+	pcm := make([]byte, 10*audio.DiscordFrameBytes)
+	if err := sink.SendAudioWithLimit(context.Background(), pcm, 30*time.Millisecond); err != nil {
+		t.Fatalf("SendAudioWithLimit() error = %v", err)
+	}
+	if err := sink.SendAudioStreamWithLimit(context.Background(), bytes.NewReader(pcm), 20*time.Millisecond); err != nil {
+		t.Fatalf("SendAudioStreamWithLimit() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := sink.SendAudioWithLimit(ctx, pcm, 0); err == nil {
+		t.Error("SendAudioWithLimit(cancelled) error = nil, want an error")
+	}
+	sink.Close()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 30ms rounds up to two frames, 20ms is one
+	if want := int64(3 * audio.DiscordFrameBytes); info.Size() != want {
+		t.Errorf("sink file size = %d, want %d", info.Size(), want)
+	}
+}
@@ -1,9 +1,13 @@
 package playback
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"io"
 	"log/slog"
+	"sync/atomic"
+	"time"
 
 	"github.com/dgnsrekt/discorgeous-go/internal/audio"
 	"github.com/dgnsrekt/discorgeous-go/internal/discord"
@@ -11,6 +15,10 @@ import (
 	"github.com/dgnsrekt/discorgeous-go/internal/tts"
 )
 
+// dropConnectTimeout bounds the connect attempt made for a job when
+// dropping jobs while disconnected.
+const dropConnectTimeout = 2 * time.Second
+
 var (
 	// ErrNoTTSEngine is returned when no TTS engine is available.
 	ErrNoTTSEngine = errors.New("no TTS engine available")
@@ -18,21 +26,128 @@ var (
 	ErrPlaybackSynthesisFailed = errors.New("playback synthesis failed")
 	// ErrConversionFailed is returned when audio conversion fails.
 	ErrConversionFailed = errors.New("audio conversion failed")
+	// ErrSynthesisTimeout is returned when synthesis and conversion take
+	// longer than the configured synthesis timeout.
+	ErrSynthesisTimeout = errors.New("synthesis timed out")
+	// ErrDroppedDisconnected is returned when a job is dropped because the
+	// bot was not connected and a quick connect attempt failed.
+	ErrDroppedDisconnected = errors.New("dropped: not connected to voice")
+
+	// ErrPermanent marks a playback error that will fail again if retried.
+	ErrPermanent = errors.New("permanent playback error")
+	// ErrTransient marks a playback error that may succeed if retried.
+	ErrTransient = errors.New("transient playback error")
+)
+
+// IsTransient reports whether err was classified as worth retrying.
+func IsTransient(err error) bool {
+	return errors.Is(err, ErrTransient)
+}
+
+// classify wraps err with ErrPermanent or ErrTransient. Cancellation and
+// already classified errors are returned unchanged.
+func classify(err error) error {
+	switch {
+	case err == nil,
+		errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, ErrPermanent),
+		errors.Is(err, ErrTransient):
+		return err
+	case errors.Is(err, ErrNoTTSEngine),
+		errors.Is(err, tts.ErrEmptyText),
+		errors.Is(err, tts.ErrEmptyOutput),
+		errors.Is(err, tts.ErrPiperNotFound),
+		errors.Is(err, tts.ErrNoModelSpecified),
+		errors.Is(err, tts.ErrInvalidPollyVoice),
+		errors.Is(err, tts.ErrGoogleAuthFailed),
+		errors.Is(err, tts.ErrOutputTooLarge),
+		errors.Is(err, audio.ErrEmptyInput),
+		errors.Is(err, audio.ErrFFmpegNotFound),
+		errors.Is(err, audio.ErrOutputTooLarge),
+		errors.Is(err, discord.ErrPartialSend):
+		return errors.Join(ErrPermanent, err)
+	default:
+		// Process crashes, voice send failures and dropped connections
+		return errors.Join(ErrTransient, err)
+	}
+}
+
+// VoiceSender is the voice connection a Handler plays audio on.
+// *discord.VoiceManager implements it; tests substitute a fake.
+type VoiceSender interface {
+	// IsConnected reports whether audio can be sent without connecting.
+	IsConnected() bool
+	// Connect joins the voice channel.
+	Connect(ctx context.Context) error
+	// SendAudioWithLimit plays buffered Discord PCM, stopping after limit
+	// if it is positive.
+	SendAudioWithLimit(ctx context.Context, pcm []byte, limit time.Duration) error
+	// SendAudioStreamWithLimit plays Discord PCM read from r, stopping
+	// after limit if it is positive.
+	SendAudioStreamWithLimit(ctx context.Context, r io.Reader, limit time.Duration) error
+}
+
+// ChannelTargeter is implemented by VoiceSenders that can play a job in a
+// voice channel other than their usual one. *discord.VoiceManager does.
+type ChannelTargeter interface {
+	// TargetChannel makes audio play in channelID, or the usual channel if
+	// it is empty.
+	TargetChannel(channelID string) error
+}
+
+// EngineRegistry looks up the TTS engines a Handler synthesizes with.
+// *tts.Registry implements it.
+type EngineRegistry interface {
+	// Get returns the engine registered under name.
+	Get(name string) (tts.Engine, error)
+	// Default returns the engine used when a job names none.
+	Default() (tts.Engine, error)
+	// ForLanguage returns the engine mapped to a language code.
+	ForLanguage(lang string) (tts.Engine, error)
+}
+
+// AudioConverter turns synthesized audio into Discord PCM.
+// *audio.Converter implements it.
+type AudioConverter interface {
+	// ConvertToDiscordPCM converts a complete audio file.
+	ConvertToDiscordPCM(ctx context.Context, data []byte) ([]byte, error)
+	// ConvertStreamToDiscordPCM converts raw PCM as it is read from r.
+	ConvertStreamToDiscordPCM(ctx context.Context, r io.Reader, sampleRate, channels, bitsPerSample int) (io.ReadCloser, error)
+}
+
+var (
+	_ VoiceSender     = (*discord.VoiceManager)(nil)
+	_ ChannelTargeter = (*discord.VoiceManager)(nil)
+	_ EngineRegistry  = (*tts.Registry)(nil)
+	_ AudioConverter  = (*audio.Converter)(nil)
 )
 
 // Handler processes speech jobs using TTS and Discord voice.
 type Handler struct {
-	ttsRegistry  *tts.Registry
-	audioConv    *audio.Converter
-	voiceManager *discord.VoiceManager
+	ttsRegistry  EngineRegistry
+	audioConv    AudioConverter
+	voiceManager VoiceSender
 	logger       *slog.Logger
+	streaming    bool
+	normalize    bool
+	stripMD      bool
+	emojiMode    tts.EmojiMode
+	redactor     *tts.Redactor
+	detectLang   bool
+	dropIfDown   bool
+	synthTimeout time.Duration
+	leadSilence  []byte
+	trailSilence []byte
+	chime        chimeCache
+	recorder     recorder
 }
 
 // NewHandler creates a new playback handler.
 func NewHandler(
-	ttsRegistry *tts.Registry,
-	audioConv *audio.Converter,
-	voiceManager *discord.VoiceManager,
+	ttsRegistry EngineRegistry,
+	audioConv AudioConverter,
+	voiceManager VoiceSender,
 	logger *slog.Logger,
 ) *Handler {
 	return &Handler{
@@ -43,9 +158,165 @@ func NewHandler(
 	}
 }
 
-// Handle processes a single speech job.
+// SetStreaming enables piping synthesized PCM straight through the converter
+// to Discord for engines that support it, instead of buffering each stage.
+func (h *Handler) SetStreaming(enabled bool) {
+	h.streaming = enabled
+}
+
+// SetNormalizeText enables rewriting text with tts.NormalizeForSpeech
+// before synthesis.
+func (h *Handler) SetNormalizeText(enabled bool) {
+	h.normalize = enabled
+}
+
+// SetStripMarkdown enables removing markdown formatting with
+// tts.StripMarkdown before synthesis.
+func (h *Handler) SetStripMarkdown(enabled bool) {
+	h.stripMD = enabled
+}
+
+// SetEmojiMode sets what tts.ReplaceEmoji does with emoji before
+// synthesis. The zero value leaves them alone.
+func (h *Handler) SetEmojiMode(mode tts.EmojiMode) {
+	h.emojiMode = mode
+}
+
+// SetRedactor sets the filter applied to text before synthesis. A nil
+// redactor disables redaction.
+func (h *Handler) SetRedactor(r *tts.Redactor) {
+	h.redactor = r
+}
+
+// SetDetectLanguage enables picking the engine for default-voice jobs by
+// detecting the text's language and consulting the registry's language map.
+func (h *Handler) SetDetectLanguage(enabled bool) {
+	h.detectLang = enabled
+}
+
+// SetDropIfDisconnected makes a job that finds the bot disconnected try to
+// connect only briefly, and fail permanently instead of being retried if
+// that attempt fails. Suits ephemeral alerts that are worthless late.
+func (h *Handler) SetDropIfDisconnected(enabled bool) {
+	h.dropIfDown = enabled
+}
+
+// SetSynthesisTimeout bounds how long synthesizing and converting one piece
+// of text may take, so a hung engine fails the job instead of stalling the
+// queue. When streaming it bounds the wait for the first audio instead.
+// Zero disables the limit.
+func (h *Handler) SetSynthesisTimeout(d time.Duration) {
+	h.synthTimeout = d
+}
+
+// SetSilencePadding adds lead silence before and trail silence after each
+// job's audio, rounded up to whole frames, so the start isn't clipped and
+// listeners get a beat between messages. Zero disables either.
+func (h *Handler) SetSilencePadding(lead, trail time.Duration) {
+	h.leadSilence = audio.SilencePCM(lead)
+	h.trailSilence = audio.SilencePCM(trail)
+}
+
+// engineFor returns the engine to synthesize a job with. An explicit
+// job.Engine wins; otherwise a voice naming a registered engine selects
+// it. Jobs that ask for the default voice use the engine mapped to their
+// detected language, if detection is enabled and confident; everything
+// else uses the default.
+func (h *Handler) engineFor(job *queue.SpeakJob) (tts.Engine, error) {
+	if job.Engine != "" {
+		if engine, err := h.ttsRegistry.Get(job.Engine); err == nil {
+			return engine, nil
+		}
+		h.logger.Warn("unknown engine for job, selecting by voice",
+			"job_id", job.ID,
+			"engine", job.Engine,
+		)
+	}
+
+	if job.Voice != "" {
+		if engine, err := h.ttsRegistry.Get(job.Voice); err == nil {
+			return engine, nil
+		}
+	}
+
+	if h.detectLang && (job.Voice == "" || job.Voice == "default") {
+		lang, confidence := tts.DetectLanguage(job.Text)
+		if confidence >= tts.MinLanguageConfidence {
+			if engine, err := h.ttsRegistry.ForLanguage(lang); err == nil {
+				h.logger.Debug("using engine for detected language",
+					"job_id", job.ID,
+					"language", lang,
+					"confidence", confidence,
+					"engine", engine.Name(),
+				)
+				return engine, nil
+			}
+		}
+	}
+
+	engine, err := h.ttsRegistry.Default()
+	if err != nil {
+		return nil, ErrNoTTSEngine
+	}
+	return engine, nil
+}
+
+// voiceFor returns the voice to request from engine. A voice that selected
+// the engine itself is not also a speaker, so the engine default is used.
+func voiceFor(job *queue.SpeakJob, engine tts.Engine) string {
+	if job.Voice == engine.Name() {
+		return ""
+	}
+	return job.Voice
+}
+
+// speechText returns one of a job's texts as it should be sent to the
+// engine. Markdown is stripped first so link URLs are gone before the
+// other rewrites, and redaction runs before normalization so it cannot
+// split a listed word.
+func (h *Handler) speechText(job *queue.SpeakJob, text string) string {
+	if h.stripMD {
+		text = tts.StripMarkdown(text)
+	}
+	text = tts.ReplaceEmoji(text, h.emojiMode)
+	text = h.redactor.Redact(text)
+	if h.normalize {
+		return tts.NormalizeForSpeechLocale(text, job.Locale)
+	}
+	return text
+}
+
+// Handle processes a single speech job. Errors are classified with
+// ErrPermanent or ErrTransient so the queue can decide whether to retry.
 // This is the function passed to queue.SetPlaybackHandler.
 func (h *Handler) Handle(ctx context.Context, job *queue.SpeakJob) error {
+	prepared, err := h.Prepare(ctx, job)
+	if err != nil {
+		return err
+	}
+	return h.Play(ctx, prepared)
+}
+
+// Synthesize renders a job's text to Discord PCM without queueing or
+// playing it, using the same engine selection and text filters as playback.
+func (h *Handler) Synthesize(ctx context.Context, job *queue.SpeakJob) ([]byte, error) {
+	engine, err := h.engineFor(job)
+	if err != nil {
+		return nil, classify(err)
+	}
+	pcm, err := h.synthesizePCM(ctx, engine, job, job.Text)
+	return pcm, classify(err)
+}
+
+// Prepare synthesizes and converts a job's audio so it is ready to send.
+// In streaming mode synthesis is deferred to Play and nothing is buffered.
+// Handler implements queue.Preparer via Prepare and Play.
+func (h *Handler) Prepare(ctx context.Context, job *queue.SpeakJob) (*queue.PreparedJob, error) {
+	prepared, err := h.prepare(ctx, job)
+	return prepared, classify(err)
+}
+
+func (h *Handler) prepare(ctx context.Context, job *queue.SpeakJob) (*queue.PreparedJob, error) {
 	h.logger.Info("processing speech job",
 		"job_id", job.ID,
 		"text_length", len(job.Text),
@@ -53,21 +324,61 @@ func (h *Handler) Handle(ctx context.Context, job *queue.SpeakJob) error {
 	)
 
 	// Step 1: Get TTS engine
-	engine, err := h.ttsRegistry.Default()
+	engine, err := h.engineFor(job)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := engine.(tts.StreamingEngine); ok && h.streaming {
+		return &queue.PreparedJob{Job: job}, nil
+	}
+
+	// Steps 2-3: Synthesize text and convert to Discord format
+	pcmData, err := h.synthesizePCM(ctx, engine, job, job.Text)
 	if err != nil {
-		return ErrNoTTSEngine
+		return nil, err
+	}
+
+	intro, outro, err := h.introOutroPCM(ctx, engine, job)
+	if err != nil {
+		return nil, err
+	}
+
+	var chime []byte
+	if !job.SkipChime {
+		chime = h.chimePCM(ctx)
+	}
+
+	pcmData = audio.ConcatPCM(h.leadSilence, chime, intro, pcmData, outro, h.trailSilence)
+
+	return &queue.PreparedJob{Job: job, Payload: pcmData}, nil
+}
+
+// synthesizePCM synthesizes text with the engine and converts it to Discord PCM,
+// giving up after the synthesis timeout if one is set.
+func (h *Handler) synthesizePCM(ctx context.Context, engine tts.Engine, job *queue.SpeakJob, text string) ([]byte, error) {
+	parent := ctx
+	if h.synthTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.synthTimeout)
+		defer cancel()
 	}
 
 	// Step 2: Synthesize text to audio
 	h.logger.Debug("synthesizing speech", "job_id", job.ID, "engine", engine.Name())
 
 	audioResult, err := engine.Synthesize(ctx, tts.SynthesizeRequest{
-		Text:  job.Text,
-		Voice: job.Voice,
+		Text:     h.speechText(job, text),
+		Voice:    voiceFor(job, engine),
+		Language: job.Language,
 	})
 	if err != nil {
+		if synthesisTimedOut(parent, ctx) {
+			h.logger.Error("TTS synthesis timed out", "job_id", job.ID, "timeout", h.synthTimeout)
+			return nil, errors.Join(ErrPlaybackSynthesisFailed, ErrSynthesisTimeout)
+		}
 		h.logger.Error("TTS synthesis failed", "job_id", job.ID, "error", err)
-		return errors.Join(ErrPlaybackSynthesisFailed, err)
+		return nil, errors.Join(ErrPlaybackSynthesisFailed, err)
 	}
 
 	h.logger.Debug("synthesis complete",
@@ -76,32 +387,86 @@ func (h *Handler) Handle(ctx context.Context, job *queue.SpeakJob) error {
 		"sample_rate", audioResult.SampleRate,
 		"channels", audioResult.Channels,
 		"bytes", len(audioResult.Data),
+		"duration", audioResult.Duration,
 	)
+	if job.MaxDuration > 0 && audioResult.Duration > job.MaxDuration {
+		h.logger.Info("speech is longer than the job's maximum duration and will be cut short",
+			"job_id", job.ID,
+			"duration", audioResult.Duration,
+			"max_duration", job.MaxDuration,
+		)
+	}
 
 	// Step 3: Convert audio to Discord format (48kHz stereo PCM)
 	h.logger.Debug("converting audio", "job_id", job.ID)
 
 	pcmData, err := h.audioConv.ConvertToDiscordPCM(ctx, audioResult.Data)
 	if err != nil {
+		if synthesisTimedOut(parent, ctx) {
+			h.logger.Error("audio conversion timed out", "job_id", job.ID, "timeout", h.synthTimeout)
+			return nil, errors.Join(ErrConversionFailed, ErrSynthesisTimeout)
+		}
 		h.logger.Error("audio conversion failed", "job_id", job.ID, "error", err)
-		return errors.Join(ErrConversionFailed, err)
+		return nil, errors.Join(ErrConversionFailed, err)
 	}
 
 	h.logger.Debug("conversion complete", "job_id", job.ID, "pcm_bytes", len(pcmData))
 
-	// Step 4: Ensure connected to voice channel
-	if !h.voiceManager.IsConnected() {
-		h.logger.Info("connecting to voice channel", "job_id", job.ID)
-		if err := h.voiceManager.Connect(ctx); err != nil {
-			h.logger.Error("voice connection failed", "job_id", job.ID, "error", err)
+	return pcmData, nil
+}
+
+// synthesisTimedOut reports whether ctx ended because the synthesis timeout
+// derived from parent expired, rather than parent itself being done.
+func synthesisTimedOut(parent, ctx context.Context) bool {
+	return parent.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
+
+// introOutroPCM synthesizes the job's optional intro and outro lines.
+func (h *Handler) introOutroPCM(ctx context.Context, engine tts.Engine, job *queue.SpeakJob) (intro, outro []byte, err error) {
+	if job.Intro != "" {
+		if intro, err = h.synthesizePCM(ctx, engine, job, job.Intro); err != nil {
+			return nil, nil, err
+		}
+	}
+	if job.Outro != "" {
+		if outro, err = h.synthesizePCM(ctx, engine, job, job.Outro); err != nil {
+			return nil, nil, err
+		}
+	}
+	return intro, outro, nil
+}
+
+// Play sends a prepared job's audio to the voice channel.
+func (h *Handler) Play(ctx context.Context, prepared *queue.PreparedJob) error {
+	return classify(h.play(ctx, prepared))
+}
+
+func (h *Handler) play(ctx context.Context, prepared *queue.PreparedJob) error {
+	job := prepared.Job
+
+	pcmData, ok := prepared.Payload.([]byte)
+	if !ok {
+		// Nothing buffered: synthesize and stream now
+		engine, err := h.engineFor(job)
+		if err != nil {
 			return err
 		}
+		streamer, ok := engine.(tts.StreamingEngine)
+		if !ok {
+			return ErrNoTTSEngine
+		}
+		return h.handleStream(ctx, job, streamer)
+	}
+
+	// Step 4: Ensure connected to voice channel
+	if err := h.ensureConnected(ctx, job); err != nil {
+		return err
 	}
 
 	// Step 5: Send audio to Discord
 	h.logger.Debug("sending audio to voice channel", "job_id", job.ID)
 
-	if err := h.voiceManager.SendAudio(ctx, pcmData); err != nil {
+	if err := h.voiceManager.SendAudioWithLimit(ctx, pcmData, job.MaxDuration); err != nil {
 		if errors.Is(err, context.Canceled) {
 			h.logger.Info("playback interrupted", "job_id", job.ID)
 		} else {
@@ -110,6 +475,245 @@ func (h *Handler) Handle(ctx context.Context, job *queue.SpeakJob) error {
 		return err
 	}
 
+	h.record(job, pcmData)
+
 	h.logger.Info("speech playback complete", "job_id", job.ID)
 	return nil
 }
+
+// handleStream plays a job by piping engine PCM through ffmpeg to Discord
+// without buffering the full utterance at any stage.
+func (h *Handler) handleStream(ctx context.Context, job *queue.SpeakJob, engine tts.StreamingEngine) error {
+	// Intro and outro are short, so they are buffered and sent around the stream
+	intro, outro, err := h.introOutroPCM(ctx, engine, job)
+	if err != nil {
+		return err
+	}
+
+	var chime []byte
+	if !job.SkipChime {
+		chime = h.chimePCM(ctx)
+	}
+	leadIn := audio.ConcatPCM(chime, intro)
+	// Silence padding isn't heard, so it alone doesn't make a send partial
+	heardLeadIn := len(leadIn) > 0
+	leadIn = audio.ConcatPCM(h.leadSilence, leadIn)
+	outro = audio.ConcatPCM(outro, h.trailSilence)
+
+	h.logger.Debug("synthesizing speech (streaming)", "job_id", job.ID, "engine", engine.Name())
+
+	// The synthesis timeout bounds the wait for the first audio; once the
+	// stream is flowing it may run as long as the speech lasts
+	streamCtx, cancelStream := context.WithCancel(ctx)
+	defer cancelStream()
+	deadline := &firstAudioDeadline{timeout: h.synthTimeout, cancel: cancelStream}
+	deadline.start()
+
+	synthStream, format, err := engine.SynthesizeStream(streamCtx, tts.SynthesizeRequest{
+		Text:     h.speechText(job, job.Text),
+		Voice:    voiceFor(job, engine),
+		Language: job.Language,
+	})
+	if err != nil {
+		deadline.stop()
+		if deadline.expired() {
+			h.logger.Error("TTS synthesis timed out", "job_id", job.ID, "timeout", h.synthTimeout)
+			return errors.Join(ErrPlaybackSynthesisFailed, ErrSynthesisTimeout)
+		}
+		h.logger.Error("TTS synthesis failed", "job_id", job.ID, "error", err)
+		return errors.Join(ErrPlaybackSynthesisFailed, err)
+	}
+
+	pcmStream, err := h.audioConv.ConvertStreamToDiscordPCM(streamCtx, synthStream, format.SampleRate, format.Channels, format.BitsPerSample)
+	deadline.stop()
+	if err != nil {
+		synthStream.Close()
+		if deadline.expired() {
+			h.logger.Error("audio conversion timed out", "job_id", job.ID, "timeout", h.synthTimeout)
+			return errors.Join(ErrConversionFailed, ErrSynthesisTimeout)
+		}
+		h.logger.Error("audio conversion failed", "job_id", job.ID, "error", err)
+		return errors.Join(ErrConversionFailed, err)
+	}
+
+	// Close the synthesizer first so a blocked ffmpeg stdin copy is released
+	closeStreams := func() (synthErr, convErr error) {
+		synthErr = synthStream.Close()
+		convErr = pcmStream.Close()
+		return synthErr, convErr
+	}
+
+	if err := h.ensureConnected(ctx, job); err != nil {
+		closeStreams()
+		return err
+	}
+
+	// The lead-in, stream and outro share one MaxDuration budget, as they
+	// would if the audio were buffered and sent in one piece
+	remaining := job.MaxDuration
+	exhausted := func() bool { return job.MaxDuration > 0 && remaining <= 0 }
+
+	if len(leadIn) > 0 {
+		if err := h.voiceManager.SendAudioWithLimit(ctx, leadIn, job.MaxDuration); err != nil {
+			closeStreams()
+			if !errors.Is(err, context.Canceled) {
+				h.logger.Error("audio send failed", "job_id", job.ID, "error", err)
+			}
+			return err
+		}
+		remaining -= pcmDuration(len(leadIn))
+	}
+
+	// Keep a copy of the streamed audio if it is being recorded
+	counted := &countingReader{r: pcmStream, onFirstRead: deadline.stop}
+	var src io.Reader = counted
+	var recorded bytes.Buffer
+	if h.recording() {
+		src = io.TeeReader(counted, &recorded)
+	}
+
+	var sendErr error
+	if !exhausted() {
+		h.logger.Debug("streaming audio to voice channel", "job_id", job.ID)
+		deadline.start()
+		sendErr = h.voiceManager.SendAudioStreamWithLimit(ctx, src, remaining)
+		deadline.stop()
+		remaining -= pcmDuration(int(counted.n))
+	}
+	synthErr, convErr := closeStreams()
+
+	// Once listeners have heard part of the job, retrying would replay it
+	// from the start
+	partial := func(err error) error {
+		if heardLeadIn || counted.n > 0 {
+			return errors.Join(discord.ErrPartialSend, err)
+		}
+		return err
+	}
+
+	if deadline.expired() {
+		h.logger.Error("TTS synthesis timed out", "job_id", job.ID, "timeout", h.synthTimeout)
+		return partial(errors.Join(ErrPlaybackSynthesisFailed, ErrSynthesisTimeout))
+	}
+
+	if sendErr != nil {
+		if errors.Is(sendErr, context.Canceled) {
+			h.logger.Info("playback interrupted", "job_id", job.ID)
+		} else {
+			h.logger.Error("audio send failed", "job_id", job.ID, "error", sendErr)
+		}
+		return partial(sendErr)
+	}
+
+	if synthErr != nil {
+		h.logger.Error("TTS synthesis failed", "job_id", job.ID, "error", synthErr)
+		return partial(errors.Join(ErrPlaybackSynthesisFailed, synthErr))
+	}
+
+	if convErr != nil {
+		h.logger.Error("audio conversion failed", "job_id", job.ID, "error", convErr)
+		return partial(errors.Join(ErrConversionFailed, convErr))
+	}
+
+	if len(outro) > 0 && !exhausted() {
+		if err := h.voiceManager.SendAudioWithLimit(ctx, outro, remaining); err != nil {
+			if !errors.Is(err, context.Canceled) {
+				h.logger.Error("audio send failed", "job_id", job.ID, "error", err)
+			}
+			return partial(err)
+		}
+	}
+
+	if h.recording() {
+		h.record(job, audio.ConcatPCM(leadIn, recorded.Bytes(), outro))
+	}
+
+	h.logger.Info("speech playback complete", "job_id", job.ID)
+	return nil
+}
+
+// pcmDuration returns how long n bytes of Discord PCM take to play.
+func pcmDuration(n int) time.Duration {
+	return time.Duration(n) * time.Second / (audio.DiscordSampleRate * audio.DiscordChannels * 2)
+}
+
+// countingReader counts the bytes read through it, calling onFirstRead,
+// if set, when the first bytes arrive.
+type countingReader struct {
+	r           io.Reader
+	n           int64
+	onFirstRead func()
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if n > 0 && c.n == 0 && c.onFirstRead != nil {
+		c.onFirstRead()
+	}
+	c.n += int64(n)
+	return n, err
+}
+
+// firstAudioDeadline cancels a stream whose engine produces no audio within
+// the synthesis timeout. The clock only runs between start and stop, so
+// time spent joining the channel or playing the lead-in is not counted.
+type firstAudioDeadline struct {
+	timeout time.Duration
+	cancel  context.CancelFunc
+	timer   *time.Timer
+	fired   atomic.Bool
+}
+
+func (d *firstAudioDeadline) start() {
+	if d.timeout <= 0 {
+		return
+	}
+	d.timer = time.AfterFunc(d.timeout, func() {
+		d.fired.Store(true)
+		d.cancel()
+	})
+}
+
+func (d *firstAudioDeadline) stop() {
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+}
+
+// expired reports whether the deadline cancelled the stream.
+func (d *firstAudioDeadline) expired() bool {
+	return d.fired.Load()
+}
+
+// ensureConnected joins the job's voice channel if not already connected,
+// moving there first if the voice connection can.
+func (h *Handler) ensureConnected(ctx context.Context, job *queue.SpeakJob) error {
+	if t, ok := h.voiceManager.(ChannelTargeter); ok {
+		if err := t.TargetChannel(job.ChannelID); err != nil {
+			h.logger.Error("failed to move to voice channel", "job_id", job.ID, "channel_id", job.ChannelID, "error", err)
+			return err
+		}
+	}
+	if h.voiceManager.IsConnected() {
+		return nil
+	}
+
+	h.logger.Info("connecting to voice channel", "job_id", job.ID)
+	if h.dropIfDown {
+		connectCtx, cancel := context.WithTimeout(ctx, dropConnectTimeout)
+		defer cancel()
+		if err := h.voiceManager.Connect(connectCtx); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			h.logger.Warn("voice connection failed, dropping job", "job_id", job.ID, "error", err)
+			return errors.Join(ErrPermanent, ErrDroppedDisconnected, err)
+		}
+		return nil
+	}
+	if err := h.voiceManager.Connect(ctx); err != nil {
+		h.logger.Error("voice connection failed", "job_id", job.ID, "error", err)
+		return err
+	}
+	return nil
+}
@@ -1,13 +1,18 @@
 package playback
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"io"
 	"log/slog"
+	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 
+	"github.com/dgnsrekt/discorgeous-go/internal/audio"
+	"github.com/dgnsrekt/discorgeous-go/internal/discord"
 	"github.com/dgnsrekt/discorgeous-go/internal/queue"
 	"github.com/dgnsrekt/discorgeous-go/internal/tts"
 )
@@ -66,10 +71,16 @@ type mockEngine struct {
 	result    *tts.AudioResult
 	err       error
 	callCount int
+	lastText  string
+	lastVoice string
+	lastLang  string
 }
 
 func (m *mockEngine) Synthesize(ctx context.Context, req tts.SynthesizeRequest) (*tts.AudioResult, error) {
 	m.callCount++
+	m.lastText = req.Text
+	m.lastVoice = req.Voice
+	m.lastLang = req.Language
 	if m.err != nil {
 		return nil, m.err
 	}
@@ -80,6 +91,334 @@ func (m *mockEngine) Name() string {
 	return m.name
 }
 
+func (m *mockEngine) MaxTextLength() int {
+	return tts.DefaultMaxTextLength
+}
+
+// fakeSender is a VoiceSender that records the audio it is given.
+type fakeSender struct {
+	connected bool
+	connects  int
+	sent      [][]byte
+	limits    []time.Duration
+	err       error
+	connErr   error
+	deadline  bool      // whether the last Connect had a deadline
+	calls     *[]string // if set, "connect" and "send" are appended
+}
+
+func (f *fakeSender) IsConnected() bool { return f.connected }
+
+func (f *fakeSender) Connect(ctx context.Context) error {
+	f.connects++
+	_, f.deadline = ctx.Deadline()
+	if f.calls != nil {
+		*f.calls = append(*f.calls, "connect")
+	}
+	if f.connErr != nil {
+		return f.connErr
+	}
+	f.connected = true
+	return nil
+}
+
+func (f *fakeSender) SendAudioWithLimit(ctx context.Context, pcm []byte, limit time.Duration) error {
+	if f.calls != nil {
+		*f.calls = append(*f.calls, "send")
+	}
+	f.sent = append(f.sent, pcm)
+	f.limits = append(f.limits, limit)
+	return f.err
+}
+
+func (f *fakeSender) SendAudioStreamWithLimit(ctx context.Context, r io.Reader, limit time.Duration) error {
+	pcm, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return f.SendAudioWithLimit(ctx, pcm, limit)
+}
+
+// targetingSender is a fakeSender that can change channels.
+type targetingSender struct {
+	*fakeSender
+}
+
+func (s targetingSender) TargetChannel(channelID string) error {
+	*s.calls = append(*s.calls, "target:"+channelID)
+	return nil
+}
+
+func TestHandler_Handle_TargetsChannel(t *testing.T) {
+	registry := tts.NewRegistry()
+	_ = registry.Register(&mockEngine{name: "mock", result: &tts.AudioResult{Data: []byte("wav"), Format: "wav"}})
+
+	var calls []string
+	sender := targetingSender{&fakeSender{calls: &calls}}
+	conv := audio.NewConverterWithPath(writeFakeFFmpeg(t, filepath.Join(t.TempDir(), "count")))
+	handler := NewHandler(registry, conv, sender, testLogger())
+
+	for _, channel := range []string{"music", ""} {
+		job := &queue.SpeakJob{ID: "job-" + channel, Text: "Hello", ChannelID: channel, SkipChime: true, CreatedAt: time.Now()}
+		if err := handler.Handle(context.Background(), job); err != nil {
+			t.Fatalf("Handle() error = %v", err)
+		}
+	}
+
+	// Each job moves to its channel before connecting or sending
+	want := []string{"target:music", "connect", "send", "target:", "send"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestHandler_Handle_SendsAudio(t *testing.T) {
+	registry := tts.NewRegistry()
+	engine := &mockEngine{name: "mock", result: &tts.AudioResult{Data: []byte("wav"), Format: "wav"}}
+	_ = registry.Register(engine)
+
+	conv := audio.NewConverterWithPath(writeFakeFFmpeg(t, filepath.Join(t.TempDir(), "count")))
+	sender := &fakeSender{}
+	handler := NewHandler(registry, conv, sender, testLogger())
+
+	job := &queue.SpeakJob{ID: "test-job", Text: "Hello", Language: "en-GB", SkipChime: true, MaxDuration: time.Second, CreatedAt: time.Now()}
+	if err := handler.Handle(context.Background(), job); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	if sender.connects != 1 {
+		t.Errorf("Connect called %d times, want 1", sender.connects)
+	}
+	if engine.lastText != "Hello" {
+		t.Errorf("engine text = %q, want Hello", engine.lastText)
+	}
+	if engine.lastLang != "en-GB" {
+		t.Errorf("engine language = %q, want the job's en-GB", engine.lastLang)
+	}
+	if len(sender.sent) != 1 || string(sender.sent[0]) != "abcdefgh" {
+		t.Fatalf("sent audio = %q, want the converter output", sender.sent)
+	}
+	if sender.limits[0] != time.Second {
+		t.Errorf("send limit = %v, want the job's MaxDuration", sender.limits[0])
+	}
+
+	// Already connected: no reconnect; a send failure is retryable
+	sender.err = errors.New("udp write failed")
+	err := handler.Handle(context.Background(), job)
+	if sender.connects != 1 {
+		t.Errorf("Connect called %d times, want 1", sender.connects)
+	}
+	if !IsTransient(err) {
+		t.Errorf("Handle() error = %v, want a transient error", err)
+	}
+}
+
+func TestHandler_Handle_SilencePadding(t *testing.T) {
+	const converted = 8 // bytes the fake ffmpeg emits per call
+
+	tests := []struct {
+		name        string
+		lead, trail time.Duration
+		wantLead    int // frames of silence before the speech
+		wantTrail   int // frames of silence after it, once padded to a frame
+	}{
+		{"none", 0, 0, 0, 0},
+		{"lead", 20 * time.Millisecond, 0, 1, 0},
+		{"lead rounded up", 30 * time.Millisecond, 0, 2, 0},
+		{"trail", 0, 40 * time.Millisecond, 0, 2},
+		{"both", 100 * time.Millisecond, 60 * time.Millisecond, 5, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := tts.NewRegistry()
+			_ = registry.Register(&mockEngine{name: "mock", result: &tts.AudioResult{Data: []byte("wav"), Format: "wav"}})
+
+			conv := audio.NewConverterWithPath(writeFakeFFmpeg(t, filepath.Join(t.TempDir(), "count")))
+			sender := &fakeSender{}
+			handler := NewHandler(registry, conv, sender, testLogger())
+			handler.SetSilencePadding(tt.lead, tt.trail)
+
+			job := &queue.SpeakJob{ID: "test-job", Text: "Hello", SkipChime: true, CreatedAt: time.Now()}
+			if err := handler.Handle(context.Background(), job); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			if len(sender.sent) != 1 {
+				t.Fatalf("sent %d buffers, want 1", len(sender.sent))
+			}
+			pcm := sender.sent[0]
+
+			speech := converted
+			if tt.wantTrail > 0 {
+				speech = audio.DiscordFrameBytes
+			}
+			wantLen := (tt.wantLead+tt.wantTrail)*audio.DiscordFrameBytes + speech
+			if len(pcm) != wantLen {
+				t.Fatalf("sent PCM length = %d, want %d", len(pcm), wantLen)
+			}
+
+			lead := tt.wantLead * audio.DiscordFrameBytes
+			if !bytes.Equal(pcm[:lead], make([]byte, lead)) {
+				t.Error("lead is not silence")
+			}
+			if string(pcm[lead:lead+converted]) != "abcdefgh" {
+				t.Errorf("speech = %q, want the converter output after the lead", pcm[lead:lead+converted])
+			}
+			if tail := pcm[lead+converted:]; !bytes.Equal(tail, make([]byte, len(tail))) {
+				t.Error("trail is not silence")
+			}
+		})
+	}
+}
+
+func TestHandler_Handle_DropIfDisconnected(t *testing.T) {
+	tests := []struct {
+		name          string
+		drop          bool
+		connErr       error
+		wantSent      int
+		wantDeadline  bool
+		wantTransient bool
+		wantDropped   bool
+	}{
+		{"connect fails, drop off", false, errors.New("gateway down"), 0, false, true, false},
+		{"connect fails, drop on", true, errors.New("gateway down"), 0, true, false, true},
+		{"connect succeeds, drop on", true, nil, 1, true, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			sender := &fakeSender{connErr: tt.connErr}
+			handler := NewHandler(
+				fakeRegistry{engine: loggingEngine{calls: &calls}},
+				fakeConverter{calls: &calls},
+				sender,
+				testLogger(),
+			)
+			handler.SetDropIfDisconnected(tt.drop)
+
+			job := &queue.SpeakJob{ID: "test-job", Text: "Hello", SkipChime: true, CreatedAt: time.Now()}
+			err := handler.Handle(context.Background(), job)
+
+			if tt.connErr == nil && err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			if tt.connErr != nil && IsTransient(err) != tt.wantTransient {
+				t.Errorf("Handle() error = %v, transient = %v, want %v", err, IsTransient(err), tt.wantTransient)
+			}
+			if got := errors.Is(err, ErrDroppedDisconnected); got != tt.wantDropped {
+				t.Errorf("Handle() error = %v, dropped = %v, want %v", err, got, tt.wantDropped)
+			}
+			if tt.wantDropped && !errors.Is(err, ErrPermanent) {
+				t.Errorf("Handle() error = %v, want a permanent error", err)
+			}
+			if sender.deadline != tt.wantDeadline {
+				t.Errorf("Connect deadline = %v, want %v", sender.deadline, tt.wantDeadline)
+			}
+			if len(sender.sent) != tt.wantSent {
+				t.Errorf("sent %d times, want %d", len(sender.sent), tt.wantSent)
+			}
+		})
+	}
+}
+
+// fakeRegistry is an EngineRegistry holding a single default engine.
+type fakeRegistry struct{ engine tts.Engine }
+
+func (f fakeRegistry) Get(name string) (tts.Engine, error) {
+	if name == f.engine.Name() {
+		return f.engine, nil
+	}
+	return nil, tts.ErrEngineNotFound
+}
+
+func (f fakeRegistry) Default() (tts.Engine, error) { return f.engine, nil }
+
+func (f fakeRegistry) ForLanguage(lang string) (tts.Engine, error) {
+	return nil, tts.ErrEngineNotFound
+}
+
+// loggingEngine is a TTS engine that appends "synthesize" to calls.
+type loggingEngine struct {
+	calls *[]string
+	err   error
+}
+
+func (e loggingEngine) Synthesize(ctx context.Context, req tts.SynthesizeRequest) (*tts.AudioResult, error) {
+	*e.calls = append(*e.calls, "synthesize")
+	if e.err != nil {
+		return nil, e.err
+	}
+	return &tts.AudioResult{Data: []byte("wav:" + req.Text), Format: "wav"}, nil
+}
+
+func (loggingEngine) Name() string { return "logging" }
+
+func (loggingEngine) MaxTextLength() int { return tts.DefaultMaxTextLength }
+
+// fakeConverter is an AudioConverter that appends "convert" to calls and
+// passes the input through unchanged.
+type fakeConverter struct {
+	calls *[]string
+	err   error
+}
+
+func (c fakeConverter) ConvertToDiscordPCM(ctx context.Context, data []byte) ([]byte, error) {
+	*c.calls = append(*c.calls, "convert")
+	if c.err != nil {
+		return nil, c.err
+	}
+	return data, nil
+}
+
+func (c fakeConverter) ConvertStreamToDiscordPCM(ctx context.Context, r io.Reader, sampleRate, channels, bitsPerSample int) (io.ReadCloser, error) {
+	return nil, errors.New("streaming not supported")
+}
+
+func TestHandler_Handle_Sequence(t *testing.T) {
+	tests := []struct {
+		name      string
+		synthErr  error
+		convErr   error
+		wantCalls []string
+		wantErr   error
+	}{
+		{"success", nil, nil, []string{"synthesize", "convert", "connect", "send"}, nil},
+		{"synthesis fails", errors.New("engine crashed"), nil, []string{"synthesize"}, ErrPlaybackSynthesisFailed},
+		{"conversion fails", nil, errors.New("bad wav"), []string{"synthesize", "convert"}, ErrConversionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			sender := &fakeSender{calls: &calls}
+			handler := NewHandler(
+				fakeRegistry{engine: loggingEngine{calls: &calls, err: tt.synthErr}},
+				fakeConverter{calls: &calls, err: tt.convErr},
+				sender,
+				testLogger(),
+			)
+
+			job := &queue.SpeakJob{ID: "test-job", Text: "Hello", SkipChime: true, CreatedAt: time.Now()}
+			err := handler.Handle(context.Background(), job)
+
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Handle() error = %v, want %v", err, tt.wantErr)
+			}
+			if !slices.Equal(calls, tt.wantCalls) {
+				t.Errorf("calls = %v, want %v", calls, tt.wantCalls)
+			}
+			if tt.wantErr == nil && (len(sender.sent) != 1 || string(sender.sent[0]) != "wav:Hello") {
+				t.Errorf("sent audio = %q, want the converted synthesis", sender.sent)
+			}
+		})
+	}
+}
+
 func TestHandler_Handle_SynthesisFails(t *testing.T) {
 	registry := tts.NewRegistry()
 	engine := &mockEngine{
@@ -105,3 +444,421 @@ func TestHandler_Handle_SynthesisFails(t *testing.T) {
 		t.Errorf("Synthesize called %d times, want 1", engine.callCount)
 	}
 }
+
+func TestHandler_Prepare_SynthesisFails(t *testing.T) {
+	registry := tts.NewRegistry()
+	_ = registry.Register(&mockEngine{name: "mock", err: errors.New("synthesis error")})
+
+	handler := NewHandler(registry, nil, nil, testLogger())
+
+	job := &queue.SpeakJob{ID: "test-job", Text: "Hello", CreatedAt: time.Now()}
+
+	prepared, err := handler.Prepare(context.Background(), job)
+	if !errors.Is(err, ErrPlaybackSynthesisFailed) {
+		t.Errorf("Prepare() error = %v, want ErrPlaybackSynthesisFailed", err)
+	}
+	if prepared != nil {
+		t.Errorf("Prepare() = %v, want nil on failure", prepared)
+	}
+}
+
+// Compile-time check that Handler can be used as a queue.Preparer
+var _ queue.Preparer = (*Handler)(nil)
+
+// blockingEngine hangs until its context is done, like a stuck piper process.
+type blockingEngine struct{}
+
+func (blockingEngine) Synthesize(ctx context.Context, req tts.SynthesizeRequest) (*tts.AudioResult, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (blockingEngine) Name() string {
+	return "blocking"
+}
+
+func (blockingEngine) MaxTextLength() int {
+	return tts.DefaultMaxTextLength
+}
+
+func TestHandler_Prepare_SynthesisTimeout(t *testing.T) {
+	registry := tts.NewRegistry()
+	_ = registry.Register(blockingEngine{})
+
+	handler := NewHandler(registry, nil, nil, testLogger())
+	handler.SetSynthesisTimeout(20 * time.Millisecond)
+
+	job := &queue.SpeakJob{ID: "test-job", Text: "Hello", CreatedAt: time.Now()}
+
+	_, err := handler.Prepare(context.Background(), job)
+	if !errors.Is(err, ErrSynthesisTimeout) {
+		t.Errorf("Prepare() error = %v, want ErrSynthesisTimeout", err)
+	}
+	if !IsTransient(err) {
+		t.Errorf("IsTransient(%v) = false, want true", err)
+	}
+}
+
+// blockingStreamEngine never produces audio, like a piper process that
+// hangs before writing anything.
+type blockingStreamEngine struct{ blockingEngine }
+
+func (blockingStreamEngine) SynthesizeStream(ctx context.Context, req tts.SynthesizeRequest) (io.ReadCloser, tts.StreamFormat, error) {
+	<-ctx.Done()
+	return nil, tts.StreamFormat{}, ctx.Err()
+}
+
+func TestHandler_Play_StreamSynthesisTimeout(t *testing.T) {
+	registry := tts.NewRegistry()
+	_ = registry.Register(blockingStreamEngine{})
+
+	handler := NewHandler(registry, nil, nil, testLogger())
+	handler.SetStreaming(true)
+	handler.SetSynthesisTimeout(20 * time.Millisecond)
+
+	job := &queue.SpeakJob{ID: "test-job", Text: "Hello", SkipChime: true, CreatedAt: time.Now()}
+
+	err := handler.Handle(context.Background(), job)
+	if !errors.Is(err, ErrSynthesisTimeout) {
+		t.Errorf("Handle() error = %v, want ErrSynthesisTimeout", err)
+	}
+	if !IsTransient(err) {
+		t.Errorf("IsTransient(%v) = false, want true", err)
+	}
+}
+
+func TestHandler_Prepare_CancelledBeforeTimeout(t *testing.T) {
+	registry := tts.NewRegistry()
+	_ = registry.Register(blockingEngine{})
+
+	handler := NewHandler(registry, nil, nil, testLogger())
+	handler.SetSynthesisTimeout(time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	job := &queue.SpeakJob{ID: "test-job", Text: "Hello", CreatedAt: time.Now()}
+
+	_, err := handler.Prepare(ctx, job)
+	if errors.Is(err, ErrSynthesisTimeout) {
+		t.Errorf("Prepare() error = %v, want the caller's deadline, not ErrSynthesisTimeout", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Prepare() error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestHandler_Prepare_IntroOutro(t *testing.T) {
+	registry := tts.NewRegistry()
+	engine := &mockEngine{
+		name:   "mock",
+		result: &tts.AudioResult{Data: []byte("wav"), Format: "wav"},
+	}
+	_ = registry.Register(engine)
+
+	conv := audio.NewConverterWithPath(writeFakeFFmpeg(t, filepath.Join(t.TempDir(), "count")))
+	handler := NewHandler(registry, conv, nil, testLogger())
+
+	job := &queue.SpeakJob{
+		ID:        "test-job",
+		Text:      "Hello",
+		Intro:     "Notification:",
+		Outro:     "End of message.",
+		CreatedAt: time.Now(),
+	}
+
+	prepared, err := handler.Prepare(context.Background(), job)
+	if err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+
+	if engine.callCount != 3 {
+		t.Errorf("Synthesize called %d times, want 3 (intro, text, outro)", engine.callCount)
+	}
+
+	// Fake ffmpeg emits 8 bytes per call; intro and text are padded to a frame
+	pcm := prepared.Payload.([]byte)
+	wantLen := 2*audio.DiscordFrameBytes + 8
+	if len(pcm) != wantLen {
+		t.Errorf("prepared PCM length = %d, want %d", len(pcm), wantLen)
+	}
+}
+
+func TestHandler_ErrorClassification(t *testing.T) {
+	wavResult := &tts.AudioResult{Data: []byte("wav"), Format: "wav"}
+
+	tests := []struct {
+		name      string
+		engine    tts.Engine
+		conv      *audio.Converter
+		transient bool
+	}{
+		{"no engine", nil, nil, false},
+		{"empty text", &mockEngine{name: "mock", err: tts.ErrEmptyText}, nil, false},
+		{"piper missing", &mockEngine{name: "mock", err: tts.ErrPiperNotFound}, nil, false},
+		{"synthesis crash", &mockEngine{name: "mock", err: errors.New("exit status 1")}, nil, true},
+		{"output too large", &mockEngine{name: "mock", err: tts.ErrOutputTooLarge}, nil, false},
+		{"empty audio", &mockEngine{name: "mock", result: &tts.AudioResult{Format: "wav"}}, audio.NewConverterWithPath("ffmpeg"), false},
+		{"ffmpeg crash", &mockEngine{name: "mock", result: wavResult}, audio.NewConverterWithPath(filepath.Join(t.TempDir(), "missing-ffmpeg")), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := tts.NewRegistry()
+			if tt.engine != nil {
+				_ = registry.Register(tt.engine)
+			}
+			handler := NewHandler(registry, tt.conv, nil, testLogger())
+
+			job := &queue.SpeakJob{ID: "test-job", Text: "Hello", CreatedAt: time.Now()}
+
+			_, err := handler.Prepare(context.Background(), job)
+			if err == nil {
+				t.Fatal("Prepare() expected error")
+			}
+			if got := IsTransient(err); got != tt.transient {
+				t.Errorf("IsTransient(%v) = %v, want %v", err, got, tt.transient)
+			}
+			if got := errors.Is(err, ErrPermanent); got == tt.transient {
+				t.Errorf("errors.Is(%v, ErrPermanent) = %v, want %v", err, got, !tt.transient)
+			}
+		})
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"voice not connected", discord.ErrNotConnected, ErrTransient},
+		{"voice connect failed", discord.ErrConnectionFailed, ErrTransient},
+		{"send failed partway", errors.Join(discord.ErrPartialSend, io.ErrClosedPipe), ErrPermanent},
+		{"conversion failed", errors.Join(ErrConversionFailed, audio.ErrConversionFailed), ErrTransient},
+		{"no engine", ErrNoTTSEngine, ErrPermanent},
+		{"ffmpeg missing", audio.ErrFFmpegNotFound, ErrPermanent},
+		{"no audio from engine", tts.ErrEmptyOutput, ErrPermanent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := classify(tt.err); !errors.Is(err, tt.want) {
+				t.Errorf("classify(%v) = %v, want %v", tt.err, err, tt.want)
+			}
+		})
+	}
+
+	if err := classify(nil); err != nil {
+		t.Errorf("classify(nil) = %v, want nil", err)
+	}
+	if err := classify(context.Canceled); err != context.Canceled {
+		t.Errorf("classify(context.Canceled) = %v, want it unchanged", err)
+	}
+	wrapped := classify(ErrNoTTSEngine)
+	if err := classify(wrapped); err != wrapped {
+		t.Errorf("classify() rewrapped an already classified error")
+	}
+}
+
+func TestHandler_Prepare_NormalizeText(t *testing.T) {
+	for _, normalize := range []bool{false, true} {
+		registry := tts.NewRegistry()
+		engine := &mockEngine{
+			name:   "mock",
+			result: &tts.AudioResult{Data: []byte("wav"), Format: "wav"},
+		}
+		_ = registry.Register(engine)
+
+		conv := audio.NewConverterWithPath(writeFakeFFmpeg(t, filepath.Join(t.TempDir(), "count")))
+		handler := NewHandler(registry, conv, nil, testLogger())
+		handler.SetNormalizeText(normalize)
+
+		job := &queue.SpeakJob{ID: "test-job", Text: "CPU at 95%", CreatedAt: time.Now()}
+		if _, err := handler.Prepare(context.Background(), job); err != nil {
+			t.Fatalf("Prepare() error = %v", err)
+		}
+
+		want := job.Text
+		if normalize {
+			want = "C P U at 95 percent"
+		}
+		if engine.lastText != want {
+			t.Errorf("normalize=%v: synthesized %q, want %q", normalize, engine.lastText, want)
+		}
+	}
+}
+
+func TestHandler_Prepare_NormalizeLocale(t *testing.T) {
+	registry := tts.NewRegistry()
+	engine := &mockEngine{name: "mock", result: &tts.AudioResult{Data: []byte("wav"), Format: "wav"}}
+	_ = registry.Register(engine)
+
+	conv := audio.NewConverterWithPath(writeFakeFFmpeg(t, filepath.Join(t.TempDir(), "count")))
+	handler := NewHandler(registry, conv, nil, testLogger())
+	handler.SetNormalizeText(true)
+
+	job := &queue.SpeakJob{ID: "test-job", Text: "Last 95,5% bei 1.000,50 €", Locale: "de-DE", CreatedAt: time.Now()}
+	if _, err := handler.Prepare(context.Background(), job); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+
+	if want := "Last 95 Komma 5 Prozent bei 1000 Euro und 50 Cent"; engine.lastText != want {
+		t.Errorf("synthesized %q, want %q", engine.lastText, want)
+	}
+}
+
+func TestHandler_Prepare_StripMarkdownAndEmoji(t *testing.T) {
+	registry := tts.NewRegistry()
+	engine := &mockEngine{
+		name:   "mock",
+		result: &tts.AudioResult{Data: []byte("wav"), Format: "wav"},
+	}
+	_ = registry.Register(engine)
+
+	conv := audio.NewConverterWithPath(writeFakeFFmpeg(t, filepath.Join(t.TempDir(), "count")))
+	handler := NewHandler(registry, conv, nil, testLogger())
+	handler.SetStripMarkdown(true)
+	handler.SetEmojiMode(tts.EmojiDescribe)
+	handler.SetNormalizeText(true)
+
+	job := &queue.SpeakJob{ID: "test-job", Text: "🔥 **CPU** at 95%, see [dashboard](https://x/y)", CreatedAt: time.Now()}
+	if _, err := handler.Prepare(context.Background(), job); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+
+	// The link text survives normalization's URL rewrite
+	if want := "fire C P U at 95 percent, see dashboard"; engine.lastText != want {
+		t.Errorf("synthesized %q, want %q", engine.lastText, want)
+	}
+}
+
+func TestHandler_Prepare_Redact(t *testing.T) {
+	registry := tts.NewRegistry()
+	engine := &mockEngine{
+		name:   "mock",
+		result: &tts.AudioResult{Data: []byte("wav"), Format: "wav"},
+	}
+	_ = registry.Register(engine)
+
+	conv := audio.NewConverterWithPath(writeFakeFFmpeg(t, filepath.Join(t.TempDir(), "count")))
+	handler := NewHandler(registry, conv, nil, testLogger())
+	handler.SetRedactor(tts.NewRedactor([]string{"darn"}, ""))
+
+	job := &queue.SpeakJob{ID: "test-job", Text: "Darn, the build broke", CreatedAt: time.Now()}
+	if _, err := handler.Prepare(context.Background(), job); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+
+	if want := "bleep, the build broke"; engine.lastText != want {
+		t.Errorf("synthesized %q, want %q", engine.lastText, want)
+	}
+}
+
+func TestHandler_Prepare_DetectLanguage(t *testing.T) {
+	registry := tts.NewRegistry()
+	english := &mockEngine{name: "english", result: &tts.AudioResult{Data: []byte("wav"), Format: "wav"}}
+	german := &mockEngine{name: "german", result: &tts.AudioResult{Data: []byte("wav"), Format: "wav"}}
+	_ = registry.Register(english)
+	_ = registry.Register(german)
+	_ = registry.SetLanguageEngine("de", "german")
+
+	conv := audio.NewConverterWithPath(writeFakeFFmpeg(t, filepath.Join(t.TempDir(), "count")))
+	handler := NewHandler(registry, conv, nil, testLogger())
+	handler.SetDetectLanguage(true)
+
+	tests := []struct {
+		name   string
+		text   string
+		voice  string
+		engine *mockEngine
+	}{
+		{"german text", "Die Sicherung auf dem Speicherserver ist fehlgeschlagen", "default", german},
+		{"english text falls back to default", "The backup on the storage server failed", "default", english},
+		{"low confidence falls back to default", "OK", "default", english},
+		{"explicit voice skips detection", "Die Sicherung ist fehlgeschlagen", "amy", english},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			english.callCount, german.callCount = 0, 0
+
+			job := &queue.SpeakJob{ID: "test-job", Text: tt.text, Voice: tt.voice, CreatedAt: time.Now()}
+			if _, err := handler.Prepare(context.Background(), job); err != nil {
+				t.Fatalf("Prepare() error = %v", err)
+			}
+
+			if tt.engine.callCount != 1 {
+				t.Errorf("expected %s engine to synthesize", tt.engine.name)
+			}
+		})
+	}
+}
+
+func TestHandler_Prepare_EngineOverridesVoice(t *testing.T) {
+	registry := tts.NewRegistry()
+	piper := &mockEngine{name: "piper", result: &tts.AudioResult{Data: []byte("wav"), Format: "wav"}}
+	polly := &mockEngine{name: "polly", result: &tts.AudioResult{Data: []byte("wav"), Format: "wav"}}
+	_ = registry.Register(piper)
+	_ = registry.Register(polly)
+
+	conv := audio.NewConverterWithPath(writeFakeFFmpeg(t, filepath.Join(t.TempDir(), "count")))
+	handler := NewHandler(registry, conv, nil, testLogger())
+
+	job := &queue.SpeakJob{ID: "test-job", Text: "Hello", Engine: "polly", Voice: "Matthew", CreatedAt: time.Now()}
+	if _, err := handler.Prepare(context.Background(), job); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	if polly.callCount != 1 || polly.lastVoice != "Matthew" {
+		t.Errorf("polly calls=%d voice=%q, want 1 call with Matthew", polly.callCount, polly.lastVoice)
+	}
+
+	// An unregistered engine falls back to selecting by voice
+	job = &queue.SpeakJob{ID: "test-job", Text: "Hello", Engine: "missing", Voice: "3", CreatedAt: time.Now()}
+	if _, err := handler.Prepare(context.Background(), job); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	if piper.callCount != 1 || piper.lastVoice != "3" {
+		t.Errorf("default engine calls=%d voice=%q, want 1 call with speaker 3", piper.callCount, piper.lastVoice)
+	}
+}
+
+func TestHandler_Prepare_VoiceSelectsEngine(t *testing.T) {
+	registry := tts.NewRegistry()
+	piper := &mockEngine{name: "piper", result: &tts.AudioResult{Data: []byte("wav"), Format: "wav"}}
+	amy := &mockEngine{name: "amy", result: &tts.AudioResult{Data: []byte("wav"), Format: "wav"}}
+	_ = registry.Register(piper)
+	_ = registry.Register(amy)
+
+	conv := audio.NewConverterWithPath(writeFakeFFmpeg(t, filepath.Join(t.TempDir(), "count")))
+	handler := NewHandler(registry, conv, nil, testLogger())
+
+	job := &queue.SpeakJob{ID: "test-job", Text: "Hello", Voice: "amy", CreatedAt: time.Now()}
+	if _, err := handler.Prepare(context.Background(), job); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	if amy.callCount != 1 || piper.callCount != 0 {
+		t.Errorf("calls: amy=%d piper=%d, want amy only", amy.callCount, piper.callCount)
+	}
+	if amy.lastVoice != "" {
+		t.Errorf("voice passed to engine = %q, want engine default", amy.lastVoice)
+	}
+
+	// A voice that is not an engine name is a speaker for the default engine
+	job = &queue.SpeakJob{ID: "test-job", Text: "Hello", Voice: "3", CreatedAt: time.Now()}
+	if _, err := handler.Prepare(context.Background(), job); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	if piper.callCount != 1 || piper.lastVoice != "3" {
+		t.Errorf("default engine calls=%d voice=%q, want 1 call with speaker 3", piper.callCount, piper.lastVoice)
+	}
+}
+
+func TestPCMDuration(t *testing.T) {
+	if got := pcmDuration(audio.DiscordFrameBytes); got != 20*time.Millisecond {
+		t.Errorf("pcmDuration(one frame) = %v, want 20ms", got)
+	}
+	if got := pcmDuration(50 * audio.DiscordFrameBytes); got != time.Second {
+		t.Errorf("pcmDuration(50 frames) = %v, want 1s", got)
+	}
+}
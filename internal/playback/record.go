@@ -0,0 +1,61 @@
+package playback
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/dgnsrekt/discorgeous-go/internal/audio"
+	"github.com/dgnsrekt/discorgeous-go/internal/queue"
+	"github.com/dgnsrekt/discorgeous-go/internal/wav"
+)
+
+// recorder saves spoken audio to disk for auditing.
+type recorder struct {
+	dir string
+	wg  sync.WaitGroup
+}
+
+// SetRecordDir saves each job's spoken audio as RECORD_DIR/<job_id>.wav.
+// An empty dir disables recording.
+func (h *Handler) SetRecordDir(dir string) {
+	h.recorder.dir = dir
+}
+
+// recording reports whether spoken audio should be saved.
+func (h *Handler) recording() bool {
+	return h.recorder.dir != ""
+}
+
+// Close waits for recordings still being written in the background.
+func (h *Handler) Close() {
+	h.recorder.wg.Wait()
+}
+
+// record writes a job's Discord PCM to the record directory in the
+// background, so a slow disk never delays playback. Failures are logged
+// and otherwise ignored.
+func (h *Handler) record(job *queue.SpeakJob, pcm []byte) {
+	if !h.recording() || len(pcm) == 0 {
+		return
+	}
+
+	dir := h.recorder.dir
+	h.recorder.wg.Add(1)
+	go func() {
+		defer h.recorder.wg.Done()
+
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			h.logger.Warn("failed to create record directory", "dir", dir, "error", err)
+			return
+		}
+
+		path := filepath.Join(dir, job.ID+".wav")
+		data := wav.WrapRawPCM(pcm, audio.DiscordSampleRate, audio.DiscordChannels, 16)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			h.logger.Warn("failed to write recording", "job_id", job.ID, "path", path, "error", err)
+			return
+		}
+		h.logger.Debug("recording saved", "job_id", job.ID, "path", path)
+	}()
+}
@@ -0,0 +1,53 @@
+package playback
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dgnsrekt/discorgeous-go/internal/queue"
+	"github.com/dgnsrekt/discorgeous-go/internal/wav"
+)
+
+func TestHandler_Record_WritesWAV(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "recordings")
+	h := NewHandler(nil, nil, nil, testLogger())
+	h.SetRecordDir(dir)
+
+	job := queue.NewSpeakJob("Hello", "default", false, 0, "")
+	pcm := bytes.Repeat([]byte{1, 2, 3, 4}, 960)
+	h.record(job, pcm)
+	h.Close()
+
+	data, err := os.ReadFile(filepath.Join(dir, job.ID+".wav"))
+	if err != nil {
+		t.Fatalf("recording not written: %v", err)
+	}
+	if string(data[:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		t.Errorf("recording is not a WAV file: header %q", data[:12])
+	}
+	if !bytes.Equal(data[wav.HeaderSize:], pcm) {
+		t.Errorf("recording holds %d bytes of audio, want the %d sent", len(data)-wav.HeaderSize, len(pcm))
+	}
+}
+
+func TestHandler_Record_UnwritableDir(t *testing.T) {
+	// A file where the directory should be makes MkdirAll fail
+	parent := filepath.Join(t.TempDir(), "not-a-dir")
+	if err := os.WriteFile(parent, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(nil, nil, nil, testLogger())
+	dir := filepath.Join(parent, "recordings")
+	h.SetRecordDir(dir)
+
+	// The failure is logged, not returned or panicked on
+	job := queue.NewSpeakJob("Hello", "default", false, 0, "")
+	h.record(job, []byte{1, 2, 3, 4})
+	h.Close()
+
+	if _, err := os.Stat(filepath.Join(dir, job.ID+".wav")); err == nil {
+		t.Error("recording written despite an unusable directory")
+	}
+}
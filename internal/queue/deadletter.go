@@ -0,0 +1,132 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// deadLetterBuffer is how many entries a DeadLetterFile holds while its
+// writer catches up; entries beyond it are dropped.
+const deadLetterBuffer = 64
+
+// DeadLetterEntry records a job that failed for good, retries included.
+type DeadLetterEntry struct {
+	Time      time.Time `json:"time"`
+	JobID     string    `json:"job_id"`
+	Text      string    `json:"text"`
+	Voice     string    `json:"voice,omitempty"`
+	Engine    string    `json:"engine,omitempty"`
+	Language  string    `json:"language,omitempty"`
+	Locale    string    `json:"locale,omitempty"`
+	Intro     string    `json:"intro,omitempty"`
+	Outro     string    `json:"outro,omitempty"`
+	DedupeKey string    `json:"dedupe_key,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Attempts  int       `json:"attempts"`
+	Error     string    `json:"error"`
+}
+
+// DeadLetter receives the jobs that fail permanently. Add is called from
+// the playback worker and must not block.
+type DeadLetter interface {
+	Add(e DeadLetterEntry)
+}
+
+// DeadLetterFile is a DeadLetter appending each entry to a file as a line
+// of JSON. Entries are written by a background goroutine so a slow disk
+// doesn't hold up playback. It is safe for concurrent use.
+type DeadLetterFile struct {
+	mu      sync.Mutex
+	closed  bool
+	file    *os.File
+	entries chan DeadLetterEntry
+	done    chan struct{}
+	logger  *slog.Logger
+}
+
+var _ DeadLetter = (*DeadLetterFile)(nil)
+
+// NewDeadLetterFile opens the file at path for appending, creating it if
+// needed, and starts writing entries added to the returned DeadLetterFile.
+func NewDeadLetterFile(path string, logger *slog.Logger) (*DeadLetterFile, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open dead-letter file: %w", err)
+	}
+	d := &DeadLetterFile{
+		file:    f,
+		entries: make(chan DeadLetterEntry, deadLetterBuffer),
+		done:    make(chan struct{}),
+		logger:  logger,
+	}
+	go d.run()
+	return d, nil
+}
+
+// Add queues e to be written. If the writer has fallen too far behind or
+// the file is closed, e is logged and dropped.
+func (d *DeadLetterFile) Add(e DeadLetterEntry) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		d.logger.Warn("dead-letter file closed, dropping entry", "job_id", e.JobID)
+		return
+	}
+	select {
+	case d.entries <- e:
+	default:
+		d.logger.Warn("dead-letter writer behind, dropping entry", "job_id", e.JobID)
+	}
+}
+
+// Close writes the entries still queued and closes the file.
+func (d *DeadLetterFile) Close() error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil
+	}
+	d.closed = true
+	close(d.entries)
+	d.mu.Unlock()
+
+	<-d.done
+	return d.file.Close()
+}
+
+// run writes entries until the channel is closed.
+func (d *DeadLetterFile) run() {
+	defer close(d.done)
+
+	enc := json.NewEncoder(d.file)
+	for e := range d.entries {
+		if err := enc.Encode(e); err != nil {
+			d.logger.Error("failed to write dead-letter entry", "job_id", e.JobID, "error", err)
+		}
+	}
+}
+
+// newDeadLetterEntry describes job, which failed with err after attempts
+// tries.
+func newDeadLetterEntry(job *SpeakJob, attempts int, err error, now time.Time) DeadLetterEntry {
+	return DeadLetterEntry{
+		Time:      now,
+		JobID:     job.ID,
+		Text:      job.Text,
+		Voice:     job.Voice,
+		Engine:    job.Engine,
+		Language:  job.Language,
+		Locale:    job.Locale,
+		Intro:     job.Intro,
+		Outro:     job.Outro,
+		DedupeKey: job.DedupeKey,
+		CreatedAt: job.CreatedAt,
+		Attempts:  attempts,
+		Error:     err.Error(),
+	}
+}
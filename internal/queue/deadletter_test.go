@@ -0,0 +1,119 @@
+package queue
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestQueue_DeadLetter(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"permanent failure", errors.New("engine exploded"), 1},
+		{"success", nil, 0},
+		{"cancelled", context.Canceled, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "dead-letter.jsonl")
+			dl, err := NewDeadLetterFile(path, testLogger())
+			if err != nil {
+				t.Fatalf("NewDeadLetterFile() error = %v", err)
+			}
+
+			q := NewQueue(10, 5*time.Minute, testLogger())
+			q.SetRetryPolicy(1, time.Millisecond, nil)
+			q.SetDeadLetter(dl)
+			q.SetPlaybackHandler(func(ctx context.Context, job *SpeakJob) error {
+				return tt.err
+			})
+			jobDone := make(chan struct{})
+			q.SetJobCompletedCallback(func(job *SpeakJob) {
+				close(jobDone)
+			})
+
+			q.Start()
+			job := NewSpeakJob("Never spoken", "amy", false, 0, "")
+			job.Locale = "de-DE"
+			q.Enqueue(job)
+
+			select {
+			case <-jobDone:
+			case <-time.After(testTimeout):
+				t.Fatal("timeout waiting for job to finish")
+			}
+			q.Stop()
+			if err := dl.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			entries := readDeadLetters(t, path)
+			if len(entries) != tt.want {
+				t.Fatalf("got %d dead-letter entries, want %d", len(entries), tt.want)
+			}
+			if tt.want == 0 {
+				return
+			}
+			e := entries[0]
+			if e.JobID != job.ID || e.Text != "Never spoken" || e.Voice != "amy" || e.Locale != "de-DE" {
+				t.Errorf("entry = %+v, want job %s", e, job.ID)
+			}
+			if e.Attempts != 2 || e.Error != tt.err.Error() {
+				t.Errorf("entry attempts = %d, error = %q, want 2 and %q", e.Attempts, e.Error, tt.err)
+			}
+		})
+	}
+}
+
+func TestDeadLetterFile_Appends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead-letter.jsonl")
+	for _, id := range []string{"first", "second"} {
+		dl, err := NewDeadLetterFile(path, testLogger())
+		if err != nil {
+			t.Fatalf("NewDeadLetterFile() error = %v", err)
+		}
+		dl.Add(DeadLetterEntry{JobID: id, Error: "failed"})
+		if err := dl.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		// Adding after Close drops the entry rather than panicking
+		dl.Add(DeadLetterEntry{JobID: "late"})
+	}
+
+	entries := readDeadLetters(t, path)
+	if len(entries) != 2 || entries[0].JobID != "first" || entries[1].JobID != "second" {
+		t.Errorf("entries = %+v, want first then second", entries)
+	}
+}
+
+func readDeadLetters(t *testing.T, path string) []DeadLetterEntry {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var entries []DeadLetterEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e DeadLetterEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("invalid dead-letter line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
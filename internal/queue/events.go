@@ -0,0 +1,97 @@
+package queue
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType identifies a job lifecycle event.
+type EventType string
+
+const (
+	// EventEnqueued is emitted when a job is added to the queue.
+	EventEnqueued EventType = "enqueued"
+	// EventStarted is emitted when the worker begins processing a job.
+	EventStarted EventType = "started"
+	// EventCompleted is emitted when a job finishes successfully.
+	EventCompleted EventType = "completed"
+	// EventFailed is emitted when a job fails or is cancelled.
+	EventFailed EventType = "failed"
+	// EventReplaced is emitted for a queued job when a newer job with the
+	// same dedupe key takes its place.
+	EventReplaced EventType = "replaced"
+)
+
+// eventBufferSize is the per-subscriber channel buffer. Events for
+// subscribers that fall this far behind are dropped.
+const eventBufferSize = 32
+
+// Event describes a change in a job's lifecycle.
+type Event struct {
+	Type  EventType `json:"type"`
+	JobID string    `json:"job_id"`
+	Error string    `json:"error,omitempty"`
+	Time  time.Time `json:"time"`
+}
+
+// broadcaster fans out events to registered subscriber channels.
+type broadcaster struct {
+	mu   sync.Mutex
+	subs map[chan Event]struct{}
+}
+
+func newBroadcaster() *broadcaster {
+	return &broadcaster{subs: make(map[chan Event]struct{})}
+}
+
+// subscribe registers a new subscriber channel.
+func (b *broadcaster) subscribe() chan Event {
+	ch := make(chan Event, eventBufferSize)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch
+}
+
+// unsubscribe removes and closes a subscriber channel.
+func (b *broadcaster) unsubscribe(ch chan Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[ch]; ok {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+// publish delivers an event to all subscribers without blocking.
+func (b *broadcaster) publish(ev Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- ev:
+		default:
+			// Slow subscriber; drop rather than stall the worker
+		}
+	}
+}
+
+// Subscribe registers for job lifecycle events. The returned function
+// unregisters the subscription and closes the channel; it is safe to call
+// more than once.
+func (q *Queue) Subscribe() (<-chan Event, func()) {
+	ch := q.events.subscribe()
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() { q.events.unsubscribe(ch) })
+	}
+}
+
+// emit publishes a job lifecycle event to subscribers.
+func (q *Queue) emit(typ EventType, job *SpeakJob, err error) {
+	ev := Event{Type: typ, JobID: job.ID, Time: q.clock.Now()}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	q.events.publish(ev)
+}
@@ -0,0 +1,71 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSubscribeReceivesLifecycleEvents(t *testing.T) {
+	q := NewQueue(10, 5*time.Minute, testLogger())
+
+	events, unsubscribe := q.Subscribe()
+	defer unsubscribe()
+
+	q.SetPlaybackHandler(func(ctx context.Context, job *SpeakJob) error {
+		if job.Text == "Bad" {
+			return errors.New("playback error")
+		}
+		return nil
+	})
+
+	q.Start()
+	defer q.Stop()
+
+	good := NewSpeakJob("Good", "default", false, 0, "")
+	bad := NewSpeakJob("Bad", "default", false, 0, "")
+	q.Enqueue(good)
+	q.Enqueue(bad)
+
+	want := map[string][]EventType{
+		good.ID: {EventEnqueued, EventStarted, EventCompleted},
+		bad.ID:  {EventEnqueued, EventStarted, EventFailed},
+	}
+	got := map[string][]EventType{}
+
+	for len(got[good.ID]) < 3 || len(got[bad.ID]) < 3 {
+		select {
+		case ev := <-events:
+			got[ev.JobID] = append(got[ev.JobID], ev.Type)
+			if ev.Type == EventFailed && ev.Error == "" {
+				t.Error("failed event should carry the error message")
+			}
+		case <-time.After(testTimeout):
+			t.Fatalf("timeout waiting for events, got %v", got)
+		}
+	}
+
+	for id, types := range want {
+		for i, typ := range types {
+			if got[id][i] != typ {
+				t.Errorf("job %s event %d = %s, want %s", id, i, got[id][i], typ)
+			}
+		}
+	}
+}
+
+func TestUnsubscribeClosesChannel(t *testing.T) {
+	q := NewQueue(10, 5*time.Minute, testLogger())
+
+	events, unsubscribe := q.Subscribe()
+	unsubscribe()
+	unsubscribe() // safe to call twice
+
+	if _, ok := <-events; ok {
+		t.Error("expected channel to be closed after unsubscribe")
+	}
+
+	// Publishing with no subscribers must not block or panic
+	q.Enqueue(NewSpeakJob("Hello", "default", false, 0, ""))
+}
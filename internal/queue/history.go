@@ -0,0 +1,136 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// defaultHistorySize is how many finished jobs a new queue remembers.
+const defaultHistorySize = 100
+
+// historyPreviewRunes is how much of a job's text a HistoryEntry keeps.
+const historyPreviewRunes = 80
+
+// Job outcomes recorded in HistoryEntry.Status.
+const (
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// HistoryEntry summarizes a job the worker has finished with.
+type HistoryEntry struct {
+	JobID string `json:"job_id"`
+	// Text is the start of the job's text, truncated for display.
+	Text   string `json:"text"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// DurationMS is how long the job took to process, retries included.
+	DurationMS int64     `json:"duration_ms"`
+	Time       time.Time `json:"time"`
+}
+
+// history is a fixed-size ring buffer of the most recent HistoryEntry
+// values. It is safe for concurrent use.
+type history struct {
+	mu      sync.Mutex
+	entries []HistoryEntry
+	next    int // index the next entry is written to
+	full    bool
+}
+
+// newHistory creates a history holding up to size entries.
+func newHistory(size int) *history {
+	return &history{entries: make([]HistoryEntry, size)}
+}
+
+// add records an entry, overwriting the oldest once the buffer is full.
+func (h *history) add(e HistoryEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.entries) == 0 {
+		return
+	}
+	h.entries[h.next] = e
+	h.next = (h.next + 1) % len(h.entries)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// recent returns up to limit entries, newest first. A limit <= 0 returns
+// every entry held.
+func (h *history) recent(limit int) []HistoryEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	n := h.next
+	if h.full {
+		n = len(h.entries)
+	}
+	if limit > 0 && limit < n {
+		n = limit
+	}
+
+	out := make([]HistoryEntry, n)
+	for i := range out {
+		idx := (h.next - 1 - i + len(h.entries)) % len(h.entries)
+		out[i] = h.entries[idx]
+	}
+	return out
+}
+
+// textPreview returns text cut to historyPreviewRunes, marking the cut
+// with an ellipsis.
+func textPreview(text string) string {
+	runes := []rune(text)
+	if len(runes) <= historyPreviewRunes {
+		return text
+	}
+	return string(runes[:historyPreviewRunes]) + "…"
+}
+
+// SetHistorySize sets how many finished jobs History remembers, discarding
+// any already recorded. Zero disables the history.
+func (q *Queue) SetHistorySize(size int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.history = newHistory(max(size, 0))
+}
+
+// History returns summaries of up to limit recently finished jobs, newest
+// first. A limit <= 0 returns all that are remembered.
+func (q *Queue) History(limit int) []HistoryEntry {
+	q.mu.Lock()
+	h := q.history
+	q.mu.Unlock()
+	return h.recent(limit)
+}
+
+// recordHistory adds the outcome of a job that started processing at
+// start to the history.
+func (q *Queue) recordHistory(job *SpeakJob, start time.Time, err error) {
+	q.mu.Lock()
+	h := q.history
+	q.mu.Unlock()
+
+	now := q.clock.Now()
+	e := HistoryEntry{
+		JobID:      job.ID,
+		Text:       textPreview(job.Text),
+		Status:     StatusCompleted,
+		DurationMS: now.Sub(start).Milliseconds(),
+		Time:       now,
+	}
+	if err != nil {
+		e.Status = StatusFailed
+		if errors.Is(err, context.Canceled) {
+			e.Status = StatusCancelled
+		}
+		e.Error = err.Error()
+	}
+	h.add(e)
+}
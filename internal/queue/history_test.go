@@ -0,0 +1,147 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHistoryWrapAround(t *testing.T) {
+	h := newHistory(3)
+	for i := 1; i <= 5; i++ {
+		h.add(HistoryEntry{JobID: fmt.Sprintf("job-%d", i)})
+	}
+
+	tests := []struct {
+		name  string
+		limit int
+		want  []string
+	}{
+		{"all", 0, []string{"job-5", "job-4", "job-3"}},
+		{"limited", 2, []string{"job-5", "job-4"}},
+		{"limit above size", 10, []string{"job-5", "job-4", "job-3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := h.recent(tt.limit)
+			if len(got) != len(tt.want) {
+				t.Fatalf("recent(%d) returned %d entries, want %d", tt.limit, len(got), len(tt.want))
+			}
+			for i, e := range got {
+				if e.JobID != tt.want[i] {
+					t.Errorf("entry %d = %s, want %s", i, e.JobID, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHistoryPartiallyFilled(t *testing.T) {
+	h := newHistory(5)
+	if got := h.recent(0); len(got) != 0 {
+		t.Errorf("empty history returned %d entries", len(got))
+	}
+
+	h.add(HistoryEntry{JobID: "job-1"})
+	h.add(HistoryEntry{JobID: "job-2"})
+	got := h.recent(0)
+	if len(got) != 2 || got[0].JobID != "job-2" || got[1].JobID != "job-1" {
+		t.Errorf("recent(0) = %v, want job-2 then job-1", got)
+	}
+}
+
+func TestHistoryDisabled(t *testing.T) {
+	h := newHistory(0)
+	h.add(HistoryEntry{JobID: "job-1"})
+	if got := h.recent(0); len(got) != 0 {
+		t.Errorf("zero-size history returned %d entries", len(got))
+	}
+}
+
+func TestHistoryConcurrentAdd(t *testing.T) {
+	h := newHistory(8)
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.add(HistoryEntry{JobID: fmt.Sprintf("job-%d", i)})
+			h.recent(3)
+		}()
+	}
+	wg.Wait()
+
+	if got := h.recent(0); len(got) != 8 {
+		t.Errorf("recent(0) returned %d entries, want 8", len(got))
+	}
+}
+
+func TestQueueRecordsHistory(t *testing.T) {
+	q := NewQueue(10, 5*time.Minute, testLogger())
+	q.SetHistorySize(10)
+
+	var wg sync.WaitGroup
+	q.SetJobCompletedCallback(func(job *SpeakJob) { wg.Done() })
+	q.SetPlaybackHandler(func(ctx context.Context, job *SpeakJob) error {
+		if job.Text == "Bad" {
+			return errors.New("playback error")
+		}
+		return nil
+	})
+
+	long := strings.Repeat("a", 200)
+	jobs := []*SpeakJob{
+		NewSpeakJob("Good", "default", false, 0, ""),
+		NewSpeakJob("Bad", "default", false, 0, ""),
+		NewSpeakJob(long, "default", false, 0, ""),
+	}
+	wg.Add(len(jobs))
+	for _, job := range jobs {
+		if err := q.Enqueue(job); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+
+	q.Start()
+	defer q.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(testTimeout):
+		t.Fatal("timeout waiting for jobs")
+	}
+
+	got := q.History(0)
+	if len(got) != 3 {
+		t.Fatalf("History(0) returned %d entries, want 3", len(got))
+	}
+
+	// Newest first
+	if got[0].JobID != jobs[2].ID || got[0].Status != StatusCompleted {
+		t.Errorf("entry 0 = %+v, want the long job completed", got[0])
+	}
+	if want := strings.Repeat("a", historyPreviewRunes) + "…"; got[0].Text != want {
+		t.Errorf("entry 0 text = %q, want it truncated to %d runes", got[0].Text, historyPreviewRunes)
+	}
+	if got[1].JobID != jobs[1].ID || got[1].Status != StatusFailed || got[1].Error != "playback error" {
+		t.Errorf("entry 1 = %+v, want the bad job failed", got[1])
+	}
+	if got[2].JobID != jobs[0].ID || got[2].Status != StatusCompleted || got[2].Text != "Good" {
+		t.Errorf("entry 2 = %+v, want the good job completed", got[2])
+	}
+
+	if limited := q.History(1); len(limited) != 1 || limited[0].JobID != jobs[2].ID {
+		t.Errorf("History(1) = %+v, want only the newest job", limited)
+	}
+}
@@ -14,6 +14,36 @@ type SpeakJob struct {
 	Interrupt bool
 	TTL       time.Duration
 	DedupeKey string
+	// ReplaceDuplicate makes Enqueue, EnqueueWait, EnqueueEach and
+	// EnqueueAll put the job in the place of a queued job with the same
+	// dedupe key instead of rejecting it with ErrDuplicateJob.
+	ReplaceDuplicate bool
+	// Replaces is the ID of the queued job this one took the place of, set
+	// when the job is enqueued.
+	Replaces string
+	// Engine names the TTS engine to use, overriding the one Voice would
+	// select. Empty means select by Voice.
+	Engine string
+	// Language is a BCP-47 language hint passed to the engine; empty
+	// leaves the language to the engine or voice.
+	Language string
+	// Locale is how numbers in Text are written, for text normalization;
+	// empty means tts.DefaultLocale.
+	Locale string
+	// SkipChime suppresses the notification chime for this job.
+	SkipChime bool
+	// Intro and Outro are optional lines spoken before and after Text.
+	Intro string
+	Outro string
+	// ChannelID is the voice channel to play in; empty means the voice
+	// connection's usual channel.
+	ChannelID string
+	// MaxDuration caps how long this job's audio may play; zero means no
+	// per-job cap (a global cap may still apply).
+	MaxDuration time.Duration
+	// Position is the job's 1-based place in the queue when it was
+	// enqueued, not counting the job playing at the time.
+	Position  int
 	CreatedAt time.Time
 	ExpiresAt time.Time
 }
@@ -31,13 +61,17 @@ func NewSpeakJob(text, voice string, interrupt bool, ttl time.Duration, dedupeKe
 		CreatedAt: now,
 	}
 
-	if ttl > 0 {
-		job.ExpiresAt = now.Add(ttl)
-	}
-
+	job.startTTL(now)
 	return job
 }
 
+// startTTL makes the job's TTL run from now.
+func (j *SpeakJob) startTTL(now time.Time) {
+	if j.TTL > 0 {
+		j.ExpiresAt = now.Add(j.TTL)
+	}
+}
+
 // IsExpired returns true if the job has passed its TTL.
 func (j *SpeakJob) IsExpired() bool {
 	if j.ExpiresAt.IsZero() {
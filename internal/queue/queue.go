@@ -3,9 +3,14 @@ package queue
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"runtime/debug"
+	"slices"
 	"sync"
 	"time"
+
+	"github.com/dgnsrekt/discorgeous-go/internal/metrics"
 )
 
 var (
@@ -15,50 +20,137 @@ var (
 	ErrQueueClosed = errors.New("queue is closed")
 	// ErrDuplicateJob is returned when a job with the same dedupe key exists.
 	ErrDuplicateJob = errors.New("duplicate job")
+	// ErrHandlerPanic is returned when the playback handler panics.
+	ErrHandlerPanic = errors.New("playback handler panicked")
 )
 
 // PlaybackHandler is called by the worker to play a job.
 // Implementations should handle the actual TTS and voice playback.
 type PlaybackHandler func(ctx context.Context, job *SpeakJob) error
 
+// InterruptMode controls how Interrupt treats the job that is playing.
+type InterruptMode string
+
+const (
+	// InterruptHard cancels the current job immediately.
+	InterruptHard InterruptMode = "hard"
+	// InterruptSoft lets the current job keep playing for a grace period
+	// before it is cancelled, so short remainders aren't cut off mid-word.
+	InterruptSoft InterruptMode = "soft"
+)
+
+// PreparedJob holds the output of a Preparer for a job, ready to be played.
+type PreparedJob struct {
+	Job *SpeakJob
+	// Payload is handler-specific prepared data (e.g. Discord-ready PCM).
+	Payload any
+}
+
+// Preparer splits playback into a Prepare step, which the worker may run
+// ahead of time for the next queued job, and a Play step.
+type Preparer interface {
+	// Prepare does the expensive work for a job (e.g. synthesis and conversion).
+	Prepare(ctx context.Context, job *SpeakJob) (*PreparedJob, error)
+	// Play plays a previously prepared job.
+	Play(ctx context.Context, prepared *PreparedJob) error
+}
+
+// prefetch tracks a Prepare call running ahead for the next queued job.
+type prefetch struct {
+	job      *SpeakJob
+	cancel   context.CancelFunc
+	done     chan struct{}
+	prepared *PreparedJob
+	err      error
+}
+
 // IdleCallback is called when the queue becomes idle.
 type IdleCallback func()
 
+// DrainedCallback is called when the worker runs out of jobs after
+// processing a contiguous run of them.
+type DrainedCallback func()
+
 // ShutdownCallback is called during graceful shutdown to clean up resources.
 type ShutdownCallback func()
 
-// JobCompletedCallback is called after each job completes (for testing).
+// HighWaterCallback is called when the queue depth rises to the high-water
+// mark, with the new depth.
+type HighWaterCallback func(depth int)
+
+// highWaterWarnInterval limits how often crossing the high-water mark is
+// logged, so a queue hovering around it doesn't flood the log.
+const highWaterWarnInterval = time.Minute
+
+// JobCompletedCallback is called after each job completes.
 type JobCompletedCallback func(job *SpeakJob)
 
 // Queue is a bounded queue with a single playback worker.
 type Queue struct {
-	mu                   sync.Mutex
-	jobs                 []*SpeakJob
-	capacity             int
-	dedupeKeys           map[string]bool
-	logger               *slog.Logger
-	closed               bool
-	idleTimeout          time.Duration
-	idleCallback         IdleCallback
-	shutdownCallback     ShutdownCallback
-	jobCompletedCallback JobCompletedCallback
-	playbackFunc         PlaybackHandler
-	cancelCurrent        context.CancelFunc
-	wg                   sync.WaitGroup
-	stopCh               chan struct{}
-	enqueueCh            chan struct{}
+	mu                sync.Mutex
+	jobs              []*SpeakJob
+	capacity          int
+	dedupeKeys        map[string]bool
+	logger            *slog.Logger
+	closed            bool
+	idleTimeout       time.Duration
+	clock             Clock
+	idleCallback      IdleCallback
+	idleDelay         time.Duration
+	minDwell          time.Duration
+	stayConnected     bool
+	drainedCallback   DrainedCallback
+	coalescePlayback  bool
+	shutdownCallback  ShutdownCallback
+	jobCompleted      []JobCompletedCallback
+	highWater         int
+	highWaterCallback HighWaterCallback
+	lastHighWaterWarn time.Time
+	playbackFunc      PlaybackHandler
+	preparer          Preparer
+	prefetch          *prefetch
+	cancelCurrent     context.CancelFunc
+	softInterrupt     *time.Timer // pending grace-period cancel, if any
+	interruptMode     InterruptMode
+	interruptGrace    time.Duration
+	maxRetries        int
+	retryDelay        time.Duration
+	retryable         func(error) bool
+	deadLetter        DeadLetter
+	quiet             *quietHours
+	wg                sync.WaitGroup
+	stopCh            chan struct{}
+	stopped           chan struct{} // closed once Stop has finished
+	enqueueCh         chan struct{}
+	spaceCh           chan struct{}
+	events            *broadcaster
+	history           *history
+	metrics           metrics.Metrics
 }
 
 // NewQueue creates a new bounded queue.
 func NewQueue(capacity int, idleTimeout time.Duration, logger *slog.Logger) *Queue {
+	return NewQueueWithClock(capacity, idleTimeout, logger, realClock{})
+}
+
+// NewQueueWithClock creates a new bounded queue whose idle timer uses clock.
+func NewQueueWithClock(capacity int, idleTimeout time.Duration, logger *slog.Logger, clock Clock) *Queue {
 	return &Queue{
 		jobs:        make([]*SpeakJob, 0, capacity),
 		capacity:    capacity,
 		dedupeKeys:  make(map[string]bool),
 		logger:      logger,
 		idleTimeout: idleTimeout,
+		clock:       clock,
 		stopCh:      make(chan struct{}),
+		stopped:     make(chan struct{}),
 		enqueueCh:   make(chan struct{}, 1),
+		spaceCh:     make(chan struct{}),
+		events:      newBroadcaster(),
+		history:     newHistory(defaultHistorySize),
+		metrics:     metrics.Nop{},
+
+		interruptMode: InterruptHard,
 	}
 }
 
@@ -69,6 +161,53 @@ func (q *Queue) SetPlaybackHandler(fn PlaybackHandler) {
 	q.playbackFunc = fn
 }
 
+// SetPreparer sets a two-phase playback handler. When set, it takes precedence
+// over the PlaybackHandler and the worker prepares the next queued job while
+// the current one plays.
+func (q *Queue) SetPreparer(p Preparer) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.preparer = p
+}
+
+// SetInterruptMode sets how Interrupt treats the job that is playing.
+// In soft mode the current job is cancelled only if it is still playing
+// after grace; pending jobs are always cleared immediately.
+func (q *Queue) SetInterruptMode(mode InterruptMode, grace time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.interruptMode = mode
+	q.interruptGrace = grace
+}
+
+// SetRetryPolicy sets how many times a failed job is retried and the base
+// delay between attempts, which doubles after each retry. retryable reports
+// whether an error is worth retrying; if nil, every error other than
+// context cancellation is retried.
+func (q *Queue) SetRetryPolicy(maxRetries int, delay time.Duration, retryable func(error) bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.maxRetries = maxRetries
+	q.retryDelay = delay
+	q.retryable = retryable
+}
+
+// SetDeadLetter sets where jobs that fail permanently, after any retries,
+// are recorded. Cancelled jobs are not. If d is nil, nothing is recorded.
+func (q *Queue) SetDeadLetter(d DeadLetter) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.deadLetter = d
+}
+
+// SetMetrics sets where the queue reports its depth and job outcomes.
+// If m is nil, nothing is reported.
+func (q *Queue) SetMetrics(m metrics.Metrics) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.metrics = metrics.OrNop(m)
+}
+
 // SetIdleCallback sets the function called when the queue becomes idle.
 func (q *Queue) SetIdleCallback(fn IdleCallback) {
 	q.mu.Lock()
@@ -76,48 +215,270 @@ func (q *Queue) SetIdleCallback(fn IdleCallback) {
 	q.idleCallback = fn
 }
 
+// SetIdleDelay debounces the idle callback: after the idle timeout it waits
+// a further delay, and at least until the worker has been active for
+// minDwell, before calling it. A job arriving in the meantime cancels the
+// call. This keeps bursty traffic from repeatedly leaving and rejoining.
+func (q *Queue) SetIdleDelay(delay, minDwell time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.idleDelay = delay
+	q.minDwell = minDwell
+}
+
+// SetStayConnected suppresses the idle callback entirely when stay is true,
+// whatever the idle timeout, so the bot never leaves voice for being idle.
+func (q *Queue) SetStayConnected(stay bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.stayConnected = stay
+}
+
+// SetDrainedCallback sets the function called when the worker finishes a
+// run of back-to-back jobs and finds the queue empty. Unlike the idle
+// callback it fires immediately, before any idle timeout.
+func (q *Queue) SetDrainedCallback(fn DrainedCallback) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.drainedCallback = fn
+}
+
+// SetCoalescePlayback makes a run of back-to-back jobs last only while the
+// next job is already prepared by the prefetch, so the drained callback
+// also fires when the next job still has to be prepared. Without a
+// Preparer nothing is prefetched and every job ends its run.
+func (q *Queue) SetCoalescePlayback(enabled bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.coalescePlayback = enabled
+}
+
 // SetShutdownCallback sets the function called during graceful shutdown.
-// This is typically used to disconnect from voice channels.
+// This is typically used to disconnect from voice channels. It is safe to
+// call at any time; Stop runs the callback set when Stop was called.
 func (q *Queue) SetShutdownCallback(fn ShutdownCallback) {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 	q.shutdownCallback = fn
 }
 
-// SetJobCompletedCallback sets the function called after each job completes.
-// This is primarily useful for testing to enable deterministic synchronization.
+// SetJobCompletedCallback sets the function called after each job completes,
+// replacing any callbacks added before; nil removes them all. This is
+// primarily useful for testing to enable deterministic synchronization.
+// It is safe to call while the worker is running and takes effect from the
+// next job to finish.
 func (q *Queue) SetJobCompletedCallback(fn JobCompletedCallback) {
 	q.mu.Lock()
 	defer q.mu.Unlock()
-	q.jobCompletedCallback = fn
+	q.jobCompleted = nil
+	if fn != nil {
+		q.jobCompleted = []JobCompletedCallback{fn}
+	}
+}
+
+// AddJobCompletedCallback adds a function called after each job completes,
+// after the callbacks already registered, so several subscribers don't
+// replace each other. Like SetJobCompletedCallback, it is safe to call while
+// the worker is running. A nil fn is ignored.
+func (q *Queue) AddJobCompletedCallback(fn JobCompletedCallback) {
+	if fn == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.jobCompleted = append(q.jobCompleted, fn)
+}
+
+// SetHighWater sets the queue depth at which enqueues warn that the queue
+// is filling up, ahead of ErrQueueFull. Zero disables the warning.
+func (q *Queue) SetHighWater(depth int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.highWater = depth
+}
+
+// SetHighWaterCallback sets the function called each time an enqueue
+// brings the queue up to the high-water mark. It is not called again until
+// the depth has dropped below the mark. The callback runs in its own
+// goroutine so it can't hold up enqueues.
+func (q *Queue) SetHighWaterCallback(fn HighWaterCallback) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.highWaterCallback = fn
 }
 
 // Enqueue adds a job to the queue.
 func (q *Queue) Enqueue(job *SpeakJob) error {
 	q.mu.Lock()
 	defer q.mu.Unlock()
+	return q.enqueueLocked(job)
+}
+
+// EnqueueWait adds a job to the queue, blocking while it is full until
+// space frees up, ctx is done, or the queue is stopped.
+func (q *Queue) EnqueueWait(ctx context.Context, job *SpeakJob) error {
+	for {
+		q.mu.Lock()
+		// Time spent waiting for space doesn't count against the TTL
+		job.startTTL(time.Now())
+		err := q.enqueueLocked(job)
+		space := q.spaceCh
+		q.mu.Unlock()
+
+		if !errors.Is(err, ErrQueueFull) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-q.stopCh:
+			return ErrQueueClosed
+		case <-space:
+			// Space freed up; try again
+		}
+	}
+}
+
+// EnqueueFront puts a job at the head of the queue so it plays next. It is
+// meant for urgent messages: capacity is not enforced, but a duplicate
+// dedupe key is rejected as with Enqueue, or with ReplaceDuplicate the
+// queued job is replaced and the replacement moved to the front.
+func (q *Queue) EnqueueFront(job *SpeakJob) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	if job.ReplaceDuplicate && job.DedupeKey != "" && q.dedupeKeys[job.DedupeKey] {
+		q.replaceLocked(job)
+		if i := job.Position - 1; i > 0 {
+			copy(q.jobs[1:i+1], q.jobs[:i])
+			q.jobs[0] = job
+			job.Position = 1
+		}
+		return nil
+	}
+
+	if job.DedupeKey != "" && q.dedupeKeys[job.DedupeKey] {
+		q.metrics.Counter("queue_jobs_rejected_total", 1, "reason", "duplicate")
+		return ErrDuplicateJob
+	}
+
+	job.Position = 1
+	q.jobs = append([]*SpeakJob{job}, q.jobs...)
+	if job.DedupeKey != "" {
+		q.dedupeKeys[job.DedupeKey] = true
+	}
+	q.checkHighWaterLocked()
+
+	q.logger.Debug("job enqueued at front", "job_id", job.ID, "queue_depth", len(q.jobs))
+	q.emit(EventEnqueued, job, nil)
+	q.metrics.Counter("queue_jobs_enqueued_total", 1)
+	q.metrics.Gauge("queue_depth", float64(len(q.jobs)))
+
+	select {
+	case q.enqueueCh <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// EnqueueAll adds jobs to the queue in order, all or none: if the queue
+// lacks room for every job or any is a duplicate, nothing is enqueued.
+func (q *Queue) EnqueueAll(jobs []*SpeakJob) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
 
 	if q.closed {
 		return ErrQueueClosed
 	}
 
+	// Duplicates against the queue or within the batch; jobs replacing a
+	// queued one take no extra room
+	keys := make(map[string]bool, len(jobs))
+	added := len(jobs)
+	for _, job := range jobs {
+		if job.DedupeKey == "" {
+			continue
+		}
+		queued := q.dedupeKeys[job.DedupeKey]
+		if keys[job.DedupeKey] || (queued && !job.ReplaceDuplicate) {
+			q.metrics.Counter("queue_jobs_rejected_total", float64(len(jobs)), "reason", "duplicate")
+			return ErrDuplicateJob
+		}
+		if queued {
+			added--
+		}
+		keys[job.DedupeKey] = true
+	}
+
+	if len(q.jobs)+added > q.capacity {
+		q.metrics.Counter("queue_jobs_rejected_total", float64(len(jobs)), "reason", "full")
+		return ErrQueueFull
+	}
+
+	for _, job := range jobs {
+		if err := q.enqueueLocked(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnqueueEach adds jobs to the queue in order, without other enqueues in
+// between, and returns the Enqueue error for each job (nil if enqueued).
+func (q *Queue) EnqueueEach(jobs []*SpeakJob) []error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	errs := make([]error, len(jobs))
+	for i, job := range jobs {
+		errs[i] = q.enqueueLocked(job)
+	}
+	return errs
+}
+
+// enqueueLocked adds a job to the queue. q.mu must be held.
+func (q *Queue) enqueueLocked(job *SpeakJob) error {
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	if job.ReplaceDuplicate && job.DedupeKey != "" && q.dedupeKeys[job.DedupeKey] {
+		q.replaceLocked(job)
+		return nil
+	}
+
 	if len(q.jobs) >= q.capacity {
+		q.metrics.Counter("queue_jobs_rejected_total", 1, "reason", "full")
 		return ErrQueueFull
 	}
 
 	// Check for duplicate dedupe key
 	if job.DedupeKey != "" && q.dedupeKeys[job.DedupeKey] {
+		q.metrics.Counter("queue_jobs_rejected_total", 1, "reason", "duplicate")
 		return ErrDuplicateJob
 	}
 
+	job.Position = len(q.jobs) + 1
 	q.jobs = append(q.jobs, job)
 	if job.DedupeKey != "" {
 		q.dedupeKeys[job.DedupeKey] = true
 	}
+	q.checkHighWaterLocked()
 
 	q.logger.Debug("job enqueued", "job_id", job.ID, "queue_depth", len(q.jobs))
+	q.emit(EventEnqueued, job, nil)
+	q.metrics.Counter("queue_jobs_enqueued_total", 1)
+	q.metrics.Gauge("queue_depth", float64(len(q.jobs)))
 
-	// Signal the worker
+	// Signal the worker. The channel holds one pending wake-up, so a dropped
+	// send means one is already queued and the worker will re-check the jobs
+	// after receiving it; no enqueue can be missed.
 	select {
 	case q.enqueueCh <- struct{}{}:
 	default:
@@ -126,23 +487,87 @@ func (q *Queue) Enqueue(job *SpeakJob) error {
 	return nil
 }
 
-// Interrupt cancels the current playback and clears the queue.
-func (q *Queue) Interrupt() {
+// replaceLocked puts job in the place of the queued job with its dedupe
+// key, which must exist. q.mu must be held.
+func (q *Queue) replaceLocked(job *SpeakJob) {
+	i := slices.IndexFunc(q.jobs, func(j *SpeakJob) bool { return j.DedupeKey == job.DedupeKey })
+	old := q.jobs[i]
+	q.jobs[i] = job
+	job.Position = i + 1
+	job.Replaces = old.ID
+
+	// The worker won't play a prefetch for a job that is no longer queued
+	if q.prefetch != nil && q.prefetch.job == old {
+		q.cancelPrefetchLocked()
+	}
+
+	q.logger.Debug("job replaced", "job_id", job.ID, "replaced_job_id", old.ID, "position", job.Position)
+	q.emit(EventReplaced, old, nil)
+	q.emit(EventEnqueued, job, nil)
+	q.metrics.Counter("queue_jobs_replaced_total", 1)
+}
+
+// checkHighWaterLocked warns and calls the high-water callback if the job
+// just added brought the queue up to the mark from below it. q.mu must be
+// held.
+func (q *Queue) checkHighWaterLocked() {
+	depth := len(q.jobs)
+	if q.highWater <= 0 || depth != q.highWater {
+		return
+	}
+
+	q.metrics.Counter("queue_high_water_total", 1)
+	if now := q.clock.Now(); now.Sub(q.lastHighWaterWarn) >= highWaterWarnInterval {
+		q.lastHighWaterWarn = now
+		q.logger.Warn("queue reached high-water mark",
+			"queue_depth", depth,
+			"high_water", q.highWater,
+			"capacity", q.capacity,
+		)
+	}
+	if fn := q.highWaterCallback; fn != nil {
+		go fn(depth)
+	}
+}
+
+// signalSpaceLocked wakes every EnqueueWait caller after jobs leave the
+// queue. q.mu must be held.
+func (q *Queue) signalSpaceLocked() {
+	close(q.spaceCh)
+	q.spaceCh = make(chan struct{})
+}
+
+// Interrupt cancels the current playback and clears the queue, returning
+// the number of queued jobs cleared.
+func (q *Queue) Interrupt() int {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
-	// Cancel current playback
+	// Cancel current playback, or schedule it in soft mode. A second
+	// interrupt during the grace period cancels straight away.
 	if q.cancelCurrent != nil {
-		q.cancelCurrent()
-		q.cancelCurrent = nil
+		if q.interruptMode == InterruptSoft && q.interruptGrace > 0 && q.softInterrupt == nil {
+			q.logger.Info("soft interrupt, letting current job finish", "grace", q.interruptGrace)
+			q.softInterrupt = time.AfterFunc(q.interruptGrace, q.cancelCurrent)
+		} else {
+			q.cancelCurrent()
+		}
 	}
 
+	// Cancel any prefetch for a job that is about to be cleared
+	q.cancelPrefetchLocked()
+
 	// Clear the queue
 	cleared := len(q.jobs)
 	q.jobs = q.jobs[:0]
 	q.dedupeKeys = make(map[string]bool)
+	if cleared > 0 {
+		q.signalSpaceLocked()
+	}
 
 	q.logger.Info("queue interrupted", "jobs_cleared", cleared)
+	q.metrics.Gauge("queue_depth", 0)
+	return cleared
 }
 
 // Len returns the current queue length.
@@ -158,18 +583,28 @@ func (q *Queue) Start() {
 	go q.worker()
 }
 
-// Stop gracefully stops the worker and calls the shutdown callback.
+// Stop gracefully stops the worker and calls the shutdown callback once
+// the worker has exited. The job playing is cancelled and queued jobs are
+// not played. Stop may be called more than once and concurrently; the
+// shutdown callback runs only once, and every call returns after it has.
 func (q *Queue) Stop() {
 	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		<-q.stopped
+		return
+	}
 	q.closed = true
 	if q.cancelCurrent != nil {
 		q.cancelCurrent()
 	}
+	q.cancelPrefetchLocked()
 	shutdownCallback := q.shutdownCallback
 	q.mu.Unlock()
 
 	close(q.stopCh)
 	q.wg.Wait()
+	defer close(q.stopped)
 
 	// Call shutdown callback after worker has stopped
 	if shutdownCallback != nil {
@@ -182,16 +617,23 @@ func (q *Queue) Stop() {
 func (q *Queue) worker() {
 	defer q.wg.Done()
 
-	var idleTimer *time.Timer
-	var idleTimerCh <-chan time.Time
+	var idleTimer, delayTimer Timer
+	var idleTimerCh, delayTimerCh <-chan time.Time
+
+	// When the worker last went from idle to busy, for the minimum dwell
+	var active bool
+	var activeSince time.Time
+
+	// Whether a job has run since the queue was last found empty
+	var busy bool
 
 	resetIdleTimer := func() {
 		if idleTimer != nil {
 			idleTimer.Stop()
 		}
 		if q.idleTimeout > 0 {
-			idleTimer = time.NewTimer(q.idleTimeout)
-			idleTimerCh = idleTimer.C
+			idleTimer = q.clock.NewTimer(q.idleTimeout)
+			idleTimerCh = idleTimer.C()
 		}
 	}
 
@@ -200,50 +642,180 @@ func (q *Queue) worker() {
 			idleTimer.Stop()
 			idleTimerCh = nil
 		}
+		// A job arriving cancels a pending delayed idle callback
+		if delayTimer != nil {
+			delayTimer.Stop()
+			delayTimerCh = nil
+		}
 	}
 
+	fireIdle := func() {
+		q.mu.Lock()
+		callback := q.idleCallback
+		stay := q.stayConnected
+		closed := q.closed
+		q.mu.Unlock()
+
+		active = false
+		// The timer may have fired as Stop was called; leave cleanup to
+		// the shutdown callback
+		if closed {
+			return
+		}
+		if stay {
+			q.logger.Debug("idle timeout reached, staying connected")
+			return
+		}
+		if callback != nil {
+			q.logger.Info("idle timeout reached")
+			callback()
+		}
+	}
+
+	// Wakes the worker when deferring quiet hours end
+	var quietTimer Timer
+	var quietTimerCh <-chan time.Time
+
 	for {
-		// Try to get next job
-		job := q.dequeue()
+		if quietTimer != nil {
+			quietTimer.Stop()
+			quietTimer, quietTimerCh = nil, nil
+		}
+
+		// Try to get next job, unless quiet hours are holding jobs back
+		var job *SpeakJob
+		quiet, mode := q.quietNow()
+		if quiet > 0 && mode == QuietDefer {
+			quietTimer = q.clock.NewTimer(quiet)
+			quietTimerCh = quietTimer.C()
+		} else {
+			job = q.dequeue()
+		}
+
+		if job != nil && quiet > 0 {
+			q.dropQuiet(job)
+			continue
+		}
 
 		if job != nil {
 			stopIdleTimer()
+			if !active {
+				active = true
+				activeSince = q.clock.Now()
+			}
 			q.processJob(job)
+			busy = true
+			// With coalesced playback, a next job that isn't ready ends the run
+			if q.gapAhead() {
+				busy = false
+				q.fireDrained()
+			}
 			continue
 		}
 
+		if busy {
+			busy = false
+			q.fireDrained()
+		}
+
 		// Queue is empty, start idle timer if not already running
-		if idleTimerCh == nil && q.idleTimeout > 0 {
+		if idleTimerCh == nil && delayTimerCh == nil && q.idleTimeout > 0 {
 			resetIdleTimer()
 		}
 
 		select {
 		case <-q.stopCh:
 			stopIdleTimer()
+			if quietTimer != nil {
+				quietTimer.Stop()
+			}
 			return
 		case <-q.enqueueCh:
-			// New job available
+			// New job available; loop back to dequeue, which drains every
+			// job enqueued since the last check
 			continue
 		case <-idleTimerCh:
-			// Idle timeout reached
-			q.mu.Lock()
-			callback := q.idleCallback
-			q.mu.Unlock()
-
-			if callback != nil {
-				q.logger.Info("idle timeout reached")
-				callback()
-			}
+			// Idle timeout reached; hold off for the delay and minimum dwell
 			idleTimerCh = nil
+			if wait := q.idleDelayFor(active, activeSince); wait > 0 {
+				q.logger.Debug("idle timeout reached, delaying idle callback", "delay", wait)
+				delayTimer = q.clock.NewTimer(wait)
+				delayTimerCh = delayTimer.C()
+				continue
+			}
+			fireIdle()
+		case <-delayTimerCh:
+			delayTimerCh = nil
+			fireIdle()
+		case <-quietTimerCh:
+			// Quiet hours are over; loop back to play what waited
+			q.logger.Info("quiet hours ended")
 		}
 	}
 }
 
+// idleDelayFor returns how long to wait after the idle timeout before
+// calling the idle callback: the idle delay, or longer if the worker has
+// been active for less than the minimum dwell.
+func (q *Queue) idleDelayFor(active bool, activeSince time.Time) time.Duration {
+	q.mu.Lock()
+	delay, minDwell := q.idleDelay, q.minDwell
+	q.mu.Unlock()
+
+	if active {
+		if remaining := minDwell - q.clock.Now().Sub(activeSince); remaining > delay {
+			return remaining
+		}
+	}
+	return delay
+}
+
+// fireDrained calls the drained callback, if set.
+func (q *Queue) fireDrained() {
+	q.mu.Lock()
+	drained := q.drainedCallback
+	q.mu.Unlock()
+	if drained != nil {
+		drained()
+	}
+}
+
+// gapAhead reports whether coalesced playback must end its run before the
+// next queued job, because that job hasn't been prepared yet. It is false
+// when the queue is empty, which ends the run anyway.
+func (q *Queue) gapAhead() bool {
+	q.mu.Lock()
+	coalesce := q.coalescePlayback
+	p := q.prefetch
+	ready := p != nil && len(q.jobs) > 0 && q.jobs[0] == p.job
+	empty := len(q.jobs) == 0
+	q.mu.Unlock()
+
+	if !coalesce || empty {
+		return false
+	}
+	if !ready {
+		return true
+	}
+	select {
+	case <-p.done:
+		return p.err != nil
+	default:
+		return true
+	}
+}
+
 // dequeue removes and returns the next job from the queue.
 func (q *Queue) dequeue() *SpeakJob {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
+	// A stopped queue plays nothing more
+	if q.closed || len(q.jobs) == 0 {
+		return nil
+	}
+	defer q.signalSpaceLocked()
+
 	for len(q.jobs) > 0 {
 		job := q.jobs[0]
 		q.jobs = q.jobs[1:]
@@ -253,9 +825,12 @@ func (q *Queue) dequeue() *SpeakJob {
 			delete(q.dedupeKeys, job.DedupeKey)
 		}
 
+		q.metrics.Gauge("queue_depth", float64(len(q.jobs)))
+
 		// Skip expired jobs
 		if job.IsExpired() {
 			q.logger.Debug("skipping expired job", "job_id", job.ID)
+			q.metrics.Counter("queue_jobs_expired_total", 1)
 			continue
 		}
 
@@ -269,37 +844,231 @@ func (q *Queue) dequeue() *SpeakJob {
 func (q *Queue) processJob(job *SpeakJob) {
 	q.mu.Lock()
 	handler := q.playbackFunc
+	preparer := q.preparer
+	maxRetries := q.maxRetries
+	retryDelay := q.retryDelay
+	retryable := q.retryable
+	deadLetter := q.deadLetter
+	m := q.metrics
 	ctx, cancel := context.WithCancel(context.Background())
 	q.cancelCurrent = cancel
+	// Stop may have run since the job was dequeued, finding nothing to
+	// cancel
+	if q.closed {
+		cancel()
+	}
 	q.mu.Unlock()
 
 	defer func() {
 		cancel()
 		q.mu.Lock()
 		q.cancelCurrent = nil
-		completedCallback := q.jobCompletedCallback
+		if q.softInterrupt != nil {
+			q.softInterrupt.Stop()
+			q.softInterrupt = nil
+		}
+		// Appends only write past this copy's length, so it is safe to
+		// read without the lock
+		completed := q.jobCompleted
 		q.mu.Unlock()
 
-		// Notify completion callback after releasing lock
-		if completedCallback != nil {
-			completedCallback(job)
+		// Notify completion callbacks, in order, after releasing lock
+		for _, fn := range completed {
+			fn(job)
 		}
 	}()
 
-	if handler == nil {
+	if handler == nil && preparer == nil {
 		q.logger.Warn("no playback handler set, skipping job", "job_id", job.ID)
 		return
 	}
 
 	q.logger.Info("processing job", "job_id", job.ID, "text_length", len(job.Text))
+	q.emit(EventStarted, job, nil)
+	start := q.clock.Now()
+	m.Observe("queue_job_wait_seconds", start.Sub(job.CreatedAt).Seconds())
+
+	err := q.playJob(ctx, handler, preparer, job, 0)
+	attempts := 1
+
+	delay := retryDelay
+	for attempt := 1; attempt <= maxRetries && shouldRetry(err, retryable); attempt++ {
+		q.logger.Warn("job failed, retrying",
+			"job_id", job.ID,
+			"attempt", attempt,
+			"delay", delay,
+			"error", err,
+		)
 
-	if err := handler(ctx, job); err != nil {
+		timer := q.clock.NewTimer(delay)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			err = ctx.Err()
+			continue
+		}
+		delay *= 2
+
+		err = q.playJob(ctx, handler, preparer, job, attempt)
+		attempts++
+	}
+
+	if err != nil {
 		if errors.Is(err, context.Canceled) {
 			q.logger.Info("job cancelled", "job_id", job.ID)
+			m.Counter("queue_jobs_processed_total", 1, "result", "cancelled")
 		} else {
 			q.logger.Error("job failed", "job_id", job.ID, "error", err)
+			m.Counter("queue_jobs_processed_total", 1, "result", "failed")
+			if deadLetter != nil {
+				deadLetter.Add(newDeadLetterEntry(job, attempts, err, q.clock.Now()))
+			}
 		}
+		q.emit(EventFailed, job, err)
 	} else {
 		q.logger.Info("job completed", "job_id", job.ID)
+		m.Counter("queue_jobs_processed_total", 1, "result", "completed")
+		q.emit(EventCompleted, job, nil)
+	}
+	q.recordHistory(job, start, err)
+}
+
+// playJob makes one attempt at playing job, converting a handler panic
+// into ErrHandlerPanic so the worker keeps running.
+func (q *Queue) playJob(ctx context.Context, handler PlaybackHandler, preparer Preparer, job *SpeakJob, attempt int) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			q.logger.Error("playback handler panicked",
+				"job_id", job.ID,
+				"panic", r,
+				"stack", string(debug.Stack()),
+			)
+			err = fmt.Errorf("%w: %v", ErrHandlerPanic, r)
+		}
+	}()
+
+	if preparer == nil {
+		return handler(ctx, job)
+	}
+	if attempt == 0 {
+		return q.prepareAndPlay(ctx, preparer, job)
+	}
+
+	// On retries the prefetch slot holds the next job, so prepare directly
+	prepared, err := preparer.Prepare(ctx, job)
+	if err != nil {
+		return err
+	}
+	return preparer.Play(ctx, prepared)
+}
+
+// shouldRetry reports whether a failed job should be attempted again.
+// Panics are not retried since they usually indicate a bug.
+func shouldRetry(err error, retryable func(error) bool) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, ErrHandlerPanic) {
+		return false
+	}
+	return retryable == nil || retryable(err)
+}
+
+// prepareAndPlay plays a job with a Preparer, reusing a matching prefetch
+// and starting a prefetch for the next queued job before playing.
+func (q *Queue) prepareAndPlay(ctx context.Context, preparer Preparer, job *SpeakJob) error {
+	prepared, err := q.takePrefetch(ctx, job)
+	if err != nil {
+		return err
+	}
+
+	if prepared == nil {
+		prepared, err = preparer.Prepare(ctx, job)
+		if err != nil {
+			return err
+		}
+	}
+
+	q.startPrefetch(preparer)
+
+	return preparer.Play(ctx, prepared)
+}
+
+// takePrefetch returns the prefetched result for job, waiting for it to finish.
+// It returns nil without error if there is no usable prefetch for the job.
+func (q *Queue) takePrefetch(ctx context.Context, job *SpeakJob) (*PreparedJob, error) {
+	q.mu.Lock()
+	p := q.prefetch
+	q.prefetch = nil
+	q.mu.Unlock()
+
+	if p == nil {
+		return nil, nil
+	}
+
+	if p.job != job {
+		// The prefetched job expired or was otherwise skipped
+		p.cancel()
+		return nil, nil
+	}
+
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		p.cancel()
+		return nil, ctx.Err()
+	}
+	p.cancel()
+
+	if p.err != nil {
+		if errors.Is(p.err, context.Canceled) {
+			// Prefetch was abandoned; prepare again in the job's own context
+			return nil, nil
+		}
+		return nil, p.err
+	}
+
+	q.logger.Debug("using prefetched job", "job_id", job.ID)
+	return p.prepared, nil
+}
+
+// startPrefetch begins preparing the job at the head of the queue, if any.
+func (q *Queue) startPrefetch(preparer Preparer) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed || q.prefetch != nil || len(q.jobs) == 0 {
+		return
+	}
+
+	next := q.jobs[0]
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &prefetch{
+		job:    next,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	q.prefetch = p
+
+	q.logger.Debug("prefetching next job", "job_id", next.ID)
+
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		defer close(p.done)
+		defer func() {
+			if r := recover(); r != nil {
+				q.logger.Error("prefetch panicked", "job_id", next.ID, "panic", r)
+				p.prepared, p.err = nil, fmt.Errorf("%w: %v", ErrHandlerPanic, r)
+			}
+		}()
+		p.prepared, p.err = preparer.Prepare(ctx, next)
+	}()
+}
+
+// cancelPrefetchLocked cancels and discards any in-flight prefetch.
+// The caller must hold q.mu.
+func (q *Queue) cancelPrefetchLocked() {
+	if q.prefetch != nil {
+		q.prefetch.cancel()
+		q.prefetch = nil
 	}
 }
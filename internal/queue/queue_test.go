@@ -2,13 +2,16 @@ package queue
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/dgnsrekt/discorgeous-go/internal/logging"
+	"github.com/dgnsrekt/discorgeous-go/internal/metrics"
 )
 
 // testTimeout is the maximum time to wait for any test condition.
@@ -53,6 +56,289 @@ func TestQueueCapacity(t *testing.T) {
 	}
 }
 
+func TestQueueEnqueueFront(t *testing.T) {
+	q := NewQueue(1, 5*time.Minute, testLogger())
+	q.Enqueue(NewSpeakJob("Queued", "default", false, 0, ""))
+
+	// Full, but an urgent job still goes in ahead of the rest
+	if err := q.EnqueueFront(NewSpeakJob("Urgent", "default", true, 0, "")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q.Len() != 2 {
+		t.Errorf("expected queue length 2, got %d", q.Len())
+	}
+	if job := q.dequeue(); job.Text != "Urgent" {
+		t.Errorf("expected urgent job first, got %q", job.Text)
+	}
+}
+
+func TestQueueEnqueueFrontDuplicate(t *testing.T) {
+	q := NewQueue(10, 5*time.Minute, testLogger())
+	q.Enqueue(NewSpeakJob("Queued", "default", false, 0, "alert"))
+
+	if err := q.EnqueueFront(NewSpeakJob("Urgent", "default", true, 0, "alert")); err != ErrDuplicateJob {
+		t.Errorf("EnqueueFront() error = %v, want ErrDuplicateJob", err)
+	}
+	if q.Len() != 1 {
+		t.Errorf("expected queue length 1, got %d", q.Len())
+	}
+}
+
+func TestQueueEnqueueFrontReplace(t *testing.T) {
+	q := NewQueue(2, 5*time.Minute, testLogger())
+	q.Enqueue(NewSpeakJob("First", "default", false, 0, ""))
+	old := NewSpeakJob("Old alert", "default", false, 0, "alert")
+	q.Enqueue(old)
+
+	job := NewSpeakJob("New alert", "default", true, 0, "alert")
+	job.ReplaceDuplicate = true
+	if err := q.EnqueueFront(job); err != nil {
+		t.Fatalf("EnqueueFront() error = %v, want the queued duplicate replaced", err)
+	}
+	if job.Position != 1 || job.Replaces != old.ID {
+		t.Errorf("Position = %d, Replaces = %q, want 1 and %q", job.Position, job.Replaces, old.ID)
+	}
+
+	var got []string
+	for j := q.dequeue(); j != nil; j = q.dequeue() {
+		got = append(got, j.Text)
+	}
+	if want := []string{"New alert", "First"}; !slices.Equal(got, want) {
+		t.Errorf("queue = %v, want %v", got, want)
+	}
+}
+
+func TestQueueEnqueueAll(t *testing.T) {
+	q := NewQueue(3, 5*time.Minute, testLogger())
+	q.Enqueue(NewSpeakJob("Existing", "default", false, 0, ""))
+
+	// Three more do not fit alongside the existing job
+	batch := []*SpeakJob{
+		NewSpeakJob("One", "default", false, 0, ""),
+		NewSpeakJob("Two", "default", false, 0, ""),
+		NewSpeakJob("Three", "default", false, 0, ""),
+	}
+	if err := q.EnqueueAll(batch); err != ErrQueueFull {
+		t.Errorf("expected ErrQueueFull, got %v", err)
+	}
+	if q.Len() != 1 {
+		t.Errorf("expected a rejected batch to leave length 1, got %d", q.Len())
+	}
+
+	// Duplicate keys within the batch reject it too
+	batch = []*SpeakJob{
+		NewSpeakJob("One", "default", false, 0, "key"),
+		NewSpeakJob("Two", "default", false, 0, "key"),
+	}
+	if err := q.EnqueueAll(batch); err != ErrDuplicateJob {
+		t.Errorf("expected ErrDuplicateJob, got %v", err)
+	}
+
+	batch = batch[:1]
+	batch = append(batch, NewSpeakJob("Two", "default", false, 0, ""))
+	if err := q.EnqueueAll(batch); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q.Len() != 3 {
+		t.Errorf("expected queue length 3, got %d", q.Len())
+	}
+	if job := q.dequeue(); job.Text != "Existing" {
+		t.Errorf("expected existing job first, got %q", job.Text)
+	}
+	if job := q.dequeue(); job.Text != "One" {
+		t.Errorf("expected batch order to be kept, got %q", job.Text)
+	}
+}
+
+func TestQueueEnqueueEach(t *testing.T) {
+	q := NewQueue(2, 5*time.Minute, testLogger())
+
+	errs := q.EnqueueEach([]*SpeakJob{
+		NewSpeakJob("One", "default", false, 0, ""),
+		NewSpeakJob("Two", "default", false, 0, ""),
+		NewSpeakJob("Three", "default", false, 0, ""),
+	})
+
+	if errs[0] != nil || errs[1] != nil {
+		t.Errorf("expected the first two to be enqueued, got %v", errs)
+	}
+	if errs[2] != ErrQueueFull {
+		t.Errorf("expected ErrQueueFull for the third, got %v", errs[2])
+	}
+	if q.Len() != 2 {
+		t.Errorf("expected queue length 2, got %d", q.Len())
+	}
+}
+
+func TestHighWaterCallback(t *testing.T) {
+	q := NewQueue(10, 5*time.Minute, testLogger())
+	q.SetHighWater(3)
+	crossed := make(chan int, 10)
+	q.SetHighWaterCallback(func(depth int) { crossed <- depth })
+
+	enqueue := func(n int) {
+		t.Helper()
+		for range n {
+			if err := q.Enqueue(NewSpeakJob("Hello", "default", false, 0, "")); err != nil {
+				t.Fatalf("Enqueue() error = %v", err)
+			}
+		}
+	}
+	expectCrossings := func(want int) {
+		t.Helper()
+		for range want {
+			select {
+			case depth := <-crossed:
+				if depth != 3 {
+					t.Errorf("callback depth = %d, want 3", depth)
+				}
+			case <-time.After(testTimeout):
+				t.Fatal("timeout waiting for high-water callback")
+			}
+		}
+		// The callback runs in a goroutine; give a spurious call a chance
+		select {
+		case depth := <-crossed:
+			t.Fatalf("unexpected high-water callback at depth %d", depth)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	enqueue(2)
+	expectCrossings(0)
+
+	enqueue(1)
+	expectCrossings(1)
+
+	// Hovering at or above the mark doesn't fire again
+	enqueue(2)
+	q.dequeue()
+	q.dequeue()
+	enqueue(1)
+	expectCrossings(0)
+
+	// Dropping below and rising again is a new crossing
+	for q.Len() >= 3 {
+		q.dequeue()
+	}
+	enqueue(1)
+	expectCrossings(1)
+}
+
+func TestHighWaterDisabled(t *testing.T) {
+	q := NewQueue(3, 5*time.Minute, testLogger())
+	called := make(chan int, 3)
+	q.SetHighWaterCallback(func(depth int) { called <- depth })
+
+	for range 3 {
+		if err := q.Enqueue(NewSpeakJob("Hello", "default", false, 0, "")); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+
+	select {
+	case depth := <-called:
+		t.Errorf("callback called at depth %d without a high-water mark", depth)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestEnqueueWaitBlocksUntilSpace(t *testing.T) {
+	q := NewQueue(1, 5*time.Minute, testLogger())
+
+	if err := q.Enqueue(NewSpeakJob("Hello", "default", false, 0, "")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	job := NewSpeakJob("World", "default", false, time.Minute, "")
+	result := make(chan error, 1)
+	go func() {
+		result <- q.EnqueueWait(context.Background(), job)
+	}()
+
+	select {
+	case err := <-result:
+		t.Fatalf("EnqueueWait() returned %v before space was available", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Taking a job off the queue frees a slot
+	if job := q.dequeue(); job == nil {
+		t.Fatal("expected a job to dequeue")
+	}
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Errorf("EnqueueWait() error = %v", err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("timeout waiting for EnqueueWait")
+	}
+
+	if q.Len() != 1 {
+		t.Errorf("expected queue length 1, got %d", q.Len())
+	}
+	// The TTL runs from when the job was queued, not created
+	if min := job.CreatedAt.Add(job.TTL + 50*time.Millisecond); job.ExpiresAt.Before(min) {
+		t.Errorf("ExpiresAt = %v, want at least %v after the wait", job.ExpiresAt, min)
+	}
+}
+
+func TestEnqueueWaitContextCancelled(t *testing.T) {
+	q := NewQueue(1, 5*time.Minute, testLogger())
+
+	if err := q.Enqueue(NewSpeakJob("Hello", "default", false, 0, "")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := q.EnqueueWait(ctx, NewSpeakJob("World", "default", false, 0, ""))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestEnqueueWaitQueueStopped(t *testing.T) {
+	q := NewQueue(1, 5*time.Minute, testLogger())
+	started := make(chan struct{})
+	q.SetPlaybackHandler(func(ctx context.Context, job *SpeakJob) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	q.Start()
+
+	// One job playing, one filling the queue
+	q.Enqueue(NewSpeakJob("Playing", "default", false, 0, ""))
+	select {
+	case <-started:
+	case <-time.After(testTimeout):
+		t.Fatal("timeout waiting for playback to start")
+	}
+	if err := q.Enqueue(NewSpeakJob("Hello", "default", false, 0, "")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		result <- q.EnqueueWait(context.Background(), NewSpeakJob("World", "default", false, 0, ""))
+	}()
+
+	q.Stop()
+
+	select {
+	case err := <-result:
+		if !errors.Is(err, ErrQueueClosed) {
+			t.Errorf("expected ErrQueueClosed, got %v", err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("timeout waiting for EnqueueWait")
+	}
+}
+
 func TestQueueDeduplication(t *testing.T) {
 	q := NewQueue(10, 5*time.Minute, testLogger())
 
@@ -69,6 +355,91 @@ func TestQueueDeduplication(t *testing.T) {
 	}
 }
 
+func TestQueueDeduplicationReplace(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		replace  bool
+		wantErr  error
+		wantText []string
+	}{
+		{"reject", 4, false, ErrDuplicateJob, []string{"First", "Status: building", "Last"}},
+		// A full queue, as a replacement takes no room of its own
+		{"replace", 3, true, nil, []string{"First", "Status: deployed", "Last"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewQueue(tt.capacity, 5*time.Minute, testLogger())
+			old := NewSpeakJob("Status: building", "default", false, 0, "status")
+			for _, job := range []*SpeakJob{
+				NewSpeakJob("First", "default", false, 0, ""),
+				old,
+				NewSpeakJob("Last", "default", false, 0, ""),
+			} {
+				if err := q.Enqueue(job); err != nil {
+					t.Fatalf("Enqueue() error = %v", err)
+				}
+			}
+			events, unsubscribe := q.Subscribe()
+			defer unsubscribe()
+
+			job := NewSpeakJob("Status: deployed", "default", false, 0, "status")
+			job.ReplaceDuplicate = tt.replace
+			if err := q.Enqueue(job); err != tt.wantErr {
+				t.Fatalf("Enqueue() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.replace {
+				if job.Position != 2 || job.Replaces != old.ID {
+					t.Errorf("Position = %d, Replaces = %q, want 2 and %q", job.Position, job.Replaces, old.ID)
+				}
+				for _, want := range []Event{{Type: EventReplaced, JobID: old.ID}, {Type: EventEnqueued, JobID: job.ID}} {
+					select {
+					case ev := <-events:
+						if ev.Type != want.Type || ev.JobID != want.JobID {
+							t.Errorf("event = %s %s, want %s %s", ev.Type, ev.JobID, want.Type, want.JobID)
+						}
+					case <-time.After(testTimeout):
+						t.Fatal("timeout waiting for event")
+					}
+				}
+			}
+
+			var got []string
+			for j := q.dequeue(); j != nil; j = q.dequeue() {
+				got = append(got, j.Text)
+			}
+			if !slices.Equal(got, tt.wantText) {
+				t.Errorf("queue = %v, want %v", got, tt.wantText)
+			}
+
+			// The key is released along with the job holding it
+			if err := q.Enqueue(NewSpeakJob("Again", "default", false, 0, "status")); err != nil {
+				t.Errorf("Enqueue() after draining error = %v", err)
+			}
+		})
+	}
+}
+
+func TestQueueEnqueueAllReplace(t *testing.T) {
+	q := NewQueue(2, 5*time.Minute, testLogger())
+	q.Enqueue(NewSpeakJob("Old status", "default", false, 0, "status"))
+	q.Enqueue(NewSpeakJob("Other", "default", false, 0, ""))
+
+	replacement := NewSpeakJob("New status", "default", false, 0, "status")
+	replacement.ReplaceDuplicate = true
+	if err := q.EnqueueAll([]*SpeakJob{replacement}); err != nil {
+		t.Fatalf("EnqueueAll() error = %v, want a replacement to fit a full queue", err)
+	}
+	if q.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", q.Len())
+	}
+	if job := q.dequeue(); job != replacement {
+		t.Errorf("head = %q, want the replacement", job.Text)
+	}
+}
+
 func TestQueueDeduplicationEmptyKey(t *testing.T) {
 	q := NewQueue(10, 5*time.Minute, testLogger())
 
@@ -100,6 +471,37 @@ func TestQueueClosed(t *testing.T) {
 	}
 }
 
+func TestQueuePosition(t *testing.T) {
+	q := NewQueue(10, 5*time.Minute, testLogger())
+
+	for want := 1; want <= 3; want++ {
+		job := NewSpeakJob("Hello", "default", false, 0, "")
+		if err := q.Enqueue(job); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+		if job.Position != want {
+			t.Errorf("job %d Position = %d, want %d", want, job.Position, want)
+		}
+	}
+
+	urgent := NewSpeakJob("Urgent", "default", true, 0, "")
+	if err := q.EnqueueFront(urgent); err != nil {
+		t.Fatalf("EnqueueFront() error = %v", err)
+	}
+	if urgent.Position != 1 {
+		t.Errorf("front job Position = %d, want 1", urgent.Position)
+	}
+
+	q.Interrupt()
+	job := NewSpeakJob("After", "default", false, 0, "")
+	if err := q.Enqueue(job); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if job.Position != 1 {
+		t.Errorf("Position after interrupt = %d, want 1", job.Position)
+	}
+}
+
 func TestQueueInterrupt(t *testing.T) {
 	q := NewQueue(10, 5*time.Minute, testLogger())
 
@@ -280,134 +682,822 @@ func TestWorkerCancelCurrentJob(t *testing.T) {
 	}
 }
 
-func TestIdleCallback(t *testing.T) {
-	idleTimeout := 50 * time.Millisecond
-	q := NewQueue(10, idleTimeout, testLogger())
-
-	idleCalled := make(chan struct{})
-	jobDone := make(chan struct{})
+func TestSoftInterruptCancelsAfterGrace(t *testing.T) {
+	q := NewQueue(10, 5*time.Minute, testLogger())
+	q.SetInterruptMode(InterruptSoft, 100*time.Millisecond)
 
-	q.SetIdleCallback(func() {
-		close(idleCalled)
-	})
+	started := make(chan struct{})
+	cancelledAt := make(chan time.Time, 1)
 
 	q.SetPlaybackHandler(func(ctx context.Context, job *SpeakJob) error {
-		return nil
-	})
-
-	q.SetJobCompletedCallback(func(job *SpeakJob) {
-		close(jobDone)
+		close(started)
+		<-ctx.Done()
+		cancelledAt <- time.Now()
+		return ctx.Err()
 	})
 
 	q.Start()
 	defer q.Stop()
 
-	// Enqueue a job to process
-	q.Enqueue(NewSpeakJob("Hello", "default", false, 0, ""))
+	q.Enqueue(NewSpeakJob("Long running", "default", false, 0, ""))
 
-	// Wait for job to complete
 	select {
-	case <-jobDone:
-		// Job completed
+	case <-started:
 	case <-time.After(testTimeout):
-		t.Fatal("timeout waiting for job to complete")
+		t.Fatal("timeout waiting for job to start")
 	}
 
-	// Wait for idle callback with timeout failsafe
+	interruptedAt := time.Now()
+	q.Interrupt()
+
 	select {
-	case <-idleCalled:
-		// Success
+	case at := <-cancelledAt:
+		if elapsed := at.Sub(interruptedAt); elapsed < 100*time.Millisecond {
+			t.Errorf("job cancelled after %v, want at least the 100ms grace", elapsed)
+		}
 	case <-time.After(testTimeout):
-		t.Fatal("timeout waiting for idle callback")
+		t.Fatal("timeout waiting for job cancellation")
 	}
 }
 
-func TestIdleCallbackNotCalledWhileProcessing(t *testing.T) {
-	idleTimeout := 20 * time.Millisecond
-	q := NewQueue(10, idleTimeout, testLogger())
+func TestSoftInterruptLetsJobFinish(t *testing.T) {
+	q := NewQueue(10, 5*time.Minute, testLogger())
+	q.SetInterruptMode(InterruptSoft, time.Second)
 
-	var idleCalledDuringProcessing atomic.Bool
-	var processingComplete atomic.Bool
-	processingDone := make(chan struct{})
-	idleCalled := make(chan struct{})
-	continueProcessing := make(chan struct{})
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	result := make(chan error, 1)
 
-	q.SetIdleCallback(func() {
-		if !processingComplete.Load() {
-			idleCalledDuringProcessing.Store(true)
-		}
+	q.SetPlaybackHandler(func(ctx context.Context, job *SpeakJob) error {
+		close(started)
 		select {
-		case <-idleCalled:
-			// Already closed
-		default:
-			close(idleCalled)
+		case <-finish:
+			result <- nil
+		case <-ctx.Done():
+			result <- ctx.Err()
 		}
-	})
-
-	q.SetPlaybackHandler(func(ctx context.Context, job *SpeakJob) error {
-		// Wait longer than idle timeout, but use a channel for determinism
-		<-continueProcessing
 		return nil
 	})
 
-	q.SetJobCompletedCallback(func(job *SpeakJob) {
-		processingComplete.Store(true)
-		close(processingDone)
-	})
-
 	q.Start()
 	defer q.Stop()
 
-	q.Enqueue(NewSpeakJob("Hello", "default", false, 0, ""))
-
-	// Let the idle timeout pass while processing
-	time.Sleep(idleTimeout * 3)
-
-	// Now let processing complete
-	close(continueProcessing)
+	q.Enqueue(NewSpeakJob("Short", "default", false, 0, ""))
 
-	// Wait for processing to complete
 	select {
-	case <-processingDone:
-		// Success
+	case <-started:
 	case <-time.After(testTimeout):
-		t.Fatal("timeout waiting for processing to complete")
+		t.Fatal("timeout waiting for job to start")
 	}
 
-	// Wait for idle callback
+	q.Interrupt()
+	close(finish)
+
 	select {
-	case <-idleCalled:
-		// Success
+	case err := <-result:
+		if err != nil {
+			t.Errorf("job error = %v, want it to finish uninterrupted", err)
+		}
 	case <-time.After(testTimeout):
-		t.Fatal("timeout waiting for idle callback")
-	}
-
-	if idleCalledDuringProcessing.Load() {
-		t.Error("idle callback was called during processing")
+		t.Fatal("timeout waiting for job to finish")
 	}
 }
 
-func TestNoPlaybackHandler(t *testing.T) {
+func TestSoftInterruptRepeatCancelsImmediately(t *testing.T) {
 	q := NewQueue(10, 5*time.Minute, testLogger())
+	q.SetInterruptMode(InterruptSoft, time.Minute)
 
-	jobDone := make(chan struct{})
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
 
-	// Don't set a playback handler, but set completion callback
-	q.SetJobCompletedCallback(func(job *SpeakJob) {
-		close(jobDone)
+	q.SetPlaybackHandler(func(ctx context.Context, job *SpeakJob) error {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
 	})
 
 	q.Start()
 	defer q.Stop()
 
-	q.Enqueue(NewSpeakJob("Hello", "default", false, 0, ""))
+	q.Enqueue(NewSpeakJob("Long running", "default", false, 0, ""))
 
-	// Wait for job to be processed (skipped) with timeout failsafe
 	select {
-	case <-jobDone:
-		// Success
+	case <-started:
 	case <-time.After(testTimeout):
-		t.Fatal("timeout waiting for job to be processed")
+		t.Fatal("timeout waiting for job to start")
+	}
+
+	q.Interrupt()
+	select {
+	case <-cancelled:
+		t.Fatal("first soft interrupt cancelled the job before the grace period")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	q.Interrupt()
+	select {
+	case <-cancelled:
+	case <-time.After(testTimeout):
+		t.Fatal("second interrupt did not cancel the job")
+	}
+}
+
+func TestStopCancelsDuringSoftInterruptGrace(t *testing.T) {
+	q := NewQueue(10, 5*time.Minute, testLogger())
+	q.SetInterruptMode(InterruptSoft, time.Minute)
+
+	started := make(chan struct{})
+	q.SetPlaybackHandler(func(ctx context.Context, job *SpeakJob) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	q.Start()
+	q.Enqueue(NewSpeakJob("Long running", "default", false, 0, ""))
+
+	select {
+	case <-started:
+	case <-time.After(testTimeout):
+		t.Fatal("timeout waiting for job to start")
+	}
+
+	q.Interrupt()
+
+	stopped := make(chan struct{})
+	go func() {
+		q.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(testTimeout):
+		t.Fatal("Stop() waited out the soft interrupt grace period")
+	}
+}
+
+func TestRetryFlakyHandler(t *testing.T) {
+	q := NewQueue(10, 5*time.Minute, testLogger())
+	q.SetRetryPolicy(2, time.Millisecond, nil)
+
+	var attempts atomic.Int32
+	q.SetPlaybackHandler(func(ctx context.Context, job *SpeakJob) error {
+		if attempts.Add(1) == 1 {
+			return errors.New("transient failure")
+		}
+		return nil
+	})
+
+	events, unsubscribe := q.Subscribe()
+	defer unsubscribe()
+
+	q.Start()
+	defer q.Stop()
+
+	q.Enqueue(NewSpeakJob("Flaky", "default", false, 0, ""))
+
+	for {
+		select {
+		case ev := <-events:
+			switch ev.Type {
+			case EventFailed:
+				t.Fatalf("job failed: %s", ev.Error)
+			case EventCompleted:
+				if got := attempts.Load(); got != 2 {
+					t.Errorf("attempts = %d, want 2", got)
+				}
+				return
+			}
+		case <-time.After(testTimeout):
+			t.Fatal("timeout waiting for job to complete")
+		}
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		retryable func(error) bool
+		want      int32
+	}{
+		{"exhausts retries", errors.New("still broken"), nil, 3},
+		{"permanent error", errPermanentTest, func(err error) bool { return !errors.Is(err, errPermanentTest) }, 1},
+		{"cancelled", context.Canceled, nil, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewQueue(10, 5*time.Minute, testLogger())
+			q.SetRetryPolicy(2, time.Millisecond, tt.retryable)
+
+			var attempts atomic.Int32
+			q.SetPlaybackHandler(func(ctx context.Context, job *SpeakJob) error {
+				attempts.Add(1)
+				return tt.err
+			})
+
+			jobDone := make(chan struct{})
+			q.SetJobCompletedCallback(func(job *SpeakJob) {
+				close(jobDone)
+			})
+
+			q.Start()
+			defer q.Stop()
+
+			q.Enqueue(NewSpeakJob("Broken", "default", false, 0, ""))
+
+			select {
+			case <-jobDone:
+			case <-time.After(testTimeout):
+				t.Fatal("timeout waiting for job to finish")
+			}
+
+			if got := attempts.Load(); got != tt.want {
+				t.Errorf("attempts = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+var errPermanentTest = errors.New("permanent failure")
+
+func TestRetryBackoffUsesClock(t *testing.T) {
+	clock := newFakeClock()
+	q := NewQueueWithClock(10, 5*time.Minute, testLogger(), clock)
+	q.SetRetryPolicy(1, time.Hour, nil)
+
+	var attempts atomic.Int32
+	q.SetPlaybackHandler(func(ctx context.Context, job *SpeakJob) error {
+		if attempts.Add(1) == 1 {
+			return errors.New("flaky")
+		}
+		return nil
+	})
+	jobDone := make(chan struct{})
+	q.SetJobCompletedCallback(func(job *SpeakJob) {
+		close(jobDone)
+	})
+
+	q.Start()
+	defer q.Stop()
+
+	q.Enqueue(NewSpeakJob("Flaky", "default", false, 0, ""))
+
+	// The retry waits on the queue's clock, not the wall clock
+	clock.waitForTimer(t)
+	if got := attempts.Load(); got != 1 {
+		t.Fatalf("attempts = %d before the backoff elapsed, want 1", got)
+	}
+	clock.Advance(time.Hour)
+
+	select {
+	case <-jobDone:
+	case <-time.After(testTimeout):
+		t.Fatal("timeout waiting for retry")
+	}
+	if got := attempts.Load(); got != 2 {
+		t.Errorf("attempts = %d, want 2", got)
+	}
+}
+
+func TestWorkerRecoversFromHandlerPanic(t *testing.T) {
+	q := NewQueue(10, 5*time.Minute, testLogger())
+
+	var processed []string
+	var mu sync.Mutex
+	done := make(chan struct{})
+
+	q.SetPlaybackHandler(func(ctx context.Context, job *SpeakJob) error {
+		if job.Text == "Panic" {
+			panic("engine exploded")
+		}
+		mu.Lock()
+		processed = append(processed, job.Text)
+		mu.Unlock()
+		if job.Text == "After" {
+			close(done)
+		}
+		return nil
+	})
+
+	events, unsubscribe := q.Subscribe()
+	defer unsubscribe()
+
+	q.Enqueue(NewSpeakJob("Panic", "default", false, 0, ""))
+	q.Enqueue(NewSpeakJob("After", "default", false, 0, ""))
+
+	q.Start()
+	defer q.Stop()
+
+	select {
+	case <-done:
+	case <-time.After(testTimeout):
+		t.Fatal("timeout waiting for job after panic to process")
+	}
+
+	mu.Lock()
+	if len(processed) != 1 || processed[0] != "After" {
+		t.Errorf("processed = %v, want [After]", processed)
+	}
+	mu.Unlock()
+
+	for {
+		select {
+		case ev := <-events:
+			if ev.Type == EventFailed {
+				if ev.Error == "" {
+					t.Error("failed event has no error")
+				}
+				return
+			}
+		case <-time.After(testTimeout):
+			t.Fatal("timeout waiting for failed event for panicking job")
+		}
+	}
+}
+
+func TestConcurrentEnqueueAllProcessed(t *testing.T) {
+	const producers, perProducer = 50, 20
+	total := producers * perProducer
+	q := NewQueue(total, 5*time.Minute, testLogger())
+
+	var processed atomic.Int32
+	allDone := make(chan struct{})
+
+	q.SetPlaybackHandler(func(ctx context.Context, job *SpeakJob) error {
+		return nil
+	})
+
+	q.SetJobCompletedCallback(func(job *SpeakJob) {
+		if processed.Add(1) == int32(total) {
+			close(allDone)
+		}
+	})
+
+	q.Start()
+	defer q.Stop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < producers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perProducer; j++ {
+				if err := q.Enqueue(NewSpeakJob("Hello", "default", false, 0, "")); err != nil {
+					t.Errorf("Enqueue() error = %v", err)
+				}
+				// Give the worker a chance to drain and go back to waiting
+				if j%5 == 0 {
+					time.Sleep(time.Microsecond)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	select {
+	case <-allDone:
+		// Success
+	case <-time.After(testTimeout):
+		t.Fatalf("processed %d of %d jobs", processed.Load(), total)
+	}
+}
+
+// fakeClock is a Clock whose timers only fire when the test advances it.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(0, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	timer := &fakeTimer{clock: c, deadline: c.now.Add(d), ch: make(chan time.Time, 1)}
+	c.timers = append(c.timers, timer)
+	return timer
+}
+
+// Advance moves the clock forward, firing every timer that comes due.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	for _, timer := range c.timers {
+		if !timer.done && !timer.deadline.After(c.now) {
+			timer.done = true
+			timer.ch <- c.now
+		}
+	}
+}
+
+// waitForTimer blocks until some timer is pending, i.e. the worker is idle
+// and waiting on it.
+func (c *fakeClock) waitForTimer(t *testing.T) {
+	t.Helper()
+	deadline := time.Now().Add(testTimeout)
+	for time.Now().Before(deadline) {
+		c.mu.Lock()
+		for _, timer := range c.timers {
+			if !timer.done {
+				c.mu.Unlock()
+				return
+			}
+		}
+		c.mu.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("timeout waiting for idle timer")
+}
+
+type fakeTimer struct {
+	clock    *fakeClock
+	deadline time.Time
+	done     bool
+	ch       chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.ch }
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	wasPending := !t.done
+	t.done = true
+	return wasPending
+}
+
+func TestDrainedCallbackOncePerRun(t *testing.T) {
+	q := NewQueue(10, 5*time.Minute, testLogger())
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 10)
+	var processed atomic.Int32
+	drained := make(chan int32, 10)
+
+	q.SetPlaybackHandler(func(ctx context.Context, job *SpeakJob) error {
+		started <- struct{}{}
+		<-release
+		processed.Add(1)
+		return nil
+	})
+	q.SetDrainedCallback(func() {
+		drained <- processed.Load()
+	})
+
+	q.Start()
+	defer q.Stop()
+
+	// A run of three back-to-back jobs drains once, after the last
+	q.Enqueue(NewSpeakJob("One", "default", false, 0, ""))
+	<-started
+	q.Enqueue(NewSpeakJob("Two", "default", false, 0, ""))
+	q.Enqueue(NewSpeakJob("Three", "default", false, 0, ""))
+	close(release)
+
+	select {
+	case n := <-drained:
+		if n != 3 {
+			t.Errorf("drained after %d jobs, want 3", n)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("timeout waiting for drained callback")
+	}
+
+	// A later job starts a new run
+	q.Enqueue(NewSpeakJob("Four", "default", false, 0, ""))
+	select {
+	case n := <-drained:
+		if n != 4 {
+			t.Errorf("drained after %d jobs, want 4", n)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("timeout waiting for second drained callback")
+	}
+
+	select {
+	case <-drained:
+		t.Error("drained callback called more than once per run")
+	default:
+	}
+}
+
+func TestIdleCallback(t *testing.T) {
+	idleTimeout := 5 * time.Minute
+	clock := newFakeClock()
+	q := NewQueueWithClock(10, idleTimeout, testLogger(), clock)
+
+	var idleCalls atomic.Int32
+	idleCalled := make(chan struct{}, 1)
+	jobDone := make(chan struct{})
+
+	q.SetIdleCallback(func() {
+		idleCalls.Add(1)
+		idleCalled <- struct{}{}
+	})
+
+	q.SetPlaybackHandler(func(ctx context.Context, job *SpeakJob) error {
+		return nil
+	})
+
+	q.SetJobCompletedCallback(func(job *SpeakJob) {
+		close(jobDone)
+	})
+
+	q.Start()
+	defer q.Stop()
+
+	// Enqueue a job to process
+	q.Enqueue(NewSpeakJob("Hello", "default", false, 0, ""))
+
+	// Wait for job to complete
+	select {
+	case <-jobDone:
+		// Job completed
+	case <-time.After(testTimeout):
+		t.Fatal("timeout waiting for job to complete")
+	}
+
+	// Just short of the timeout nothing fires
+	clock.waitForTimer(t)
+	clock.Advance(idleTimeout - time.Second)
+	if n := idleCalls.Load(); n != 0 {
+		t.Fatalf("idle callback called %d times before timeout", n)
+	}
+
+	clock.Advance(time.Second)
+
+	select {
+	case <-idleCalled:
+		// Success
+	case <-time.After(testTimeout):
+		t.Fatal("timeout waiting for idle callback")
+	}
+}
+
+func TestIdleCallbackSuppressedWhenStayingConnected(t *testing.T) {
+	idleTimeout := 5 * time.Minute
+	clock := newFakeClock()
+	q := NewQueueWithClock(10, idleTimeout, testLogger(), clock)
+	q.SetStayConnected(true)
+
+	var idleCalls atomic.Int32
+	jobDone := make(chan struct{})
+
+	q.SetIdleCallback(func() {
+		idleCalls.Add(1)
+	})
+	q.SetPlaybackHandler(func(ctx context.Context, job *SpeakJob) error {
+		return nil
+	})
+	q.SetJobCompletedCallback(func(job *SpeakJob) {
+		close(jobDone)
+	})
+
+	q.Start()
+	defer q.Stop()
+
+	q.Enqueue(NewSpeakJob("Hello", "default", false, 0, ""))
+	select {
+	case <-jobDone:
+	case <-time.After(testTimeout):
+		t.Fatal("timeout waiting for job to complete")
+	}
+
+	// Let several idle timeouts pass; the worker re-arms the timer after
+	// each, so waiting for it means the previous one was handled
+	for range 3 {
+		clock.waitForTimer(t)
+		clock.Advance(idleTimeout)
+	}
+	clock.waitForTimer(t)
+
+	if n := idleCalls.Load(); n != 0 {
+		t.Errorf("idle callback called %d times, want 0 while staying connected", n)
+	}
+}
+
+func TestIdleCallbackNotCalledWhileProcessing(t *testing.T) {
+	idleTimeout := 5 * time.Minute
+	clock := newFakeClock()
+	q := NewQueueWithClock(10, idleTimeout, testLogger(), clock)
+
+	var idleCalledDuringProcessing atomic.Bool
+	var processingComplete atomic.Bool
+	processingStarted := make(chan struct{})
+	processingDone := make(chan struct{})
+	idleCalled := make(chan struct{})
+	continueProcessing := make(chan struct{})
+
+	q.SetIdleCallback(func() {
+		if !processingComplete.Load() {
+			idleCalledDuringProcessing.Store(true)
+		}
+		select {
+		case <-idleCalled:
+			// Already closed
+		default:
+			close(idleCalled)
+		}
+	})
+
+	q.SetPlaybackHandler(func(ctx context.Context, job *SpeakJob) error {
+		close(processingStarted)
+		<-continueProcessing
+		return nil
+	})
+
+	q.SetJobCompletedCallback(func(job *SpeakJob) {
+		processingComplete.Store(true)
+		close(processingDone)
+	})
+
+	q.Start()
+	defer q.Stop()
+
+	q.Enqueue(NewSpeakJob("Hello", "default", false, 0, ""))
+
+	select {
+	case <-processingStarted:
+	case <-time.After(testTimeout):
+		t.Fatal("timeout waiting for processing to start")
+	}
+
+	// Let the idle timeout pass several times over while processing
+	clock.Advance(idleTimeout * 3)
+
+	// Now let processing complete
+	close(continueProcessing)
+
+	// Wait for processing to complete
+	select {
+	case <-processingDone:
+		// Success
+	case <-time.After(testTimeout):
+		t.Fatal("timeout waiting for processing to complete")
+	}
+
+	clock.waitForTimer(t)
+	clock.Advance(idleTimeout)
+
+	// Wait for idle callback
+	select {
+	case <-idleCalled:
+		// Success
+	case <-time.After(testTimeout):
+		t.Fatal("timeout waiting for idle callback")
+	}
+
+	if idleCalledDuringProcessing.Load() {
+		t.Error("idle callback was called during processing")
+	}
+}
+
+func TestIdleDelayCancelledByJob(t *testing.T) {
+	idleTimeout, delay := 5*time.Minute, 10*time.Second
+	clock := newFakeClock()
+	q := NewQueueWithClock(10, idleTimeout, testLogger(), clock)
+	q.SetIdleDelay(delay, 0)
+
+	var idleCalls atomic.Int32
+	idleCalled := make(chan struct{}, 1)
+	jobDone := make(chan struct{}, 1)
+
+	q.SetIdleCallback(func() {
+		idleCalls.Add(1)
+		idleCalled <- struct{}{}
+	})
+	q.SetPlaybackHandler(func(ctx context.Context, job *SpeakJob) error {
+		return nil
+	})
+	q.SetJobCompletedCallback(func(job *SpeakJob) {
+		jobDone <- struct{}{}
+	})
+
+	q.Start()
+	defer q.Stop()
+
+	waitJob := func() {
+		t.Helper()
+		select {
+		case <-jobDone:
+		case <-time.After(testTimeout):
+			t.Fatal("timeout waiting for job to complete")
+		}
+	}
+
+	q.Enqueue(NewSpeakJob("Hello", "default", false, 0, ""))
+	waitJob()
+
+	// Idle timeout passes, then a job arrives within the delay
+	clock.waitForTimer(t)
+	clock.Advance(idleTimeout)
+	clock.waitForTimer(t)
+	q.Enqueue(NewSpeakJob("World", "default", false, 0, ""))
+	waitJob()
+
+	clock.Advance(delay)
+	if n := idleCalls.Load(); n != 0 {
+		t.Fatalf("idle callback called %d times, want the job to cancel it", n)
+	}
+
+	// Left alone, the idle callback fires after the timeout plus the delay
+	clock.waitForTimer(t)
+	clock.Advance(idleTimeout)
+	clock.waitForTimer(t)
+	clock.Advance(delay)
+
+	select {
+	case <-idleCalled:
+		// Success
+	case <-time.After(testTimeout):
+		t.Fatal("timeout waiting for idle callback")
+	}
+}
+
+func TestIdleDelayMinDwell(t *testing.T) {
+	idleTimeout, minDwell := 5*time.Minute, 10*time.Minute
+	clock := newFakeClock()
+	q := NewQueueWithClock(10, idleTimeout, testLogger(), clock)
+	q.SetIdleDelay(0, minDwell)
+
+	var idleCalls atomic.Int32
+	idleCalled := make(chan struct{}, 1)
+	jobDone := make(chan struct{})
+
+	q.SetIdleCallback(func() {
+		idleCalls.Add(1)
+		idleCalled <- struct{}{}
+	})
+	q.SetPlaybackHandler(func(ctx context.Context, job *SpeakJob) error {
+		return nil
+	})
+	q.SetJobCompletedCallback(func(job *SpeakJob) {
+		close(jobDone)
+	})
+
+	q.Start()
+	defer q.Stop()
+
+	q.Enqueue(NewSpeakJob("Hello", "default", false, 0, ""))
+	select {
+	case <-jobDone:
+	case <-time.After(testTimeout):
+		t.Fatal("timeout waiting for job to complete")
+	}
+
+	// Active for only the idle timeout so far, so wait out the rest of the dwell
+	clock.waitForTimer(t)
+	clock.Advance(idleTimeout)
+	clock.waitForTimer(t)
+	clock.Advance(minDwell - idleTimeout - time.Second)
+	if n := idleCalls.Load(); n != 0 {
+		t.Fatalf("idle callback called %d times before the minimum dwell", n)
+	}
+
+	clock.Advance(time.Second)
+
+	select {
+	case <-idleCalled:
+		// Success
+	case <-time.After(testTimeout):
+		t.Fatal("timeout waiting for idle callback")
+	}
+}
+
+func TestNoPlaybackHandler(t *testing.T) {
+	q := NewQueue(10, 5*time.Minute, testLogger())
+
+	jobDone := make(chan struct{})
+
+	// Don't set a playback handler, but set completion callback
+	q.SetJobCompletedCallback(func(job *SpeakJob) {
+		close(jobDone)
+	})
+
+	q.Start()
+	defer q.Stop()
+
+	q.Enqueue(NewSpeakJob("Hello", "default", false, 0, ""))
+
+	// Wait for job to be processed (skipped) with timeout failsafe
+	select {
+	case <-jobDone:
+		// Success
+	case <-time.After(testTimeout):
+		t.Fatal("timeout waiting for job to be processed")
 	}
 
 	// Queue should be empty
@@ -490,6 +1580,51 @@ func TestShutdownCallback(t *testing.T) {
 	}
 }
 
+func TestJobCompletedCallbacks(t *testing.T) {
+	q := NewQueue(10, 5*time.Minute, testLogger())
+	q.SetPlaybackHandler(func(ctx context.Context, job *SpeakJob) error {
+		return nil
+	})
+
+	var mu sync.Mutex
+	var calls []string
+	record := func(name string) JobCompletedCallback {
+		return func(job *SpeakJob) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, name+":"+job.Text)
+		}
+	}
+	done := make(chan struct{}, 2)
+
+	q.SetJobCompletedCallback(record("first"))
+	q.AddJobCompletedCallback(record("second"))
+	q.AddJobCompletedCallback(nil)
+	q.AddJobCompletedCallback(func(job *SpeakJob) { done <- struct{}{} })
+	q.Start()
+	defer q.Stop()
+
+	for _, text := range []string{"one", "two"} {
+		q.Enqueue(NewSpeakJob(text, "default", false, 0, ""))
+		// Registering while the worker runs must not race with it
+		q.AddJobCompletedCallback(func(job *SpeakJob) {})
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-done:
+		case <-time.After(testTimeout):
+			t.Fatal("timeout waiting for jobs to complete")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"first:one", "second:one", "first:two", "second:two"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("callbacks = %v, want %v", calls, want)
+	}
+}
+
 func TestShutdownCallbackCalledAfterWorkerStops(t *testing.T) {
 	q := NewQueue(10, 5*time.Minute, testLogger())
 
@@ -538,3 +1673,375 @@ func TestShutdownCallbackCalledAfterWorkerStops(t *testing.T) {
 		t.Error("shutdown callback was called before worker stopped")
 	}
 }
+
+func TestStopDuringProcessing(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		clock := newFakeClock()
+		q := NewQueueWithClock(10, time.Second, testLogger(), clock)
+
+		var played, shutdowns atomic.Int32
+		var shutDown atomic.Bool
+		started := make(chan struct{}, 1)
+		q.SetPlaybackHandler(func(ctx context.Context, job *SpeakJob) error {
+			played.Add(1)
+			select {
+			case started <- struct{}{}:
+			default:
+			}
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		q.SetIdleCallback(func() {
+			if shutDown.Load() {
+				t.Error("idle callback called after shutdown")
+			}
+		})
+		q.SetShutdownCallback(func() {
+			shutDown.Store(true)
+			shutdowns.Add(1)
+		})
+
+		q.Start()
+		for j := 0; j < 3; j++ {
+			q.Enqueue(NewSpeakJob("Hello", "default", false, 0, ""))
+		}
+		select {
+		case <-started:
+		case <-time.After(testTimeout):
+			t.Fatal("timeout waiting for playback to start")
+		}
+
+		// Race enqueues, idle timers and several Stop calls
+		var wg sync.WaitGroup
+		for j := 0; j < 4; j++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				if err := q.Enqueue(NewSpeakJob("Late", "default", false, 0, "")); err != nil && !errors.Is(err, ErrQueueClosed) {
+					t.Errorf("Enqueue() error = %v, want nil or ErrQueueClosed", err)
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				clock.Advance(time.Second)
+				q.Stop()
+				if shutdowns.Load() != 1 {
+					t.Errorf("Stop returned with the shutdown callback called %d times, want 1", shutdowns.Load())
+				}
+			}()
+		}
+		wg.Wait()
+		clock.Advance(time.Second)
+
+		if got := shutdowns.Load(); got != 1 {
+			t.Fatalf("shutdown callback called %d times, want 1", got)
+		}
+		// Only the job playing when Stop was called ran
+		if got := played.Load(); got != 1 {
+			t.Fatalf("handler called %d times, want 1", got)
+		}
+	}
+}
+
+// funcPreparer adapts functions to the Preparer interface for tests.
+type funcPreparer struct {
+	prepare func(ctx context.Context, job *SpeakJob) (*PreparedJob, error)
+	play    func(ctx context.Context, prepared *PreparedJob) error
+}
+
+func (f *funcPreparer) Prepare(ctx context.Context, job *SpeakJob) (*PreparedJob, error) {
+	return f.prepare(ctx, job)
+}
+
+func (f *funcPreparer) Play(ctx context.Context, prepared *PreparedJob) error {
+	return f.play(ctx, prepared)
+}
+
+func TestPreparerPrefetchesNextJob(t *testing.T) {
+	q := NewQueue(10, 5*time.Minute, testLogger())
+
+	var prepareCount atomic.Int32
+	secondPrepared := make(chan struct{})
+	releaseFirst := make(chan struct{})
+	allDone := make(chan struct{})
+
+	q.SetPreparer(&funcPreparer{
+		prepare: func(ctx context.Context, job *SpeakJob) (*PreparedJob, error) {
+			prepareCount.Add(1)
+			if job.Text == "Second" {
+				close(secondPrepared)
+			}
+			return &PreparedJob{Job: job, Payload: "audio:" + job.Text}, nil
+		},
+		play: func(ctx context.Context, prepared *PreparedJob) error {
+			if prepared.Payload != "audio:"+prepared.Job.Text {
+				t.Errorf("payload = %v, want audio:%s", prepared.Payload, prepared.Job.Text)
+			}
+			if prepared.Job.Text == "First" {
+				<-releaseFirst
+			}
+			return nil
+		},
+	})
+
+	q.SetJobCompletedCallback(func(job *SpeakJob) {
+		if job.Text == "Second" {
+			close(allDone)
+		}
+	})
+
+	// Enqueue both before starting so the second is queued while the first plays
+	q.Enqueue(NewSpeakJob("First", "default", false, 0, ""))
+	q.Enqueue(NewSpeakJob("Second", "default", false, 0, ""))
+
+	q.Start()
+	defer q.Stop()
+
+	// Second job should be prepared while the first is still playing
+	select {
+	case <-secondPrepared:
+		// Success
+	case <-time.After(testTimeout):
+		t.Fatal("timeout waiting for second job to be prefetched")
+	}
+
+	close(releaseFirst)
+
+	select {
+	case <-allDone:
+		// Success
+	case <-time.After(testTimeout):
+		t.Fatal("timeout waiting for jobs to complete")
+	}
+
+	// Prefetched result is reused rather than preparing again
+	if prepareCount.Load() != 2 {
+		t.Errorf("Prepare called %d times, want 2", prepareCount.Load())
+	}
+}
+
+func TestCoalescePlaybackEndsRunBeforeUnpreparedJob(t *testing.T) {
+	q := NewQueue(10, 5*time.Minute, testLogger())
+	q.SetCoalescePlayback(true)
+
+	twoPrepared := make(chan struct{})
+	releaseThree := make(chan struct{})
+	var drainedCount atomic.Int32
+	q.SetDrainedCallback(func() {
+		if drainedCount.Add(1) == 1 {
+			close(releaseThree)
+		}
+	})
+
+	var mu sync.Mutex
+	drainsBefore := make(map[string]int32)
+	q.SetPreparer(&funcPreparer{
+		prepare: func(ctx context.Context, job *SpeakJob) (*PreparedJob, error) {
+			switch job.Text {
+			case "Two":
+				close(twoPrepared)
+			case "Three":
+				// Still synthesizing when Two finishes playing
+				<-releaseThree
+			}
+			return &PreparedJob{Job: job}, nil
+		},
+		play: func(ctx context.Context, prepared *PreparedJob) error {
+			if prepared.Job.Text == "One" {
+				<-twoPrepared
+			}
+			mu.Lock()
+			drainsBefore[prepared.Job.Text] = drainedCount.Load()
+			mu.Unlock()
+			return nil
+		},
+	})
+
+	done := make(chan struct{})
+	q.SetJobCompletedCallback(func(job *SpeakJob) {
+		if job.Text == "Three" {
+			close(done)
+		}
+	})
+
+	q.Enqueue(NewSpeakJob("One", "default", false, 0, ""))
+	q.Enqueue(NewSpeakJob("Two", "default", false, 0, ""))
+	q.Enqueue(NewSpeakJob("Three", "default", false, 0, ""))
+	q.Start()
+	defer q.Stop()
+
+	select {
+	case <-done:
+	case <-time.After(testTimeout):
+		t.Fatal("timeout waiting for jobs to complete")
+	}
+
+	// One and Two play in one run; Three, unprepared when Two ends, starts another
+	mu.Lock()
+	defer mu.Unlock()
+	want := map[string]int32{"One": 0, "Two": 0, "Three": 1}
+	for text, n := range want {
+		if drainsBefore[text] != n {
+			t.Errorf("drained %d times before %s played, want %d", drainsBefore[text], text, n)
+		}
+	}
+
+	deadline := time.After(testTimeout)
+	for drainedCount.Load() != 2 {
+		select {
+		case <-deadline:
+			t.Fatalf("drained %d times, want 2", drainedCount.Load())
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+func TestInterruptCancelsPrefetch(t *testing.T) {
+	q := NewQueue(10, 5*time.Minute, testLogger())
+
+	firstPlaying := make(chan struct{})
+	prefetchStarted := make(chan struct{})
+	prefetchCancelled := make(chan struct{})
+	firstDone := make(chan struct{})
+
+	q.SetPreparer(&funcPreparer{
+		prepare: func(ctx context.Context, job *SpeakJob) (*PreparedJob, error) {
+			if job.Text == "Second" {
+				close(prefetchStarted)
+				<-ctx.Done()
+				close(prefetchCancelled)
+				return nil, ctx.Err()
+			}
+			return &PreparedJob{Job: job}, nil
+		},
+		play: func(ctx context.Context, prepared *PreparedJob) error {
+			close(firstPlaying)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+
+	q.SetJobCompletedCallback(func(job *SpeakJob) {
+		close(firstDone)
+	})
+
+	q.Enqueue(NewSpeakJob("First", "default", false, 0, ""))
+	q.Enqueue(NewSpeakJob("Second", "default", false, 0, ""))
+
+	q.Start()
+	defer q.Stop()
+
+	for _, ch := range []chan struct{}{firstPlaying, prefetchStarted} {
+		select {
+		case <-ch:
+		case <-time.After(testTimeout):
+			t.Fatal("timeout waiting for playback and prefetch to start")
+		}
+	}
+
+	q.Interrupt()
+
+	select {
+	case <-prefetchCancelled:
+		// Success
+	case <-time.After(testTimeout):
+		t.Fatal("interrupt did not cancel the prefetch")
+	}
+
+	select {
+	case <-firstDone:
+		// Success
+	case <-time.After(testTimeout):
+		t.Fatal("timeout waiting for current job cancellation")
+	}
+}
+
+func TestQueueMetrics(t *testing.T) {
+	rec := metrics.NewRecorder()
+	q := NewQueue(1, 5*time.Minute, testLogger())
+	q.SetMetrics(rec)
+
+	jobDone := make(chan struct{}, 2)
+	q.SetPlaybackHandler(func(ctx context.Context, job *SpeakJob) error {
+		if job.Text == "fail" {
+			return errors.New("playback failed")
+		}
+		return nil
+	})
+	q.SetRetryPolicy(0, 0, nil)
+	q.SetJobCompletedCallback(func(job *SpeakJob) {
+		jobDone <- struct{}{}
+	})
+
+	// Rejections are counted before the worker starts draining
+	if err := q.Enqueue(NewSpeakJob("Hello", "default", false, 0, "key")); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if err := q.Enqueue(NewSpeakJob("Again", "default", false, 0, "")); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Enqueue() error = %v, want ErrQueueFull", err)
+	}
+	if depth, _ := rec.GaugeValue("queue_depth"); depth != 1 {
+		t.Errorf("queue_depth = %v, want 1", depth)
+	}
+
+	q.Start()
+	defer q.Stop()
+
+	waitDone := func() {
+		t.Helper()
+		select {
+		case <-jobDone:
+		case <-time.After(testTimeout):
+			t.Fatal("timeout waiting for job to complete")
+		}
+	}
+	waitDone()
+	if err := q.Enqueue(NewSpeakJob("fail", "default", false, 0, "")); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	waitDone()
+
+	if got := rec.CounterValue("queue_jobs_enqueued_total"); got != 2 {
+		t.Errorf("queue_jobs_enqueued_total = %v, want 2", got)
+	}
+	if got := rec.CounterValue("queue_jobs_rejected_total", "reason", "full"); got != 1 {
+		t.Errorf("queue_jobs_rejected_total{reason=full} = %v, want 1", got)
+	}
+	if got := rec.CounterValue("queue_jobs_processed_total", "result", "completed"); got != 1 {
+		t.Errorf("queue_jobs_processed_total{result=completed} = %v, want 1", got)
+	}
+	if got := rec.CounterValue("queue_jobs_processed_total", "result", "failed"); got != 1 {
+		t.Errorf("queue_jobs_processed_total{result=failed} = %v, want 1", got)
+	}
+	if got := rec.Observations("queue_job_wait_seconds"); len(got) != 2 {
+		t.Errorf("queue_job_wait_seconds has %d observations, want 2", len(got))
+	}
+	if depth, _ := rec.GaugeValue("queue_depth"); depth != 0 {
+		t.Errorf("queue_depth = %v, want 0", depth)
+	}
+}
+
+func TestQueueNilMetrics(t *testing.T) {
+	q := NewQueue(10, 5*time.Minute, testLogger())
+	q.SetMetrics(nil)
+
+	jobDone := make(chan struct{})
+	q.SetPlaybackHandler(func(ctx context.Context, job *SpeakJob) error {
+		return nil
+	})
+	q.SetJobCompletedCallback(func(job *SpeakJob) {
+		close(jobDone)
+	})
+
+	q.Start()
+	defer q.Stop()
+
+	if err := q.Enqueue(NewSpeakJob("Hello", "default", false, 0, "")); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	select {
+	case <-jobDone:
+	case <-time.After(testTimeout):
+		t.Fatal("timeout waiting for job to complete")
+	}
+}
@@ -0,0 +1,106 @@
+package queue
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrQuietHours is the error reported for a job dropped during quiet hours.
+var ErrQuietHours = errors.New("dropped during quiet hours")
+
+// QuietMode controls what happens to jobs during quiet hours.
+type QuietMode string
+
+const (
+	// QuietDrop discards jobs that come up for playback during quiet hours.
+	QuietDrop QuietMode = "drop"
+	// QuietDefer holds jobs in the queue until quiet hours end. They still
+	// expire with their TTL while they wait.
+	QuietDefer QuietMode = "defer"
+)
+
+// day is the length of the clock a quiet window is read from.
+const day = 24 * time.Hour
+
+// quietHours is a daily window, given as offsets from midnight in loc,
+// during which nothing is played. A start after end crosses midnight.
+type quietHours struct {
+	start, end time.Duration
+	loc        *time.Location
+	mode       QuietMode
+}
+
+// remaining returns how long the window has left to run at t, or 0 if t
+// is outside it.
+func (h *quietHours) remaining(t time.Time) time.Duration {
+	if h == nil || h.start == h.end {
+		return 0
+	}
+	t = t.In(h.loc)
+	hour, minute, second := t.Clock()
+	now := time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute +
+		time.Duration(second)*time.Second + time.Duration(t.Nanosecond())
+
+	inside := now >= h.start && now < h.end
+	if h.start > h.end {
+		inside = now >= h.start || now < h.end
+	}
+	if !inside {
+		return 0
+	}
+	left := h.end - now
+	if left <= 0 {
+		left += day
+	}
+	return left
+}
+
+// SetQuietHours stops playback every day from start until end, both given
+// as the time since midnight in loc; a start after end spans midnight. mode
+// says whether jobs due during the window are dropped or wait for it to
+// end. The job playing when the window opens is left to finish. If start
+// equals end, quiet hours are turned off; a nil loc means local time.
+func (q *Queue) SetQuietHours(start, end time.Duration, loc *time.Location, mode QuietMode) {
+	if loc == nil {
+		loc = time.Local
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if start == end {
+		q.quiet = nil
+	} else {
+		q.quiet = &quietHours{start: start % day, end: end % day, loc: loc, mode: mode}
+	}
+	// Wake the worker so a deferral it is waiting out is re-evaluated
+	select {
+	case q.enqueueCh <- struct{}{}:
+	default:
+	}
+}
+
+// quietNow returns how long quiet hours have left to run and the quiet
+// mode, or 0 when playback is allowed.
+func (q *Queue) quietNow() (time.Duration, QuietMode) {
+	q.mu.Lock()
+	h := q.quiet
+	q.mu.Unlock()
+
+	if h == nil {
+		return 0, ""
+	}
+	return h.remaining(q.clock.Now()), h.mode
+}
+
+// dropQuiet reports job as dropped for quiet hours.
+func (q *Queue) dropQuiet(job *SpeakJob) {
+	q.logger.Info("dropping job during quiet hours", "job_id", job.ID)
+	q.mu.Lock()
+	m := q.metrics
+	q.mu.Unlock()
+
+	m.Counter("queue_jobs_processed_total", 1, "result", "dropped")
+	start := q.clock.Now()
+	q.emit(EventFailed, job, ErrQuietHours)
+	q.recordHistory(job, start, ErrQuietHours)
+}
@@ -0,0 +1,116 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestQuietHoursRemaining(t *testing.T) {
+	overnight := &quietHours{start: 22 * time.Hour, end: 7 * time.Hour, loc: time.UTC}
+	daytime := &quietHours{start: 9 * time.Hour, end: 17*time.Hour + 30*time.Minute, loc: time.UTC}
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 3, 1, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name  string
+		hours *quietHours
+		t     time.Time
+		want  time.Duration
+	}{
+		{"overnight before start", overnight, at(21, 59), 0},
+		{"overnight at start", overnight, at(22, 0), 9 * time.Hour},
+		{"overnight before midnight", overnight, at(23, 30), 7*time.Hour + 30*time.Minute},
+		{"overnight after midnight", overnight, at(3, 0), 4 * time.Hour},
+		{"overnight at end", overnight, at(7, 0), 0},
+		{"overnight midday", overnight, at(12, 0), 0},
+		{"daytime inside", daytime, at(12, 0), 5*time.Hour + 30*time.Minute},
+		{"daytime before", daytime, at(8, 0), 0},
+		{"daytime after", daytime, at(18, 0), 0},
+		{"other timezone", &quietHours{start: 22 * time.Hour, end: 7 * time.Hour, loc: time.FixedZone("UTC+2", 2*60*60)}, at(21, 0), 8 * time.Hour},
+		{"disabled", nil, at(23, 0), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hours.remaining(tt.t); got != tt.want {
+				t.Errorf("remaining(%s) = %v, want %v", tt.t.Format("15:04"), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueue_QuietHours(t *testing.T) {
+	tests := []struct {
+		name string
+		mode QuietMode
+	}{
+		{"drop", QuietDrop},
+		{"defer", QuietDefer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The fake clock starts at midnight UTC, an hour into the window
+			clock := newFakeClock()
+			q := NewQueueWithClock(10, 0, testLogger(), clock)
+			q.SetQuietHours(23*time.Hour, time.Hour, time.UTC, tt.mode)
+
+			played := make(chan string, 1)
+			q.SetPlaybackHandler(func(ctx context.Context, job *SpeakJob) error {
+				played <- job.ID
+				return nil
+			})
+			events, unsubscribe := q.Subscribe()
+			defer unsubscribe()
+
+			q.Start()
+			defer q.Stop()
+
+			job := NewSpeakJob("Too late", "", false, 0, "")
+			if err := q.Enqueue(job); err != nil {
+				t.Fatalf("Enqueue() error = %v", err)
+			}
+			if tt.mode == QuietDrop {
+				for ev := range events {
+					if ev.JobID == job.ID && ev.Type == EventFailed {
+						if ev.Error != ErrQuietHours.Error() {
+							t.Errorf("event error = %q, want %q", ev.Error, ErrQuietHours)
+						}
+						break
+					}
+				}
+				if q.Len() != 0 {
+					t.Errorf("Len() = %d after drop, want 0", q.Len())
+				}
+				select {
+				case id := <-played:
+					t.Fatalf("job %s played during quiet hours", id)
+				default:
+				}
+				return
+			}
+
+			clock.waitForTimer(t)
+			select {
+			case id := <-played:
+				t.Fatalf("job %s played during quiet hours", id)
+			case <-time.After(20 * time.Millisecond):
+			}
+			if q.Len() != 1 {
+				t.Errorf("Len() = %d while deferred, want 1", q.Len())
+			}
+
+			clock.Advance(time.Hour)
+			select {
+			case id := <-played:
+				if id != job.ID {
+					t.Errorf("played %s, want %s", id, job.ID)
+				}
+			case <-time.After(testTimeout):
+				t.Fatal("deferred job not played after quiet hours")
+			}
+		})
+	}
+}
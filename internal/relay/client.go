@@ -7,13 +7,19 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/dgnsrekt/discorgeous-go/internal/metrics"
+	"github.com/dgnsrekt/discorgeous-go/pkg/discorgeous"
 )
 
 // NtfyMessage represents a message received from the ntfy JSON stream.
@@ -26,33 +32,132 @@ type NtfyMessage struct {
 	Message string `json:"message"`
 }
 
-// SpeakRequest represents the request body for POST /v1/speak.
-type SpeakRequest struct {
-	Text      string `json:"text"`
-	Interrupt bool   `json:"interrupt,omitempty"`
-	TTLMS     int    `json:"ttl_ms,omitempty"`
-	DedupeKey string `json:"dedupe_key,omitempty"`
+const (
+	// maxForwardAttempts is how many times a message is sent while
+	// Discorgeous answers 503 with a Retry-After header.
+	maxForwardAttempts = 3
+
+	// maxRetryAfter caps how long a topic is paused for a single Retry-After.
+	maxRetryAfter = 5 * time.Minute
+)
+
+// retryAfterError is returned by forwardToDiscorgeous when Discorgeous is
+// unavailable and has said how long to wait before trying again.
+type retryAfterError struct {
+	*discorgeous.APIError
+	delay time.Duration
 }
 
+func (e *retryAfterError) Error() string {
+	return fmt.Sprintf("%v (retry after %s)", e.APIError, e.delay)
+}
+
+// Outcome describes what HandleMessage did with a message.
+type Outcome string
+
+const (
+	// OutcomeForwarded means the message was accepted by Discorgeous.
+	OutcomeForwarded Outcome = "forwarded"
+	// OutcomeFiltered means the message had nothing to speak.
+	OutcomeFiltered Outcome = "filtered"
+	// OutcomeDeduped means the message repeated one inside the dedupe window.
+	OutcomeDeduped Outcome = "deduped"
+	// OutcomeTooLong means the message was over MaxTextLength and
+	// NTFY_ON_TOO_LONG is skip.
+	OutcomeTooLong Outcome = "too_long"
+	// OutcomeFailed means forwarding to Discorgeous failed.
+	OutcomeFailed Outcome = "failed"
+)
+
 // Client is the ntfy relay client that subscribes to ntfy topics
 // and forwards messages to the Discorgeous API.
 type Client struct {
-	cfg        *Config
-	logger     *slog.Logger
-	httpClient *http.Client
-	dedupeMap  map[string]time.Time
-	dedupeMu   sync.Mutex
+	cfg       *Config
+	logger    *slog.Logger
+	api       *discorgeous.Client
+	dedupeMap map[string]time.Time
+	dedupeMu  sync.Mutex
+
+	// inFlight bounds concurrent forwards; nil means unbounded.
+	inFlight chan struct{}
+	// after is time.After, replaceable in tests.
+	after func(time.Duration) <-chan time.Time
+
+	// lastIDs records the last message ID seen per topic, used as since=
+	// on reconnect when NtfySince is set.
+	lastIDs   map[string]string
+	lastIDsMu sync.Mutex
+
+	// connected records which topic streams are currently open.
+	connected   map[string]bool
+	connectedMu sync.Mutex
+
+	metrics metrics.Metrics
 }
 
 // NewClient creates a new relay client.
 func NewClient(cfg *Config, logger *slog.Logger) *Client {
+	var inFlight chan struct{}
+	if cfg.MaxInFlight > 0 {
+		inFlight = make(chan struct{}, cfg.MaxInFlight)
+	}
+	api := discorgeous.New(cfg.DiscorgeousAPIURL, cfg.DiscorgeousBearerToken)
+	api.SetHTTPClient(newHTTPClient(cfg))
 	return &Client{
-		cfg:    cfg,
-		logger: logger,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		cfg:       cfg,
+		logger:    logger,
+		api:       api,
 		dedupeMap: make(map[string]time.Time),
+		inFlight:  inFlight,
+		after:     time.After,
+		lastIDs:   make(map[string]string),
+		connected: make(map[string]bool),
+		metrics:   metrics.Nop{},
+	}
+}
+
+// newHTTPClient returns the HTTP client used to forward to Discorgeous. Its
+// transport keeps up to APIMaxIdleConns idle connections to the API so
+// forwards, including concurrent ones, reuse them rather than dialing anew.
+func newHTTPClient(cfg *Config) *http.Client {
+	timeout := cfg.APITimeout
+	if timeout == 0 {
+		timeout = DefaultAPITimeout
+	}
+	idle := cfg.APIMaxIdleConns
+	if idle == 0 {
+		idle = DefaultAPIMaxIdleConns
+	}
+
+	// DefaultTransport already sends TCP keepalives and drops connections
+	// idle for 90s
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = idle
+	transport.MaxIdleConnsPerHost = idle
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// SetMetrics sets where the client reports message counts. If m is nil,
+// nothing is reported. It must be called before Run.
+func (c *Client) SetMetrics(m metrics.Metrics) {
+	c.metrics = metrics.OrNop(m)
+}
+
+// Connected reports whether at least one topic stream is connected.
+func (c *Client) Connected() bool {
+	c.connectedMu.Lock()
+	defer c.connectedMu.Unlock()
+	return len(c.connected) > 0
+}
+
+// setConnected records whether the stream for topic is open.
+func (c *Client) setConnected(topic string, connected bool) {
+	c.connectedMu.Lock()
+	defer c.connectedMu.Unlock()
+	if connected {
+		c.connected[topic] = true
+	} else {
+		delete(c.connected, topic)
 	}
 }
 
@@ -122,7 +227,7 @@ func (c *Client) subscribeLoop(ctx context.Context, topic string) {
 
 // subscribe connects to the ntfy JSON stream for a topic and processes messages.
 func (c *Client) subscribe(ctx context.Context, topic string) error {
-	url := fmt.Sprintf("%s/%s/json", strings.TrimSuffix(c.cfg.NtfyServer, "/"), topic)
+	url := c.subscribeURL(topic)
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
@@ -143,8 +248,12 @@ func (c *Client) subscribe(ctx context.Context, topic string) error {
 	}
 
 	c.logger.Info("connected to ntfy stream", "topic", topic)
+	c.setConnected(topic, true)
+	defer c.setConnected(topic, false)
 
 	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, min(64*1024, c.cfg.MaxLineBytes)), c.cfg.MaxLineBytes)
+	scanner.Split(c.splitLines(topic))
 	for scanner.Scan() {
 		select {
 		case <-ctx.Done():
@@ -169,7 +278,9 @@ func (c *Client) subscribe(ctx context.Context, topic string) error {
 			continue
 		}
 
-		c.handleMessage(msg)
+		c.recordLastID(topic, msg.ID)
+
+		c.HandleMessage(ctx, msg)
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -179,42 +290,128 @@ func (c *Client) subscribe(ctx context.Context, topic string) error {
 	return nil
 }
 
-// handleMessage processes a single ntfy message and forwards it to Discorgeous.
-func (c *Client) handleMessage(msg NtfyMessage) {
+// splitLines is bufio.ScanLines, except that a line longer than
+// MaxLineBytes is logged and skipped instead of failing the scan with
+// bufio.ErrTooLong, which would tear down the stream.
+func (c *Client) splitLines(topic string) bufio.SplitFunc {
+	skipping := false
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			if skipping {
+				skipping = false
+				return i + 1, nil, nil
+			}
+			return i + 1, bytes.TrimSuffix(data[:i], []byte("\r")), nil
+		}
+
+		if atEOF {
+			if len(data) == 0 || skipping {
+				return len(data), nil, nil
+			}
+			return len(data), bytes.TrimSuffix(data, []byte("\r")), nil
+		}
+
+		if len(data) >= c.cfg.MaxLineBytes {
+			if !skipping {
+				c.logger.Warn("skipping ntfy line longer than NTFY_MAX_LINE_BYTES",
+					"topic", topic,
+					"max_line_bytes", c.cfg.MaxLineBytes,
+				)
+				skipping = true
+			}
+			return len(data), nil, nil
+		}
+
+		// Request more data
+		return 0, nil, nil
+	}
+}
+
+// subscribeURL returns the JSON stream URL for topic. Without NtfySince,
+// ntfy sends only new messages. With it, the first connect asks for
+// NtfySince and later reconnects resume after the last message seen, so
+// missed messages are fetched without replaying ones already spoken.
+func (c *Client) subscribeURL(topic string) string {
+	streamURL := fmt.Sprintf("%s/%s/json", strings.TrimSuffix(c.cfg.NtfyServer, "/"), topic)
+	if c.cfg.NtfySince == "" {
+		return streamURL
+	}
+
+	since := c.cfg.NtfySince
+	c.lastIDsMu.Lock()
+	if id := c.lastIDs[topic]; id != "" {
+		since = id
+	}
+	c.lastIDsMu.Unlock()
+
+	return streamURL + "?since=" + url.QueryEscape(since)
+}
+
+// recordLastID records the latest message ID seen on topic.
+func (c *Client) recordLastID(topic, id string) {
+	if id == "" {
+		return
+	}
+	c.lastIDsMu.Lock()
+	defer c.lastIDsMu.Unlock()
+	c.lastIDs[topic] = id
+}
+
+// HandleMessage processes a single ntfy message and forwards it to
+// Discorgeous. The error is set only when the outcome is OutcomeFailed.
+func (c *Client) HandleMessage(ctx context.Context, msg NtfyMessage) (Outcome, error) {
 	c.logger.Debug("received ntfy message",
 		"id", msg.ID,
 		"topic", msg.Topic,
 		"title", msg.Title,
 		"message", msg.Message,
 	)
+	c.metrics.Counter("relay_messages_received_total", 1, "topic", msg.Topic)
 
 	// Build the text to speak
-	text := c.FormatText(msg.Title, msg.Message)
+	text := c.joinText(msg.Topic, msg.Title, msg.Message)
 	if text == "" {
 		c.logger.Debug("skipping empty message", "id", msg.ID)
-		return
+		c.metrics.Counter("relay_messages_filtered_total", 1, "topic", msg.Topic)
+		return OutcomeFiltered, nil
+	}
+	if len(text) > c.cfg.MaxTextLength {
+		if c.cfg.OnTooLong == TooLongSkip {
+			c.logger.Info("skipping message over max length",
+				"id", msg.ID,
+				"topic", msg.Topic,
+				"text_length", len(text),
+				"max", c.cfg.MaxTextLength,
+			)
+			c.metrics.Counter("relay_messages_too_long_total", 1, "topic", msg.Topic)
+			return OutcomeTooLong, nil
+		}
+		text = text[:c.cfg.MaxTextLength]
 	}
 
 	// Generate dedupe key if dedupe window is enabled
 	var dedupeKey string
 	if c.cfg.DedupeWindow > 0 {
-		dedupeKey = c.generateDedupeKey(text)
+		dedupeKey = c.generateDedupeKey(msg.Topic, text)
 		if c.isDuplicate(dedupeKey) {
 			c.logger.Debug("skipping duplicate message", "id", msg.ID, "dedupe_key", dedupeKey)
-			return
+			c.metrics.Counter("relay_messages_deduped_total", 1, "topic", msg.Topic)
+			return OutcomeDeduped, nil
 		}
 		c.recordDedupeKey(dedupeKey)
 	}
 
 	// Forward to Discorgeous
-	if err := c.forwardToDiscorgeous(text, dedupeKey); err != nil {
+	if err := c.forward(ctx, msg.Topic, text, dedupeKey); err != nil {
 		c.logger.Error("failed to forward message to Discorgeous",
 			"error", err,
 			"ntfy_id", msg.ID,
 			"text_length", len(text),
 		)
-		return
+		c.metrics.Counter("relay_forward_failures_total", 1, "topic", msg.Topic)
+		return OutcomeFailed, err
 	}
+	c.metrics.Counter("relay_messages_forwarded_total", 1, "topic", msg.Topic)
 
 	c.logger.Info("forwarded message to Discorgeous",
 		"ntfy_id", msg.ID,
@@ -222,16 +419,36 @@ func (c *Client) handleMessage(msg NtfyMessage) {
 		"text_length", len(text),
 		"interrupt", c.cfg.Interrupt,
 	)
+	return OutcomeForwarded, nil
 }
 
 // FormatText combines title and message with optional prefix and enforces max length.
-func (c *Client) FormatText(title, message string) string {
+// With SpeakTopic set, the topic is spoken between the prefix and the title,
+// but only when there is a title or message to go with it, and not when the
+// title already repeats the topic.
+func (c *Client) FormatText(topic, title, message string) string {
+	text := c.joinText(topic, title, message)
+
+	// Enforce max length
+	if len(text) > c.cfg.MaxTextLength {
+		text = text[:c.cfg.MaxTextLength]
+	}
+
+	return text
+}
+
+// joinText is FormatText without the length limit.
+func (c *Client) joinText(topic, title, message string) string {
 	var parts []string
 
 	if c.cfg.Prefix != "" {
 		parts = append(parts, c.cfg.Prefix)
 	}
 
+	if c.cfg.SpeakTopic && topic != "" && (title != "" || message != "") && !strings.EqualFold(topic, title) {
+		parts = append(parts, topic)
+	}
+
 	if title != "" {
 		parts = append(parts, title)
 	}
@@ -240,59 +457,119 @@ func (c *Client) FormatText(title, message string) string {
 		parts = append(parts, message)
 	}
 
-	text := strings.Join(parts, ": ")
+	return strings.Join(parts, ": ")
+}
 
-	// Enforce max length
-	if len(text) > c.cfg.MaxTextLength {
-		text = text[:c.cfg.MaxTextLength]
-	}
+// forward sends the text to Discorgeous, holding one of the in-flight slots
+// per attempt. When Discorgeous answers 503 with Retry-After, forward waits
+// that long and retries, so the topic's subscription sends nothing more in
+// the meantime. After the last attempt the error is returned at once.
+func (c *Client) forward(ctx context.Context, topic, text, dedupeKey string) error {
+	for attempt := 1; ; attempt++ {
+		if err := c.acquireForward(ctx); err != nil {
+			return err
+		}
+		err := c.forwardToDiscorgeous(ctx, text, dedupeKey)
+		c.releaseForward()
 
-	return text
-}
+		var retryErr *retryAfterError
+		if !errors.As(err, &retryErr) {
+			return err
+		}
 
-// forwardToDiscorgeous sends the text to the Discorgeous /v1/speak API.
-func (c *Client) forwardToDiscorgeous(text, dedupeKey string) error {
-	url := fmt.Sprintf("%s/v1/speak", strings.TrimSuffix(c.cfg.DiscorgeousAPIURL, "/"))
+		if attempt == maxForwardAttempts {
+			return err
+		}
 
-	speakReq := SpeakRequest{
-		Text:      text,
-		Interrupt: c.cfg.Interrupt,
-		DedupeKey: dedupeKey,
+		c.logger.Warn("Discorgeous is unavailable, pausing topic",
+			"topic", topic,
+			"retry_after", retryErr.delay,
+			"attempt", attempt,
+		)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.after(retryErr.delay):
+		}
 	}
+}
 
-	body, err := json.Marshal(speakReq)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
+// acquireForward takes an in-flight slot, waiting until one is free or ctx
+// is done.
+func (c *Client) acquireForward(ctx context.Context) error {
+	if c.inFlight == nil {
+		return nil
+	}
+	select {
+	case c.inFlight <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
+}
 
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+// releaseForward frees a slot taken by acquireForward.
+func (c *Client) releaseForward() {
+	if c.inFlight != nil {
+		<-c.inFlight
 	}
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	if c.cfg.DiscorgeousBearerToken != "" {
-		req.Header.Set("Authorization", "Bearer "+c.cfg.DiscorgeousBearerToken)
+// parseRetryAfter parses a Retry-After header given as delay seconds or an
+// HTTP date, capped at maxRetryAfter.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
 	}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		delay = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(value); err == nil {
+		delay = max(at.Sub(now), 0)
+	} else {
+		return 0, false
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
-		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(respBody))
+	return min(delay, maxRetryAfter), true
+}
+
+// forwardToDiscorgeous sends the text to the Discorgeous /v1/speak API.
+func (c *Client) forwardToDiscorgeous(ctx context.Context, text, dedupeKey string) error {
+	speakReq := discorgeous.SpeakRequest{
+		Text:      text,
+		Voice:     c.cfg.Voice,
+		Interrupt: c.cfg.Interrupt,
+		DedupeKey: dedupeKey,
 	}
 
-	return nil
+	start := time.Now()
+	_, err := c.api.Speak(ctx, speakReq)
+	c.metrics.Observe("relay_forward_seconds", time.Since(start).Seconds())
+
+	var apiErr *discorgeous.APIError
+	if errors.As(err, &apiErr) && errors.Is(err, discorgeous.ErrUnavailable) {
+		if delay, ok := parseRetryAfter(apiErr.Header.Get("Retry-After"), time.Now()); ok {
+			return &retryAfterError{APIError: apiErr, delay: delay}
+		}
+	}
+	return err
 }
 
-// generateDedupeKey creates a hash-based dedupe key from the text.
-func (c *Client) generateDedupeKey(text string) string {
-	hash := sha256.Sum256([]byte(text))
-	return hex.EncodeToString(hash[:8])
+// generateDedupeKey creates a hash-based dedupe key from the topic and text,
+// so identical text on different topics is not treated as a duplicate. The
+// key is the first DedupeKeyBytes of the SHA-256 digest, hex-encoded.
+func (c *Client) generateDedupeKey(topic, text string) string {
+	hash := sha256.Sum256([]byte(topic + "\x00" + text))
+	n := c.cfg.DedupeKeyBytes
+	if n <= 0 {
+		n = DefaultDedupeKeyBytes
+	}
+	return hex.EncodeToString(hash[:min(n, len(hash))])
 }
 
 // isDuplicate checks if a dedupe key has been seen within the dedupe window.
@@ -313,6 +590,7 @@ func (c *Client) recordDedupeKey(key string) {
 	c.dedupeMu.Lock()
 	defer c.dedupeMu.Unlock()
 	c.dedupeMap[key] = time.Now()
+	c.metrics.Gauge("relay_dedupe_entries", float64(len(c.dedupeMap)))
 }
 
 // dedupeCleanupLoop periodically removes expired dedupe keys.
@@ -341,4 +619,5 @@ func (c *Client) cleanupDedupeMap() {
 			delete(c.dedupeMap, key)
 		}
 	}
+	c.metrics.Gauge("relay_dedupe_entries", float64(len(c.dedupeMap)))
 }
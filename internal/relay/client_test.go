@@ -3,14 +3,20 @@ package relay
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/dgnsrekt/discorgeous-go/internal/metrics"
+	"github.com/dgnsrekt/discorgeous-go/pkg/discorgeous"
 )
 
 func newTestLogger() *slog.Logger {
@@ -21,6 +27,7 @@ func TestFormatText(t *testing.T) {
 	tests := []struct {
 		name    string
 		cfg     *Config
+		topic   string
 		title   string
 		message string
 		want    string
@@ -81,6 +88,67 @@ func TestFormatText(t *testing.T) {
 			message: "This is a very long message that should be truncated",
 			want:    "This is a ",
 		},
+		{
+			name: "topic ignored unless enabled",
+			cfg: &Config{
+				MaxTextLength: 1000,
+			},
+			topic:   "backups",
+			message: "Job failed",
+			want:    "Job failed",
+		},
+		{
+			name: "topic and message",
+			cfg: &Config{
+				SpeakTopic:    true,
+				MaxTextLength: 1000,
+			},
+			topic:   "backups",
+			message: "Job failed",
+			want:    "backups: Job failed",
+		},
+		{
+			name: "prefix, topic, title, message",
+			cfg: &Config{
+				Prefix:        "NTFY",
+				SpeakTopic:    true,
+				MaxTextLength: 1000,
+			},
+			topic:   "backups",
+			title:   "Nightly",
+			message: "Job failed",
+			want:    "NTFY: backups: Nightly: Job failed",
+		},
+		{
+			name: "title repeating topic is spoken once",
+			cfg: &Config{
+				SpeakTopic:    true,
+				MaxTextLength: 1000,
+			},
+			topic:   "backups",
+			title:   "Backups",
+			message: "Job failed",
+			want:    "Backups: Job failed",
+		},
+		{
+			name: "topic alone is not spoken",
+			cfg: &Config{
+				SpeakTopic:    true,
+				MaxTextLength: 1000,
+			},
+			topic: "backups",
+			want:  "",
+		},
+		{
+			name: "prefix and topic with empty message",
+			cfg: &Config{
+				Prefix:        "NTFY",
+				SpeakTopic:    true,
+				MaxTextLength: 1000,
+			},
+			topic: "backups",
+			want:  "NTFY",
+		},
 		{
 			name: "empty everything",
 			cfg: &Config{
@@ -95,7 +163,7 @@ func TestFormatText(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			client := NewClient(tt.cfg, newTestLogger())
-			got := client.FormatText(tt.title, tt.message)
+			got := client.FormatText(tt.topic, tt.title, tt.message)
 			if got != tt.want {
 				t.Errorf("FormatText() = %q, want %q", got, tt.want)
 			}
@@ -115,7 +183,7 @@ func TestDeduplication(t *testing.T) {
 	client := NewClient(cfg, newTestLogger())
 
 	// Generate dedupe key
-	key := client.generateDedupeKey("test message")
+	key := client.generateDedupeKey("test", "test message")
 	if key == "" {
 		t.Fatal("generateDedupeKey returned empty string")
 	}
@@ -142,6 +210,96 @@ func TestDeduplication(t *testing.T) {
 	}
 }
 
+func TestDeduplicationPerTopic(t *testing.T) {
+	var mu sync.Mutex
+	var received []discorgeous.SpeakRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req discorgeous.SpeakRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		received = append(received, req)
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	cfg := &Config{
+		DiscorgeousAPIURL: server.URL,
+		MaxTextLength:     1000,
+		DedupeWindow:      time.Minute,
+	}
+	client := NewClient(cfg, newTestLogger())
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		topic string
+		want  Outcome
+	}{
+		{"alerts", OutcomeForwarded},
+		{"backups", OutcomeForwarded},
+		{"alerts", OutcomeDeduped},
+		{"backups", OutcomeDeduped},
+	} {
+		got, err := client.HandleMessage(ctx, NtfyMessage{Topic: tt.topic, Message: "Job failed"})
+		if err != nil || got != tt.want {
+			t.Errorf("HandleMessage(%s) = %q, %v, want %q", tt.topic, got, err, tt.want)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 {
+		t.Fatalf("received %d requests, want 2", len(received))
+	}
+	if received[0].DedupeKey == received[1].DedupeKey {
+		t.Errorf("topics share dedupe key %q", received[0].DedupeKey)
+	}
+}
+
+func TestGenerateDedupeKeyLength(t *testing.T) {
+	tests := []struct {
+		name    string
+		bytes   int
+		wantLen int
+	}{
+		{"unset uses default", 0, 2 * DefaultDedupeKeyBytes},
+		{"default", DefaultDedupeKeyBytes, 16},
+		{"short", 4, 8},
+		{"full digest", 32, 64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(&Config{DedupeKeyBytes: tt.bytes}, newTestLogger())
+			key := client.generateDedupeKey("alerts", "disk full")
+			if len(key) != tt.wantLen {
+				t.Errorf("key %q has length %d, want %d", key, len(key), tt.wantLen)
+			}
+		})
+	}
+
+	// Longer keys extend shorter ones, since both are digest prefixes
+	short := NewClient(&Config{DedupeKeyBytes: 4}, newTestLogger()).generateDedupeKey("alerts", "disk full")
+	full := NewClient(&Config{DedupeKeyBytes: 32}, newTestLogger()).generateDedupeKey("alerts", "disk full")
+	if !strings.HasPrefix(full, short) {
+		t.Errorf("full key %q does not start with short key %q", full, short)
+	}
+}
+
+func TestGenerateDedupeKeyNoCollisions(t *testing.T) {
+	client := NewClient(&Config{DedupeKeyBytes: DefaultDedupeKeyBytes}, newTestLogger())
+
+	seen := make(map[string]string)
+	for i := range 10000 {
+		text := fmt.Sprintf("backup job %d failed", i)
+		key := client.generateDedupeKey("alerts", text)
+		if other, ok := seen[key]; ok {
+			t.Fatalf("%q and %q share dedupe key %q", other, text, key)
+		}
+		seen[key] = text
+	}
+}
+
 func TestDedupeCleanup(t *testing.T) {
 	cfg := &Config{
 		NtfyServer:        "https://ntfy.sh",
@@ -174,7 +332,7 @@ func TestDedupeCleanup(t *testing.T) {
 
 func TestForwardToDiscorgeous(t *testing.T) {
 	var mu sync.Mutex
-	var receivedReq SpeakRequest
+	var receivedReq discorgeous.SpeakRequest
 	var receivedAuth string
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -216,11 +374,12 @@ func TestForwardToDiscorgeous(t *testing.T) {
 		DiscorgeousBearerToken: "test-token",
 		MaxTextLength:          1000,
 		Interrupt:              true,
+		Voice:                  "amy",
 	}
 
 	client := NewClient(cfg, newTestLogger())
 
-	err := client.forwardToDiscorgeous("Hello world", "dedupe-123")
+	err := client.forwardToDiscorgeous(context.Background(), "Hello world", "dedupe-123")
 	if err != nil {
 		t.Errorf("forwardToDiscorgeous() error = %v", err)
 	}
@@ -236,6 +395,10 @@ func TestForwardToDiscorgeous(t *testing.T) {
 		t.Error("expected interrupt to be true")
 	}
 
+	if receivedReq.Voice != "amy" {
+		t.Errorf("expected voice 'amy', got %q", receivedReq.Voice)
+	}
+
 	if receivedReq.DedupeKey != "dedupe-123" {
 		t.Errorf("expected dedupe_key 'dedupe-123', got %q", receivedReq.DedupeKey)
 	}
@@ -272,7 +435,7 @@ func TestForwardToDiscorgeousNoAuth(t *testing.T) {
 
 	client := NewClient(cfg, newTestLogger())
 
-	err := client.forwardToDiscorgeous("Test message", "")
+	err := client.forwardToDiscorgeous(context.Background(), "Test message", "")
 	if err != nil {
 		t.Errorf("forwardToDiscorgeous() error = %v", err)
 	}
@@ -303,7 +466,7 @@ func TestForwardToDiscorgeousError(t *testing.T) {
 
 	client := NewClient(cfg, newTestLogger())
 
-	err := client.forwardToDiscorgeous("Test message", "")
+	err := client.forwardToDiscorgeous(context.Background(), "Test message", "")
 	if err == nil {
 		t.Error("expected error for 500 response, got nil")
 	}
@@ -313,15 +476,290 @@ func TestForwardToDiscorgeousError(t *testing.T) {
 	}
 }
 
+// instantAfter replaces Client.after, recording each requested delay and
+// firing immediately.
+func instantAfter(mu *sync.Mutex, delays *[]time.Duration) func(time.Duration) <-chan time.Time {
+	return func(d time.Duration) <-chan time.Time {
+		mu.Lock()
+		*delays = append(*delays, d)
+		mu.Unlock()
+		ch := make(chan time.Time, 1)
+		ch <- time.Now()
+		return ch
+	}
+}
+
+func TestForwardHonorsRetryAfter(t *testing.T) {
+	var mu sync.Mutex
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		n := calls
+		mu.Unlock()
+
+		if n == 1 {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":"queue is full"}`))
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	client := NewClient(&Config{DiscorgeousAPIURL: server.URL, MaxTextLength: 1000}, newTestLogger())
+	var delays []time.Duration
+	client.after = instantAfter(&mu, &delays)
+
+	if err := client.forward(context.Background(), "alerts", "Hello", ""); err != nil {
+		t.Fatalf("forward() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+	if len(delays) != 1 || delays[0] != 7*time.Second {
+		t.Errorf("delays = %v, want [7s]", delays)
+	}
+}
+
+func TestForwardGivesUpAfterRetries(t *testing.T) {
+	var mu sync.Mutex
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(&Config{DiscorgeousAPIURL: server.URL, MaxTextLength: 1000}, newTestLogger())
+	var delays []time.Duration
+	client.after = instantAfter(&mu, &delays)
+
+	err := client.forward(context.Background(), "alerts", "Hello", "")
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("forward() error = %v, want a 503 error", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != maxForwardAttempts {
+		t.Errorf("calls = %d, want %d", calls, maxForwardAttempts)
+	}
+	// No pause after the last attempt
+	if len(delays) != maxForwardAttempts-1 {
+		t.Errorf("delays = %v, want %d pauses", delays, maxForwardAttempts-1)
+	}
+}
+
+func TestForward503WithoutRetryAfter(t *testing.T) {
+	var mu sync.Mutex
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(&Config{DiscorgeousAPIURL: server.URL, MaxTextLength: 1000}, newTestLogger())
+	var delays []time.Duration
+	client.after = instantAfter(&mu, &delays)
+
+	if err := client.forward(context.Background(), "alerts", "Hello", ""); err == nil {
+		t.Fatal("forward() error = nil, want error")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 1 || len(delays) != 0 {
+		t.Errorf("calls = %d, delays = %v, want 1 call and no pause", calls, delays)
+	}
+}
+
+func TestForwardRetryAfterCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(&Config{DiscorgeousAPIURL: server.URL, MaxTextLength: 1000}, newTestLogger())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := client.forward(ctx, "alerts", "Hello", ""); err != context.DeadlineExceeded {
+		t.Errorf("forward() error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestForwardMaxInFlight(t *testing.T) {
+	const limit = 2
+	var active, peak atomic.Int32
+	started := make(chan struct{}, limit+1)
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := active.Add(1)
+		defer active.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	client := NewClient(&Config{DiscorgeousAPIURL: server.URL, MaxTextLength: 1000, MaxInFlight: limit}, newTestLogger())
+
+	var wg sync.WaitGroup
+	for range limit + 1 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := client.forward(context.Background(), "alerts", "Hello", ""); err != nil {
+				t.Errorf("forward() error = %v", err)
+			}
+		}()
+	}
+
+	for range limit {
+		select {
+		case <-started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for forwards to start")
+		}
+	}
+	select {
+	case <-started:
+		t.Fatal("forward started beyond the in-flight limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	wg.Wait()
+
+	if got := peak.Load(); got != limit {
+		t.Errorf("peak in-flight = %d, want %d", got, limit)
+	}
+}
+
+func TestForwardReusesConnections(t *testing.T) {
+	const pool = 4
+	var conns atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(discorgeous.SpeakResponse{JobID: "job-1", Message: "queued"})
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	client := NewClient(&Config{DiscorgeousAPIURL: server.URL, MaxTextLength: 1000, APIMaxIdleConns: pool}, newTestLogger())
+
+	for range 5 {
+		if err := client.forward(context.Background(), "alerts", "Hello", ""); err != nil {
+			t.Fatalf("forward() error = %v", err)
+		}
+	}
+	if got := conns.Load(); got != 1 {
+		t.Errorf("sequential forwards opened %d connections, want 1", got)
+	}
+
+	// Rounds of concurrent forwards draw on the pool rather than dialing
+	for range 3 {
+		var wg sync.WaitGroup
+		for range pool {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := client.forward(context.Background(), "alerts", "Hello", ""); err != nil {
+					t.Errorf("forward() error = %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+	if got := conns.Load(); got > pool {
+		t.Errorf("concurrent forwards opened %d connections, want at most %d", got, pool)
+	}
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         Config
+		wantTimeout time.Duration
+		wantIdle    int
+	}{
+		{"defaults", Config{}, DefaultAPITimeout, DefaultAPIMaxIdleConns},
+		{"configured", Config{APITimeout: 5 * time.Second, APIMaxIdleConns: 2}, 5 * time.Second, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc := newHTTPClient(&tt.cfg)
+			if hc.Timeout != tt.wantTimeout {
+				t.Errorf("Timeout = %v, want %v", hc.Timeout, tt.wantTimeout)
+			}
+			transport := hc.Transport.(*http.Transport)
+			if transport.MaxIdleConnsPerHost != tt.wantIdle || transport.MaxIdleConns != tt.wantIdle {
+				t.Errorf("idle conns = %d per host, %d total, want %d", transport.MaxIdleConnsPerHost, transport.MaxIdleConns, tt.wantIdle)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{" 0 ", 0, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{"3600", maxRetryAfter, true},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second, true},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
 func TestHandleMessage(t *testing.T) {
 	var mu sync.Mutex
-	var receivedReqs []SpeakRequest
+	var receivedReqs []discorgeous.SpeakRequest
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		mu.Lock()
 		defer mu.Unlock()
 
-		var req SpeakRequest
+		var req discorgeous.SpeakRequest
 		json.NewDecoder(r.Body).Decode(&req)
 		receivedReqs = append(receivedReqs, req)
 
@@ -344,7 +782,7 @@ func TestHandleMessage(t *testing.T) {
 	client := NewClient(cfg, newTestLogger())
 
 	// Test with title and message
-	client.handleMessage(NtfyMessage{
+	client.HandleMessage(context.Background(), NtfyMessage{
 		ID:      "msg1",
 		Event:   "message",
 		Topic:   "test",
@@ -362,7 +800,7 @@ func TestHandleMessage(t *testing.T) {
 	mu.Unlock()
 
 	// Test with empty message (should not forward)
-	client.handleMessage(NtfyMessage{
+	client.HandleMessage(context.Background(), NtfyMessage{
 		ID:      "msg2",
 		Event:   "message",
 		Topic:   "test",
@@ -413,3 +851,288 @@ func TestRunCancellation(t *testing.T) {
 		t.Error("Run did not exit after context cancellation")
 	}
 }
+
+func TestConnected(t *testing.T) {
+	opened := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		close(opened)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	cfg := &Config{
+		NtfyServer:        server.URL,
+		NtfyTopics:        []string{"alerts"},
+		DiscorgeousAPIURL: "http://localhost:8080",
+		MaxTextLength:     1000,
+		MaxLineBytes:      1024,
+	}
+	client := NewClient(cfg, newTestLogger())
+
+	if client.Connected() {
+		t.Fatal("Connected() = true before subscribing")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		client.subscribe(ctx, "alerts")
+		close(done)
+	}()
+
+	select {
+	case <-opened:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for stream to open")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !client.Connected() {
+		if time.Now().After(deadline) {
+			t.Fatal("Connected() = false with the stream open")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	<-done
+	if client.Connected() {
+		t.Error("Connected() = true after the stream closed")
+	}
+}
+
+func TestHandleMessageOnTooLong(t *testing.T) {
+	tests := []struct {
+		name        string
+		onTooLong   string
+		message     string
+		wantOutcome Outcome
+		wantText    string
+	}{
+		{"truncate by default", "", "Disk almost full", OutcomeForwarded, "Disk almos"},
+		{"truncate", TooLongTruncate, "Disk almost full", OutcomeForwarded, "Disk almos"},
+		{"skip", TooLongSkip, "Disk almost full", OutcomeTooLong, ""},
+		{"skip leaves short text alone", TooLongSkip, "Disk full", OutcomeForwarded, "Disk full"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var received []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req discorgeous.SpeakRequest
+				json.NewDecoder(r.Body).Decode(&req)
+				mu.Lock()
+				received = append(received, req.Text)
+				mu.Unlock()
+				w.WriteHeader(http.StatusAccepted)
+			}))
+			defer server.Close()
+
+			cfg := &Config{
+				DiscorgeousAPIURL: server.URL,
+				MaxTextLength:     10,
+				OnTooLong:         tt.onTooLong,
+			}
+			client := NewClient(cfg, newTestLogger())
+
+			outcome, err := client.HandleMessage(context.Background(), NtfyMessage{ID: "1", Topic: "alerts", Message: tt.message})
+			if err != nil {
+				t.Fatalf("HandleMessage() error = %v", err)
+			}
+			if outcome != tt.wantOutcome {
+				t.Errorf("HandleMessage() = %q, want %q", outcome, tt.wantOutcome)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if tt.wantText == "" {
+				if len(received) != 0 {
+					t.Errorf("forwarded %q, want nothing", received)
+				}
+				return
+			}
+			if len(received) != 1 || received[0] != tt.wantText {
+				t.Errorf("forwarded %q, want [%q]", received, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestHandleMessageMetrics(t *testing.T) {
+	var mu sync.Mutex
+	fail := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	cfg := &Config{
+		DiscorgeousAPIURL: server.URL,
+		MaxTextLength:     1000,
+		DedupeWindow:      time.Minute,
+	}
+	client := NewClient(cfg, newTestLogger())
+	recorder := metrics.NewRecorder()
+	client.SetMetrics(recorder)
+
+	ctx := context.Background()
+	expectOutcome := func(msg NtfyMessage, want Outcome) {
+		t.Helper()
+		got, err := client.HandleMessage(ctx, msg)
+		if got != want || (err != nil) != (want == OutcomeFailed) {
+			t.Errorf("HandleMessage(%s) = %q, %v, want %q", msg.ID, got, err, want)
+		}
+	}
+	expectOutcome(NtfyMessage{ID: "1", Topic: "alerts", Message: "Disk full"}, OutcomeForwarded)
+	expectOutcome(NtfyMessage{ID: "2", Topic: "alerts", Message: "Disk full"}, OutcomeDeduped)
+	expectOutcome(NtfyMessage{ID: "3", Topic: "alerts"}, OutcomeFiltered)
+	mu.Lock()
+	fail = true
+	mu.Unlock()
+	expectOutcome(NtfyMessage{ID: "4", Topic: "backups", Message: "Job failed"}, OutcomeFailed)
+
+	for _, tt := range []struct {
+		name  string
+		topic string
+		want  float64
+	}{
+		{"relay_messages_received_total", "alerts", 3},
+		{"relay_messages_received_total", "backups", 1},
+		{"relay_messages_forwarded_total", "alerts", 1},
+		{"relay_messages_deduped_total", "alerts", 1},
+		{"relay_messages_filtered_total", "alerts", 1},
+		{"relay_forward_failures_total", "backups", 1},
+		{"relay_messages_forwarded_total", "backups", 0},
+	} {
+		if got := recorder.CounterValue(tt.name, "topic", tt.topic); got != tt.want {
+			t.Errorf("%s{topic=%q} = %v, want %v", tt.name, tt.topic, got, tt.want)
+		}
+	}
+
+	if got, ok := recorder.GaugeValue("relay_dedupe_entries"); !ok || got != 2 {
+		t.Errorf("relay_dedupe_entries = %v, %v, want 2", got, ok)
+	}
+	if got := recorder.Observations("relay_forward_seconds"); len(got) != 2 {
+		t.Errorf("relay_forward_seconds observations = %d, want 2", len(got))
+	}
+}
+
+func TestDedupeEntriesGaugeAfterCleanup(t *testing.T) {
+	client := NewClient(&Config{MaxTextLength: 1000, DedupeWindow: time.Minute}, newTestLogger())
+	recorder := metrics.NewRecorder()
+	client.SetMetrics(recorder)
+
+	client.recordDedupeKey("fresh")
+	client.dedupeMu.Lock()
+	client.dedupeMap["stale"] = time.Now().Add(-2 * time.Minute)
+	client.dedupeMu.Unlock()
+
+	client.cleanupDedupeMap()
+	if got, ok := recorder.GaugeValue("relay_dedupe_entries"); !ok || got != 1 {
+		t.Errorf("relay_dedupe_entries = %v, %v, want 1", got, ok)
+	}
+}
+
+func TestSubscribeURLSince(t *testing.T) {
+	tests := []struct {
+		name   string
+		since  string
+		lastID string
+		want   string
+	}{
+		{"new messages only", "", "", "https://ntfy.example/alerts/json"},
+		{"ignores last id without since", "", "abc123", "https://ntfy.example/alerts/json"},
+		{"duration", "5m", "", "https://ntfy.example/alerts/json?since=5m"},
+		{"all", "all", "", "https://ntfy.example/alerts/json?since=all"},
+		{"resumes after last id", "all", "abc123", "https://ntfy.example/alerts/json?since=abc123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(&Config{NtfyServer: "https://ntfy.example/", NtfySince: tt.since, MaxTextLength: 1000}, newTestLogger())
+			client.recordLastID("alerts", tt.lastID)
+			if got := client.subscribeURL("alerts"); got != tt.want {
+				t.Errorf("subscribeURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubscribeSendsSince(t *testing.T) {
+	queries := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries <- r.URL.Query().Get("since")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id":"msg1","event":"message","topic":"alerts","message":""}` + "\n"))
+	}))
+	defer server.Close()
+
+	cfg := &Config{
+		NtfyServer:        server.URL,
+		DiscorgeousAPIURL: "http://localhost:8080",
+		NtfySince:         "10m",
+		MaxTextLength:     1000,
+		MaxLineBytes:      1024,
+	}
+	client := NewClient(cfg, newTestLogger())
+
+	// First connect asks for NtfySince; the reconnect resumes after msg1
+	for _, want := range []string{"10m", "msg1"} {
+		if err := client.subscribe(context.Background(), "alerts"); err != nil {
+			t.Fatalf("subscribe() error = %v", err)
+		}
+		if got := <-queries; got != want {
+			t.Errorf("since = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestSubscribeSkipsOversizedLine(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req discorgeous.SpeakRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		received = append(received, req.Text)
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer api.Close()
+
+	oversized := `{"id":"big","event":"message","topic":"alerts","message":"` + strings.Repeat("x", 4096) + `"}`
+	ntfy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, `{"id":"1","event":"message","topic":"alerts","message":"before"}`+"\n")
+		io.WriteString(w, oversized+"\n")
+		io.WriteString(w, `{"id":"2","event":"message","topic":"alerts","message":"after"}`+"\r\n")
+		io.WriteString(w, oversized)
+	}))
+	defer ntfy.Close()
+
+	cfg := &Config{
+		NtfyServer:        ntfy.URL,
+		DiscorgeousAPIURL: api.URL,
+		MaxTextLength:     10000,
+		MaxLineBytes:      1024,
+	}
+	client := NewClient(cfg, newTestLogger())
+
+	if err := client.subscribe(context.Background(), "alerts"); err != nil {
+		t.Fatalf("subscribe() error = %v, want the stream to survive oversized lines", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 || received[0] != "before" || received[1] != "after" {
+		t.Errorf("forwarded %q, want [before after]", received)
+	}
+}
@@ -1,28 +1,62 @@
 package relay
 
 import (
+	"crypto/sha256"
 	"errors"
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// Defaults for the HTTP client forwarding to Discorgeous, used unless
+// DISCORGEOUS_TIMEOUT or DISCORGEOUS_MAX_IDLE_CONNS say otherwise.
+const (
+	DefaultAPITimeout      = 30 * time.Second
+	DefaultAPIMaxIdleConns = 16
+)
+
+// DefaultDedupeKeyBytes is how much of the SHA-256 digest dedupe keys keep
+// unless NTFY_DEDUPE_KEY_BYTES says otherwise.
+const DefaultDedupeKeyBytes = 8
+
+// NTFY_ON_TOO_LONG values: what the relay does with a message whose
+// formatted text exceeds MaxTextLength.
+const (
+	// TooLongTruncate cuts the text at MaxTextLength.
+	TooLongTruncate = "truncate"
+	// TooLongSkip drops the message.
+	TooLongSkip = "skip"
+)
+
 // Config holds all ntfy relay configuration.
 type Config struct {
 	// Ntfy settings
-	NtfyServer string
-	NtfyTopics []string
+	NtfyServer   string
+	NtfyTopics   []string
+	NtfySince    string // ntfy since= value for the first connect; empty means new messages only
+	MaxLineBytes int    // Longest stream line read; longer lines are skipped
 
 	// Discorgeous API settings
 	DiscorgeousAPIURL      string
 	DiscorgeousBearerToken string
+	MaxInFlight            int           // Maximum concurrent forwards; 0 means unlimited
+	APITimeout             time.Duration // Per-request timeout; 0 uses DefaultAPITimeout
+	APIMaxIdleConns        int           // Idle connections kept for reuse; 0 uses DefaultAPIMaxIdleConns
 
 	// Formatting settings
-	Prefix        string
-	Interrupt     bool
-	DedupeWindow  time.Duration
-	MaxTextLength int
+	Voice          string // Voice for forwarded messages; empty uses the server default
+	Prefix         string
+	SpeakTopic     bool // Speak the ntfy topic before the title and message
+	Interrupt      bool
+	DedupeWindow   time.Duration
+	DedupeKeyBytes int // SHA-256 bytes kept in dedupe keys; sha256.Size keeps the full digest, 0 the default
+	MaxTextLength  int
+	OnTooLong      string // TooLongTruncate or TooLongSkip; empty truncates
+
+	// HTTP settings
+	HTTPPort int // Port for /healthz and /ready; 0 disables the server
 
 	// Logging settings
 	LogLevel  string
@@ -44,24 +78,41 @@ func Load() (*Config, error) {
 
 	cfg := &Config{
 		// Ntfy settings
-		NtfyServer: getEnvString("NTFY_SERVER", "https://ntfy.sh"),
-		NtfyTopics: topics,
+		NtfyServer:   getEnvString("NTFY_SERVER", "https://ntfy.sh"),
+		NtfyTopics:   topics,
+		NtfySince:    strings.TrimSpace(os.Getenv("NTFY_SINCE")),
+		MaxLineBytes: getEnvInt("NTFY_MAX_LINE_BYTES", 1024*1024),
 
 		// Discorgeous API settings
-		DiscorgeousAPIURL:      getEnvString("DISCORGEOUS_API_URL", "http://discorgeous:8080"),
-		DiscorgeousBearerToken: os.Getenv("DISCORGEOUS_BEARER_TOKEN"),
+		DiscorgeousAPIURL: getEnvString("DISCORGEOUS_API_URL", "http://discorgeous:8080"),
+		MaxInFlight:       getEnvInt("NTFY_MAX_IN_FLIGHT", 4),
+		APITimeout:        getEnvDuration("DISCORGEOUS_TIMEOUT", DefaultAPITimeout),
+		APIMaxIdleConns:   getEnvInt("DISCORGEOUS_MAX_IDLE_CONNS", DefaultAPIMaxIdleConns),
 
 		// Formatting settings
-		Prefix:        os.Getenv("NTFY_PREFIX"),
-		Interrupt:     getEnvBool("NTFY_INTERRUPT", false),
-		DedupeWindow:  getEnvDuration("NTFY_DEDUPE_WINDOW", 0),
-		MaxTextLength: getEnvInt("NTFY_MAX_TEXT_LENGTH", 1000),
+		Voice:          os.Getenv("NTFY_VOICE"),
+		Prefix:         os.Getenv("NTFY_PREFIX"),
+		SpeakTopic:     getEnvBool("NTFY_SPEAK_TOPIC", false),
+		Interrupt:      getEnvBool("NTFY_INTERRUPT", false),
+		DedupeWindow:   getEnvDuration("NTFY_DEDUPE_WINDOW", 0),
+		DedupeKeyBytes: getEnvInt("NTFY_DEDUPE_KEY_BYTES", DefaultDedupeKeyBytes),
+		MaxTextLength:  getEnvInt("NTFY_MAX_TEXT_LENGTH", 1000),
+		OnTooLong:      getEnvString("NTFY_ON_TOO_LONG", TooLongTruncate),
+
+		// HTTP settings
+		HTTPPort: getEnvInt("RELAY_HTTP_PORT", 0),
 
 		// Logging settings
 		LogLevel:  getEnvString("LOG_LEVEL", "info"),
 		LogFormat: getEnvString("LOG_FORMAT", "text"),
 	}
 
+	token, err := getEnvSecret("DISCORGEOUS_BEARER_TOKEN")
+	if err != nil {
+		return nil, err
+	}
+	cfg.DiscorgeousBearerToken = token
+
 	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
@@ -79,6 +130,14 @@ func (c *Config) Validate() error {
 		return errors.New("NTFY_SERVER cannot be empty")
 	}
 
+	if !validSince(c.NtfySince) {
+		return errors.New("NTFY_SINCE must be empty, \"all\", a duration (e.g. 5m), or a Unix timestamp")
+	}
+
+	if c.MaxLineBytes < 1 {
+		return errors.New("NTFY_MAX_LINE_BYTES must be at least 1")
+	}
+
 	if c.DiscorgeousAPIURL == "" {
 		return errors.New("DISCORGEOUS_API_URL cannot be empty")
 	}
@@ -87,10 +146,35 @@ func (c *Config) Validate() error {
 		return errors.New("NTFY_MAX_TEXT_LENGTH must be at least 1")
 	}
 
+	if c.OnTooLong != "" && c.OnTooLong != TooLongTruncate && c.OnTooLong != TooLongSkip {
+		return errors.New("NTFY_ON_TOO_LONG must be one of: truncate, skip")
+	}
+
+	if c.HTTPPort < 0 || c.HTTPPort > 65535 {
+		return errors.New("RELAY_HTTP_PORT must be between 0 and 65535")
+	}
+
+	if c.MaxInFlight < 0 {
+		return errors.New("NTFY_MAX_IN_FLIGHT must be non-negative")
+	}
+
+	if c.APITimeout < 0 {
+		return errors.New("DISCORGEOUS_TIMEOUT must be non-negative")
+	}
+
+	if c.APIMaxIdleConns < 0 {
+		return errors.New("DISCORGEOUS_MAX_IDLE_CONNS must be non-negative")
+	}
+
 	if c.DedupeWindow < 0 {
 		return errors.New("NTFY_DEDUPE_WINDOW must be non-negative")
 	}
 
+	// Zero leaves DefaultDedupeKeyBytes in effect
+	if c.DedupeKeyBytes < 0 || c.DedupeKeyBytes > sha256.Size {
+		return errors.New("NTFY_DEDUPE_KEY_BYTES must be between 1 and 32")
+	}
+
 	validLogLevels := map[string]bool{"debug": true, "info": true, "warn": true, "error": true}
 	if !validLogLevels[c.LogLevel] {
 		return errors.New("LOG_LEVEL must be one of: debug, info, warn, error")
@@ -104,6 +188,21 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// validSince reports whether value is an NTFY_SINCE setting the relay
+// understands.
+func validSince(value string) bool {
+	if value == "" || value == "all" {
+		return true
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return d > 0
+	}
+	if ts, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return ts > 0
+	}
+	return false
+}
+
 // getEnvString returns the environment variable value or a default.
 func getEnvString(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -141,3 +240,22 @@ func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	}
 	return defaultValue
 }
+
+// getEnvSecret returns the secret named key, read from the file named by
+// key+"_FILE" with surrounding whitespace trimmed if that is set, and from
+// key itself otherwise. A file that can't be read or is empty is an error.
+func getEnvSecret(key string) (string, error) {
+	path := os.Getenv(key + "_FILE")
+	if path == "" {
+		return os.Getenv(key), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("%s_FILE: %w", key, err)
+	}
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return "", fmt.Errorf("%s_FILE: %s is empty", key, path)
+	}
+	return secret, nil
+}
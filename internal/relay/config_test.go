@@ -2,6 +2,7 @@ package relay
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -9,9 +10,9 @@ import (
 func TestLoad(t *testing.T) {
 	// Save and restore environment
 	envVars := []string{
-		"NTFY_SERVER", "NTFY_TOPICS", "DISCORGEOUS_API_URL", "DISCORGEOUS_BEARER_TOKEN",
-		"NTFY_PREFIX", "NTFY_INTERRUPT", "NTFY_DEDUPE_WINDOW", "NTFY_MAX_TEXT_LENGTH",
-		"LOG_LEVEL", "LOG_FORMAT",
+		"NTFY_SERVER", "NTFY_TOPICS", "NTFY_SINCE", "NTFY_MAX_LINE_BYTES", "DISCORGEOUS_API_URL", "DISCORGEOUS_BEARER_TOKEN", "DISCORGEOUS_BEARER_TOKEN_FILE",
+		"NTFY_MAX_IN_FLIGHT", "DISCORGEOUS_TIMEOUT", "DISCORGEOUS_MAX_IDLE_CONNS", "NTFY_VOICE", "NTFY_PREFIX", "NTFY_SPEAK_TOPIC", "NTFY_INTERRUPT", "NTFY_DEDUPE_WINDOW", "NTFY_DEDUPE_KEY_BYTES", "NTFY_MAX_TEXT_LENGTH", "NTFY_ON_TOO_LONG",
+		"RELAY_HTTP_PORT", "LOG_LEVEL", "LOG_FORMAT",
 	}
 	saved := make(map[string]string)
 	for _, k := range envVars {
@@ -32,6 +33,11 @@ func TestLoad(t *testing.T) {
 		os.Unsetenv(k)
 	}
 
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("file-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
 	tests := []struct {
 		name      string
 		envSetup  map[string]string
@@ -54,7 +60,17 @@ func TestLoad(t *testing.T) {
 					len(c.NtfyTopics) == 1 &&
 					c.NtfyTopics[0] == "test-topic" &&
 					c.DiscorgeousAPIURL == "http://discorgeous:8080" &&
-					c.MaxTextLength == 1000
+					c.MaxTextLength == 1000 &&
+					c.MaxInFlight == 4 &&
+					c.APITimeout == DefaultAPITimeout &&
+					c.APIMaxIdleConns == DefaultAPIMaxIdleConns &&
+					c.Voice == "" &&
+					c.HTTPPort == 0 &&
+					c.NtfySince == "" &&
+					c.MaxLineBytes == 1024*1024 &&
+					c.DedupeKeyBytes == DefaultDedupeKeyBytes &&
+					c.OnTooLong == TooLongTruncate &&
+					!c.SpeakTopic
 			},
 		},
 		{
@@ -73,30 +89,70 @@ func TestLoad(t *testing.T) {
 		{
 			name: "full config",
 			envSetup: map[string]string{
-				"NTFY_SERVER":              "https://custom.ntfy.server",
-				"NTFY_TOPICS":              "topic1",
-				"DISCORGEOUS_API_URL":      "http://localhost:9090",
-				"DISCORGEOUS_BEARER_TOKEN": "secret-token",
-				"NTFY_PREFIX":              "Alert",
-				"NTFY_INTERRUPT":           "true",
-				"NTFY_DEDUPE_WINDOW":       "5m",
-				"NTFY_MAX_TEXT_LENGTH":     "500",
-				"LOG_LEVEL":                "debug",
-				"LOG_FORMAT":               "json",
+				"NTFY_SERVER":                "https://custom.ntfy.server",
+				"NTFY_TOPICS":                "topic1",
+				"NTFY_SINCE":                 "5m",
+				"NTFY_MAX_LINE_BYTES":        "65536",
+				"DISCORGEOUS_API_URL":        "http://localhost:9090",
+				"DISCORGEOUS_BEARER_TOKEN":   "secret-token",
+				"NTFY_MAX_IN_FLIGHT":         "2",
+				"DISCORGEOUS_TIMEOUT":        "10s",
+				"DISCORGEOUS_MAX_IDLE_CONNS": "4",
+				"RELAY_HTTP_PORT":            "8081",
+				"NTFY_VOICE":                 "amy",
+				"NTFY_PREFIX":                "Alert",
+				"NTFY_SPEAK_TOPIC":           "true",
+				"NTFY_INTERRUPT":             "true",
+				"NTFY_DEDUPE_WINDOW":         "5m",
+				"NTFY_DEDUPE_KEY_BYTES":      "32",
+				"NTFY_MAX_TEXT_LENGTH":       "500",
+				"NTFY_ON_TOO_LONG":           "skip",
+				"LOG_LEVEL":                  "debug",
+				"LOG_FORMAT":                 "json",
 			},
 			wantErr: false,
 			checkFunc: func(c *Config) bool {
 				return c.NtfyServer == "https://custom.ntfy.server" &&
 					c.DiscorgeousAPIURL == "http://localhost:9090" &&
 					c.DiscorgeousBearerToken == "secret-token" &&
+					c.MaxInFlight == 2 &&
+					c.APITimeout == 10*time.Second &&
+					c.APIMaxIdleConns == 4 &&
+					c.HTTPPort == 8081 &&
+					c.NtfySince == "5m" &&
+					c.MaxLineBytes == 65536 &&
+					c.Voice == "amy" &&
 					c.Prefix == "Alert" &&
+					c.SpeakTopic &&
 					c.Interrupt == true &&
 					c.DedupeWindow == 5*time.Minute &&
+					c.DedupeKeyBytes == 32 &&
 					c.MaxTextLength == 500 &&
+					c.OnTooLong == TooLongSkip &&
 					c.LogLevel == "debug" &&
 					c.LogFormat == "json"
 			},
 		},
+		{
+			name: "bearer token file",
+			envSetup: map[string]string{
+				"NTFY_TOPICS":                   "topic1",
+				"DISCORGEOUS_BEARER_TOKEN":      "inline-token",
+				"DISCORGEOUS_BEARER_TOKEN_FILE": tokenFile,
+			},
+			wantErr: false,
+			checkFunc: func(c *Config) bool {
+				return c.DiscorgeousBearerToken == "file-token"
+			},
+		},
+		{
+			name: "missing bearer token file",
+			envSetup: map[string]string{
+				"NTFY_TOPICS":                   "topic1",
+				"DISCORGEOUS_BEARER_TOKEN_FILE": tokenFile + ".missing",
+			},
+			wantErr: true,
+		},
 		{
 			name: "invalid log level",
 			envSetup: map[string]string{
@@ -113,6 +169,30 @@ func TestLoad(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "dedupe key bytes too large",
+			envSetup: map[string]string{
+				"NTFY_TOPICS":           "topic1",
+				"NTFY_DEDUPE_KEY_BYTES": "33",
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative dedupe key bytes",
+			envSetup: map[string]string{
+				"NTFY_TOPICS":           "topic1",
+				"NTFY_DEDUPE_KEY_BYTES": "-1",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid on too long",
+			envSetup: map[string]string{
+				"NTFY_TOPICS":      "topic1",
+				"NTFY_ON_TOO_LONG": "drop",
+			},
+			wantErr: true,
+		},
 		{
 			name: "invalid max text length",
 			envSetup: map[string]string{
@@ -121,6 +201,74 @@ func TestLoad(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "negative max in flight",
+			envSetup: map[string]string{
+				"NTFY_TOPICS":        "topic1",
+				"NTFY_MAX_IN_FLIGHT": "-1",
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative api timeout",
+			envSetup: map[string]string{
+				"NTFY_TOPICS":         "topic1",
+				"DISCORGEOUS_TIMEOUT": "-1s",
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative max idle conns",
+			envSetup: map[string]string{
+				"NTFY_TOPICS":                "topic1",
+				"DISCORGEOUS_MAX_IDLE_CONNS": "-1",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid relay http port",
+			envSetup: map[string]string{
+				"NTFY_TOPICS":     "topic1",
+				"RELAY_HTTP_PORT": "70000",
+			},
+			wantErr: true,
+		},
+		{
+			name: "since all",
+			envSetup: map[string]string{
+				"NTFY_TOPICS": "topic1",
+				"NTFY_SINCE":  "all",
+			},
+			checkFunc: func(c *Config) bool {
+				return c.NtfySince == "all"
+			},
+		},
+		{
+			name: "since unix timestamp",
+			envSetup: map[string]string{
+				"NTFY_TOPICS": "topic1",
+				"NTFY_SINCE":  "1735689600",
+			},
+			checkFunc: func(c *Config) bool {
+				return c.NtfySince == "1735689600"
+			},
+		},
+		{
+			name: "invalid since",
+			envSetup: map[string]string{
+				"NTFY_TOPICS": "topic1",
+				"NTFY_SINCE":  "yesterday",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid max line bytes",
+			envSetup: map[string]string{
+				"NTFY_TOPICS":         "topic1",
+				"NTFY_MAX_LINE_BYTES": "0",
+			},
+			wantErr: true,
+		},
 		{
 			name: "empty topics after trimming",
 			envSetup: map[string]string{
@@ -176,6 +324,7 @@ func TestValidate(t *testing.T) {
 				NtfyTopics:        []string{"topic1"},
 				DiscorgeousAPIURL: "http://localhost:8080",
 				MaxTextLength:     1000,
+				MaxLineBytes:      1024,
 				LogLevel:          "info",
 				LogFormat:         "text",
 			},
@@ -188,6 +337,7 @@ func TestValidate(t *testing.T) {
 				NtfyTopics:        []string{},
 				DiscorgeousAPIURL: "http://localhost:8080",
 				MaxTextLength:     1000,
+				MaxLineBytes:      1024,
 				LogLevel:          "info",
 				LogFormat:         "text",
 			},
@@ -200,6 +350,7 @@ func TestValidate(t *testing.T) {
 				NtfyTopics:        []string{"topic1"},
 				DiscorgeousAPIURL: "http://localhost:8080",
 				MaxTextLength:     1000,
+				MaxLineBytes:      1024,
 				LogLevel:          "info",
 				LogFormat:         "text",
 			},
@@ -212,6 +363,7 @@ func TestValidate(t *testing.T) {
 				NtfyTopics:        []string{"topic1"},
 				DiscorgeousAPIURL: "",
 				MaxTextLength:     1000,
+				MaxLineBytes:      1024,
 				LogLevel:          "info",
 				LogFormat:         "text",
 			},
@@ -224,6 +376,7 @@ func TestValidate(t *testing.T) {
 				NtfyTopics:        []string{"topic1"},
 				DiscorgeousAPIURL: "http://localhost:8080",
 				MaxTextLength:     1000,
+				MaxLineBytes:      1024,
 				DedupeWindow:      -1 * time.Second,
 				LogLevel:          "info",
 				LogFormat:         "text",
@@ -0,0 +1,262 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"text/template"
+
+	"github.com/dgnsrekt/discorgeous-go/internal/logging"
+	"github.com/dgnsrekt/discorgeous-go/internal/wav"
+)
+
+var (
+	// ErrNoCommandSpecified is returned when a command engine has no command.
+	ErrNoCommandSpecified = errors.New("no TTS command specified")
+	// ErrInvalidCommand is returned when a command template cannot be parsed.
+	ErrInvalidCommand = errors.New("invalid TTS command")
+)
+
+// CommandConfig holds configuration for a command-line TTS engine.
+type CommandConfig struct {
+	// Name is the registry name of the engine. If empty, "command" is used.
+	Name string
+	// Command is the command line to run, e.g. "mytts --voice {{.Voice}}".
+	// It is split into arguments like a shell would, honouring single and
+	// double quotes, but never run through a shell. Each argument is then a
+	// text/template over {{.Voice}} and {{.Language}}, so a substituted
+	// value can't add arguments. The text is written to stdin.
+	Command string
+	// DefaultVoice is the voice used when a request names none.
+	DefaultVoice string
+	// SampleRate is the sample rate of the command's output in Hz if it
+	// writes raw 16-bit PCM. If zero, the output must be a WAV file.
+	SampleRate int
+	// Channels is the number of channels in raw PCM output.
+	// If zero, 1 is used.
+	Channels int
+	// MaxOutputBytes caps the command's output per synthesis; the command
+	// is killed and ErrOutputTooLarge returned if it writes more. Zero is
+	// unlimited.
+	MaxOutputBytes int
+}
+
+// commandData is the data the command template is executed with.
+type commandData struct {
+	Voice    string
+	Language string
+}
+
+// CommandEngine implements the Engine interface by running an external
+// program, for TTS tools without a Go integration.
+type CommandEngine struct {
+	config CommandConfig
+	binary string
+	args   []*template.Template
+	logger *slog.Logger
+}
+
+// NewCommandEngine creates a TTS engine that runs cfg.Command.
+func NewCommandEngine(cfg CommandConfig, logger *slog.Logger) (*CommandEngine, error) {
+	fields, err := splitCommand(cfg.Command)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCommand, err)
+	}
+	if len(fields) == 0 {
+		return nil, ErrNoCommandSpecified
+	}
+
+	// The program itself is not templated, so a voice can't change it
+	binary, err := exec.LookPath(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCommand, err)
+	}
+
+	args := make([]*template.Template, len(fields)-1)
+	for i, field := range fields[1:] {
+		tmpl, err := template.New("arg").Option("missingkey=error").Parse(field)
+		if err != nil {
+			return nil, fmt.Errorf("%w: argument %d: %v", ErrInvalidCommand, i+1, err)
+		}
+		args[i] = tmpl
+	}
+
+	if cfg.Channels <= 0 {
+		cfg.Channels = 1
+	}
+
+	engine := &CommandEngine{
+		config: cfg,
+		binary: binary,
+		args:   args,
+		logger: logger,
+	}
+
+	// Catch references to unknown fields now rather than on the first job
+	if _, _, err := engine.buildArgs(SynthesizeRequest{}); err != nil {
+		return nil, err
+	}
+	return engine, nil
+}
+
+// splitCommand splits a command line into arguments on unquoted
+// whitespace. Single quotes preserve everything literally; double quotes
+// allow \" and \\ escapes.
+func splitCommand(command string) ([]string, error) {
+	var fields []string
+	var current strings.Builder
+	inField := false
+	var quote rune
+
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case quote == '"':
+			switch {
+			case r == '"':
+				quote = 0
+			case r == '\\' && i+1 < len(runes) && (runes[i+1] == '"' || runes[i+1] == '\\'):
+				i++
+				current.WriteRune(runes[i])
+			default:
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inField = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inField {
+				fields = append(fields, current.String())
+				current.Reset()
+				inField = false
+			}
+		default:
+			current.WriteRune(r)
+			inField = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if inField {
+		fields = append(fields, current.String())
+	}
+	return fields, nil
+}
+
+// Name returns the engine identifier.
+func (c *CommandEngine) Name() string {
+	if c.config.Name != "" {
+		return c.config.Name
+	}
+	return "command"
+}
+
+// MaxTextLength returns DefaultMaxTextLength; the text is passed on stdin.
+func (c *CommandEngine) MaxTextLength() int {
+	return DefaultMaxTextLength
+}
+
+// buildArgs returns the command arguments and resolved voice for a request.
+func (c *CommandEngine) buildArgs(req SynthesizeRequest) ([]string, string, error) {
+	voice := req.Voice
+	if voice == "" || voice == "default" {
+		voice = c.config.DefaultVoice
+	}
+
+	data := commandData{Voice: voice, Language: req.Language}
+	args := make([]string, len(c.args))
+	for i, tmpl := range c.args {
+		var buf strings.Builder
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, "", fmt.Errorf("%w: argument %d: %v", ErrInvalidCommand, i+1, err)
+		}
+		args[i] = buf.String()
+	}
+	return args, voice, nil
+}
+
+// Synthesize converts text to audio by running the command.
+func (c *CommandEngine) Synthesize(ctx context.Context, req SynthesizeRequest) (*AudioResult, error) {
+	if req.Text == "" {
+		return nil, ErrEmptyText
+	}
+
+	args, voice, err := c.buildArgs(req)
+	if err != nil {
+		return nil, err
+	}
+
+	c.logger.Debug("running TTS command",
+		"binary", c.binary,
+		"voice", voice,
+		"text_length", len(req.Text),
+	)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cmd := exec.CommandContext(runCtx, c.binary, args...)
+	cmd.Stdin = strings.NewReader(req.Text)
+
+	stdout := &cappedBuffer{max: c.config.MaxOutputBytes, onOverflow: cancel}
+	var stderr bytes.Buffer
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if stdout.overflowed {
+			c.logger.Error("TTS command output too large, killed it", "max_bytes", c.config.MaxOutputBytes)
+			return nil, fmt.Errorf("%w: more than %d bytes", ErrOutputTooLarge, c.config.MaxOutputBytes)
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		c.logger.Error("TTS command failed",
+			"error", err,
+			"stderr", logging.SanitizeStderr(stderr.String()),
+		)
+		return nil, fmt.Errorf("%w: %v", ErrSynthesisFailed, err)
+	}
+
+	output := stdout.Bytes()
+	if len(output) == 0 {
+		return nil, fmt.Errorf("%w: no audio output", ErrSynthesisFailed)
+	}
+
+	c.logger.Debug("TTS command complete", "output_bytes", len(output))
+
+	if c.config.SampleRate > 0 {
+		data := wav.WrapRawPCM(output, c.config.SampleRate, c.config.Channels, 16)
+		return &AudioResult{
+			Data:       data,
+			Format:     "wav",
+			SampleRate: c.config.SampleRate,
+			Channels:   c.config.Channels,
+			Duration:   wavDuration(data),
+		}, nil
+	}
+
+	if len(output) < wav.HeaderSize || string(output[0:4]) != "RIFF" || string(output[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("%w: output is not a WAV file", ErrSynthesisFailed)
+	}
+	return &AudioResult{
+		Data:       output,
+		Format:     "wav",
+		SampleRate: int(binary.LittleEndian.Uint32(output[24:28])),
+		Channels:   int(binary.LittleEndian.Uint16(output[22:24])),
+		Duration:   wavDuration(output),
+	}, nil
+}
@@ -0,0 +1,245 @@
+package tts
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dgnsrekt/discorgeous-go/internal/wav"
+)
+
+// writeFakeTTS creates a script that discards stdin and runs body.
+func writeFakeTTS(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fake-tts")
+	script := "#!/bin/sh\ncat > /dev/null\n" + body + "\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatalf("failed to write fake TTS command: %v", err)
+	}
+	return path
+}
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    []string
+		wantErr bool
+	}{
+		{"plain", "mytts --voice {{.Voice}}", []string{"mytts", "--voice", "{{.Voice}}"}, false},
+		{"extra whitespace", "  mytts \t --fast  ", []string{"mytts", "--fast"}, false},
+		{"single quotes", "mytts --name 'a b \"c\"'", []string{"mytts", "--name", `a b "c"`}, false},
+		{"double quotes", `mytts --name "a \"b\" \\ c"`, []string{"mytts", "--name", `a "b" \ c`}, false},
+		{"empty quoted argument", `mytts ""`, []string{"mytts", ""}, false},
+		{"joined quotes", `mytts --out=/tmp/"my file"`, []string{"mytts", "--out=/tmp/my file"}, false},
+		{"empty", "", nil, false},
+		{"unterminated quote", "mytts 'oops", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitCommand(tt.command)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("splitCommand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCommandEngine_Errors(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	tests := []struct {
+		name    string
+		command string
+		want    error
+	}{
+		{"empty", "", ErrNoCommandSpecified},
+		{"unknown binary", "/nonexistent/mytts", ErrInvalidCommand},
+		{"bad template", "echo {{.Voice", ErrInvalidCommand},
+		{"unknown field", "echo {{.Speed}}", ErrInvalidCommand},
+		{"unterminated quote", "echo 'oops", ErrInvalidCommand},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCommandEngine(CommandConfig{Command: tt.command}, logger)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("NewCommandEngine() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandEngine_BuildArgs(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	engine, err := NewCommandEngine(CommandConfig{
+		Command:      "echo --voice {{.Voice}} --tag voice-{{.Voice}}",
+		DefaultVoice: "amy",
+	}, logger)
+	if err != nil {
+		t.Fatalf("NewCommandEngine() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		voice string
+		want  []string
+	}{
+		{"default voice", "", []string{"--voice", "amy", "--tag", "voice-amy"}},
+		{"request voice", "bob", []string{"--voice", "bob", "--tag", "voice-bob"}},
+		// Shell syntax in a voice stays inside its one argument
+		{"no injection", "x; rm -rf / --evil", []string{"--voice", "x; rm -rf / --evil", "--tag", "voice-x; rm -rf / --evil"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, _, err := engine.buildArgs(SynthesizeRequest{Text: "hello", Voice: tt.voice})
+			if err != nil {
+				t.Fatalf("buildArgs() error = %v", err)
+			}
+			if !slices.Equal(args, tt.want) {
+				t.Errorf("buildArgs() = %q, want %q", args, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandEngine_BuildArgs_Language(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	engine, err := NewCommandEngine(CommandConfig{Command: "echo --lang {{.Language}}"}, logger)
+	if err != nil {
+		t.Fatalf("NewCommandEngine() error = %v", err)
+	}
+
+	args, _, err := engine.buildArgs(SynthesizeRequest{Text: "hallo", Language: "de-DE"})
+	if err != nil {
+		t.Fatalf("buildArgs() error = %v", err)
+	}
+	if want := []string{"--lang", "de-DE"}; !slices.Equal(args, want) {
+		t.Errorf("buildArgs() = %q, want %q", args, want)
+	}
+}
+
+func TestCommandEngine_Name(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	engine, err := NewCommandEngine(CommandConfig{Command: "echo"}, logger)
+	if err != nil {
+		t.Fatalf("NewCommandEngine() error = %v", err)
+	}
+	if engine.Name() != "command" {
+		t.Errorf("expected name 'command', got '%s'", engine.Name())
+	}
+}
+
+func TestCommandEngine_Synthesize_EmptyOutput(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	engine, err := NewCommandEngine(CommandConfig{Command: writeFakeTTS(t, "true")}, logger)
+	if err != nil {
+		t.Fatalf("NewCommandEngine() error = %v", err)
+	}
+
+	_, err = engine.Synthesize(context.Background(), SynthesizeRequest{Text: "hello"})
+	if !errors.Is(err, ErrSynthesisFailed) {
+		t.Errorf("expected ErrSynthesisFailed, got %v", err)
+	}
+}
+
+func TestCommandEngine_Synthesize_OutputTooLarge(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	engine, err := NewCommandEngine(CommandConfig{Command: writeFakeTTS(t, "exec yes"), SampleRate: 22050, MaxOutputBytes: 4096}, logger)
+	if err != nil {
+		t.Fatalf("NewCommandEngine() error = %v", err)
+	}
+
+	_, err = engine.Synthesize(context.Background(), SynthesizeRequest{Text: "hello"})
+	if !errors.Is(err, ErrOutputTooLarge) {
+		t.Errorf("Synthesize() error = %v, want ErrOutputTooLarge", err)
+	}
+}
+
+func TestCommandEngine_Synthesize_EmptyText(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	engine, err := NewCommandEngine(CommandConfig{Command: "echo"}, logger)
+	if err != nil {
+		t.Fatalf("NewCommandEngine() error = %v", err)
+	}
+
+	if _, err := engine.Synthesize(context.Background(), SynthesizeRequest{}); !errors.Is(err, ErrEmptyText) {
+		t.Errorf("expected ErrEmptyText, got %v", err)
+	}
+}
+
+func TestCommandEngine_Synthesize_RawPCM(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	engine, err := NewCommandEngine(CommandConfig{
+		Command:    writeFakeTTS(t, "printf 'abcd'"),
+		SampleRate: 16000,
+	}, logger)
+	if err != nil {
+		t.Fatalf("NewCommandEngine() error = %v", err)
+	}
+
+	result, err := engine.Synthesize(context.Background(), SynthesizeRequest{Text: "hello"})
+	if err != nil {
+		t.Fatalf("Synthesize() error = %v", err)
+	}
+	if result.SampleRate != 16000 || result.Channels != 1 {
+		t.Errorf("format = %d Hz, %d channels, want 16000 Hz mono", result.SampleRate, result.Channels)
+	}
+	if len(result.Data) != wav.HeaderSize+4 || string(result.Data[wav.HeaderSize:]) != "abcd" {
+		t.Errorf("expected the PCM wrapped in a WAV header, got %d bytes", len(result.Data))
+	}
+	if want := 2 * time.Second / 16000; result.Duration != want {
+		t.Errorf("Duration = %v, want %v for 2 samples at 16kHz", result.Duration, want)
+	}
+}
+
+func TestCommandEngine_Synthesize_WAV(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	dir := t.TempDir()
+	wavPath := filepath.Join(dir, "out.wav")
+	if err := os.WriteFile(wavPath, wav.CreateMinimal(10, 24000, 2, 16), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	engine, err := NewCommandEngine(CommandConfig{Command: writeFakeTTS(t, "cat "+wavPath)}, logger)
+	if err != nil {
+		t.Fatalf("NewCommandEngine() error = %v", err)
+	}
+
+	result, err := engine.Synthesize(context.Background(), SynthesizeRequest{Text: "hello"})
+	if err != nil {
+		t.Fatalf("Synthesize() error = %v", err)
+	}
+	if result.SampleRate != 24000 || result.Channels != 2 {
+		t.Errorf("format = %d Hz, %d channels, want 24000 Hz stereo from the header", result.SampleRate, result.Channels)
+	}
+	if want := 10 * time.Second / 24000; result.Duration != want {
+		t.Errorf("Duration = %v, want %v for 10 samples at 24kHz", result.Duration, want)
+	}
+
+	// Raw bytes without a sample rate configured are rejected
+	engine, err = NewCommandEngine(CommandConfig{Command: writeFakeTTS(t, "printf 'abcd'")}, logger)
+	if err != nil {
+		t.Fatalf("NewCommandEngine() error = %v", err)
+	}
+	if _, err := engine.Synthesize(context.Background(), SynthesizeRequest{Text: "hello"}); !errors.Is(err, ErrSynthesisFailed) {
+		t.Errorf("expected ErrSynthesisFailed for non-WAV output, got %v", err)
+	}
+}
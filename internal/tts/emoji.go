@@ -0,0 +1,130 @@
+package tts
+
+// emojiNames maps emoji, without variation selectors or skin tones, to the
+// words spoken for them in EmojiDescribe mode. The names follow the Unicode
+// CLDR short names, shortened to what reads naturally in an alert.
+var emojiNames = map[string]string{
+	// Status
+	"✅":   "check",
+	"✔":   "check",
+	"☑":   "check",
+	"❌":   "cross",
+	"✖":   "cross",
+	"❎":   "cross",
+	"⚠":   "warning",
+	"🚨":   "alert",
+	"⛔":   "no entry",
+	"🚫":   "prohibited",
+	"❗":   "exclamation",
+	"❕":   "exclamation",
+	"❓":   "question",
+	"❔":   "question",
+	"ℹ":   "info",
+	"🆗":   "OK",
+	"🆕":   "new",
+	"🆘":   "SOS",
+	"🟢":   "green",
+	"🟡":   "yellow",
+	"🟠":   "orange",
+	"🔴":   "red",
+	"🔵":   "blue",
+	"⚪":   "white",
+	"⚫":   "black",
+	"🔔":   "bell",
+	"🔕":   "muted bell",
+	"📢":   "loudspeaker",
+	"📣":   "megaphone",
+	"🏁":   "finish flag",
+	"🚩":   "red flag",
+	"⏰":   "alarm clock",
+	"⏳":   "hourglass",
+	"⌛":   "hourglass",
+	"⏱":   "stopwatch",
+	"🕐":   "clock",
+	"🔒":   "locked",
+	"🔓":   "unlocked",
+	"🔑":   "key",
+	"🛡":   "shield",
+	"🐛":   "bug",
+	"🔧":   "wrench",
+	"🔨":   "hammer",
+	"🛠":   "tools",
+	"⚙":   "gear",
+	"🔄":   "refresh",
+	"🔁":   "repeat",
+	"⬆":   "up",
+	"⬇":   "down",
+	"➡":   "right",
+	"⬅":   "left",
+	"📈":   "trending up",
+	"📉":   "trending down",
+	"📊":   "chart",
+	"💾":   "disk",
+	"💻":   "laptop",
+	"🖥":   "computer",
+	"📦":   "package",
+	"📧":   "email",
+	"📨":   "incoming mail",
+	"📝":   "memo",
+	"📅":   "calendar",
+	"📌":   "pin",
+	"📍":   "pin",
+	"🔗":   "link",
+	"🔍":   "search",
+	"🚀":   "rocket",
+	"💥":   "collision",
+	"🔥":   "fire",
+	"⚡":   "lightning",
+	"💧":   "droplet",
+	"🌡":   "thermometer",
+	"☀":   "sun",
+	"🌙":   "moon",
+	"⭐":   "star",
+	"🌟":   "star",
+	"✨":   "sparkles",
+	"🎉":   "party",
+	"🎊":   "party",
+	"🏆":   "trophy",
+	"💯":   "hundred",
+	"💰":   "money",
+	"💸":   "money",
+	"🤖":   "robot",
+	"👻":   "ghost",
+	"💀":   "skull",
+	"☠":   "skull and crossbones",
+	"❤":   "heart",
+	"💔":   "broken heart",
+	"👍":   "thumbs up",
+	"👎":   "thumbs down",
+	"👋":   "wave",
+	"👏":   "clapping",
+	"🙏":   "thanks",
+	"👀":   "eyes",
+	"💪":   "strong",
+	"🤞":   "fingers crossed",
+	"🙂":   "smile",
+	"😀":   "grin",
+	"😃":   "grin",
+	"😄":   "grin",
+	"😁":   "grin",
+	"😊":   "smile",
+	"😉":   "wink",
+	"😂":   "laughing",
+	"🤣":   "laughing",
+	"😅":   "nervous laugh",
+	"😎":   "cool",
+	"🤔":   "thinking",
+	"😐":   "neutral face",
+	"😬":   "grimace",
+	"😢":   "sad",
+	"😭":   "crying",
+	"😱":   "scream",
+	"😡":   "angry",
+	"😴":   "sleeping",
+	"🤯":   "mind blown",
+	"🥳":   "celebrate",
+	"🧑‍💻": "technologist",
+	"👨‍💻": "technologist",
+	"👩‍💻": "technologist",
+	"❤‍🔥": "heart on fire",
+}
@@ -3,12 +3,25 @@ package tts
 import (
 	"context"
 	"io"
+	"time"
+
+	"github.com/dgnsrekt/discorgeous-go/internal/wav"
 )
 
 // SynthesizeRequest contains parameters for TTS synthesis.
 type SynthesizeRequest struct {
 	Text  string
 	Voice string
+	// Language is a BCP-47 language code such as "en-US" for engines that
+	// need one. Empty uses the engine default. Engines whose language is
+	// fixed by the voice or model, like Piper, ignore it.
+	Language string
+	// LengthScale, NoiseScale and NoiseW override the engine's synthesis
+	// parameters for this request. Zero uses the engine default; engines
+	// without such parameters ignore them.
+	LengthScale float64
+	NoiseScale  float64
+	NoiseW      float64
 }
 
 // AudioResult represents synthesized audio output.
@@ -21,6 +34,18 @@ type AudioResult struct {
 	SampleRate int
 	// Channels is the number of audio channels.
 	Channels int
+	// Duration is how long the audio plays for; zero if unknown.
+	Duration time.Duration
+}
+
+// wavDuration returns the playing time of WAV data, or zero if it can't
+// be parsed.
+func wavDuration(data []byte) time.Duration {
+	format, pcm, err := wav.Parse(data)
+	if err != nil {
+		return 0
+	}
+	return wav.Duration(format, len(pcm))
 }
 
 // Reader returns an io.Reader for the audio data.
@@ -48,4 +73,55 @@ type Engine interface {
 	Synthesize(ctx context.Context, req SynthesizeRequest) (*AudioResult, error)
 	// Name returns the engine identifier.
 	Name() string
+	// MaxTextLength returns the longest text, in bytes, the engine accepts
+	// in one request. Engines without a cap of their own return
+	// DefaultMaxTextLength.
+	MaxTextLength() int
+}
+
+// DefaultMaxTextLength is the text length limit of engines that don't
+// impose one; the global MAX_TEXT_LENGTH is normally far lower.
+const DefaultMaxTextLength = 1 << 20
+
+// VoiceLister is implemented by engines that know which voices they
+// accept.
+type VoiceLister interface {
+	// Voices returns the accepted voice names, or nil if the engine can't
+	// tell.
+	Voices() []string
+}
+
+// EngineVoices returns the voices an engine accepts, looking through the
+// wrappers added by Limit and the registry's metrics. It returns nil if
+// the engine doesn't list its voices.
+func EngineVoices(engine Engine) []string {
+	for {
+		if lister, ok := engine.(VoiceLister); ok {
+			return lister.Voices()
+		}
+		wrapper, ok := engine.(interface{ Unwrap() Engine })
+		if !ok {
+			return nil
+		}
+		engine = wrapper.Unwrap()
+	}
+}
+
+// StreamFormat describes the raw PCM produced by a streaming engine.
+type StreamFormat struct {
+	// SampleRate is the audio sample rate in Hz.
+	SampleRate int
+	// Channels is the number of audio channels.
+	Channels int
+	// BitsPerSample is the bit depth of each sample (signed little-endian).
+	BitsPerSample int
+}
+
+// StreamingEngine is implemented by engines that can emit raw PCM as it is
+// produced instead of buffering the whole utterance.
+type StreamingEngine interface {
+	Engine
+	// SynthesizeStream starts synthesis and returns a reader of raw PCM.
+	// Callers must Close the reader, which releases any underlying process.
+	SynthesizeStream(ctx context.Context, req SynthesizeRequest) (io.ReadCloser, StreamFormat, error)
 }
@@ -0,0 +1,284 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"golang.org/x/oauth2/google"
+
+	"github.com/dgnsrekt/discorgeous-go/internal/wav"
+)
+
+var (
+	// ErrGoogleAuthFailed is returned when Google rejects the service
+	// account credentials or they cannot be loaded.
+	ErrGoogleAuthFailed = errors.New("google TTS authentication failed")
+	// ErrGoogleQuotaExceeded is returned when the project's Text-to-Speech
+	// quota or rate limit is exhausted.
+	ErrGoogleQuotaExceeded = errors.New("google TTS quota exceeded")
+)
+
+const (
+	// googleTTSEndpoint is the Text-to-Speech REST synthesize method.
+	googleTTSEndpoint = "https://texttospeech.googleapis.com/v1/text:synthesize"
+	// googleTTSScope is the OAuth scope for the Text-to-Speech API.
+	googleTTSScope = "https://www.googleapis.com/auth/cloud-platform"
+	// googleSampleRate is the rate LINEAR16 audio is requested at.
+	googleSampleRate = 24000
+	// googleDefaultVoice is used when neither the config nor the request
+	// names a voice.
+	googleDefaultVoice = "en-US-Standard-C"
+	// maxGoogleErrorBytes bounds how much of an error response is read.
+	maxGoogleErrorBytes = 4096
+	// googleMaxTextLength is the API's limit on input bytes per request.
+	googleMaxTextLength = 5000
+)
+
+// GoogleConfig holds configuration for the Google Cloud TTS engine.
+type GoogleConfig struct {
+	// Name is the registry name of the engine. If empty, "google" is used.
+	Name string
+	// CredentialsFile is the path to a service account JSON key.
+	CredentialsFile string
+	// DefaultVoice is the voice name used when a request names none, e.g.
+	// "en-GB-Wavenet-B". If empty, en-US-Standard-C is used.
+	DefaultVoice string
+	// Endpoint overrides the synthesize URL, for tests.
+	Endpoint string
+}
+
+// GoogleEngine implements the Engine interface using the Google Cloud
+// Text-to-Speech REST API. Text wrapped in <speak> is sent as SSML.
+type GoogleEngine struct {
+	config GoogleConfig
+	client *http.Client
+	logger *slog.Logger
+}
+
+// NewGoogleEngine creates a Google TTS engine authenticated with the
+// service account key at cfg.CredentialsFile.
+func NewGoogleEngine(ctx context.Context, cfg GoogleConfig, logger *slog.Logger) (*GoogleEngine, error) {
+	data, err := os.ReadFile(cfg.CredentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGoogleAuthFailed, err)
+	}
+	creds, err := google.CredentialsFromJSON(ctx, data, googleTTSScope)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGoogleAuthFailed, err)
+	}
+	client := &http.Client{Transport: &googleTransport{source: creds}}
+	return NewGoogleEngineWithClient(cfg, client, logger), nil
+}
+
+// NewGoogleEngineWithClient creates a Google TTS engine that sends requests
+// with client, which must add authentication itself.
+func NewGoogleEngineWithClient(cfg GoogleConfig, client *http.Client, logger *slog.Logger) *GoogleEngine {
+	if cfg.DefaultVoice == "" {
+		cfg.DefaultVoice = googleDefaultVoice
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = googleTTSEndpoint
+	}
+	return &GoogleEngine{
+		config: cfg,
+		client: client,
+		logger: logger,
+	}
+}
+
+// googleTransport adds a bearer token to each request, reporting token
+// failures as ErrGoogleAuthFailed.
+type googleTransport struct {
+	source *google.Credentials
+}
+
+func (t *googleTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.source.TokenSource.Token()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGoogleAuthFailed, err)
+	}
+	req = req.Clone(req.Context())
+	token.SetAuthHeader(req)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// Name returns the engine identifier.
+func (g *GoogleEngine) Name() string {
+	if g.config.Name != "" {
+		return g.config.Name
+	}
+	return "google"
+}
+
+// MaxTextLength returns the API's per-request input limit.
+func (g *GoogleEngine) MaxTextLength() int {
+	return googleMaxTextLength
+}
+
+// googleSynthesizeRequest is the body of a text:synthesize call.
+type googleSynthesizeRequest struct {
+	Input struct {
+		Text string `json:"text,omitempty"`
+		SSML string `json:"ssml,omitempty"`
+	} `json:"input"`
+	Voice struct {
+		LanguageCode string `json:"languageCode"`
+		Name         string `json:"name"`
+	} `json:"voice"`
+	AudioConfig struct {
+		AudioEncoding   string `json:"audioEncoding"`
+		SampleRateHertz int    `json:"sampleRateHertz"`
+	} `json:"audioConfig"`
+}
+
+// googleSynthesizeResponse is the body of a successful text:synthesize call.
+type googleSynthesizeResponse struct {
+	// AudioContent is base64 in JSON, which []byte decodes
+	AudioContent []byte `json:"audioContent"`
+}
+
+// googleErrorResponse is the body of a failed call.
+type googleErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+// isSSML reports whether text is an SSML document.
+func isSSML(text string) bool {
+	return strings.HasPrefix(strings.TrimSpace(text), "<speak")
+}
+
+// buildRequest returns the synthesize request body for req.
+func (g *GoogleEngine) buildRequest(req SynthesizeRequest) googleSynthesizeRequest {
+	voice := req.Voice
+	if voice == "" || voice == "default" {
+		voice = g.config.DefaultVoice
+	}
+
+	var body googleSynthesizeRequest
+	if isSSML(req.Text) {
+		body.Input.SSML = req.Text
+	} else {
+		body.Input.Text = req.Text
+	}
+	body.Voice.Name = voice
+	body.Voice.LanguageCode = req.Language
+	if body.Voice.LanguageCode == "" {
+		body.Voice.LanguageCode = languageCodeFor(voice)
+	}
+	body.AudioConfig.AudioEncoding = "LINEAR16"
+	body.AudioConfig.SampleRateHertz = googleSampleRate
+	return body
+}
+
+// languageCodeFor returns the BCP-47 language code a Google voice name
+// starts with, e.g. "en-GB" for "en-GB-Wavenet-B".
+func languageCodeFor(voice string) string {
+	parts := strings.SplitN(voice, "-", 3)
+	if len(parts) < 2 {
+		return voice
+	}
+	return parts[0] + "-" + parts[1]
+}
+
+// Synthesize converts text to audio using Google Cloud TTS.
+func (g *GoogleEngine) Synthesize(ctx context.Context, req SynthesizeRequest) (*AudioResult, error) {
+	if req.Text == "" {
+		return nil, ErrEmptyText
+	}
+
+	body := g.buildRequest(req)
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSynthesisFailed, err)
+	}
+
+	g.logger.Debug("calling google TTS",
+		"voice", body.Voice.Name,
+		"ssml", body.Input.SSML != "",
+		"text_length", len(req.Text),
+	)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, g.config.Endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSynthesisFailed, err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := g.client.Do(httpReq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if errors.Is(err, ErrGoogleAuthFailed) {
+			g.logger.Error("google TTS authentication failed", "error", err)
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrSynthesisFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, g.responseError(resp)
+	}
+
+	var result googleSynthesizeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("%w: invalid response: %v", ErrSynthesisFailed, err)
+	}
+	if len(result.AudioContent) == 0 {
+		return nil, fmt.Errorf("%w: no audio output", ErrSynthesisFailed)
+	}
+
+	g.logger.Debug("google TTS synthesis complete", "output_bytes", len(result.AudioContent))
+
+	// LINEAR16 normally arrives with a WAV header; wrap it if not
+	data := result.AudioContent
+	if len(data) < wav.HeaderSize || string(data[0:4]) != "RIFF" {
+		data = wav.WrapRawPCM(data, googleSampleRate, 1, 16)
+	}
+
+	return &AudioResult{
+		Data:       data,
+		Format:     "wav",
+		SampleRate: googleSampleRate,
+		Channels:   1,
+		Duration:   wavDuration(data),
+	}, nil
+}
+
+// responseError maps a failed API response to an error.
+func (g *GoogleEngine) responseError(resp *http.Response) error {
+	var apiErr googleErrorResponse
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, maxGoogleErrorBytes))
+	message := strings.TrimSpace(string(raw))
+	if json.Unmarshal(raw, &apiErr) == nil && apiErr.Error.Message != "" {
+		message = apiErr.Error.Message
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized,
+		resp.StatusCode == http.StatusForbidden && apiErr.Error.Status != "RESOURCE_EXHAUSTED":
+		g.logger.Error("google TTS authentication failed", "status", resp.StatusCode, "message", message)
+		return fmt.Errorf("%w: %s", ErrGoogleAuthFailed, message)
+	case resp.StatusCode == http.StatusTooManyRequests, apiErr.Error.Status == "RESOURCE_EXHAUSTED":
+		g.logger.Warn("google TTS quota exceeded", "status", resp.StatusCode, "message", message)
+		return fmt.Errorf("%w: %s", ErrGoogleQuotaExceeded, message)
+	default:
+		g.logger.Error("google TTS synthesis failed", "status", resp.StatusCode, "message", message)
+		return fmt.Errorf("%w: status %d: %s", ErrSynthesisFailed, resp.StatusCode, message)
+	}
+}
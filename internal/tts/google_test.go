@@ -0,0 +1,151 @@
+package tts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/dgnsrekt/discorgeous-go/internal/wav"
+)
+
+// googleTestServer serves text:synthesize with handler and records the
+// last request body.
+func googleTestServer(t *testing.T, handler http.HandlerFunc) (*GoogleEngine, *googleSynthesizeRequest) {
+	t.Helper()
+	var got googleSynthesizeRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	engine := NewGoogleEngineWithClient(GoogleConfig{Endpoint: server.URL}, server.Client(), logger)
+	return engine, &got
+}
+
+func TestGoogleEngine_Synthesize(t *testing.T) {
+	audio := wav.CreateMinimal(10, googleSampleRate, 1, 16)
+	engine, got := googleTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(googleSynthesizeResponse{AudioContent: audio})
+	})
+
+	result, err := engine.Synthesize(context.Background(), SynthesizeRequest{Text: "hello", Voice: "en-GB-Wavenet-B"})
+	if err != nil {
+		t.Fatalf("Synthesize() error = %v", err)
+	}
+
+	if got.Voice.Name != "en-GB-Wavenet-B" || got.Voice.LanguageCode != "en-GB" {
+		t.Errorf("voice = %q/%q, want en-GB-Wavenet-B/en-GB", got.Voice.Name, got.Voice.LanguageCode)
+	}
+	if got.AudioConfig.AudioEncoding != "LINEAR16" {
+		t.Errorf("audioEncoding = %q, want LINEAR16", got.AudioConfig.AudioEncoding)
+	}
+	if got.Input.Text != "hello" || got.Input.SSML != "" {
+		t.Errorf("input = %+v, want plain text", got.Input)
+	}
+	if string(result.Data) != string(audio) {
+		t.Error("expected WAV output to be returned unchanged")
+	}
+	if result.Format != "wav" || result.SampleRate != googleSampleRate || result.Channels != 1 {
+		t.Errorf("result = %s %d Hz %d channels, want wav %d Hz mono", result.Format, result.SampleRate, result.Channels, googleSampleRate)
+	}
+}
+
+func TestGoogleEngine_Language(t *testing.T) {
+	engine, got := googleTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(googleSynthesizeResponse{AudioContent: wav.CreateMinimal(10, googleSampleRate, 1, 16)})
+	})
+
+	// The request's language wins over the one in the voice name
+	req := SynthesizeRequest{Text: "hello", Voice: "en-GB-Wavenet-B", Language: "en-US"}
+	if _, err := engine.Synthesize(context.Background(), req); err != nil {
+		t.Fatalf("Synthesize() error = %v", err)
+	}
+	if got.Voice.LanguageCode != "en-US" {
+		t.Errorf("languageCode = %q, want en-US", got.Voice.LanguageCode)
+	}
+}
+
+func TestGoogleEngine_SSMLAndRawPCM(t *testing.T) {
+	engine, got := googleTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(googleSynthesizeResponse{AudioContent: []byte("abcd")})
+	})
+
+	ssml := `<speak>Hello <break time="1s"/> world</speak>`
+	result, err := engine.Synthesize(context.Background(), SynthesizeRequest{Text: ssml})
+	if err != nil {
+		t.Fatalf("Synthesize() error = %v", err)
+	}
+
+	if got.Input.SSML != ssml || got.Input.Text != "" {
+		t.Errorf("input = %+v, want SSML", got.Input)
+	}
+	if got.Voice.Name != googleDefaultVoice || got.Voice.LanguageCode != "en-US" {
+		t.Errorf("voice = %q/%q, want the default", got.Voice.Name, got.Voice.LanguageCode)
+	}
+	if len(result.Data) != wav.HeaderSize+4 || string(result.Data[wav.HeaderSize:]) != "abcd" {
+		t.Errorf("expected headerless PCM wrapped as WAV, got %d bytes", len(result.Data))
+	}
+}
+
+func TestGoogleEngine_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   error
+	}{
+		{"unauthenticated", http.StatusUnauthorized, `{"error":{"message":"bad token","status":"UNAUTHENTICATED"}}`, ErrGoogleAuthFailed},
+		{"permission denied", http.StatusForbidden, `{"error":{"message":"API disabled","status":"PERMISSION_DENIED"}}`, ErrGoogleAuthFailed},
+		{"quota", http.StatusTooManyRequests, `{"error":{"message":"quota","status":"RESOURCE_EXHAUSTED"}}`, ErrGoogleQuotaExceeded},
+		{"server error", http.StatusInternalServerError, `oops`, ErrSynthesisFailed},
+		{"empty audio", http.StatusOK, `{}`, ErrSynthesisFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, _ := googleTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+			if _, err := engine.Synthesize(context.Background(), SynthesizeRequest{Text: "hello"}); !errors.Is(err, tt.want) {
+				t.Errorf("Synthesize() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoogleEngine_Cancelled(t *testing.T) {
+	engine, _ := googleTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := engine.Synthesize(ctx, SynthesizeRequest{Text: "hello"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Synthesize() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestNewGoogleEngine_BadCredentials(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	path := t.TempDir() + "/key.json"
+	if err := os.WriteFile(path, []byte("not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range []string{"/nonexistent/key.json", path} {
+		if _, err := NewGoogleEngine(context.Background(), GoogleConfig{CredentialsFile: file}, logger); !errors.Is(err, ErrGoogleAuthFailed) {
+			t.Errorf("NewGoogleEngine(%s) error = %v, want ErrGoogleAuthFailed", file, err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package tts
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/dgnsrekt/discorgeous-go/internal/metrics"
+)
+
+// instrument returns an engine that reports each synthesis to m, labelled
+// with the engine name. Streaming engines stay streaming.
+func instrument(engine Engine, m metrics.Metrics) Engine {
+	instrumented := &instrumentedEngine{Engine: engine, metrics: m}
+	if streamer, ok := engine.(StreamingEngine); ok {
+		return &instrumentedStreamingEngine{instrumentedEngine: instrumented, streamer: streamer}
+	}
+	return instrumented
+}
+
+// instrumentedEngine records timings and failures of the wrapped engine.
+type instrumentedEngine struct {
+	Engine
+	metrics metrics.Metrics
+}
+
+// Unwrap returns the instrumented engine.
+func (e *instrumentedEngine) Unwrap() Engine {
+	return e.Engine
+}
+
+// record reports a finished call that started at start. Cancellation is
+// not counted as a failure.
+func (e *instrumentedEngine) record(start time.Time, err error) {
+	name := e.Name()
+	switch {
+	case err == nil:
+		e.metrics.Observe("tts_synthesis_seconds", time.Since(start).Seconds(), "engine", name)
+	case !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded):
+		e.metrics.Counter("tts_synthesis_failures_total", 1, "engine", name)
+	}
+}
+
+// Synthesize calls the wrapped engine and records the outcome.
+func (e *instrumentedEngine) Synthesize(ctx context.Context, req SynthesizeRequest) (*AudioResult, error) {
+	start := time.Now()
+	result, err := e.Engine.Synthesize(ctx, req)
+	e.record(start, err)
+	return result, err
+}
+
+// instrumentedStreamingEngine is an instrumentedEngine over a StreamingEngine.
+type instrumentedStreamingEngine struct {
+	*instrumentedEngine
+	streamer StreamingEngine
+}
+
+// SynthesizeStream starts a stream on the wrapped engine. Only failures to
+// start are recorded, since the stream's duration is the playback's.
+func (e *instrumentedStreamingEngine) SynthesizeStream(ctx context.Context, req SynthesizeRequest) (io.ReadCloser, StreamFormat, error) {
+	stream, format, err := e.streamer.SynthesizeStream(ctx, req)
+	if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		e.metrics.Counter("tts_synthesis_failures_total", 1, "engine", e.Name())
+	}
+	return stream, format, err
+}
@@ -0,0 +1,117 @@
+package tts
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// MinLanguageConfidence is the detection confidence below which callers
+// should fall back to the default engine.
+const MinLanguageConfidence = 0.9
+
+// languageSamples is representative text each language profile is built from.
+// It leans on everyday and operational vocabulary since that is what alerts use.
+var languageSamples = map[string]string{
+	"en": `the quick brown fox jumps over the lazy dog. this is a notification
+that the server is down and the backup job has failed. please check the
+logs and restart the service when you can. there was an error while
+connecting to the database. the deployment finished successfully and all
+systems are running normally. your order has been shipped and will arrive
+tomorrow. someone is at the front door. the temperature in the living room
+is high. we are going to be late for the meeting this afternoon. it is
+time to take out the trash and water the plants. what would you like for
+dinner tonight? the weather will be sunny with a chance of rain in the
+evening. disk usage is above the threshold on the primary node. the
+certificate will expire in seven days. reminder: the build is broken
+again, which means nobody can merge until it is fixed.`,
+	"de": `der schnelle braune fuchs springt über den faulen hund. dies ist eine
+benachrichtigung, dass der server nicht erreichbar ist und die sicherung
+fehlgeschlagen ist. bitte prüfe die protokolle und starte den dienst neu,
+wenn du kannst. beim verbinden mit der datenbank ist ein fehler
+aufgetreten. die bereitstellung wurde erfolgreich abgeschlossen und alle
+systeme laufen normal. deine bestellung wurde versendet und kommt morgen
+an. jemand steht an der haustür. die temperatur im wohnzimmer ist hoch.
+wir werden uns heute nachmittag für die besprechung verspäten. es ist
+zeit, den müll hinauszubringen und die pflanzen zu gießen. was möchtest
+du heute abend essen? das wetter wird sonnig mit einer chance auf regen
+am abend. die speicherbelegung liegt über dem schwellenwert auf dem
+hauptknoten. das zertifikat läuft in sieben tagen ab. erinnerung: der
+build ist schon wieder kaputt, das heißt niemand kann zusammenführen, bis
+er repariert ist.`,
+}
+
+// languageProfiles holds the trigram counts of each language sample.
+var languageProfiles = buildProfiles(languageSamples)
+
+// languageProfile is a trigram frequency model for one language.
+type languageProfile struct {
+	counts map[string]int
+	total  int
+}
+
+// DetectLanguage guesses the language of text among the built-in profiles
+// with a naive Bayes trigram model. It returns the ISO 639-1 code and a
+// confidence in [0, 1]; short or ambiguous text scores near zero.
+func DetectLanguage(text string) (lang string, confidence float64) {
+	trigrams := countTrigrams(text)
+	if len(trigrams) == 0 {
+		return "", 0
+	}
+
+	// Log-likelihood of the text under each profile, with add-one smoothing
+	scores := make(map[string]float64, len(languageProfiles))
+	best := math.Inf(-1)
+	for l, profile := range languageProfiles {
+		score := 0.0
+		for t, n := range trigrams {
+			p := float64(profile.counts[t]+1) / float64(profile.total+len(profile.counts))
+			score += float64(n) * math.Log(p)
+		}
+		scores[l] = score
+		if score > best || (score == best && l < lang) {
+			best, lang = score, l
+		}
+	}
+
+	// Posterior of the best language, rescaled so a tie is zero confidence
+	sum := 0.0
+	for _, score := range scores {
+		sum += math.Exp(score - best)
+	}
+	k := float64(len(scores))
+	if k < 2 {
+		return lang, 1
+	}
+	return lang, (1/sum - 1/k) / (1 - 1/k)
+}
+
+// buildProfiles counts the trigrams of each language sample.
+func buildProfiles(samples map[string]string) map[string]languageProfile {
+	profiles := make(map[string]languageProfile, len(samples))
+	for lang, sample := range samples {
+		counts := countTrigrams(sample)
+		total := 0
+		for _, n := range counts {
+			total += n
+		}
+		profiles[lang] = languageProfile{counts: counts, total: total}
+	}
+	return profiles
+}
+
+// countTrigrams counts the letter trigrams of text, with words padded by
+// spaces so word edges count.
+func countTrigrams(text string) map[string]int {
+	counts := make(map[string]int)
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, word := range fields {
+		runes := []rune(" " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			counts[string(runes[i:i+3])]++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,36 @@
+package tts
+
+import "testing"
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"The backup failed on the storage server, please check it", "en"},
+		{"Someone rang the doorbell while you were away", "en"},
+		{"Die Sicherung auf dem Speicherserver ist fehlgeschlagen, bitte prüfen", "de"},
+		{"Jemand hat an der Tür geklingelt, während du weg warst", "de"},
+		{"Die Temperatur im Serverraum ist zu hoch", "de"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			lang, confidence := DetectLanguage(tt.text)
+			if lang != tt.want {
+				t.Errorf("DetectLanguage() = %s (%.2f), want %s", lang, confidence, tt.want)
+			}
+			if confidence < MinLanguageConfidence {
+				t.Errorf("DetectLanguage() confidence = %.2f, want at least %.2f", confidence, MinLanguageConfidence)
+			}
+		})
+	}
+}
+
+func TestDetectLanguage_LowConfidence(t *testing.T) {
+	for _, text := range []string{"", "42", "OK"} {
+		if _, confidence := DetectLanguage(text); confidence >= MinLanguageConfidence {
+			t.Errorf("DetectLanguage(%q) confidence = %.2f, want below %.2f", text, confidence, MinLanguageConfidence)
+		}
+	}
+}
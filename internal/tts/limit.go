@@ -0,0 +1,93 @@
+package tts
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// Limit returns an engine that allows at most n Synthesize calls to engine
+// at once. Further callers block until a slot frees or their context is
+// cancelled. If n <= 0, engine is returned unchanged.
+//
+// A streaming engine stays streaming; each open stream holds a slot until
+// it is closed.
+func Limit(engine Engine, n int) Engine {
+	if n <= 0 {
+		return engine
+	}
+	limited := &limitedEngine{Engine: engine, slots: make(chan struct{}, n)}
+	if streamer, ok := engine.(StreamingEngine); ok {
+		return &limitedStreamingEngine{limitedEngine: limited, streamer: streamer}
+	}
+	return limited
+}
+
+// limitedEngine bounds concurrent calls to the wrapped engine.
+type limitedEngine struct {
+	Engine
+	slots chan struct{}
+}
+
+// Unwrap returns the limited engine.
+func (l *limitedEngine) Unwrap() Engine {
+	return l.Engine
+}
+
+// acquire takes a slot, waiting until one is free or ctx is done.
+func (l *limitedEngine) acquire(ctx context.Context) error {
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees a slot taken by acquire.
+func (l *limitedEngine) release() {
+	<-l.slots
+}
+
+// Synthesize calls the wrapped engine once a slot is free.
+func (l *limitedEngine) Synthesize(ctx context.Context, req SynthesizeRequest) (*AudioResult, error) {
+	if err := l.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer l.release()
+	return l.Engine.Synthesize(ctx, req)
+}
+
+// limitedStreamingEngine is a limitedEngine over a StreamingEngine.
+type limitedStreamingEngine struct {
+	*limitedEngine
+	streamer StreamingEngine
+}
+
+// SynthesizeStream starts a stream once a slot is free. The slot is held
+// until the returned reader is closed.
+func (l *limitedStreamingEngine) SynthesizeStream(ctx context.Context, req SynthesizeRequest) (io.ReadCloser, StreamFormat, error) {
+	if err := l.acquire(ctx); err != nil {
+		return nil, StreamFormat{}, err
+	}
+	stream, format, err := l.streamer.SynthesizeStream(ctx, req)
+	if err != nil {
+		l.release()
+		return nil, StreamFormat{}, err
+	}
+	return &slotReadCloser{ReadCloser: stream, release: l.release}, format, nil
+}
+
+// slotReadCloser releases its engine slot when closed.
+type slotReadCloser struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+// Close closes the stream and frees the slot; later calls only close.
+func (s *slotReadCloser) Close() error {
+	err := s.ReadCloser.Close()
+	s.once.Do(s.release)
+	return err
+}
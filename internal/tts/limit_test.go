@@ -0,0 +1,155 @@
+package tts
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingEngine holds each Synthesize call until release is closed,
+// tracking how many calls run at once.
+type blockingEngine struct {
+	started chan struct{}
+	release chan struct{}
+	active  atomic.Int32
+	peak    atomic.Int32
+}
+
+func newBlockingEngine() *blockingEngine {
+	return &blockingEngine{
+		started: make(chan struct{}, 16),
+		release: make(chan struct{}),
+	}
+}
+
+func (b *blockingEngine) Name() string {
+	return "cloud"
+}
+
+func (b *blockingEngine) MaxTextLength() int {
+	return DefaultMaxTextLength
+}
+
+func (b *blockingEngine) Synthesize(ctx context.Context, req SynthesizeRequest) (*AudioResult, error) {
+	n := b.active.Add(1)
+	defer b.active.Add(-1)
+	for {
+		peak := b.peak.Load()
+		if n <= peak || b.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	b.started <- struct{}{}
+	<-b.release
+	return &AudioResult{Data: []byte("wav"), Format: "wav"}, nil
+}
+
+// streamingMockEngine is a StreamingEngine whose streams are plain readers.
+type streamingMockEngine struct {
+	mockEngine
+}
+
+func (s *streamingMockEngine) SynthesizeStream(ctx context.Context, req SynthesizeRequest) (io.ReadCloser, StreamFormat, error) {
+	return io.NopCloser(strings.NewReader("pcm")), StreamFormat{SampleRate: 22050, Channels: 1, BitsPerSample: 16}, nil
+}
+
+func TestLimit_SerializesExtraCalls(t *testing.T) {
+	const limit = 2
+	inner := newBlockingEngine()
+	engine := Limit(inner, limit)
+
+	if engine.Name() != "cloud" {
+		t.Errorf("Name() = %q, want the wrapped engine's name", engine.Name())
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, limit+1)
+	for range limit + 1 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := engine.Synthesize(context.Background(), SynthesizeRequest{Text: "Hello"})
+			errs <- err
+		}()
+	}
+
+	// The first calls take every slot; the extra one must wait
+	for range limit {
+		select {
+		case <-inner.started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for calls to start")
+		}
+	}
+	select {
+	case <-inner.started:
+		t.Fatal("call started beyond the limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(inner.release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Synthesize() error = %v", err)
+		}
+	}
+	if peak := inner.peak.Load(); peak != limit {
+		t.Errorf("peak concurrency = %d, want %d", peak, limit)
+	}
+}
+
+func TestLimit_ContextCancelledWhileWaiting(t *testing.T) {
+	inner := newBlockingEngine()
+	defer close(inner.release)
+	engine := Limit(inner, 1)
+
+	go engine.Synthesize(context.Background(), SynthesizeRequest{Text: "Hello"})
+	<-inner.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := engine.Synthesize(ctx, SynthesizeRequest{Text: "Hello"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Synthesize() error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestLimit_StreamHoldsSlotUntilClosed(t *testing.T) {
+	engine := Limit(&streamingMockEngine{mockEngine{name: "piper"}}, 1)
+
+	streamer, ok := engine.(StreamingEngine)
+	if !ok {
+		t.Fatal("Limit() dropped StreamingEngine")
+	}
+
+	stream, _, err := streamer.SynthesizeStream(context.Background(), SynthesizeRequest{Text: "Hello"})
+	if err != nil {
+		t.Fatalf("SynthesizeStream() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := engine.Synthesize(ctx, SynthesizeRequest{Text: "Hello"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Synthesize() with stream open error = %v, want context.DeadlineExceeded", err)
+	}
+
+	stream.Close()
+	stream.Close()
+	if _, err := engine.Synthesize(context.Background(), SynthesizeRequest{Text: "Hello"}); err != nil {
+		t.Errorf("Synthesize() after close error = %v", err)
+	}
+}
+
+func TestLimit_ZeroIsUnlimited(t *testing.T) {
+	inner := &mockEngine{name: "piper"}
+	if got := Limit(inner, 0); got != Engine(inner) {
+		t.Error("Limit(engine, 0) wrapped the engine, want it unchanged")
+	}
+}
@@ -0,0 +1,392 @@
+package tts
+
+import (
+	"regexp"
+	"strings"
+)
+
+// normalizeRule rewrites every match of pattern with replacement, which may
+// reference capture groups as $1, $2, ...
+type normalizeRule struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// linkRules run before numbers are expanded, so digits in URLs are left
+// alone.
+var linkRules = []normalizeRule{
+	// URLs are unreadable aloud, so say that there is one
+	{regexp.MustCompile(`\b(?:https?|ftp)://\S+`), "link"},
+	{regexp.MustCompile(`\bwww\.\S+`), "link"},
+}
+
+// normalizeRules are applied in order after numbers are expanded; later
+// rules see earlier rewrites.
+var normalizeRules = []normalizeRule{
+	// Units glued to numbers
+	{regexp.MustCompile(`(\d+)\s*ms\b`), "$1 milliseconds"},
+	{regexp.MustCompile(`(\d+)\s*(?:KB|kb)\b`), "$1 kilobytes"},
+	{regexp.MustCompile(`(\d+)\s*(?:MB|mb)\b`), "$1 megabytes"},
+	{regexp.MustCompile(`(\d+)\s*(?:GB|gb)\b`), "$1 gigabytes"},
+	{regexp.MustCompile(`(\d+)\s*(?:TB|tb)\b`), "$1 terabytes"},
+
+	// Identifiers like srv-01: split the number off and drop leading zeros
+	{regexp.MustCompile(`\b([A-Za-z]+)-0*(\d+)\b`), "$1 $2"},
+
+	// Common abbreviations
+	{regexp.MustCompile(`\bsrv\b`), "server"},
+	{regexp.MustCompile(`\bdb\b`), "database"},
+	{regexp.MustCompile(`\bmsg\b`), "message"},
+	{regexp.MustCompile(`\bapprox\.?`), "approximately"},
+	{regexp.MustCompile(`\bvs\.?(\s)`), "versus$1"},
+	{regexp.MustCompile(`\be\.g\.`), "for example"},
+	{regexp.MustCompile(`\bi\.e\.`), "that is"},
+	{regexp.MustCompile(`\betc\.`), "et cetera"},
+	{regexp.MustCompile(`\bCPU\b`), "C P U"},
+	{regexp.MustCompile(`\bGPU\b`), "G P U"},
+	{regexp.MustCompile(`\bAPI\b`), "A P I"},
+	{regexp.MustCompile(`\bDNS\b`), "D N S"},
+	{regexp.MustCompile(`&`), " and "},
+}
+
+// whitespacePattern matches runs of whitespace left behind by rewrites.
+var whitespacePattern = regexp.MustCompile(`\s+`)
+
+// DefaultLocale is the locale numbers are read in when none is given.
+const DefaultLocale = "en-US"
+
+// NormalizeForSpeech rewrites alert-style text so it reads naturally when
+// spoken: URLs become "link", common abbreviations and units are expanded,
+// and numbers, decimals and percentages are spelled the way people say them.
+// Numbers are read as DefaultLocale writes them.
+func NormalizeForSpeech(text string) string {
+	return NormalizeForSpeechLocale(text, DefaultLocale)
+}
+
+// NormalizeForSpeechLocale is like NormalizeForSpeech but reads numbers,
+// percentages and currency amounts as locale writes them, e.g. "1.000,50 €"
+// in de-DE. Locales are matched by language; unknown ones, and "", use
+// DefaultLocale.
+func NormalizeForSpeechLocale(text, locale string) string {
+	for _, rule := range linkRules {
+		text = rule.pattern.ReplaceAllString(text, rule.replacement)
+	}
+	text = numberFormatFor(locale).expand(text)
+	for _, rule := range normalizeRules {
+		text = rule.pattern.ReplaceAllString(text, rule.replacement)
+	}
+	return strings.TrimSpace(whitespacePattern.ReplaceAllString(text, " "))
+}
+
+// currencyWords are how a locale says a currency's main and fractional
+// units, in the singular and plural.
+type currencyWords struct {
+	one, many           string
+	minorOne, minorMany string
+}
+
+// numberFormat is how a locale writes numbers and says the parts of them
+// that aren't digits.
+type numberFormat struct {
+	point      string // decimal point
+	percent    string
+	and        string // joins main and fractional currency units
+	currencies map[string]currencyWords
+	pattern    *regexp.Regexp
+}
+
+// newNumberFormat returns a numberFormat for numbers grouped with the
+// characters in the regexp class group and with decimal as the separator.
+func newNumberFormat(group, decimal, point, percent, and string, currencies map[string]currencyWords) *numberFormat {
+	// Grouped digits must be followed by a non-word character, so 1,0000
+	// isn't read as 1000 and 0
+	amount := `(\d{1,3}(?:` + group + `\d{3})+\b|\d+)(?:` + regexp.QuoteMeta(decimal) + `(\d+))?`
+	return &numberFormat{
+		point:      point,
+		percent:    percent,
+		and:        and,
+		currencies: currencies,
+		// A currency symbol before or after the amount, or a percent sign
+		pattern: regexp.MustCompile(`([$€£])\s?` + amount + `|` + amount + `(?:\s?([$€£])|(\s*%))?`),
+	}
+}
+
+// numberFormats are the locales numbers can be read in, by language.
+var numberFormats = map[string]*numberFormat{
+	"en": newNumberFormat(`,`, ".", "point", "percent", "and", map[string]currencyWords{
+		"$": {"dollar", "dollars", "cent", "cents"},
+		"€": {"euro", "euros", "cent", "cents"},
+		"£": {"pound", "pounds", "penny", "pence"},
+	}),
+	"de": newNumberFormat(`[.\x{00A0}\x{202F}]`, ",", "Komma", "Prozent", "und", map[string]currencyWords{
+		"$": {"Dollar", "Dollar", "Cent", "Cent"},
+		"€": {"Euro", "Euro", "Cent", "Cent"},
+		"£": {"Pfund", "Pfund", "Penny", "Pence"},
+	}),
+	"fr": newNumberFormat(`[.\x{00A0}\x{202F}]`, ",", "virgule", "pour cent", "et", map[string]currencyWords{
+		"$": {"dollar", "dollars", "cent", "cents"},
+		"€": {"euro", "euros", "centime", "centimes"},
+		"£": {"livre", "livres", "penny", "pence"},
+	}),
+	"es": newNumberFormat(`[.\x{00A0}\x{202F}]`, ",", "coma", "por ciento", "con", map[string]currencyWords{
+		"$": {"dólar", "dólares", "centavo", "centavos"},
+		"€": {"euro", "euros", "céntimo", "céntimos"},
+		"£": {"libra", "libras", "penique", "peniques"},
+	}),
+}
+
+// SupportedLocale reports whether numbers can be read in locale, a
+// language tag such as "de" or "de-AT".
+func SupportedLocale(locale string) bool {
+	_, ok := numberFormats[localeLanguage(locale)]
+	return ok
+}
+
+// localeLanguage returns the lowercased language subtag of a locale.
+func localeLanguage(locale string) string {
+	lang, _, _ := strings.Cut(strings.ReplaceAll(locale, "_", "-"), "-")
+	return strings.ToLower(lang)
+}
+
+// numberFormatFor returns the format for locale, falling back to
+// DefaultLocale's.
+func numberFormatFor(locale string) *numberFormat {
+	if f, ok := numberFormats[localeLanguage(locale)]; ok {
+		return f
+	}
+	return numberFormats[localeLanguage(DefaultLocale)]
+}
+
+// expand rewrites the numbers in text the way they are said: group
+// separators are dropped, decimals get the locale's word for the point,
+// and percentages and currency amounts are spelled out.
+func (f *numberFormat) expand(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range f.pattern.FindAllStringSubmatchIndex(text, -1) {
+		group := func(i int) string {
+			if m[2*i] < 0 {
+				return ""
+			}
+			return text[m[2*i]:m[2*i+1]]
+		}
+
+		b.WriteString(text[last:m[0]])
+		last = m[1]
+
+		// Groups: 1 leading symbol, 2-3 its amount; 4-5 a bare amount,
+		// 6 a trailing symbol, 7 a percent sign
+		symbol, whole, frac := group(1), group(2), group(3)
+		if symbol == "" {
+			symbol, whole, frac = group(6), group(4), group(5)
+		}
+		whole = strings.Map(func(r rune) rune {
+			if r < '0' || r > '9' {
+				return -1
+			}
+			return r
+		}, whole)
+
+		switch {
+		case symbol != "":
+			b.WriteString(f.sayAmount(whole, frac, f.currencies[symbol]))
+		case group(7) != "":
+			b.WriteString(f.sayNumber(whole, frac) + " " + f.percent)
+		default:
+			b.WriteString(f.sayNumber(whole, frac))
+		}
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// sayNumber joins a number's whole and fractional digits with the word
+// for the decimal point.
+func (f *numberFormat) sayNumber(whole, frac string) string {
+	if frac == "" {
+		return whole
+	}
+	return whole + " " + f.point + " " + frac
+}
+
+// sayAmount says a currency amount. Two decimal places are read as the
+// fractional unit, as in "5 dollars and 50 cents"; any other number of
+// places is read as a plain decimal.
+func (f *numberFormat) sayAmount(whole, frac string, c currencyWords) string {
+	if frac != "" && len(frac) != 2 {
+		return f.sayNumber(whole, frac) + " " + c.many
+	}
+
+	unit := c.many
+	if whole == "1" {
+		unit = c.one
+	}
+	said := whole + " " + unit
+	if cents := strings.TrimLeft(frac, "0"); cents != "" {
+		minor := c.minorMany
+		if cents == "1" {
+			minor = c.minorOne
+		}
+		said += " " + f.and + " " + cents + " " + minor
+	}
+	return said
+}
+
+// markdownRules remove markdown formatting, in order: code first so its
+// contents aren't read as markup, then links, line prefixes and emphasis.
+var markdownRules = []normalizeRule{
+	// Code blocks are unreadable aloud, so say that there is one
+	{regexp.MustCompile("(?s)```.*?```"), " code block "},
+	{regexp.MustCompile("`([^`\n]*)`"), "$1"},
+
+	// Images and links keep only their text
+	{regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`), "$1"},
+	{regexp.MustCompile(`\[([^\]]+)\]\([^)]*\)`), "$1"},
+
+	// Headings, quotes and list bullets
+	{regexp.MustCompile(`(?m)^[ \t]*#{1,6}[ \t]+`), ""},
+	{regexp.MustCompile(`(?m)^[ \t]*>[ \t]?`), ""},
+	{regexp.MustCompile(`(?m)^[ \t]*[-*+][ \t]+`), ""},
+
+	// Emphasis; underscores only at word edges so snake_case survives
+	{regexp.MustCompile(`\*\*(\S(?:[^*]*\S)?)\*\*`), "$1"},
+	{regexp.MustCompile(`\b__(\S(?:[^_]*\S)?)__\b`), "$1"},
+	{regexp.MustCompile(`\*(\S(?:[^*]*\S)?)\*`), "$1"},
+	{regexp.MustCompile(`\b_(\S(?:[^_]*\S)?)_\b`), "$1"},
+	{regexp.MustCompile(`~~(\S(?:[^~]*\S)?)~~`), "$1"},
+}
+
+// StripMarkdown removes markdown formatting so it isn't read aloud:
+// emphasis markers, headings and bullets are dropped, links and images
+// become their text, inline code its contents and code blocks the words
+// "code block".
+func StripMarkdown(text string) string {
+	for _, rule := range markdownRules {
+		text = rule.pattern.ReplaceAllString(text, rule.replacement)
+	}
+	return strings.TrimSpace(whitespacePattern.ReplaceAllString(text, " "))
+}
+
+// EmojiMode selects what ReplaceEmoji does with emoji.
+type EmojiMode string
+
+const (
+	// EmojiKeep leaves emoji for the engine.
+	EmojiKeep EmojiMode = "keep"
+	// EmojiStrip removes emoji.
+	EmojiStrip EmojiMode = "strip"
+	// EmojiDescribe replaces common emoji with words and removes the rest.
+	EmojiDescribe EmojiMode = "describe"
+)
+
+// ReplaceEmoji strips emoji from text or, in EmojiDescribe mode, replaces
+// the common ones with words ("✅" becomes "check"). Multi-codepoint emoji
+// (skin tones, ZWJ sequences, flags, keycaps) are handled as one. An empty
+// mode or EmojiKeep returns text unchanged.
+func ReplaceEmoji(text string, mode EmojiMode) string {
+	if mode != EmojiStrip && mode != EmojiDescribe {
+		return text
+	}
+
+	var b strings.Builder
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		if isEmojiModifier(r) {
+			// Left over from a keycap or a variation of plain text
+			i++
+			continue
+		}
+		if !isEmoji(r) {
+			b.WriteRune(r)
+			i++
+			continue
+		}
+
+		j := emojiEnd(runes, i)
+		if mode == EmojiDescribe {
+			if name := describeEmoji(runes[i:j]); name != "" {
+				b.WriteString(" " + name + " ")
+			}
+		}
+		i = j
+	}
+	return strings.TrimSpace(whitespacePattern.ReplaceAllString(b.String(), " "))
+}
+
+// emojiEnd returns the index just past the emoji sequence starting at
+// runes[i].
+func emojiEnd(runes []rune, i int) int {
+	j := i + 1
+	if isRegionalIndicator(runes[i]) && j < len(runes) && isRegionalIndicator(runes[j]) {
+		return j + 1
+	}
+	for j < len(runes) {
+		switch {
+		case isEmojiModifier(runes[j]):
+			j++
+		case runes[j] == zeroWidthJoiner && j+1 < len(runes) && isEmoji(runes[j+1]):
+			j += 2
+		default:
+			return j
+		}
+	}
+	return j
+}
+
+// describeEmoji returns the words for an emoji sequence, or "" if it has
+// none. Sequences not in the table fall back to their first emoji.
+func describeEmoji(seq []rune) string {
+	if isRegionalIndicator(seq[0]) {
+		return "flag"
+	}
+
+	key := make([]rune, 0, len(seq))
+	for _, r := range seq {
+		if !isEmojiModifier(r) {
+			key = append(key, r)
+		}
+	}
+	if name, ok := emojiNames[string(key)]; ok {
+		return name
+	}
+	return emojiNames[string(seq[0])]
+}
+
+// zeroWidthJoiner joins emoji into one sequence, e.g. 🧑 + 💻.
+const zeroWidthJoiner = 0x200D
+
+// isEmoji reports whether r starts an emoji.
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF: // pictographs, emoticons, transport, flags
+		return true
+	case r >= 0x2600 && r <= 0x27BF: // miscellaneous symbols and dingbats
+		return true
+	case r >= 0x2300 && r <= 0x23FF: // watches, hourglasses, media controls
+		return true
+	case r >= 0x2B00 && r <= 0x2BFF: // arrows, stars and circles
+		return true
+	}
+	return r == 0x203C || r == 0x2049 || r == 0x2139 // ‼ ⁉ ℹ
+}
+
+// isEmojiModifier reports whether r only modifies the emoji before it:
+// variation selectors, skin tones, the keycap mark and flag tags.
+func isEmojiModifier(r rune) bool {
+	switch {
+	case r == 0xFE0E || r == 0xFE0F || r == 0x20E3:
+		return true
+	case r >= 0x1F3FB && r <= 0x1F3FF:
+		return true
+	case r >= 0xE0020 && r <= 0xE007F:
+		return true
+	}
+	return false
+}
+
+// isRegionalIndicator reports whether r is one of the letters that pair up
+// into a flag.
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
@@ -0,0 +1,196 @@
+package tts
+
+import "testing"
+
+func TestNormalizeForSpeech(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "alert with url and percentage",
+			text: "CPU at 95% on srv-01 http://x/y",
+			want: "C P U at 95 percent on server 1 link",
+		},
+		{
+			name: "plain text unchanged",
+			text: "Backup finished successfully",
+			want: "Backup finished successfully",
+		},
+		{
+			name: "decimal percentage",
+			text: "Disk usage 87.5%",
+			want: "Disk usage 87 point 5 percent",
+		},
+		{
+			name: "thousands separators",
+			text: "1,234,567 requests served",
+			want: "1234567 requests served",
+		},
+		{
+			name: "units",
+			text: "latency 250ms, 12GB free",
+			want: "latency 250 milliseconds, 12 gigabytes free",
+		},
+		{
+			name: "abbreviations",
+			text: "db msg: primary vs replica, e.g. lag",
+			want: "database message: primary versus replica, for example lag",
+		},
+		{
+			name: "www link and ampersand",
+			text: "See www.example.com/status & retry",
+			want: "See link and retry",
+		},
+		{
+			name: "collapses whitespace",
+			text: "  too   many\tspaces ",
+			want: "too many spaces",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeForSpeech(tt.text); got != tt.want {
+				t.Errorf("NormalizeForSpeech(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeForSpeechLocale(t *testing.T) {
+	tests := []struct {
+		locale string
+		text   string
+		want   string
+	}{
+		{"", "1,000.50 requests", "1000 point 50 requests"},
+		{"en-US", "Bill is $1,234.56", "Bill is 1234 dollars and 56 cents"},
+		{"en-US", "$1 fee, $1.01 total, $5.00 cap", "1 dollar fee, 1 dollar and 1 cent total, 5 dollars cap"},
+		{"en-GB", "£3.5 or 20p", "3 point 5 pounds or 20p"},
+		{"en-US", "1,0000 rows", "1,0000 rows"},
+		{"de-DE", "CPU bei 95,5%", "C P U bei 95 Komma 5 Prozent"},
+		{"de-DE", "Kosten: 1.000,50 €", "Kosten: 1000 Euro und 50 Cent"},
+		{"de-AT", "1.234.567 Anfragen", "1234567 Anfragen"},
+		{"de", "1,000 Sekunden", "1 Komma 000 Sekunden"},
+		{"fr-FR", "Disque plein à 87,5 %", "Disque plein à 87 virgule 5 pour cent"},
+		{"fr-FR", "12\u00a0000,99 €", "12000 euros et 99 centimes"},
+		{"es-ES", "Total 2.500,00 €", "Total 2500 euros"},
+		{"es_MX", "$1,50", "1 dólar con 50 centavos"},
+		{"xx-YY", "1,000.5", "1000 point 5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale+" "+tt.text, func(t *testing.T) {
+			if got := NormalizeForSpeechLocale(tt.text, tt.locale); got != tt.want {
+				t.Errorf("NormalizeForSpeechLocale(%q, %q) = %q, want %q", tt.text, tt.locale, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSupportedLocale(t *testing.T) {
+	for _, locale := range []string{"en", "en-US", "de-DE", "DE", "fr_CA", "es-419"} {
+		if !SupportedLocale(locale) {
+			t.Errorf("SupportedLocale(%q) = false, want true", locale)
+		}
+	}
+	for _, locale := range []string{"", "xx", "ja-JP"} {
+		if SupportedLocale(locale) {
+			t.Errorf("SupportedLocale(%q) = true, want false", locale)
+		}
+	}
+}
+
+func TestStripMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "emphasis",
+			text: "**Build** *failed* on __main__, ~~retrying~~ _not_ retrying",
+			want: "Build failed on main, retrying not retrying",
+		},
+		{
+			name: "links and images",
+			text: "See [the dashboard](https://grafana.example/d/1) ![graph](https://x/y.png)",
+			want: "See the dashboard graph",
+		},
+		{
+			name: "inline code",
+			text: "Run `make deploy` again",
+			want: "Run make deploy again",
+		},
+		{
+			name: "code fence",
+			text: "Error:\n```go\npanic(\"**boom**\")\n```\nplease check",
+			want: "Error: code block please check",
+		},
+		{
+			name: "headings, quotes and bullets",
+			text: "## Alerts\n> disk full\n- srv-01\n* srv-02",
+			want: "Alerts disk full srv-01 srv-02",
+		},
+		{
+			name: "snake_case and lone markers untouched",
+			text: "queue_depth is 5 * 3 and a_b_c",
+			want: "queue_depth is 5 * 3 and a_b_c",
+		},
+		{
+			name: "plain text unchanged",
+			text: "Backup finished successfully",
+			want: "Backup finished successfully",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StripMarkdown(tt.text); got != tt.want {
+				t.Errorf("StripMarkdown(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplaceEmoji(t *testing.T) {
+	tests := []struct {
+		name string
+		mode EmojiMode
+		text string
+		want string
+	}{
+		{"keep", EmojiKeep, "✅ deploy done 🔥", "✅ deploy done 🔥"},
+		{"empty mode keeps", "", "deploy 🔥", "deploy 🔥"},
+		{"strip", EmojiStrip, "✅ deploy done 🔥", "deploy done"},
+		{"strip glued", EmojiStrip, "🚨ALERT🚨", "ALERT"},
+		{"describe", EmojiDescribe, "✅ deploy done 🔥", "check deploy done fire"},
+		{"describe glued", EmojiDescribe, "🚨ALERT🚨", "alert ALERT alert"},
+		{"describe unknown is stripped", EmojiDescribe, "build 🦩 ok", "build ok"},
+		{"variation selector", EmojiDescribe, "⚠️ disk", "warning disk"},
+		{"strip variation selector", EmojiStrip, "⚠️ disk", "disk"},
+
+		// Outside the Basic Multilingual Plane, so surrogate pairs in
+		// UTF-16 and JSON escapes; here each is one rune
+		{"surrogate pair strip", EmojiStrip, "hot \U0001F525 take", "hot take"},
+		{"surrogate pair describe", EmojiDescribe, "\U0001F680 launched", "rocket launched"},
+		{"skin tone", EmojiDescribe, "👍🏽 approved", "thumbs up approved"},
+		{"zwj sequence", EmojiDescribe, "🧑‍💻 on call", "technologist on call"},
+		{"unknown zwj falls back to first", EmojiDescribe, "🔥‍🚀 hot", "fire hot"},
+		{"strip zwj sequence", EmojiStrip, "🧑‍💻 on call", "on call"},
+		{"flag", EmojiDescribe, "🇩🇪 region down", "flag region down"},
+		{"flag strip", EmojiStrip, "🇩🇪 region down", "region down"},
+		{"keycap", EmojiStrip, "step 1️⃣ done", "step 1 done"},
+		{"plain text unchanged", EmojiDescribe, "Backup finished 100%", "Backup finished 100%"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ReplaceEmoji(tt.text, tt.mode); got != tt.want {
+				t.Errorf("ReplaceEmoji(%q, %q) = %q, want %q", tt.text, tt.mode, got, tt.want)
+			}
+		})
+	}
+}
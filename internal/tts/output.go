@@ -0,0 +1,40 @@
+package tts
+
+import (
+	"bytes"
+	"errors"
+)
+
+// ErrOutputTooLarge is returned when an engine's output exceeds its
+// configured maximum size.
+var ErrOutputTooLarge = errors.New("TTS output too large")
+
+// cappedBuffer collects a process's output, refusing writes past max bytes
+// and calling onOverflow the first time that happens so the process can be
+// killed instead of filling memory. A max of zero or less is unlimited.
+//
+// It deliberately has no ReadFrom, so every write goes through the check.
+type cappedBuffer struct {
+	buf        bytes.Buffer
+	max        int
+	onOverflow func()
+	overflowed bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if b.max > 0 && b.buf.Len()+len(p) > b.max {
+		if !b.overflowed {
+			b.overflowed = true
+			if b.onOverflow != nil {
+				b.onOverflow()
+			}
+		}
+		return 0, ErrOutputTooLarge
+	}
+	return b.buf.Write(p)
+}
+
+// Bytes returns the output collected so far.
+func (b *cappedBuffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
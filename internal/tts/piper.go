@@ -3,11 +3,22 @@ package tts
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"maps"
+	"os"
 	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
 
+	"github.com/dgnsrekt/discorgeous-go/internal/logging"
 	"github.com/dgnsrekt/discorgeous-go/internal/wav"
 )
 
@@ -18,22 +29,63 @@ var (
 	ErrNoModelSpecified = errors.New("no piper model specified")
 	// ErrSynthesisFailed is returned when TTS synthesis fails.
 	ErrSynthesisFailed = errors.New("TTS synthesis failed")
+	// ErrEmptyText is returned when there is no text to synthesize.
+	ErrEmptyText = errors.New("empty text")
+	// ErrEmptyOutput is returned when an engine runs successfully but
+	// produces no audio, even after a retry.
+	ErrEmptyOutput = errors.New("TTS engine produced no audio")
 )
 
 // PiperConfig holds configuration for the Piper TTS engine.
 type PiperConfig struct {
+	// Name is the registry name of the engine. If empty, "piper" is used.
+	Name string
 	// BinaryPath is the path to the piper executable.
 	BinaryPath string
 	// ModelPath is the path to the ONNX model file.
 	ModelPath string
 	// DefaultVoice is the default voice/speaker to use.
 	DefaultVoice string
+	// SampleRate is the sample rate of Piper's raw output in Hz.
+	// If zero, it is read from the model's .onnx.json file, falling back
+	// to wav.PiperSampleRate when the file is missing or unreadable.
+	SampleRate int
+	// Channels is the number of channels in Piper's raw output.
+	// If zero, wav.PiperChannels is used.
+	Channels int
+	// LengthScale, NoiseScale and NoiseW are passed to Piper as
+	// --length-scale, --noise-scale and --noise-w. If zero, the model's
+	// recommended inference values from its .onnx.json are used; if the
+	// model has none either, the flag is omitted and Piper's own default
+	// applies.
+	LengthScale float64
+	NoiseScale  float64
+	NoiseW      float64
+	// MaxOutputBytes caps Piper's raw output per synthesis; Piper is killed
+	// and ErrOutputTooLarge returned if it writes more. Zero is unlimited.
+	MaxOutputBytes int
+}
+
+// piperModelConfig is the subset of a Piper model's .onnx.json we read.
+type piperModelConfig struct {
+	Audio struct {
+		SampleRate int `json:"sample_rate"`
+	} `json:"audio"`
+	Inference struct {
+		NoiseScale  float64 `json:"noise_scale"`
+		LengthScale float64 `json:"length_scale"`
+		NoiseW      float64 `json:"noise_w"`
+	} `json:"inference"`
+	SpeakerIDMap map[string]int `json:"speaker_id_map"`
 }
 
 // PiperEngine implements the Engine interface using local Piper TTS.
 type PiperEngine struct {
 	config PiperConfig
 	logger *slog.Logger
+	// speakers maps the model's speaker names to the IDs Piper's --speaker
+	// takes; nil for single-speaker or unknown models.
+	speakers map[string]int
 }
 
 // NewPiperEngine creates a new Piper TTS engine.
@@ -51,24 +103,102 @@ func NewPiperEngine(cfg PiperConfig, logger *slog.Logger) (*PiperEngine, error)
 		return nil, ErrNoModelSpecified
 	}
 
+	// A missing or unreadable model config leaves mc zero; the sample rate
+	// check below reports it
+	mc, mcErr := readModelConfig(cfg.ModelPath)
+
+	if cfg.SampleRate <= 0 {
+		rate, err := modelSampleRate(mc, mcErr)
+		if err != nil {
+			if logger != nil {
+				logger.Warn("could not read piper model sample rate, using default",
+					"model", cfg.ModelPath,
+					"default_sample_rate", wav.PiperSampleRate,
+					"error", err,
+				)
+			}
+			rate = wav.PiperSampleRate
+		}
+		cfg.SampleRate = rate
+	}
+
+	if cfg.Channels <= 0 {
+		cfg.Channels = wav.PiperChannels
+	}
+
+	if cfg.LengthScale <= 0 {
+		cfg.LengthScale = mc.Inference.LengthScale
+	}
+	if cfg.NoiseScale <= 0 {
+		cfg.NoiseScale = mc.Inference.NoiseScale
+	}
+	if cfg.NoiseW <= 0 {
+		cfg.NoiseW = mc.Inference.NoiseW
+	}
+
 	return &PiperEngine{
-		config: cfg,
-		logger: logger,
+		config:   cfg,
+		logger:   logger,
+		speakers: mc.SpeakerIDMap,
 	}, nil
 }
 
+// readModelConfig reads the model's .onnx.json file.
+func readModelConfig(modelPath string) (piperModelConfig, error) {
+	var mc piperModelConfig
+	data, err := os.ReadFile(modelPath + ".json")
+	if err != nil {
+		return mc, err
+	}
+
+	if err := json.Unmarshal(data, &mc); err != nil {
+		return piperModelConfig{}, err
+	}
+	return mc, nil
+}
+
+// modelSampleRate returns audio.sample_rate from a model config read by
+// readModelConfig, or the error that prevented reading it.
+func modelSampleRate(mc piperModelConfig, readErr error) (int, error) {
+	if readErr != nil {
+		return 0, readErr
+	}
+	if mc.Audio.SampleRate <= 0 {
+		return 0, errors.New("model config has no audio.sample_rate")
+	}
+	return mc.Audio.SampleRate, nil
+}
+
 // Name returns the engine identifier.
 func (p *PiperEngine) Name() string {
+	if p.config.Name != "" {
+		return p.config.Name
+	}
 	return "piper"
 }
 
-// Synthesize converts text to audio using Piper.
-func (p *PiperEngine) Synthesize(ctx context.Context, req SynthesizeRequest) (*AudioResult, error) {
-	if req.Text == "" {
-		return nil, errors.New("empty text")
+// MaxTextLength returns DefaultMaxTextLength; Piper has no limit of its own.
+func (p *PiperEngine) MaxTextLength() int {
+	return DefaultMaxTextLength
+}
+
+// Voices returns the speaker names of a multi-speaker model, read from
+// its .onnx.json, followed by their numeric IDs. It returns nil if the
+// model config lists no speakers.
+func (p *PiperEngine) Voices() []string {
+	if len(p.speakers) == 0 {
+		return nil
+	}
+
+	voices := slices.Sorted(maps.Keys(p.speakers))
+	for _, id := range slices.Sorted(maps.Values(p.speakers)) {
+		voices = append(voices, strconv.Itoa(id))
 	}
+	return voices
+}
 
-	// Build piper command arguments
+// buildArgs returns the piper command-line arguments and resolved voice for a request.
+func (p *PiperEngine) buildArgs(req SynthesizeRequest) ([]string, string) {
 	args := []string{
 		"--model", p.config.ModelPath,
 		"--output-raw",
@@ -80,9 +210,44 @@ func (p *PiperEngine) Synthesize(ctx context.Context, req SynthesizeRequest) (*A
 		voice = p.config.DefaultVoice
 	}
 	if voice != "" && voice != "default" {
-		args = append(args, "--speaker", voice)
+		// Piper takes speaker IDs; names from the model config are mapped
+		speaker := voice
+		if id, ok := p.speakers[voice]; ok {
+			speaker = strconv.Itoa(id)
+		}
+		args = append(args, "--speaker", speaker)
+	}
+
+	// Synthesis parameters: the request wins over the configured defaults
+	scales := []struct {
+		flag        string
+		req, config float64
+	}{
+		{"--length-scale", req.LengthScale, p.config.LengthScale},
+		{"--noise-scale", req.NoiseScale, p.config.NoiseScale},
+		{"--noise-w", req.NoiseW, p.config.NoiseW},
+	}
+	for _, s := range scales {
+		v := s.req
+		if v <= 0 {
+			v = s.config
+		}
+		if v > 0 {
+			args = append(args, s.flag, strconv.FormatFloat(v, 'f', -1, 64))
+		}
+	}
+
+	return args, voice
+}
+
+// Synthesize converts text to audio using Piper.
+func (p *PiperEngine) Synthesize(ctx context.Context, req SynthesizeRequest) (*AudioResult, error) {
+	if req.Text == "" {
+		return nil, ErrEmptyText
 	}
 
+	args, voice := p.buildArgs(req)
+
 	p.logger.Debug("running piper",
 		"binary", p.config.BinaryPath,
 		"model", p.config.ModelPath,
@@ -90,46 +255,207 @@ func (p *PiperEngine) Synthesize(ctx context.Context, req SynthesizeRequest) (*A
 		"text_length", len(req.Text),
 	)
 
-	// Create command with context for cancellation
-	cmd := exec.CommandContext(ctx, p.config.BinaryPath, args...)
+	rawAudio, err := p.run(ctx, args, req.Text)
+	if err != nil {
+		return nil, err
+	}
+
+	// Piper occasionally emits nothing for odd input; retry once with the
+	// text tidied up before giving up on it
+	if len(rawAudio) == 0 {
+		retryText := tidyForRetry(req.Text)
+		p.logger.Warn("piper produced no audio, retrying with tidied text",
+			"text_length", len(req.Text),
+			"retry_length", len(retryText),
+		)
+		if retryText != "" {
+			if rawAudio, err = p.run(ctx, args, retryText); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(rawAudio) == 0 {
+		p.logger.Error("piper produced no audio", "text_length", len(req.Text))
+		return nil, ErrEmptyOutput
+	}
+
+	p.logger.Debug("piper synthesis complete",
+		"output_bytes", len(rawAudio),
+	)
+
+	// Piper outputs raw 16-bit PCM at the model's sample rate (mono)
+	// Wrap it in a WAV header for consistency
+	sampleRate, channels := p.outputFormat()
+	wavData := wav.WrapRawPCM(rawAudio, sampleRate, channels, wav.PiperBitsPerSample)
+
+	return &AudioResult{
+		Data:       wavData,
+		Format:     "wav",
+		SampleRate: sampleRate,
+		Channels:   channels,
+		Duration:   wavDuration(wavData),
+	}, nil
+}
+
+// run runs piper once with text on stdin and returns its raw output.
+func (p *PiperEngine) run(ctx context.Context, args []string, text string) ([]byte, error) {
+	// Create command with context for cancellation; runaway output
+	// cancels it too
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cmd := exec.CommandContext(runCtx, p.config.BinaryPath, args...)
 
 	// Set up stdin with the text
-	cmd.Stdin = bytes.NewReader([]byte(req.Text))
+	cmd.Stdin = bytes.NewReader([]byte(text))
 
 	// Capture stdout (raw audio) and stderr (logs/errors)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
+	stdout := &cappedBuffer{max: p.config.MaxOutputBytes, onOverflow: cancel}
+	var stderr bytes.Buffer
+	cmd.Stdout = stdout
 	cmd.Stderr = &stderr
 
 	// Run the command
 	if err := cmd.Run(); err != nil {
+		if stdout.overflowed {
+			p.logger.Error("piper output too large, killed it",
+				"max_bytes", p.config.MaxOutputBytes,
+				"text_length", len(text),
+			)
+			return nil, fmt.Errorf("%w: more than %d bytes", ErrOutputTooLarge, p.config.MaxOutputBytes)
+		}
 		if ctx.Err() != nil {
 			return nil, ctx.Err()
 		}
 		p.logger.Error("piper failed",
 			"error", err,
-			"stderr", stderr.String(),
+			"stderr", logging.SanitizeStderr(stderr.String()),
 		)
 		return nil, fmt.Errorf("%w: %v", ErrSynthesisFailed, err)
 	}
+	return stdout.Bytes(), nil
+}
 
-	rawAudio := stdout.Bytes()
-	if len(rawAudio) == 0 {
-		return nil, fmt.Errorf("%w: no audio output", ErrSynthesisFailed)
+// tidyForRetry collapses whitespace and control characters in text and
+// ends it with a full stop, which is what Piper copes with best.
+func tidyForRetry(text string) string {
+	text = strings.Join(strings.FieldsFunc(text, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r)
+	}), " ")
+	if text == "" || strings.ContainsRune(".!?", rune(text[len(text)-1])) {
+		return text
 	}
+	return text + "."
+}
 
-	p.logger.Debug("piper synthesis complete",
-		"output_bytes", len(rawAudio),
+// outputFormat returns the sample rate and channel count of Piper's raw output.
+func (p *PiperEngine) outputFormat() (sampleRate, channels int) {
+	sampleRate = p.config.SampleRate
+	if sampleRate <= 0 {
+		sampleRate = wav.PiperSampleRate
+	}
+	channels = p.config.Channels
+	if channels <= 0 {
+		channels = wav.PiperChannels
+	}
+	return sampleRate, channels
+}
+
+// SynthesizeStream starts Piper and returns its raw PCM stdout as it is produced.
+// The returned reader must be closed; closing before EOF kills the process.
+func (p *PiperEngine) SynthesizeStream(ctx context.Context, req SynthesizeRequest) (io.ReadCloser, StreamFormat, error) {
+	if req.Text == "" {
+		return nil, StreamFormat{}, ErrEmptyText
+	}
+
+	args, voice := p.buildArgs(req)
+	sampleRate, channels := p.outputFormat()
+	format := StreamFormat{
+		SampleRate:    sampleRate,
+		Channels:      channels,
+		BitsPerSample: wav.PiperBitsPerSample,
+	}
+
+	p.logger.Debug("starting piper stream",
+		"binary", p.config.BinaryPath,
+		"model", p.config.ModelPath,
+		"voice", voice,
+		"text_length", len(req.Text),
 	)
 
-	// Piper outputs raw 16-bit PCM at 22050 Hz mono by default
-	// Wrap it in a WAV header for consistency
-	wavData := wav.WrapRawPCM(rawAudio, wav.PiperSampleRate, wav.PiperChannels, wav.PiperBitsPerSample)
+	cmd := exec.CommandContext(ctx, p.config.BinaryPath, args...)
+	cmd.Stdin = bytes.NewReader([]byte(req.Text))
 
-	return &AudioResult{
-		Data:       wavData,
-		Format:     "wav",
-		SampleRate: wav.PiperSampleRate,
-		Channels:   wav.PiperChannels,
-	}, nil
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, StreamFormat{}, fmt.Errorf("%w: %v", ErrSynthesisFailed, err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		if ctx.Err() != nil {
+			return nil, StreamFormat{}, ctx.Err()
+		}
+		return nil, StreamFormat{}, fmt.Errorf("%w: %v", ErrSynthesisFailed, err)
+	}
+
+	return &piperStream{
+		ctx:    ctx,
+		cmd:    cmd,
+		stdout: stdout,
+		stderr: stderr,
+		logger: p.logger,
+	}, format, nil
+}
+
+// piperStream reads Piper's stdout and reaps the process on Close.
+type piperStream struct {
+	ctx    context.Context
+	cmd    *exec.Cmd
+	stdout io.ReadCloser
+	stderr *bytes.Buffer
+	logger *slog.Logger
+
+	// eof is set by Read, which may run on another goroutine than Close
+	eof      atomic.Bool
+	once     sync.Once
+	closeErr error
+}
+
+// Read reads raw PCM from Piper's stdout.
+func (s *piperStream) Read(b []byte) (int, error) {
+	n, err := s.stdout.Read(b)
+	if err == io.EOF {
+		s.eof.Store(true)
+	}
+	return n, err
+}
+
+// Close waits for Piper to exit, killing it first if output was not fully read.
+func (s *piperStream) Close() error {
+	s.once.Do(func() {
+		if !s.eof.Load() {
+			// Consumer stopped early (interrupt or downstream failure).
+			// Drain the pipe after the kill so no read is in progress
+			// when Wait closes it.
+			_ = s.cmd.Process.Kill()
+			_, _ = io.Copy(io.Discard, s.stdout)
+			_ = s.cmd.Wait()
+			return
+		}
+
+		if err := s.cmd.Wait(); err != nil {
+			if s.ctx.Err() != nil {
+				s.closeErr = s.ctx.Err()
+				return
+			}
+			s.logger.Error("piper failed",
+				"error", err,
+				"stderr", logging.SanitizeStderr(s.stderr.String()),
+			)
+			s.closeErr = fmt.Errorf("%w: %v", ErrSynthesisFailed, err)
+		}
+	})
+	return s.closeErr
 }
@@ -3,10 +3,15 @@ package tts
 import (
 	"context"
 	"errors"
+	"io"
 	"log/slog"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/dgnsrekt/discorgeous-go/internal/wav"
 )
@@ -102,3 +107,423 @@ func TestPiperUsesWavPackage(t *testing.T) {
 		t.Errorf("PiperBitsPerSample = %d, want 16", wav.PiperBitsPerSample)
 	}
 }
+
+// writeFakePiper creates a shell script that ignores its arguments and
+// writes a few bytes of raw PCM to stdout, standing in for the piper binary.
+func writeFakePiper(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "piper")
+	script := "#!/bin/sh\ncat > /dev/null\nprintf 'abcd'\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatalf("failed to write fake piper: %v", err)
+	}
+	return path
+}
+
+func TestPiperEngine_Synthesize_EmptyOutput(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	// A stub that consumes its input and never writes any audio
+	dir := t.TempDir()
+	countPath := filepath.Join(dir, "count")
+	path := filepath.Join(dir, "piper")
+	script := "#!/bin/sh\ncat > /dev/null\necho run >> " + countPath + "\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatalf("failed to write fake piper: %v", err)
+	}
+
+	engine, err := NewPiperEngine(PiperConfig{BinaryPath: path, ModelPath: "/path/to/model.onnx"}, logger)
+	if err != nil {
+		t.Fatalf("NewPiperEngine() error = %v", err)
+	}
+
+	_, err = engine.Synthesize(context.Background(), SynthesizeRequest{Text: "hello"})
+	if !errors.Is(err, ErrEmptyOutput) {
+		t.Fatalf("Synthesize() error = %v, want ErrEmptyOutput", err)
+	}
+
+	runs, err := os.ReadFile(countPath)
+	if err != nil {
+		t.Fatalf("failed to read run count: %v", err)
+	}
+	if n := strings.Count(string(runs), "run"); n != 2 {
+		t.Errorf("piper ran %d times, want 2 (one retry)", n)
+	}
+}
+
+func TestPiperEngine_Synthesize_OutputTooLarge(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	// A stub that never stops writing; it must be killed at the cap
+	path := filepath.Join(t.TempDir(), "piper")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\ncat > /dev/null\nexec yes\n"), 0o755); err != nil {
+		t.Fatalf("failed to write fake piper: %v", err)
+	}
+
+	engine, err := NewPiperEngine(PiperConfig{BinaryPath: path, ModelPath: "/path/to/model.onnx", MaxOutputBytes: 4096}, logger)
+	if err != nil {
+		t.Fatalf("NewPiperEngine() error = %v", err)
+	}
+
+	_, err = engine.Synthesize(context.Background(), SynthesizeRequest{Text: "hello"})
+	if !errors.Is(err, ErrOutputTooLarge) {
+		t.Errorf("Synthesize() error = %v, want ErrOutputTooLarge", err)
+	}
+
+	// Output within the cap is unaffected
+	engine.config.BinaryPath = writeFakePiper(t)
+	if _, err := engine.Synthesize(context.Background(), SynthesizeRequest{Text: "hello"}); err != nil {
+		t.Errorf("Synthesize() under the cap error = %v", err)
+	}
+}
+
+func TestPiperEngine_Synthesize_RetriesWithTidiedText(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	// A stub that only speaks the tidied form of the text
+	path := filepath.Join(t.TempDir(), "piper")
+	script := "#!/bin/sh\ntext=$(cat)\n[ \"$text\" = 'hello there.' ] && printf 'abcd'\nexit 0\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatalf("failed to write fake piper: %v", err)
+	}
+
+	engine, err := NewPiperEngine(PiperConfig{BinaryPath: path, ModelPath: "/path/to/model.onnx"}, logger)
+	if err != nil {
+		t.Fatalf("NewPiperEngine() error = %v", err)
+	}
+
+	result, err := engine.Synthesize(context.Background(), SynthesizeRequest{Text: " hello\n\tthere "})
+	if err != nil {
+		t.Fatalf("Synthesize() error = %v", err)
+	}
+	if len(result.Data) != wav.HeaderSize+4 {
+		t.Errorf("output = %d bytes, want header plus the retry's 4 bytes", len(result.Data))
+	}
+}
+
+func TestNewPiperEngine_DetectsSampleRate(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	modelPath := filepath.Join(t.TempDir(), "model.onnx")
+	modelJSON := `{"audio":{"sample_rate":16000},"num_speakers":1}`
+	if err := os.WriteFile(modelPath+".json", []byte(modelJSON), 0o644); err != nil {
+		t.Fatalf("failed to write model json: %v", err)
+	}
+
+	engine, err := NewPiperEngine(PiperConfig{
+		BinaryPath: writeFakePiper(t),
+		ModelPath:  modelPath,
+	}, logger)
+	if err != nil {
+		t.Fatalf("NewPiperEngine() error = %v", err)
+	}
+
+	if engine.config.SampleRate != 16000 {
+		t.Errorf("SampleRate = %d, want 16000", engine.config.SampleRate)
+	}
+
+	result, err := engine.Synthesize(context.Background(), SynthesizeRequest{Text: "hello"})
+	if err != nil {
+		t.Fatalf("Synthesize() error = %v", err)
+	}
+
+	if result.SampleRate != 16000 {
+		t.Errorf("AudioResult.SampleRate = %d, want 16000", result.SampleRate)
+	}
+
+	// Sample rate lives at bytes 24-28 of the WAV header
+	headerRate := uint32(result.Data[24]) | uint32(result.Data[25])<<8 |
+		uint32(result.Data[26])<<16 | uint32(result.Data[27])<<24
+	if headerRate != 16000 {
+		t.Errorf("WAV header sample rate = %d, want 16000", headerRate)
+	}
+}
+
+func TestNewPiperEngine_SampleRateFallback(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	// No .onnx.json next to the model
+	engine, err := NewPiperEngine(PiperConfig{
+		BinaryPath: "echo",
+		ModelPath:  filepath.Join(t.TempDir(), "model.onnx"),
+	}, logger)
+	if err != nil {
+		t.Fatalf("NewPiperEngine() error = %v", err)
+	}
+
+	if engine.config.SampleRate != wav.PiperSampleRate {
+		t.Errorf("SampleRate = %d, want %d", engine.config.SampleRate, wav.PiperSampleRate)
+	}
+	if engine.config.Channels != wav.PiperChannels {
+		t.Errorf("Channels = %d, want %d", engine.config.Channels, wav.PiperChannels)
+	}
+}
+
+func TestNewPiperEngine_ExplicitSampleRate(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	modelPath := filepath.Join(t.TempDir(), "model.onnx")
+	if err := os.WriteFile(modelPath+".json", []byte(`{"audio":{"sample_rate":16000}}`), 0o644); err != nil {
+		t.Fatalf("failed to write model json: %v", err)
+	}
+
+	engine, err := NewPiperEngine(PiperConfig{
+		BinaryPath: "echo",
+		ModelPath:  modelPath,
+		SampleRate: 24000,
+	}, logger)
+	if err != nil {
+		t.Fatalf("NewPiperEngine() error = %v", err)
+	}
+
+	if engine.config.SampleRate != 24000 {
+		t.Errorf("SampleRate = %d, want 24000 (explicit config wins)", engine.config.SampleRate)
+	}
+}
+
+func TestNewPiperEngine_InferenceDefaults(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	modelPath := filepath.Join(t.TempDir(), "model.onnx")
+	modelJSON := `{"audio":{"sample_rate":22050},"inference":{"noise_scale":0.667,"length_scale":1,"noise_w":0.8}}`
+	if err := os.WriteFile(modelPath+".json", []byte(modelJSON), 0o644); err != nil {
+		t.Fatalf("failed to write model json: %v", err)
+	}
+
+	engine, err := NewPiperEngine(PiperConfig{
+		BinaryPath:  "echo",
+		ModelPath:   modelPath,
+		LengthScale: 1.2, // explicit config wins over the model
+	}, logger)
+	if err != nil {
+		t.Fatalf("NewPiperEngine() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		req  SynthesizeRequest
+		want []string
+	}{
+		{
+			"model and config defaults",
+			SynthesizeRequest{Text: "hello"},
+			[]string{"--length-scale", "1.2", "--noise-scale", "0.667", "--noise-w", "0.8"},
+		},
+		{
+			"request overrides",
+			SynthesizeRequest{Text: "hello", LengthScale: 0.9, NoiseW: 0.5},
+			[]string{"--length-scale", "0.9", "--noise-scale", "0.667", "--noise-w", "0.5"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, _ := engine.buildArgs(tt.req)
+			want := append([]string{"--model", modelPath, "--output-raw"}, tt.want...)
+			if !slices.Equal(args, want) {
+				t.Errorf("buildArgs() = %v, want %v", args, want)
+			}
+		})
+	}
+}
+
+func TestNewPiperEngine_NoInferenceDefaults(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	modelPath := filepath.Join(t.TempDir(), "model.onnx")
+	if err := os.WriteFile(modelPath+".json", []byte(`{"audio":{"sample_rate":16000}}`), 0o644); err != nil {
+		t.Fatalf("failed to write model json: %v", err)
+	}
+
+	engine, err := NewPiperEngine(PiperConfig{BinaryPath: "echo", ModelPath: modelPath}, logger)
+	if err != nil {
+		t.Fatalf("NewPiperEngine() error = %v", err)
+	}
+
+	// Without model or config values, Piper's own defaults apply
+	args, _ := engine.buildArgs(SynthesizeRequest{Text: "hello"})
+	if want := []string{"--model", modelPath, "--output-raw"}; !slices.Equal(args, want) {
+		t.Errorf("buildArgs() = %v, want %v", args, want)
+	}
+}
+
+func TestNewPiperEngine_Speakers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	modelPath := filepath.Join(t.TempDir(), "model.onnx")
+	modelJSON := `{"audio":{"sample_rate":22050},"num_speakers":2,"speaker_id_map":{"bob":1,"amy":0}}`
+	if err := os.WriteFile(modelPath+".json", []byte(modelJSON), 0o644); err != nil {
+		t.Fatalf("failed to write model json: %v", err)
+	}
+
+	engine, err := NewPiperEngine(PiperConfig{BinaryPath: "echo", ModelPath: modelPath}, logger)
+	if err != nil {
+		t.Fatalf("NewPiperEngine() error = %v", err)
+	}
+
+	if got, want := engine.Voices(), []string{"amy", "bob", "0", "1"}; !slices.Equal(got, want) {
+		t.Errorf("Voices() = %v, want %v", got, want)
+	}
+
+	// Speaker names are passed to Piper as their IDs
+	args, _ := engine.buildArgs(SynthesizeRequest{Text: "hello", Voice: "bob"})
+	if want := []string{"--model", modelPath, "--output-raw", "--speaker", "1"}; !slices.Equal(args, want) {
+		t.Errorf("buildArgs() = %v, want %v", args, want)
+	}
+	args, _ = engine.buildArgs(SynthesizeRequest{Text: "hello", Voice: "0"})
+	if want := []string{"--model", modelPath, "--output-raw", "--speaker", "0"}; !slices.Equal(args, want) {
+		t.Errorf("buildArgs() = %v, want %v", args, want)
+	}
+}
+
+func TestNewPiperEngine_SingleSpeaker(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	modelPath := filepath.Join(t.TempDir(), "model.onnx")
+	if err := os.WriteFile(modelPath+".json", []byte(`{"audio":{"sample_rate":22050},"num_speakers":1}`), 0o644); err != nil {
+		t.Fatalf("failed to write model json: %v", err)
+	}
+
+	engine, err := NewPiperEngine(PiperConfig{BinaryPath: "echo", ModelPath: modelPath}, logger)
+	if err != nil {
+		t.Fatalf("NewPiperEngine() error = %v", err)
+	}
+
+	if got := engine.Voices(); got != nil {
+		t.Errorf("Voices() = %v, want nil for a model without speakers", got)
+	}
+}
+
+func TestPiperEngine_SynthesizeStream(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	engine := &PiperEngine{
+		config: PiperConfig{
+			BinaryPath: writeFakePiper(t),
+			ModelPath:  "/fake/model.onnx",
+			SampleRate: 16000,
+		},
+		logger: logger,
+	}
+
+	stream, format, err := engine.SynthesizeStream(context.Background(), SynthesizeRequest{Text: "hello"})
+	if err != nil {
+		t.Fatalf("SynthesizeStream() error = %v", err)
+	}
+
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if err := stream.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+
+	// Stream is raw PCM, not WAV-wrapped
+	if string(data) != "abcd" {
+		t.Errorf("stream data = %q, want %q", data, "abcd")
+	}
+	if format.SampleRate != 16000 {
+		t.Errorf("format.SampleRate = %d, want 16000", format.SampleRate)
+	}
+	if format.Channels != wav.PiperChannels {
+		t.Errorf("format.Channels = %d, want %d", format.Channels, wav.PiperChannels)
+	}
+	if format.BitsPerSample != wav.PiperBitsPerSample {
+		t.Errorf("format.BitsPerSample = %d, want %d", format.BitsPerSample, wav.PiperBitsPerSample)
+	}
+}
+
+func TestPiperEngine_SynthesizeStream_CloseEarly(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	// A piper stand-in that never finishes on its own
+	path := filepath.Join(t.TempDir(), "piper")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\nexec sleep 30\n"), 0o755); err != nil {
+		t.Fatalf("failed to write fake piper: %v", err)
+	}
+
+	engine := &PiperEngine{
+		config: PiperConfig{BinaryPath: path, ModelPath: "/fake/model.onnx"},
+		logger: logger,
+	}
+
+	stream, _, err := engine.SynthesizeStream(context.Background(), SynthesizeRequest{Text: "hello"})
+	if err != nil {
+		t.Fatalf("SynthesizeStream() error = %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		stream.Close()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close() did not kill the piper process")
+	}
+}
+
+func TestPiperEngine_SynthesizeStream_CloseDuringRead(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	// A piper stand-in that writes some audio and then hangs
+	path := filepath.Join(t.TempDir(), "piper")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\nprintf abcd\nexec sleep 30\n"), 0o755); err != nil {
+		t.Fatalf("failed to write fake piper: %v", err)
+	}
+
+	engine := &PiperEngine{
+		config: PiperConfig{BinaryPath: path, ModelPath: "/fake/model.onnx"},
+		logger: logger,
+	}
+
+	stream, _, err := engine.SynthesizeStream(context.Background(), SynthesizeRequest{Text: "hello"})
+	if err != nil {
+		t.Fatalf("SynthesizeStream() error = %v", err)
+	}
+
+	// Read on another goroutine, as ffmpeg's stdin copy does, while Close
+	// runs here
+	readDone := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, stream)
+		close(readDone)
+	}()
+
+	closeDone := make(chan struct{})
+	go func() {
+		stream.Close()
+		close(closeDone)
+	}()
+
+	for _, done := range []chan struct{}{closeDone, readDone} {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Close() did not stop the reader and the piper process")
+		}
+	}
+}
+
+func TestPiperEngine_SynthesizeStream_EmptyText(t *testing.T) {
+	engine := &PiperEngine{
+		config: PiperConfig{BinaryPath: "echo", ModelPath: "/fake/model.onnx"},
+		logger: slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError})),
+	}
+
+	_, _, err := engine.SynthesizeStream(context.Background(), SynthesizeRequest{Text: ""})
+	if err == nil || err.Error() != "empty text" {
+		t.Errorf("expected 'empty text' error, got %v", err)
+	}
+}
+
+func TestPiperEngine_CustomName(t *testing.T) {
+	engine := &PiperEngine{config: PiperConfig{Name: "amy"}}
+
+	if engine.Name() != "amy" {
+		t.Errorf("expected name 'amy', got '%s'", engine.Name())
+	}
+}
@@ -0,0 +1,193 @@
+package tts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/polly"
+	"github.com/aws/aws-sdk-go-v2/service/polly/types"
+
+	"github.com/dgnsrekt/discorgeous-go/internal/wav"
+)
+
+var (
+	// ErrPollyThrottled is returned when Polly rejects a request for
+	// exceeding the account's rate limit, after the SDK's own retries.
+	ErrPollyThrottled = errors.New("polly request throttled")
+	// ErrInvalidPollyVoice is returned when a voice names an unknown Polly engine.
+	ErrInvalidPollyVoice = errors.New("invalid polly voice")
+)
+
+const (
+	// pollySampleRate is the rate Polly PCM is requested at; 16kHz is the
+	// highest it supports for PCM.
+	pollySampleRate = 16000
+	// pollyDefaultVoice is used when neither the config nor the request
+	// names a voice.
+	pollyDefaultVoice = "Joanna"
+	// pollyMaxTextLength is Polly's limit on billed characters per
+	// SynthesizeSpeech request.
+	pollyMaxTextLength = 3000
+)
+
+// PollyClient is the subset of the Polly API client the engine uses.
+type PollyClient interface {
+	SynthesizeSpeech(ctx context.Context, params *polly.SynthesizeSpeechInput, optFns ...func(*polly.Options)) (*polly.SynthesizeSpeechOutput, error)
+}
+
+// PollyConfig holds configuration for the AWS Polly TTS engine.
+type PollyConfig struct {
+	// Name is the registry name of the engine. If empty, "polly" is used.
+	Name string
+	// Region is the AWS region to call, e.g. "us-east-1".
+	Region string
+	// DefaultVoice is the voice used when a request names none, in the
+	// same form as a request voice: a Polly voice ID, optionally followed
+	// by ":" and an engine, e.g. "Matthew:neural". If empty, Joanna is used.
+	DefaultVoice string
+	// Engine is the Polly engine for voices that don't name one, e.g.
+	// "neural". If empty, Polly's default (standard) is used.
+	Engine string
+}
+
+// PollyEngine implements the Engine interface using AWS Polly.
+type PollyEngine struct {
+	config PollyConfig
+	client PollyClient
+	logger *slog.Logger
+}
+
+// NewPollyEngine creates a Polly engine using the default AWS credential
+// chain (environment, shared config, instance role). It fails if no
+// credentials can be found.
+func NewPollyEngine(ctx context.Context, cfg PollyConfig, logger *slog.Logger) (*PollyEngine, error) {
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(cfg.Region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	if _, err := awsCfg.Credentials.Retrieve(ctx); err != nil {
+		return nil, fmt.Errorf("no AWS credentials: %w", err)
+	}
+	return NewPollyEngineWithClient(cfg, polly.NewFromConfig(awsCfg), logger)
+}
+
+// NewPollyEngineWithClient creates a Polly engine that calls client.
+func NewPollyEngineWithClient(cfg PollyConfig, client PollyClient, logger *slog.Logger) (*PollyEngine, error) {
+	if cfg.Engine != "" && !validPollyEngine(cfg.Engine) {
+		return nil, fmt.Errorf("%w: unknown engine %q", ErrInvalidPollyVoice, cfg.Engine)
+	}
+	if cfg.DefaultVoice == "" {
+		cfg.DefaultVoice = pollyDefaultVoice
+	}
+
+	return &PollyEngine{
+		config: cfg,
+		client: client,
+		logger: logger,
+	}, nil
+}
+
+// validPollyEngine reports whether name is a Polly engine.
+func validPollyEngine(name string) bool {
+	return slices.Contains(types.Engine("").Values(), types.Engine(name))
+}
+
+// Name returns the engine identifier.
+func (p *PollyEngine) Name() string {
+	if p.config.Name != "" {
+		return p.config.Name
+	}
+	return "polly"
+}
+
+// MaxTextLength returns Polly's per-request text limit.
+func (p *PollyEngine) MaxTextLength() int {
+	return pollyMaxTextLength
+}
+
+// voiceFor resolves a request voice to a Polly voice ID and engine.
+func (p *PollyEngine) voiceFor(voice string) (types.VoiceId, types.Engine, error) {
+	if voice == "" || voice == "default" {
+		voice = p.config.DefaultVoice
+	}
+
+	id, engine, named := strings.Cut(voice, ":")
+	if !named {
+		engine = p.config.Engine
+	}
+	if engine != "" && !validPollyEngine(engine) {
+		return "", "", fmt.Errorf("%w: unknown engine %q", ErrInvalidPollyVoice, engine)
+	}
+	return types.VoiceId(id), types.Engine(engine), nil
+}
+
+// Synthesize converts text to audio using Polly.
+func (p *PollyEngine) Synthesize(ctx context.Context, req SynthesizeRequest) (*AudioResult, error) {
+	if req.Text == "" {
+		return nil, ErrEmptyText
+	}
+
+	voiceID, engine, err := p.voiceFor(req.Voice)
+	if err != nil {
+		return nil, err
+	}
+
+	p.logger.Debug("calling polly",
+		"voice", voiceID,
+		"engine", engine,
+		"text_length", len(req.Text),
+	)
+
+	out, err := p.client.SynthesizeSpeech(ctx, &polly.SynthesizeSpeechInput{
+		Text:         aws.String(req.Text),
+		VoiceId:      voiceID,
+		Engine:       engine,
+		LanguageCode: types.LanguageCode(req.Language),
+		OutputFormat: types.OutputFormatPcm,
+		SampleRate:   aws.String(strconv.Itoa(pollySampleRate)),
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		var throttled *types.ThrottlingException
+		if errors.As(err, &throttled) {
+			p.logger.Warn("polly request throttled", "error", err)
+			return nil, fmt.Errorf("%w: %v", ErrPollyThrottled, err)
+		}
+		p.logger.Error("polly synthesis failed", "error", err)
+		return nil, fmt.Errorf("%w: %v", ErrSynthesisFailed, err)
+	}
+	defer out.AudioStream.Close()
+
+	pcm, err := io.ReadAll(out.AudioStream)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("%w: %v", ErrSynthesisFailed, err)
+	}
+	if len(pcm) == 0 {
+		return nil, fmt.Errorf("%w: no audio output", ErrSynthesisFailed)
+	}
+
+	p.logger.Debug("polly synthesis complete", "output_bytes", len(pcm))
+
+	// Polly PCM is headerless 16-bit signed little-endian mono
+	data := wav.WrapRawPCM(pcm, pollySampleRate, 1, 16)
+	return &AudioResult{
+		Data:       data,
+		Format:     "wav",
+		SampleRate: pollySampleRate,
+		Channels:   1,
+		Duration:   wavDuration(data),
+	}, nil
+}
@@ -0,0 +1,160 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/polly"
+	"github.com/aws/aws-sdk-go-v2/service/polly/types"
+
+	"github.com/dgnsrekt/discorgeous-go/internal/wav"
+)
+
+// fakePolly records the last request and returns audio or err.
+type fakePolly struct {
+	input *polly.SynthesizeSpeechInput
+	audio []byte
+	err   error
+}
+
+func (f *fakePolly) SynthesizeSpeech(ctx context.Context, params *polly.SynthesizeSpeechInput, optFns ...func(*polly.Options)) (*polly.SynthesizeSpeechOutput, error) {
+	f.input = params
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &polly.SynthesizeSpeechOutput{AudioStream: io.NopCloser(bytes.NewReader(f.audio))}, nil
+}
+
+func TestPollyEngine_Synthesize(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	client := &fakePolly{audio: []byte("abcd")}
+
+	engine, err := NewPollyEngineWithClient(PollyConfig{DefaultVoice: "Amy"}, client, logger)
+	if err != nil {
+		t.Fatalf("NewPollyEngineWithClient() error = %v", err)
+	}
+
+	result, err := engine.Synthesize(context.Background(), SynthesizeRequest{Text: "hello"})
+	if err != nil {
+		t.Fatalf("Synthesize() error = %v", err)
+	}
+
+	if client.input.OutputFormat != types.OutputFormatPcm || *client.input.SampleRate != "16000" {
+		t.Errorf("requested %s at %s Hz, want pcm at 16000 Hz", client.input.OutputFormat, *client.input.SampleRate)
+	}
+	if client.input.VoiceId != "Amy" {
+		t.Errorf("VoiceId = %q, want Amy", client.input.VoiceId)
+	}
+	if client.input.LanguageCode != "" {
+		t.Errorf("LanguageCode = %q, want none without a language", client.input.LanguageCode)
+	}
+	if result.Format != "wav" || result.SampleRate != 16000 || result.Channels != 1 {
+		t.Errorf("result = %s %d Hz %d channels, want wav 16000 Hz mono", result.Format, result.SampleRate, result.Channels)
+	}
+	if string(result.Data[wav.HeaderSize:]) != "abcd" {
+		t.Errorf("expected the PCM wrapped in a WAV header, got %d bytes", len(result.Data))
+	}
+}
+
+func TestPollyEngine_VoiceMapping(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	tests := []struct {
+		name       string
+		engine     string
+		voice      string
+		wantVoice  types.VoiceId
+		wantEngine types.Engine
+		wantErr    error
+	}{
+		{"default voice", "", "", "Joanna", "", nil},
+		{"configured engine", "neural", "Matthew", "Matthew", types.EngineNeural, nil},
+		{"voice names engine", "neural", "Brian:standard", "Brian", types.EngineStandard, nil},
+		{"unknown engine", "", "Brian:turbo", "", "", ErrInvalidPollyVoice},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakePolly{audio: []byte("abcd")}
+			engine, err := NewPollyEngineWithClient(PollyConfig{Engine: tt.engine}, client, logger)
+			if err != nil {
+				t.Fatalf("NewPollyEngineWithClient() error = %v", err)
+			}
+
+			_, err = engine.Synthesize(context.Background(), SynthesizeRequest{Text: "hello", Voice: tt.voice})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Synthesize() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if client.input.VoiceId != tt.wantVoice || client.input.Engine != tt.wantEngine {
+				t.Errorf("requested %q/%q, want %q/%q", client.input.VoiceId, client.input.Engine, tt.wantVoice, tt.wantEngine)
+			}
+		})
+	}
+}
+
+func TestPollyEngine_Language(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	client := &fakePolly{audio: []byte("abcd")}
+
+	engine, err := NewPollyEngineWithClient(PollyConfig{DefaultVoice: "Aditi"}, client, logger)
+	if err != nil {
+		t.Fatalf("NewPollyEngineWithClient() error = %v", err)
+	}
+
+	if _, err := engine.Synthesize(context.Background(), SynthesizeRequest{Text: "hello", Language: "hi-IN"}); err != nil {
+		t.Fatalf("Synthesize() error = %v", err)
+	}
+	if client.input.LanguageCode != types.LanguageCodeHiIn {
+		t.Errorf("LanguageCode = %q, want hi-IN", client.input.LanguageCode)
+	}
+}
+
+func TestPollyEngine_Errors(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		client *fakePolly
+		want   error
+	}{
+		{"throttled", context.Background(), &fakePolly{err: &types.ThrottlingException{}}, ErrPollyThrottled},
+		{"service failure", context.Background(), &fakePolly{err: &types.ServiceFailureException{}}, ErrSynthesisFailed},
+		{"empty audio", context.Background(), &fakePolly{}, ErrSynthesisFailed},
+		{"cancelled", cancelled, &fakePolly{audio: []byte("abcd")}, context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewPollyEngineWithClient(PollyConfig{}, tt.client, logger)
+			if err != nil {
+				t.Fatalf("NewPollyEngineWithClient() error = %v", err)
+			}
+			if _, err := engine.Synthesize(tt.ctx, SynthesizeRequest{Text: "hello"}); !errors.Is(err, tt.want) {
+				t.Errorf("Synthesize() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPollyEngineWithClient_InvalidEngine(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	if _, err := NewPollyEngineWithClient(PollyConfig{Engine: "turbo"}, &fakePolly{}, logger); !errors.Is(err, ErrInvalidPollyVoice) {
+		t.Errorf("expected ErrInvalidPollyVoice, got %v", err)
+	}
+}
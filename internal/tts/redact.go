@@ -0,0 +1,53 @@
+package tts
+
+import (
+	"cmp"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// DefaultRedactPlaceholder replaces redacted words when none is configured.
+const DefaultRedactPlaceholder = "bleep"
+
+// Redactor replaces configured words in text before synthesis.
+type Redactor struct {
+	pattern     *regexp.Regexp
+	placeholder string
+}
+
+// NewRedactor creates a redactor that replaces whole-word, case-insensitive
+// matches of words with placeholder. It returns nil if words is empty; a nil
+// Redactor leaves text unchanged.
+func NewRedactor(words []string, placeholder string) *Redactor {
+	var quoted []string
+	for _, w := range words {
+		if w = strings.TrimSpace(w); w != "" {
+			quoted = append(quoted, regexp.QuoteMeta(w))
+		}
+	}
+	if len(quoted) == 0 {
+		return nil
+	}
+
+	// Longest first so overlapping entries like "darn" and "darnit"
+	// redact the whole word rather than leaving a suffix behind
+	slices.SortFunc(quoted, func(a, b string) int { return cmp.Compare(len(b), len(a)) })
+
+	if placeholder == "" {
+		placeholder = DefaultRedactPlaceholder
+	}
+
+	return &Redactor{
+		pattern:     regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`),
+		placeholder: placeholder,
+	}
+}
+
+// Redact returns text with every configured word replaced by the placeholder.
+func (r *Redactor) Redact(text string) string {
+	if r == nil {
+		return text
+	}
+	return r.pattern.ReplaceAllLiteralString(text, r.placeholder)
+}
@@ -0,0 +1,57 @@
+package tts
+
+import "testing"
+
+func TestRedactor_Redact(t *testing.T) {
+	r := NewRedactor([]string{"darn", "darnit", "heck", "ass"}, "")
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"plain match", "well darn", "well bleep"},
+		{"case insensitive", "DARN it, Heck", "bleep it, bleep"},
+		{"punctuation boundaries", "(darn)! heck... 'ass'", "(bleep)! bleep... 'bleep'"},
+		{"whole words only", "class assignment in Heckmondwike", "class assignment in Heckmondwike"},
+		{"overlap prefers longest", "darnit darn", "bleep bleep"},
+		{"adjacent matches", "darn,darn", "bleep,bleep"},
+		{"no match", "all systems nominal", "all systems nominal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.Redact(tt.text); got != tt.want {
+				t.Errorf("Redact(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactor_Placeholder(t *testing.T) {
+	r := NewRedactor([]string{"secret"}, "redacted")
+
+	if got := r.Redact("the secret is out"); got != "the redacted is out" {
+		t.Errorf("Redact() = %q, want custom placeholder", got)
+	}
+}
+
+func TestNewRedactor_Empty(t *testing.T) {
+	r := NewRedactor([]string{"", "  "}, "")
+	if r != nil {
+		t.Fatal("NewRedactor() with no words should return nil")
+	}
+
+	// A nil redactor is a no-op
+	if got := r.Redact("anything"); got != "anything" {
+		t.Errorf("nil Redact() = %q, want input unchanged", got)
+	}
+}
+
+func TestRedactor_RegexMetacharacters(t *testing.T) {
+	r := NewRedactor([]string{"a.b"}, "")
+
+	if got := r.Redact("a.b axb"); got != "bleep axb" {
+		t.Errorf("Redact() = %q, want metacharacters matched literally", got)
+	}
+}
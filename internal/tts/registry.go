@@ -3,6 +3,8 @@ package tts
 import (
 	"errors"
 	"sync"
+
+	"github.com/dgnsrekt/discorgeous-go/internal/metrics"
 )
 
 var (
@@ -14,18 +16,29 @@ var (
 
 // Registry manages available TTS engines.
 type Registry struct {
-	mu      sync.RWMutex
-	engines map[string]Engine
-	def     string
+	mu        sync.RWMutex
+	engines   map[string]Engine
+	def       string
+	languages map[string]string // ISO 639-1 code -> engine name
+	metrics   metrics.Metrics
 }
 
 // NewRegistry creates a new TTS engine registry.
 func NewRegistry() *Registry {
 	return &Registry{
-		engines: make(map[string]Engine),
+		engines:   make(map[string]Engine),
+		languages: make(map[string]string),
 	}
 }
 
+// SetMetrics sets where engines registered afterwards report synthesis
+// timings and failures. If m is nil, later engines report nothing.
+func (r *Registry) SetMetrics(m metrics.Metrics) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = m
+}
+
 // Register adds an engine to the registry.
 func (r *Registry) Register(engine Engine) error {
 	r.mu.Lock()
@@ -36,6 +49,9 @@ func (r *Registry) Register(engine Engine) error {
 		return ErrEngineExists
 	}
 
+	if r.metrics != nil {
+		engine = instrument(engine, r.metrics)
+	}
 	r.engines[name] = engine
 
 	// Set as default if first engine
@@ -84,6 +100,68 @@ func (r *Registry) SetDefault(name string) error {
 	return nil
 }
 
+// SetLanguageEngine maps a language code to a registered engine name.
+func (r *Registry) SetLanguageEngine(lang, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.engines[name]; !exists {
+		return ErrEngineNotFound
+	}
+
+	r.languages[lang] = name
+	return nil
+}
+
+// ForLanguage returns the engine mapped to a language code.
+func (r *Registry) ForLanguage(lang string) (Engine, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	name, ok := r.languages[lang]
+	if !ok {
+		return nil, ErrEngineNotFound
+	}
+
+	return r.engines[name], nil
+}
+
+// Voices returns the voices accepted by the named engine, or by the
+// default engine if name is empty. ok is false if there is no such engine
+// or it doesn't list its voices.
+func (r *Registry) Voices(name string) (voices []string, ok bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if name == "" {
+		name = r.def
+	}
+	engine, exists := r.engines[name]
+	if !exists {
+		return nil, false
+	}
+
+	voices = EngineVoices(engine)
+	return voices, voices != nil
+}
+
+// MaxTextLength returns the text length limit of the named engine, or of
+// the default engine if name is empty. ok is false if there is no such
+// engine.
+func (r *Registry) MaxTextLength(name string) (limit int, ok bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if name == "" {
+		name = r.def
+	}
+	engine, exists := r.engines[name]
+	if !exists {
+		return 0, false
+	}
+	return engine.MaxTextLength(), true
+}
+
 // List returns all registered engine names.
 func (r *Registry) List() []string {
 	r.mu.RLock()
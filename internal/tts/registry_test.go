@@ -3,18 +3,29 @@ package tts
 import (
 	"context"
 	"errors"
+	"slices"
 	"testing"
+
+	"github.com/dgnsrekt/discorgeous-go/internal/metrics"
 )
 
 // mockEngine is a test implementation of Engine.
 type mockEngine struct {
-	name string
+	name    string
+	maxText int // 0 reports DefaultMaxTextLength
 }
 
 func (m *mockEngine) Name() string {
 	return m.name
 }
 
+func (m *mockEngine) MaxTextLength() int {
+	if m.maxText == 0 {
+		return DefaultMaxTextLength
+	}
+	return m.maxText
+}
+
 func (m *mockEngine) Synthesize(ctx context.Context, req SynthesizeRequest) (*AudioResult, error) {
 	return &AudioResult{
 		Data:       []byte("mock audio"),
@@ -168,3 +179,151 @@ func TestRegistry_List(t *testing.T) {
 		}
 	}
 }
+
+func TestRegistry_ForLanguage(t *testing.T) {
+	reg := NewRegistry()
+	_ = reg.Register(&mockEngine{name: "english"})
+	_ = reg.Register(&mockEngine{name: "german"})
+
+	if err := reg.SetLanguageEngine("de", "german"); err != nil {
+		t.Fatalf("SetLanguageEngine() error = %v", err)
+	}
+
+	got, err := reg.ForLanguage("de")
+	if err != nil {
+		t.Fatalf("ForLanguage() error = %v", err)
+	}
+	if got.Name() != "german" {
+		t.Errorf("ForLanguage(de) = %s, want german", got.Name())
+	}
+
+	if _, err := reg.ForLanguage("fr"); !errors.Is(err, ErrEngineNotFound) {
+		t.Errorf("ForLanguage(fr) error = %v, want ErrEngineNotFound", err)
+	}
+
+	if err := reg.SetLanguageEngine("fr", "french"); !errors.Is(err, ErrEngineNotFound) {
+		t.Errorf("SetLanguageEngine() with unknown engine error = %v, want ErrEngineNotFound", err)
+	}
+}
+
+// failingEngine always fails synthesis.
+type failingEngine struct {
+	mockEngine
+}
+
+func (f *failingEngine) Synthesize(ctx context.Context, req SynthesizeRequest) (*AudioResult, error) {
+	return nil, ErrSynthesisFailed
+}
+
+func TestRegistry_Metrics(t *testing.T) {
+	rec := metrics.NewRecorder()
+	reg := NewRegistry()
+	reg.SetMetrics(rec)
+	_ = reg.Register(&mockEngine{name: "piper"})
+	_ = reg.Register(&failingEngine{mockEngine{name: "polly"}})
+	_ = reg.Register(&streamingMockEngine{mockEngine{name: "streamer"}})
+
+	piper, _ := reg.Get("piper")
+	if _, err := piper.Synthesize(context.Background(), SynthesizeRequest{Text: "Hello"}); err != nil {
+		t.Fatalf("Synthesize() error = %v", err)
+	}
+	polly, _ := reg.Get("polly")
+	if _, err := polly.Synthesize(context.Background(), SynthesizeRequest{Text: "Hello"}); !errors.Is(err, ErrSynthesisFailed) {
+		t.Fatalf("Synthesize() error = %v, want ErrSynthesisFailed", err)
+	}
+
+	if got := rec.Observations("tts_synthesis_seconds", "engine", "piper"); len(got) != 1 {
+		t.Errorf("tts_synthesis_seconds{engine=piper} has %d observations, want 1", len(got))
+	}
+	if got := rec.CounterValue("tts_synthesis_failures_total", "engine", "polly"); got != 1 {
+		t.Errorf("tts_synthesis_failures_total{engine=polly} = %v, want 1", got)
+	}
+	if got := rec.CounterValue("tts_synthesis_failures_total", "engine", "piper"); got != 0 {
+		t.Errorf("tts_synthesis_failures_total{engine=piper} = %v, want 0", got)
+	}
+
+	streamer, _ := reg.Get("streamer")
+	if _, ok := streamer.(StreamingEngine); !ok {
+		t.Error("instrumented engine dropped StreamingEngine")
+	}
+	if streamer.Name() != "streamer" {
+		t.Errorf("Name() = %q, want streamer", streamer.Name())
+	}
+}
+
+func TestRegistry_NilMetrics(t *testing.T) {
+	reg := NewRegistry()
+	reg.SetMetrics(nil)
+	engine := &mockEngine{name: "piper"}
+	_ = reg.Register(engine)
+
+	got, _ := reg.Get("piper")
+	if got != Engine(engine) {
+		t.Error("engine was wrapped without metrics")
+	}
+}
+
+// listingEngine is a mockEngine that lists its voices.
+type listingEngine struct {
+	mockEngine
+	voices []string
+}
+
+func (l *listingEngine) Voices() []string {
+	return l.voices
+}
+
+func TestRegistry_MaxTextLength(t *testing.T) {
+	reg := NewRegistry()
+	reg.SetMetrics(metrics.NewRecorder())
+	_ = reg.Register(&mockEngine{name: "piper"})
+	_ = reg.Register(Limit(&mockEngine{name: "polly", maxText: 3000}, 1))
+
+	tests := []struct {
+		name      string
+		engine    string
+		wantLimit int
+		wantOK    bool
+	}{
+		{"default engine", "", DefaultMaxTextLength, true},
+		{"through wrappers", "polly", 3000, true},
+		{"unknown engine", "nope", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit, ok := reg.MaxTextLength(tt.engine)
+			if limit != tt.wantLimit || ok != tt.wantOK {
+				t.Errorf("MaxTextLength(%q) = %d, %v, want %d, %v", tt.engine, limit, ok, tt.wantLimit, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRegistry_Voices(t *testing.T) {
+	reg := NewRegistry()
+	reg.SetMetrics(metrics.NewRecorder())
+	_ = reg.Register(Limit(&listingEngine{mockEngine{name: "piper"}, []string{"amy", "bob"}}, 1))
+	_ = reg.Register(&mockEngine{name: "polly"})
+
+	tests := []struct {
+		name       string
+		engine     string
+		wantVoices []string
+		wantOK     bool
+	}{
+		{"default engine", "", []string{"amy", "bob"}, true},
+		{"through wrappers", "piper", []string{"amy", "bob"}, true},
+		{"engine without listing", "polly", nil, false},
+		{"unknown engine", "nope", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			voices, ok := reg.Voices(tt.engine)
+			if ok != tt.wantOK || !slices.Equal(voices, tt.wantVoices) {
+				t.Errorf("Voices(%q) = %v, %v, want %v, %v", tt.engine, voices, ok, tt.wantVoices, tt.wantOK)
+			}
+		})
+	}
+}
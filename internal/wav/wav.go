@@ -1,6 +1,14 @@
 // Package wav provides utilities for WAV audio file handling.
 package wav
 
+import (
+	"errors"
+	"time"
+)
+
+// ErrInvalid is returned when data is not a WAV file Parse understands.
+var ErrInvalid = errors.New("invalid WAV data")
+
 // WAV format constants.
 const (
 	// HeaderSize is the size of a standard WAV file header in bytes.
@@ -60,6 +68,78 @@ func WrapRawPCM(pcm []byte, sampleRate, channels, bitsPerSample int) []byte {
 	return append(header, pcm...)
 }
 
+// Format describes the audio in a WAV file.
+type Format struct {
+	AudioFormat   int // FormatPCM for uncompressed PCM
+	SampleRate    int
+	Channels      int
+	BitsPerSample int
+}
+
+// Parse walks the RIFF chunks of a WAV file, returning its format and the
+// samples in its data chunk. A data chunk claiming more bytes than remain,
+// as written by encoders that stream to stdout, is cut at the end of data.
+func Parse(data []byte) (Format, []byte, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return Format{}, nil, ErrInvalid
+	}
+
+	var format Format
+	var haveFormat bool
+	for offset := 12; offset+8 <= len(data); {
+		id := string(data[offset : offset+4])
+		size := int(le32(data[offset+4 : offset+8]))
+		body := data[offset+8:]
+		if size < 0 || size > len(body) {
+			size = len(body)
+		}
+		body = body[:size]
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return Format{}, nil, ErrInvalid
+			}
+			format = Format{
+				AudioFormat:   int(le16(body[0:2])),
+				Channels:      int(le16(body[2:4])),
+				SampleRate:    int(le32(body[4:8])),
+				BitsPerSample: int(le16(body[14:16])),
+			}
+			haveFormat = true
+		case "data":
+			if !haveFormat {
+				return Format{}, nil, ErrInvalid
+			}
+			return format, body, nil
+		}
+
+		// Chunks are padded to an even length
+		offset += 8 + size + size%2
+	}
+	return Format{}, nil, ErrInvalid
+}
+
+// Duration returns how long dataSize bytes of samples in format play for,
+// or zero if the format doesn't describe a playable stream.
+func Duration(format Format, dataSize int) time.Duration {
+	bytesPerSecond := format.SampleRate * format.Channels * format.BitsPerSample / 8
+	if bytesPerSecond <= 0 || dataSize <= 0 {
+		return 0
+	}
+	return time.Duration(dataSize) * time.Second / time.Duration(bytesPerSecond)
+}
+
+// le16 reads a little-endian uint16.
+func le16(b []byte) uint16 {
+	return uint16(b[0]) | uint16(b[1])<<8
+}
+
+// le32 reads a little-endian uint32.
+func le32(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
+}
+
 // PutLE16 writes a uint16 value in little-endian format to a byte slice.
 func PutLE16(b []byte, v uint16) {
 	b[0] = byte(v)
@@ -3,6 +3,7 @@ package wav
 import (
 	"bytes"
 	"testing"
+	"time"
 )
 
 func TestConstants(t *testing.T) {
@@ -226,3 +227,87 @@ func TestWrapRawPCM_EmptyData(t *testing.T) {
 		t.Errorf("data size = %d, want 0", dataSize)
 	}
 }
+
+func TestParse(t *testing.T) {
+	pcm := []byte{1, 2, 3, 4, 5, 6}
+	format, samples, err := Parse(WrapRawPCM(pcm, 22050, 1, 16))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	want := Format{AudioFormat: FormatPCM, SampleRate: 22050, Channels: 1, BitsPerSample: 16}
+	if format != want {
+		t.Errorf("Parse() format = %+v, want %+v", format, want)
+	}
+	if !bytes.Equal(samples, pcm) {
+		t.Errorf("Parse() samples = %v, want %v", samples, pcm)
+	}
+}
+
+func TestParse_StreamedSize(t *testing.T) {
+	// Streaming encoders write a placeholder size they can't seek back to fix
+	data := WrapRawPCM([]byte{1, 2, 3, 4}, 22050, 1, 16)
+	PutLE32(data[40:44], 0xFFFFFFFF)
+
+	_, samples, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(samples) != 4 {
+		t.Errorf("Parse() samples length = %d, want 4", len(samples))
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"not riff", []byte("RIFX\x00\x00\x00\x00WAVEfmt ")},
+		{"no data chunk", WrapRawPCM(nil, 22050, 1, 16)[:36]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := Parse(tt.data); err != ErrInvalid {
+				t.Errorf("Parse() error = %v, want ErrInvalid", err)
+			}
+		})
+	}
+}
+
+func TestDuration(t *testing.T) {
+	piper := Format{AudioFormat: FormatPCM, SampleRate: PiperSampleRate, Channels: PiperChannels, BitsPerSample: PiperBitsPerSample}
+
+	tests := []struct {
+		name     string
+		format   Format
+		dataSize int
+		want     time.Duration
+	}{
+		{"piper one second", piper, 22050 * 2, time.Second},
+		{"piper half second", piper, 11025 * 2, 500 * time.Millisecond},
+		{"discord 20ms frame", Format{SampleRate: 48000, Channels: 2, BitsPerSample: 16}, 3840, 20 * time.Millisecond},
+		{"16kHz mono 250ms", Format{SampleRate: 16000, Channels: 1, BitsPerSample: 16}, 8000, 250 * time.Millisecond},
+		{"no data", piper, 0, 0},
+		{"unknown format", Format{}, 1000, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Duration(tt.format, tt.dataSize); got != tt.want {
+				t.Errorf("Duration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Round trip through a generated file
+	format, pcm, err := Parse(CreateMinimalPiper(44100))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got := Duration(format, len(pcm)); got != 2*time.Second {
+		t.Errorf("Duration(2s Piper WAV) = %v, want 2s", got)
+	}
+}
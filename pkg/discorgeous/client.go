@@ -0,0 +1,151 @@
+// Package discorgeous is a Go client for the Discorgeous HTTP API, and
+// holds the request and response types the server itself uses.
+package discorgeous
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Errors matched by errors.Is against an *APIError with the corresponding
+// status.
+var (
+	// ErrBadRequest means the request was invalid (400).
+	ErrBadRequest = errors.New("bad request")
+	// ErrUnauthorized means the token was missing or wrong (401).
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrConflict means a job with the same dedupe key is queued (409).
+	ErrConflict = errors.New("duplicate job")
+	// ErrUnavailable means the queue is full or a dependency is not
+	// configured (503).
+	ErrUnavailable = errors.New("service unavailable")
+)
+
+// statusErrors maps statuses to the sentinel errors above.
+var statusErrors = map[int]error{
+	http.StatusBadRequest:         ErrBadRequest,
+	http.StatusUnauthorized:       ErrUnauthorized,
+	http.StatusConflict:           ErrConflict,
+	http.StatusServiceUnavailable: ErrUnavailable,
+}
+
+// APIError is returned when Discorgeous answers with a non-2xx status.
+type APIError struct {
+	StatusCode int
+	// Message is the server's error message, or the raw body if it was
+	// not an ErrorResponse.
+	Message string
+	// Header holds the response headers, such as Retry-After.
+	Header http.Header
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Message)
+}
+
+// Unwrap returns the sentinel error for the status, if there is one.
+func (e *APIError) Unwrap() error {
+	return statusErrors[e.StatusCode]
+}
+
+// Client calls the Discorgeous API.
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// New creates a client for the API at baseURL. If token is set, requests
+// carry it as a bearer token.
+func New(baseURL, token string) *Client {
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// SetHTTPClient replaces the HTTP client used for requests, for example
+// to change the timeout or transport. The default has a 30s timeout.
+func (c *Client) SetHTTPClient(hc *http.Client) {
+	c.httpClient = hc
+}
+
+// Health reports the server's status with GET /v1/healthz.
+func (c *Client) Health(ctx context.Context) (HealthResponse, error) {
+	var resp HealthResponse
+	err := c.do(ctx, http.MethodGet, "/v1/healthz", nil, &resp)
+	return resp, err
+}
+
+// Interrupt stops the current playback and clears the queue with
+// POST /v1/interrupt.
+func (c *Client) Interrupt(ctx context.Context) (InterruptResponse, error) {
+	var resp InterruptResponse
+	err := c.do(ctx, http.MethodPost, "/v1/interrupt", nil, &resp)
+	return resp, err
+}
+
+// Speak queues req with POST /v1/speak.
+func (c *Client) Speak(ctx context.Context, req SpeakRequest) (SpeakResponse, error) {
+	var resp SpeakResponse
+	err := c.do(ctx, http.MethodPost, "/v1/speak", req, &resp)
+	return resp, err
+}
+
+// do sends a request with body encoded as JSON, if set, and decodes a
+// successful response into out, if set. A non-2xx status returns *APIError.
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		message := strings.TrimSpace(string(respBody))
+		var errResp ErrorResponse
+		if json.Unmarshal(respBody, &errResp) == nil && errResp.Error != "" {
+			message = errResp.Error
+		}
+		return &APIError{StatusCode: resp.StatusCode, Message: message, Header: resp.Header}
+	}
+
+	if out != nil {
+		// An empty body leaves out at its zero value
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil && err != io.EOF {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return nil
+}
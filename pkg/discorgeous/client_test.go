@@ -0,0 +1,247 @@
+package discorgeous
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSpeak(t *testing.T) {
+	var gotReq SpeakRequest
+	var gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/speak" {
+			t.Errorf("request = %s %s, want POST /v1/speak", r.Method, r.URL.Path)
+		}
+		gotAuth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&gotReq)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(SpeakResponse{JobID: "job-1", Message: "job enqueued", QueuePosition: 2})
+	}))
+	defer server.Close()
+
+	client := New(server.URL+"/", "secret")
+	resp, err := client.Speak(context.Background(), SpeakRequest{Text: "Hello", Voice: "amy"})
+	if err != nil {
+		t.Fatalf("Speak() error = %v", err)
+	}
+
+	if gotAuth != "Bearer secret" {
+		t.Errorf("Authorization = %q, want %q", gotAuth, "Bearer secret")
+	}
+	if gotReq.Text != "Hello" || gotReq.Voice != "amy" {
+		t.Errorf("request = %+v, want text Hello and voice amy", gotReq)
+	}
+	if resp.JobID != "job-1" || resp.QueuePosition != 2 {
+		t.Errorf("response = %+v, want job-1 at position 2", resp)
+	}
+}
+
+func TestSpeakNoToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			t.Errorf("Authorization = %q, want none", auth)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	if _, err := New(server.URL, "").Speak(context.Background(), SpeakRequest{Text: "Hello"}); err != nil {
+		t.Errorf("Speak() error = %v", err)
+	}
+}
+
+func TestSpeakAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "5")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "queue is full"})
+	}))
+	defer server.Close()
+
+	_, err := New(server.URL, "").Speak(context.Background(), SpeakRequest{Text: "Hello"})
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Speak() error = %v, want *APIError", err)
+	}
+	if apiErr.StatusCode != http.StatusServiceUnavailable || apiErr.Message != "queue is full" {
+		t.Errorf("APIError = %d %q, want 503 %q", apiErr.StatusCode, apiErr.Message, "queue is full")
+	}
+	if got := apiErr.Header.Get("Retry-After"); got != "5" {
+		t.Errorf("Retry-After = %q, want 5", got)
+	}
+}
+
+func TestSpeakNonJSONError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	_, err := New(server.URL, "").Speak(context.Background(), SpeakRequest{Text: "Hello"})
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Message != "bad gateway" {
+		t.Errorf("Speak() error = %v, want APIError with the raw body", err)
+	}
+}
+
+func TestSpeakTypedErrors(t *testing.T) {
+	tests := []struct {
+		status  int
+		message string
+		want    error
+	}{
+		{http.StatusBadRequest, "text is required", ErrBadRequest},
+		{http.StatusUnauthorized, "invalid bearer token", ErrUnauthorized},
+		{http.StatusConflict, "duplicate job", ErrConflict},
+		{http.StatusServiceUnavailable, "queue is full", ErrUnavailable},
+		{http.StatusInternalServerError, "internal error", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				json.NewEncoder(w).Encode(ErrorResponse{Error: tt.message})
+			}))
+			defer server.Close()
+
+			_, err := New(server.URL, "test-token").Speak(context.Background(), SpeakRequest{Text: "Hello"})
+			if err == nil {
+				t.Fatal("Speak() error = nil, want error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("Speak() error = %v, want errors.Is %v", err, tt.want)
+			}
+			for _, sentinel := range []error{ErrBadRequest, ErrUnauthorized, ErrConflict, ErrUnavailable} {
+				if sentinel != tt.want && errors.Is(err, sentinel) {
+					t.Errorf("Speak() error unexpectedly matches %v", sentinel)
+				}
+			}
+		})
+	}
+}
+
+func TestSpeakContextCancelled(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer server.Close()
+	// Release the handler before Close waits on it
+	defer close(done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := New(server.URL, "").Speak(ctx, SpeakRequest{Text: "Hello"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Speak() error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/v1/healthz" {
+			t.Errorf("request = %s %s, want GET /v1/healthz", r.Method, r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(HealthResponse{Status: "ok", JoinedOnStart: true})
+	}))
+	defer server.Close()
+
+	resp, err := New(server.URL, "").Health(context.Background())
+	if err != nil {
+		t.Fatalf("Health() error = %v", err)
+	}
+	if resp.Status != "ok" || !resp.JoinedOnStart {
+		t.Errorf("Health() = %+v, want ok and joined on start", resp)
+	}
+}
+
+func TestInterrupt(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/interrupt" {
+			t.Errorf("request = %s %s, want POST /v1/interrupt", r.Method, r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer test-token" {
+			t.Errorf("Authorization = %q, want %q", auth, "Bearer test-token")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(InterruptResponse{Cleared: 3})
+	}))
+	defer server.Close()
+
+	resp, err := New(server.URL, "test-token").Interrupt(context.Background())
+	if err != nil {
+		t.Fatalf("Interrupt() error = %v", err)
+	}
+	if resp.Cleared != 3 {
+		t.Errorf("Interrupt() cleared = %d, want 3", resp.Cleared)
+	}
+}
+
+// roundTripFunc adapts a function to http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestSetHTTPClient(t *testing.T) {
+	var called bool
+	client := New("http://discorgeous.invalid", "")
+	client.SetHTTPClient(&http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		called = true
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"status":"ok"}`)),
+		}, nil
+	})})
+
+	resp, err := client.Health(context.Background())
+	if err != nil {
+		t.Fatalf("Health() error = %v", err)
+	}
+	if !called || resp.Status != "ok" {
+		t.Errorf("custom client called = %v, status = %q", called, resp.Status)
+	}
+}
+
+func TestSpeakRequestTTLEncoding(t *testing.T) {
+	zero, positive := 0, 5000
+	tests := []struct {
+		name string
+		ttl  *int
+		want string
+	}{
+		{"omitted", nil, `{"text":"Hi"}`},
+		{"no TTL", &zero, `{"text":"Hi","ttl_ms":0}`},
+		{"positive", &positive, `{"text":"Hi","ttl_ms":5000}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(SpeakRequest{Text: "Hi", TTLMS: tt.ttl})
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Marshal() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package discorgeous
+
+// What a speak request does when a message with its dedupe key is already
+// queued, for SpeakRequest.OnDuplicate.
+const (
+	// OnDuplicateReject rejects the request with a 409. It is the default.
+	OnDuplicateReject = "reject"
+	// OnDuplicateReplace puts the request in the queued message's place.
+	OnDuplicateReplace = "replace"
+)
+
+// SpeakRequest represents the request body for /v1/speak.
+type SpeakRequest struct {
+	Text      string `json:"text"`
+	Voice     string `json:"voice,omitempty"`
+	Interrupt bool   `json:"interrupt,omitempty"`
+	DedupeKey string `json:"dedupe_key,omitempty"`
+	// OnDuplicate is OnDuplicateReject or OnDuplicateReplace; empty means
+	// reject.
+	OnDuplicate string `json:"on_duplicate,omitempty"`
+	// Language is a BCP-47 language hint such as "en-US" for engines that
+	// need one; empty uses the server's DEFAULT_LANGUAGE.
+	Language string `json:"language,omitempty"`
+	// Locale is how numbers, percentages and currency amounts in the text
+	// are written, such as "de-DE" for "1.000,50 €", when the server
+	// normalizes text; empty means en-US.
+	Locale string `json:"locale,omitempty"`
+	// TTLMS is the job TTL in milliseconds; 0 means no TTL, and nil uses
+	// the default TTL.
+	TTLMS *int `json:"ttl_ms,omitempty"`
+	// Chime overrides whether the notification chime plays; nil uses the default.
+	Chime *bool `json:"chime,omitempty"`
+	// Intro and Outro are optional lines spoken before and after the text.
+	Intro string `json:"intro,omitempty"`
+	Outro string `json:"outro,omitempty"`
+	// MaxSeconds caps playback length for this request; it cannot exceed
+	// the server's MAX_AUDIO_SECONDS.
+	MaxSeconds int `json:"max_seconds,omitempty"`
+	// Urgent interrupts and plays the message next even if the queue is
+	// full. It requires the admin scope.
+	Urgent bool `json:"urgent,omitempty"`
+	// ChannelID is the voice channel to speak in. It must be the default
+	// channel or one of the server's ALLOWED_CHANNELS; empty uses the
+	// default, or the followed user's channel.
+	ChannelID string `json:"channel_id,omitempty"`
+}
+
+// SpeakResponse represents the response body for /v1/speak.
+type SpeakResponse struct {
+	JobID   string `json:"job_id"`
+	Message string `json:"message"`
+	// QueuePosition is the job's 1-based place in the queue at enqueue time.
+	QueuePosition int `json:"queue_position,omitempty"`
+	// ReplacedJobID is the queued job this one replaced, with
+	// OnDuplicateReplace.
+	ReplacedJobID string `json:"replaced_job_id,omitempty"`
+}
+
+// ErrorResponse represents an error response.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// HealthResponse represents the response body for /v1/healthz.
+type HealthResponse struct {
+	Status string `json:"status"`
+	// JoinedOnStart is true if JOIN_ON_START joined voice at startup.
+	JoinedOnStart bool `json:"joined_on_start,omitempty"`
+}
+
+// InterruptResponse represents the response body for POST /v1/interrupt.
+type InterruptResponse struct {
+	Cleared      int  `json:"cleared"`
+	Disconnected bool `json:"disconnected,omitempty"`
+}